@@ -59,6 +59,7 @@ func AdditionalAPIs(ctx context.Context, config *wrangler.Context, steve *steve.
 	if features.UIExtension.Enabled() {
 		catalog.RegisterUIPluginHandlers(mux)
 	}
+	catalog.RegisterRepoWebhookHandler(mux, config.Catalog.ClusterRepo(), config.Core.Secret().Cache())
 	mux.Handle("/v1/github{path:.*}", githubHandler)
 	mux.Handle("/v3/connect", Tunnel(config))
 
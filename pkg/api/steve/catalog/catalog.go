@@ -18,6 +18,7 @@
 	"github.com/rancher/apiserver/pkg/types"
 	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
 	"github.com/rancher/rancher/pkg/apis/catalog.cattle.io"
+	catalogv1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/catalogv2/content"
 	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	schema2 "github.com/rancher/steve/pkg/schema"
@@ -63,6 +64,7 @@ func addSchemas(server *steve.Server, ops *operation, index http.Handler) {
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartInstallAction{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartInstall{}, nil)
 	server.BaseSchemas.MustImportAndCustomize(types2.ChartActionOutput{}, nil)
+	server.BaseSchemas.MustImportAndCustomize(types2.ChartDiffOutput{}, nil)
 
 	operationTemplate := schema2.Template{
 		Group: catalog.GroupName,
@@ -70,11 +72,25 @@ func addSchemas(server *steve.Server, ops *operation, index http.Handler) {
 		Customize: func(apiSchema *types.APISchema) {
 			apiSchema.LinkHandlers = map[string]http.Handler{
 				"logs": ops,
+				"diff": ops,
+			}
+			apiSchema.ActionHandlers = map[string]http.Handler{
+				"cancel": ops,
+			}
+			apiSchema.ResourceActions = map[string]schemas3.Action{
+				"cancel": {},
 			}
 			apiSchema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
 				if !resource.APIObject.Data().Bool("status", "podCreated") {
 					delete(resource.Links, "logs")
 				}
+				if !resource.APIObject.Data().Bool("status", "dryRun") {
+					delete(resource.Links, "diff")
+				}
+				// only operations waiting in the queue can be cancelled
+				if resource.APIObject.Data().String("status", "queue", "state") != catalogv1.OperationQueued {
+					delete(resource.Actions, "cancel")
+				}
 			}
 		},
 	}
@@ -8,9 +8,11 @@
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
 	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
 	"k8s.io/apimachinery/pkg/runtime"
 	schema2 "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
 )
 
@@ -47,7 +49,8 @@ func newOperation(
 // For example, if the api request is for installing a chart, then it will call the
 // install function of the Operation struct.
 //
-// All chart actions (install, upgrade, and uninstall) are served through this method.
+// All chart actions (install, upgrade, and uninstall) are served through this method, as well as the cancellation of
+// queued operations.
 func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	// Get the APIContext from the current request's context. This APIContext
 	// encapsulates the details of the API request, which will be used to
@@ -73,12 +76,16 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		op, err = o.ops.Upgrade(apiRequest.Context(), user, ns, name, req.Body, o.imageOverride)
 	case "uninstall":
 		op, err = o.ops.Uninstall(apiRequest.Context(), user, ns, name, req.Body, o.imageOverride)
+	case "cancel":
+		err = o.cancel(apiRequest, user)
 	}
 
 	switch apiRequest.Link {
 	case "logs":
 		err = o.ops.Log(apiRequest.Response, apiRequest.Request,
 			apiRequest.Namespace, apiRequest.Name)
+	case "diff":
+		err = o.diff(apiRequest)
 	}
 
 	if err != nil {
@@ -99,6 +106,46 @@ func (o *operation) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	})
 }
 
+// diff writes the changes to the resources of the releases computed by a dry-run operation. The operation is
+// retrieved through the store of its schema, so that it is only served to users allowed to get it.
+func (o *operation) diff(apiRequest *types.APIRequest) error {
+	obj, err := apiRequest.Schema.Store.ByID(apiRequest, apiRequest.Schema, apiRequest.Name)
+	if err != nil {
+		return err
+	}
+
+	status := catalog.OperationStatus{}
+	if err := convert.ToObj(obj.Data().Map("status"), &status); err != nil {
+		return err
+	}
+	if !status.DryRun {
+		return validation.NotFound
+	}
+
+	apiRequest.WriteResponse(http.StatusOK, types.APIObject{
+		Type: "chartDiffOutput",
+		Object: &catalogtypes.ChartDiffOutput{
+			Resources: status.Diff,
+		},
+	})
+	return nil
+}
+
+// cancel removes a queued operation from the queue of the operations waiting to run. The operation is retrieved
+// through the store of its schema, so that only users allowed to get it can cancel it. Users who didn't request the
+// operation must also be allowed to update operations.
+func (o *operation) cancel(apiRequest *types.APIRequest, user user.Info) error {
+	if _, err := apiRequest.Schema.Store.ByID(apiRequest, apiRequest.Schema, apiRequest.Name); err != nil {
+		return err
+	}
+	canUpdate := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema) == nil
+	if err := o.ops.Cancel(user, apiRequest.Namespace, apiRequest.Name, canUpdate); err != nil {
+		return err
+	}
+	apiRequest.Response.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 // OnAdd is registered as a callback of a Kubernetes Informer.
 // It is invoked when a new object is added to the Kubernetes cluster.
 // It purges old roles related to the object being added.
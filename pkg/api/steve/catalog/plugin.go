@@ -51,6 +51,13 @@ func pluginHandler(w http.ResponseWriter, r *http.Request) {
 		logrus.Debug(msg)
 		return
 	}
+	if !plugin.Verified(entry) {
+		// the files of plugins with pinned integrity are never proxied, they are served once verified and cached
+		msg := fmt.Sprintf("plugin [name: %s version: %s] is not verified", vars["name"], vars["version"])
+		http.Error(w, msg, http.StatusServiceUnavailable)
+		logrus.Debug(msg)
+		return
+	}
 	if entry.NoCache || entry.CacheState == plugin.Pending {
 		logrus.Debugf("[noCache: %v] proxying request to [endpoint: %v]\n", entry.NoCache, entry.Endpoint)
 		proxyRequest(entry.Endpoint, vars["rest"], w, r, denylist)
@@ -5,6 +5,10 @@
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/gorilla/mux"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/controllers/dashboard/plugin"
 )
 
 func TestProxyRequest_content_type(t *testing.T) {
@@ -38,3 +42,39 @@ func TestProxyRequest_content_type(t *testing.T) {
 		t.Errorf("read body: %s", body)
 	}
 }
+
+func TestPluginHandler_unverified(t *testing.T) {
+	proxied := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+	}))
+	defer ts.Close()
+
+	entries := plugin.Index.Entries
+	defer func() { plugin.Index.Entries = entries }()
+	plugin.Index.Entries = map[string]*plugin.UIPlugin{
+		"pinned": {
+			UIPluginEntry: &v1.UIPluginEntry{
+				Name:      "pinned",
+				Version:   "0.1.0",
+				Endpoint:  ts.URL,
+				NoAuth:    true,
+				Integrity: &v1.UIPluginIntegrity{ManifestDigest: "sha384-digest"},
+			},
+			CacheState: plugin.Pending,
+		},
+	}
+	router := mux.NewRouter()
+	RegisterUIPluginHandlers(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/uiplugins/pinned/0.1.0/plugin/index.js", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got StatusCode %v, want %v", w.Code, http.StatusServiceUnavailable)
+	}
+	if proxied {
+		t.Error("the request for the files of an unverified plugin was proxied to its endpoint")
+	}
+}
@@ -13,6 +13,7 @@
   - ChartUpgradeAction: Describes the configuration for an upgrade action.
   - ChartUpgrade: Represents a Helm chart upgrade request.
   - ChartActionOutput: Represents the output after performing a Helm chart action.
+  - ChartDiffOutput: Represents the changes computed by a dry-run Helm chart action.
 
 Each type includes fields that map directly to properties of Helm chart operations,
 allowing for a structured approach to managing Helm charts through the API.
@@ -20,6 +21,7 @@
 package types
 
 import (
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,6 +47,10 @@ type ChartInstallAction struct {
 	OperationTolerations     []corev1.Toleration `json:"operationTolerations,omitempty"`
 	AutomaticCPTolerations   bool                `json:"automaticCPTolerations,omitempty"`
 	Charts                   []ChartInstall      `json:"charts,omitempty"`
+	// DryRun renders the charts with a server-side helm dry-run and reports the changes to the resources of the
+	// releases in the status of the operation, without installing them. The namespace must exist, as it isn't created,
+	// and the charts providing the CRDs of the main chart must be installed.
+	DryRun bool `json:"dryRun,omitempty"`
 }
 
 type ChartInfo struct {
@@ -81,6 +87,13 @@ type ChartUpgradeAction struct {
 	Charts                   []ChartUpgrade      `json:"charts,omitempty"`
 	OperationTolerations     []corev1.Toleration `json:"operationTolerations,omitempty"`
 	AutomaticCPTolerations   bool                `json:"automaticCPTolerations,omitempty"`
+	// DryRun renders the charts with a server-side helm dry-run and reports the changes to the resources of the
+	// releases in the status of the operation, without upgrading them. The charts providing the CRDs of the main chart
+	// must be installed.
+	DryRun bool `json:"dryRun,omitempty"`
+	// HealthCheckTimeout enables the verification of the health of the upgraded release. Once helm completed, its
+	// workloads must be rolled out within the timeout, otherwise the release is rolled back to its previous revision.
+	HealthCheckTimeout *metav1.Duration `json:"healthCheckTimeout,omitempty"`
 }
 
 type ChartUpgrade struct {
@@ -98,3 +111,8 @@ type ChartActionOutput struct {
 	OperationName      string `json:"operationName,omitempty"`
 	OperationNamespace string `json:"operationNamespace,omitempty"`
 }
+
+// ChartDiffOutput represents the changes to the resources of the releases computed by a dry-run install or upgrade
+type ChartDiffOutput struct {
+	Resources []catalog.ResourceDiff `json:"resources,omitempty"`
+}
@@ -0,0 +1,456 @@
+package catalog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/registry"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// RepoWebhookPath is the path of the endpoint receiving the push notifications refreshing ClusterRepos.
+	RepoWebhookPath = "/v1-catalog/webhook"
+	// repoWebhookSecretKey is the key of the secret shared with the sender of the notifications in the webhook secret.
+	repoWebhookSecretKey = "secret"
+	// maxWebhookPayloadSize is the maximum size of the payload of a notification.
+	maxWebhookPayloadSize = 1024 * 1024
+
+	// failedDeliveryInterval is the minimum interval between the deliveries recorded in the status of a ClusterRepo
+	// for notifications which failed to be authenticated, as anyone can send them.
+	failedDeliveryInterval = time.Minute
+	// rejectedMessage is the response to the notifications which don't refresh any ClusterRepo, whether they match no
+	// ClusterRepo or failed to be authenticated, so that the response doesn't disclose the configured repositories.
+	rejectedMessage = "the notification was rejected"
+
+	distributionEventsMediaType = "application/vnd.docker.distribution.events.v1+json"
+	// harborProvider is the value of the provider query parameter of Harbor notifications, which carry no header
+	// identifying them.
+	harborProvider = "harbor"
+)
+
+// repoWebhook refreshes the ClusterRepos whose git repository or OCI registry notifies a push.
+type repoWebhook struct {
+	clusterRepos catalogcontrollers.ClusterRepoController
+	secrets      corev1controllers.SecretCache
+}
+
+// RegisterRepoWebhookHandler registers the endpoint receiving the push notifications refreshing ClusterRepos. The
+// endpoint is not authenticated: each notification is authenticated with the webhook secret of the ClusterRepos it
+// matches.
+func RegisterRepoWebhookHandler(router *mux.Router, clusterRepos catalogcontrollers.ClusterRepoController, secrets corev1controllers.SecretCache) {
+	router.Handle(RepoWebhookPath, &repoWebhook{
+		clusterRepos: clusterRepos,
+		secrets:      secrets,
+	})
+}
+
+func (h *repoWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookPayloadSize {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	p, err := parsePush(r.URL.Query().Get("provider"), r.Header, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p == nil {
+		// Pings and events which aren't pushes of a branch or an artifact are acknowledged without refreshing anything.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	repos, err := h.clusterRepos.Cache().List(labels.Everything())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	refreshed := 0
+	for _, repo := range repos {
+		if repo.Spec.Webhook == nil || !p.matches(repo) {
+			continue
+		}
+		if h.deliver(repo, p) {
+			refreshed++
+		}
+	}
+
+	if refreshed == 0 {
+		http.Error(w, rejectedMessage, http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// deliver refreshes the ClusterRepo if the notification is authenticated with its webhook secret, and records the
+// delivery in its status. Notifications which failed to be authenticated are recorded at most once per
+// failedDeliveryInterval, so that unauthenticated senders can't flood the ClusterRepo with status updates. It returns
+// whether the ClusterRepo was refreshed.
+func (h *repoWebhook) deliver(repo *catalog.ClusterRepo, p *push) bool {
+	delivery := &catalog.RepoWebhookDelivery{
+		Time:     metav1.Now(),
+		Provider: p.provider,
+		Event:    p.event,
+	}
+
+	secret, err := catalogv2.GetSecret(h.secrets, &catalog.RepoSpec{ClientSecret: &repo.Spec.Webhook.Secret}, "")
+	switch {
+	case err != nil:
+		delivery.Message = fmt.Sprintf("failed to get the webhook secret: %s", apierrors.ReasonForError(err))
+	case len(secret.Data[repoWebhookSecretKey]) == 0:
+		delivery.Message = fmt.Sprintf("the webhook secret has no %q key", repoWebhookSecretKey)
+	case !p.authenticate(secret.Data[repoWebhookSecretKey]):
+		delivery.Message = "the notification failed to be authenticated"
+	default:
+		delivery.Accepted = true
+		if err := h.refresh(repo.Name); err != nil {
+			delivery.Accepted = false
+			delivery.Message = fmt.Sprintf("failed to refresh the repository: %v", err)
+		}
+		h.recordDelivery(repo.Name, delivery)
+		return delivery.Accepted
+	}
+
+	if last := repo.Status.WebhookDelivery; last == nil || delivery.Time.Sub(last.Time.Time) >= failedDeliveryInterval {
+		h.recordDelivery(repo.Name, delivery)
+	}
+	return false
+}
+
+// recordDelivery records the delivery in the status of the ClusterRepo.
+func (h *repoWebhook) recordDelivery(name string, delivery *catalog.RepoWebhookDelivery) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		repo, err := h.clusterRepos.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		repo.Status.WebhookDelivery = delivery
+		_, err = h.clusterRepos.UpdateStatus(repo)
+		return err
+	})
+	if err != nil {
+		logrus.Errorf("Failed to record the webhook delivery of clusterrepo %s: %v", name, err)
+	}
+}
+
+// refresh forces the ClusterRepo to download its index.
+func (h *repoWebhook) refresh(name string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		repo, err := h.clusterRepos.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		now := metav1.Now()
+		repo.Spec.ForceUpdate = &now
+		_, err = h.clusterRepos.Update(repo)
+		return err
+	})
+}
+
+// push is a push notification parsed from the payload of one of the supported providers.
+type push struct {
+	provider string
+	// event is the pushed git reference or OCI artifact.
+	event string
+
+	// gitURLs are the normalized URLs of the git repository pushed to, branch the pushed branch and defaultBranch the
+	// default branch of the git repository, if known.
+	gitURLs       []string
+	branch        string
+	defaultBranch string
+
+	// host is the OCI registry and repositories the OCI repositories pushed to.
+	host         string
+	repositories []string
+
+	// authenticate returns whether the notification was sent with the secret.
+	authenticate func(secret []byte) bool
+}
+
+type gitRepository struct {
+	CloneURL      string `json:"clone_url"`
+	HTMLURL       string `json:"html_url"`
+	SSHURL        string `json:"ssh_url"`
+	GitURL        string `json:"git_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type gitPushPayload struct {
+	Ref        string        `json:"ref"`
+	Repository gitRepository `json:"repository"`
+	Project    struct {
+		GitHTTPURL    string `json:"git_http_url"`
+		GitSSHURL     string `json:"git_ssh_url"`
+		WebURL        string `json:"web_url"`
+		DefaultBranch string `json:"default_branch"`
+	} `json:"project"`
+}
+
+type harborPayload struct {
+	Type      string `json:"type"`
+	EventData *struct {
+		Resources []struct {
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+type distributionPayload struct {
+	Events []struct {
+		Action string `json:"action"`
+		Target struct {
+			Repository string `json:"repository"`
+			URL        string `json:"url"`
+			Tag        string `json:"tag"`
+		} `json:"target"`
+		Request struct {
+			Host string `json:"host"`
+		} `json:"request"`
+	} `json:"events"`
+}
+
+// parsePush parses the notification sent by GitHub, GitLab, Gitea, Harbor or a distribution registry. The provider is
+// identified by the headers of the notification, or by the provider query parameter for Harbor. It returns nil if the
+// notification isn't a push of a branch or an OCI artifact.
+func parsePush(provider string, header http.Header, body []byte) (*push, error) {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+
+	switch {
+	// Gitea also sets the GitHub event header, so it is checked first.
+	case header.Get("X-Gitea-Event") != "":
+		if header.Get("X-Gitea-Event") != "push" {
+			return nil, nil
+		}
+		return parseGitPush("gitea", body, hmacAuthenticator(body, header.Get("X-Gitea-Signature"), ""))
+	case header.Get("X-GitHub-Event") != "":
+		if header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+		return parseGitPush("github", body, hmacAuthenticator(body, header.Get("X-Hub-Signature-256"), "sha256="))
+	case header.Get("X-Gitlab-Event") != "":
+		if header.Get("X-Gitlab-Event") != "Push Hook" {
+			return nil, nil
+		}
+		return parseGitPush("gitlab", body, tokenAuthenticator(header.Get("X-Gitlab-Token")))
+	case mediaType == distributionEventsMediaType:
+		return parseDistributionPush(body, tokenAuthenticator(header.Get("Authorization")))
+	case provider == harborProvider:
+		return parseHarborPush(body, tokenAuthenticator(header.Get("Authorization")))
+	default:
+		return nil, errors.New("unsupported notification")
+	}
+}
+
+func parseGitPush(provider string, body []byte, authenticate func([]byte) bool) (*push, error) {
+	var payload gitPushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s push event: %w", provider, err)
+	}
+	branch, ok := strings.CutPrefix(payload.Ref, "refs/heads/")
+	if !ok {
+		// Tags are not branches a ClusterRepo can follow.
+		return nil, nil
+	}
+
+	p := &push{
+		provider:      provider,
+		event:         payload.Ref,
+		branch:        branch,
+		defaultBranch: payload.Repository.DefaultBranch,
+		authenticate:  authenticate,
+	}
+	urls := []string{payload.Repository.CloneURL, payload.Repository.HTMLURL, payload.Repository.SSHURL, payload.Repository.GitURL}
+	if provider == "gitlab" {
+		urls = []string{payload.Project.GitHTTPURL, payload.Project.GitSSHURL, payload.Project.WebURL}
+		p.defaultBranch = payload.Project.DefaultBranch
+	}
+	for _, u := range urls {
+		if u != "" {
+			p.gitURLs = append(p.gitURLs, normalizeGitURL(u))
+		}
+	}
+	if len(p.gitURLs) == 0 {
+		return nil, fmt.Errorf("the %s push event has no repository URL", provider)
+	}
+	return p, nil
+}
+
+func parseHarborPush(body []byte, authenticate func([]byte) bool) (*push, error) {
+	var payload harborPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Type == "" || payload.EventData == nil {
+		return nil, errors.New("invalid harbor notification")
+	}
+	if payload.Type != "PUSH_ARTIFACT" || len(payload.EventData.Resources) == 0 {
+		return nil, nil
+	}
+
+	resource := payload.EventData.Resources[0]
+	host, _, _ := strings.Cut(resource.ResourceURL, "/")
+	p := &push{
+		provider:     "harbor",
+		event:        payload.EventData.Repository.RepoFullName,
+		host:         strings.ToLower(host),
+		repositories: []string{payload.EventData.Repository.RepoFullName},
+		authenticate: authenticate,
+	}
+	if resource.Tag != "" {
+		p.event += ":" + resource.Tag
+	}
+	return p, nil
+}
+
+func parseDistributionPush(body []byte, authenticate func([]byte) bool) (*push, error) {
+	var payload distributionPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid distribution notification: %w", err)
+	}
+
+	var p *push
+	for _, event := range payload.Events {
+		if event.Action != "push" || event.Target.Repository == "" {
+			continue
+		}
+		host := event.Request.Host
+		if u, err := url.Parse(event.Target.URL); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		if p == nil {
+			p = &push{
+				provider:     "distribution",
+				event:        event.Target.Repository,
+				host:         strings.ToLower(host),
+				authenticate: authenticate,
+			}
+			if event.Target.Tag != "" {
+				p.event += ":" + event.Target.Tag
+			}
+		}
+		p.repositories = append(p.repositories, event.Target.Repository)
+	}
+	return p, nil
+}
+
+// matches returns whether the push is a push to the git repository and branch, or to the OCI repositories, of the
+// ClusterRepo.
+func (p *push) matches(repo *catalog.ClusterRepo) bool {
+	if len(p.gitURLs) > 0 {
+		if repo.Spec.GitRepo == "" {
+			return false
+		}
+		branch := repo.Spec.GitBranch
+		if branch == "" {
+			branch = p.defaultBranch
+		}
+		if branch != "" && branch != p.branch {
+			return false
+		}
+		gitURL := normalizeGitURL(repo.Spec.GitRepo)
+		for _, u := range p.gitURLs {
+			if u == gitURL {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !registry.IsOCI(repo.Spec.URL) {
+		return false
+	}
+	host, path, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(repo.Spec.URL, "oci://"), "/"), "/")
+	if !strings.EqualFold(host, p.host) {
+		return false
+	}
+	// The URL of a ClusterRepo can reference a single tag of a chart.
+	if i := strings.LastIndex(path, ":"); i > strings.LastIndex(path, "/") {
+		path = path[:i]
+	}
+	for _, repository := range p.repositories {
+		if path == "" || repository == path || strings.HasPrefix(repository, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeGitURL returns the host and path of a git repository URL, so that its HTTP and SSH URLs can be compared.
+func normalizeGitURL(gitURL string) string {
+	u := strings.ToLower(strings.TrimSpace(gitURL))
+	scheme := false
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+		scheme = true
+	}
+	host, path, _ := strings.Cut(u, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if scheme {
+		host, _, _ = strings.Cut(host, ":")
+	} else if h, p, ok := strings.Cut(host, ":"); ok {
+		// scp-like syntax of SSH URLs, like git@github.com:rancher/charts.git
+		host = h
+		path = strings.TrimPrefix(p+"/"+path, "/")
+		path = strings.TrimSuffix(path, "/")
+	}
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "/"), ".git")
+	return host + "/" + path
+}
+
+// hmacAuthenticator authenticates notifications signed with the hex encoded HMAC-SHA256 of their payload.
+func hmacAuthenticator(body []byte, signature, prefix string) func([]byte) bool {
+	return func(secret []byte) bool {
+		sig, ok := strings.CutPrefix(signature, prefix)
+		if !ok {
+			return false
+		}
+		expected, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		return hmac.Equal(mac.Sum(nil), expected)
+	}
+}
+
+// tokenAuthenticator authenticates notifications sent with the secret as token.
+func tokenAuthenticator(token string) func([]byte) bool {
+	return func(secret []byte) bool {
+		return token != "" && subtle.ConstantTimeCompare([]byte(token), secret) == 1
+	}
+}
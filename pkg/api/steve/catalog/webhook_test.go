@@ -0,0 +1,288 @@
+package catalog
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/generic/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	githubPushPayload = `{"ref":"refs/heads/main","repository":{"clone_url":"https://github.com/rancher/charts.git","html_url":"https://github.com/rancher/charts","ssh_url":"git@github.com:rancher/charts.git","default_branch":"main"}}`
+	gitlabPushPayload = `{"ref":"refs/heads/release","project":{"git_http_url":"https://gitlab.example.com/team/charts.git","git_ssh_url":"git@gitlab.example.com:team/charts.git","default_branch":"main"}}`
+	harborPushPayload = `{"type":"PUSH_ARTIFACT","event_data":{"resources":[{"tag":"1.0.0","resource_url":"harbor.example.com/charts/app:1.0.0"}],"repository":{"repo_full_name":"charts/app"}}}`
+	distributionPush  = `{"events":[{"action":"pull","target":{"repository":"charts/other"}},{"action":"push","target":{"repository":"charts/app","url":"https://registry.example.com/v2/charts/app/manifests/sha256:abc","tag":"1.0.0"},"request":{"host":"registry.example.com"}}]}`
+)
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestParsePush(t *testing.T) {
+	tests := []struct {
+		name         string
+		provider     string
+		header       http.Header
+		body         string
+		expected     *push
+		authenticate bool
+		expectErr    bool
+	}{
+		{
+			name:   "github push",
+			header: http.Header{"X-Github-Event": {"push"}, "X-Hub-Signature-256": {"sha256=" + sign("s3cr3t", githubPushPayload)}},
+			body:   githubPushPayload,
+			expected: &push{
+				provider:      "github",
+				event:         "refs/heads/main",
+				gitURLs:       []string{"github.com/rancher/charts", "github.com/rancher/charts", "github.com/rancher/charts"},
+				branch:        "main",
+				defaultBranch: "main",
+			},
+			authenticate: true,
+		},
+		{
+			name:   "github push with an invalid signature",
+			header: http.Header{"X-Github-Event": {"push"}, "X-Hub-Signature-256": {"sha256=" + sign("other", githubPushPayload)}},
+			body:   githubPushPayload,
+			expected: &push{
+				provider:      "github",
+				event:         "refs/heads/main",
+				gitURLs:       []string{"github.com/rancher/charts", "github.com/rancher/charts", "github.com/rancher/charts"},
+				branch:        "main",
+				defaultBranch: "main",
+			},
+		},
+		{
+			name:   "github ping",
+			header: http.Header{"X-Github-Event": {"ping"}},
+			body:   `{}`,
+		},
+		{
+			name:   "github tag push",
+			header: http.Header{"X-Github-Event": {"push"}},
+			body:   `{"ref":"refs/tags/v1.0.0"}`,
+		},
+		{
+			name:   "gitea push",
+			header: http.Header{"X-Github-Event": {"push"}, "X-Gitea-Event": {"push"}, "X-Gitea-Signature": {sign("s3cr3t", githubPushPayload)}},
+			body:   githubPushPayload,
+			expected: &push{
+				provider:      "gitea",
+				event:         "refs/heads/main",
+				gitURLs:       []string{"github.com/rancher/charts", "github.com/rancher/charts", "github.com/rancher/charts"},
+				branch:        "main",
+				defaultBranch: "main",
+			},
+			authenticate: true,
+		},
+		{
+			name:   "gitlab push",
+			header: http.Header{"X-Gitlab-Event": {"Push Hook"}, "X-Gitlab-Token": {"s3cr3t"}},
+			body:   gitlabPushPayload,
+			expected: &push{
+				provider:      "gitlab",
+				event:         "refs/heads/release",
+				gitURLs:       []string{"gitlab.example.com/team/charts", "gitlab.example.com/team/charts"},
+				branch:        "release",
+				defaultBranch: "main",
+			},
+			authenticate: true,
+		},
+		{
+			name:     "harbor push",
+			provider: "harbor",
+			header:   http.Header{"Content-Type": {"application/json"}, "Authorization": {"s3cr3t"}},
+			body:     harborPushPayload,
+			expected: &push{
+				provider:     "harbor",
+				event:        "charts/app:1.0.0",
+				host:         "harbor.example.com",
+				repositories: []string{"charts/app"},
+			},
+			authenticate: true,
+		},
+		{
+			name:     "harbor delete",
+			provider: "harbor",
+			header:   http.Header{"Content-Type": {"application/json"}},
+			body:     `{"type":"DELETE_ARTIFACT","event_data":{}}`,
+		},
+		{
+			name:      "harbor push without the provider",
+			header:    http.Header{"Content-Type": {"application/json"}, "Authorization": {"s3cr3t"}},
+			body:      harborPushPayload,
+			expectErr: true,
+		},
+		{
+			name:      "invalid harbor notification",
+			provider:  "harbor",
+			header:    http.Header{"Content-Type": {"application/json"}},
+			body:      `{"hello":"world"}`,
+			expectErr: true,
+		},
+		{
+			name:   "distribution push",
+			header: http.Header{"Content-Type": {distributionEventsMediaType}, "Authorization": {"Bearer s3cr3t"}},
+			body:   distributionPush,
+			expected: &push{
+				provider:     "distribution",
+				event:        "charts/app:1.0.0",
+				host:         "registry.example.com",
+				repositories: []string{"charts/app"},
+			},
+		},
+		{
+			name:      "unsupported notification",
+			header:    http.Header{"Content-Type": {"application/json"}},
+			body:      `{"hello":"world"}`,
+			expectErr: true,
+		},
+		{
+			name:      "invalid payload",
+			header:    http.Header{"X-Gitlab-Event": {"Push Hook"}},
+			body:      `not json`,
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := parsePush(tt.provider, tt.header, []byte(tt.body))
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, actual)
+				return
+			}
+			require.NotNil(t, actual)
+			assert.Equal(t, tt.authenticate, actual.authenticate([]byte("s3cr3t")))
+			actual.authenticate = nil
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestPushMatches(t *testing.T) {
+	gitPush := &push{gitURLs: []string{"github.com/rancher/charts"}, branch: "main", defaultBranch: "main"}
+	ociPush := &push{host: "registry.example.com", repositories: []string{"charts/app"}}
+
+	tests := []struct {
+		name     string
+		push     *push
+		spec     catalog.RepoSpec
+		expected bool
+	}{
+		{name: "same git repository and branch", push: gitPush, spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts", GitBranch: "main"}, expected: true},
+		{name: "ssh url of the git repository", push: gitPush, spec: catalog.RepoSpec{GitRepo: "git@github.com:Rancher/charts.git", GitBranch: "main"}, expected: true},
+		{name: "default branch", push: gitPush, spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts.git"}, expected: true},
+		{name: "other branch", push: gitPush, spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts", GitBranch: "dev"}},
+		{name: "other git repository", push: gitPush, spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/partner-charts"}},
+		{name: "git push to an OCI repository", push: gitPush, spec: catalog.RepoSpec{URL: "oci://registry.example.com/charts"}},
+		{name: "OCI namespace", push: ociPush, spec: catalog.RepoSpec{URL: "oci://registry.example.com/charts"}, expected: true},
+		{name: "OCI registry", push: ociPush, spec: catalog.RepoSpec{URL: "oci://registry.example.com"}, expected: true},
+		{name: "OCI chart tag", push: ociPush, spec: catalog.RepoSpec{URL: "oci://registry.example.com/charts/app:0.1.0"}, expected: true},
+		{name: "other OCI namespace", push: ociPush, spec: catalog.RepoSpec{URL: "oci://registry.example.com/charts/app-other"}},
+		{name: "other OCI registry", push: ociPush, spec: catalog.RepoSpec{URL: "oci://other.example.com/charts"}},
+		{name: "HTTP repository", push: ociPush, spec: catalog.RepoSpec{URL: "https://registry.example.com/charts"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.push.matches(&catalog.ClusterRepo{Spec: tt.spec}))
+		})
+	}
+}
+
+func TestRepoWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	clusterRepos := fake.NewMockNonNamespacedControllerInterface[*catalog.ClusterRepo, *catalog.ClusterRepoList](ctrl)
+	clusterRepoCache := fake.NewMockNonNamespacedCacheInterface[*catalog.ClusterRepo](ctrl)
+	secrets := fake.NewMockCacheInterface[*corev1.Secret](ctrl)
+
+	webhook := &catalog.RepoWebhook{Secret: catalog.SecretReference{Name: "webhook", Namespace: "cattle-system"}}
+	otherWebhook := &catalog.RepoWebhook{Secret: catalog.SecretReference{Name: "other", Namespace: "cattle-system"}}
+	repos := map[string]*catalog.ClusterRepo{
+		"charts":       {ObjectMeta: metav1.ObjectMeta{Name: "charts"}, Spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts", Webhook: webhook}},
+		"other-secret": {ObjectMeta: metav1.ObjectMeta{Name: "other-secret"}, Spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts", Webhook: otherWebhook}},
+		"no-webhook":   {ObjectMeta: metav1.ObjectMeta{Name: "no-webhook"}, Spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/charts"}},
+		"other-repo":   {ObjectMeta: metav1.ObjectMeta{Name: "other-repo"}, Spec: catalog.RepoSpec{GitRepo: "https://github.com/rancher/other", Webhook: webhook}},
+	}
+
+	clusterRepos.EXPECT().Cache().Return(clusterRepoCache).AnyTimes()
+	clusterRepoCache.EXPECT().List(gomock.Any()).DoAndReturn(func(_ interface{}) ([]*catalog.ClusterRepo, error) {
+		var result []*catalog.ClusterRepo
+		for _, repo := range repos {
+			result = append(result, repo)
+		}
+		return result, nil
+	}).AnyTimes()
+	clusterRepos.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(name string, _ metav1.GetOptions) (*catalog.ClusterRepo, error) {
+		return repos[name].DeepCopy(), nil
+	}).AnyTimes()
+	secrets.EXPECT().Get("cattle-system", "webhook").Return(&corev1.Secret{Data: map[string][]byte{"secret": []byte("s3cr3t")}}, nil).AnyTimes()
+	secrets.EXPECT().Get("cattle-system", "other").Return(&corev1.Secret{Data: map[string][]byte{"secret": []byte("other")}}, nil).AnyTimes()
+
+	var updated []string
+	clusterRepos.EXPECT().Update(gomock.Any()).DoAndReturn(func(repo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+		assert.NotNil(t, repo.Spec.ForceUpdate)
+		updated = append(updated, repo.Name)
+		return repo, nil
+	}).AnyTimes()
+	deliveries := map[string]*catalog.RepoWebhookDelivery{}
+	clusterRepos.EXPECT().UpdateStatus(gomock.Any()).DoAndReturn(func(repo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+		deliveries[repo.Name] = repo.Status.WebhookDelivery
+		repos[repo.Name] = repo
+		return repo, nil
+	}).AnyTimes()
+
+	handler := &repoWebhook{clusterRepos: clusterRepos, secrets: secrets}
+	serve := func(method, event, signature, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, RepoWebhookPath, bytes.NewBufferString(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusAccepted, serve(http.MethodPost, "push", sign("s3cr3t", githubPushPayload), githubPushPayload).Code)
+	assert.Equal(t, []string{"charts"}, updated)
+	require.Len(t, deliveries, 2)
+	assert.True(t, deliveries["charts"].Accepted)
+	assert.Equal(t, "github", deliveries["charts"].Provider)
+	assert.Equal(t, "refs/heads/main", deliveries["charts"].Event)
+	assert.False(t, deliveries["other-secret"].Accepted)
+	assert.NotEmpty(t, deliveries["other-secret"].Message)
+
+	// failed deliveries are not recorded again within failedDeliveryInterval
+	updated = nil
+	deliveries = map[string]*catalog.RepoWebhookDelivery{}
+	unauthenticated := serve(http.MethodPost, "push", sign("wrong", githubPushPayload), githubPushPayload)
+	assert.Equal(t, http.StatusForbidden, unauthenticated.Code)
+	assert.Empty(t, updated)
+	assert.Empty(t, deliveries)
+
+	// notifications matching no repository get the same response as the ones failing to be authenticated
+	unknown := `{"ref":"refs/heads/main","repository":{"clone_url":"https://github.com/rancher/unknown.git"}}`
+	unmatched := serve(http.MethodPost, "push", sign("s3cr3t", unknown), unknown)
+	assert.Equal(t, unauthenticated.Code, unmatched.Code)
+	assert.Equal(t, unauthenticated.Body.String(), unmatched.Body.String())
+
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodPost, "ping", "", `{}`).Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "push", "", "").Code)
+}
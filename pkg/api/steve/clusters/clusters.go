@@ -28,6 +28,9 @@ func Register(ctx context.Context, server *steve.Server, wrangler *wrangler.Cont
 	log := &log{
 		cg: server.ClientFactory,
 	}
+	events := &events{
+		cg: server.ClientFactory,
+	}
 	shell := &shell{
 		cg:              server.ClientFactory,
 		namespace:       "cattle-system",
@@ -66,6 +69,7 @@ func Register(ctx context.Context, server *steve.Server, wrangler *wrangler.Cont
 			}
 			schema.LinkHandlers["shell"] = shell
 			schema.LinkHandlers["log"] = log
+			schema.LinkHandlers["events"] = events
 			if schema.ActionHandlers == nil {
 				schema.ActionHandlers = map[string]http.Handler{}
 			}
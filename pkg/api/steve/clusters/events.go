@@ -0,0 +1,58 @@
+package clusters
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	"github.com/rancher/apiserver/pkg/types"
+	"github.com/rancher/rancher/pkg/provisioningevents"
+	"github.com/rancher/steve/pkg/stores/proxy"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// events serves the provisioning event timeline of a cluster, filtered by the query parameters of the request. See
+// provisioningevents.FilterFromQuery for the supported parameters.
+type events struct {
+	cg proxy.ClientGetter
+}
+
+func (e *events) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanGet(apiRequest, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	filter, err := provisioningevents.FilterFromQuery(req.URL.Query())
+	if err != nil {
+		apiRequest.WriteError(apierror.NewAPIError(validation.InvalidOption, err.Error()))
+		return
+	}
+
+	client, err := e.cg.AdminK8sInterface()
+	if err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	var eventList []provisioningevents.Event
+	cm, err := client.CoreV1().ConfigMaps(apiRequest.Name).Get(req.Context(), provisioningevents.ConfigMapName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		apiRequest.WriteError(err)
+		return
+	} else if err == nil {
+		if eventList, _, err = provisioningevents.Decode(cm); err != nil {
+			apiRequest.WriteError(err)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(provisioningevents.EventList{Data: filter.Apply(eventList)}); err != nil {
+		logrus.Errorf("Error while writing cluster events: %v", err)
+	}
+}
@@ -15,6 +15,10 @@ func Register(server *steve.Server, clients *wrangler.Context) {
 		machines: clients.CAPI.Machine(),
 		secrets:  clients.Core.Secret(),
 	}
+	provisionLog := &provisionLog{
+		machines: clients.CAPI.Machine().Cache(),
+		k8s:      clients.K8s,
+	}
 
 	server.SchemaFactory.AddTemplate(schema2.Template{
 		Group: "cluster.x-k8s.io",
@@ -25,11 +29,13 @@ func Register(server *steve.Server, clients *wrangler.Context) {
 			}
 			schema.LinkHandlers["shell"] = sshClient
 			schema.LinkHandlers["sshkeys"] = sshClient
+			schema.LinkHandlers["provisionlog"] = provisionLog
 			schema.Formatter = func(request *types.APIRequest, resource *types.RawResource) {
 				if err := request.AccessControl.CanUpdate(request, types.APIObject{}, request.Schema); err != nil ||
 					resource.APIObject.Data().String("spec", "infrastructureRef", "apiVersion") != capr.RKEMachineAPIVersion {
 					delete(resource.Links, "shell")
 					delete(resource.Links, "sshkeys")
+					delete(resource.Links, "provisionlog")
 				}
 			}
 		},
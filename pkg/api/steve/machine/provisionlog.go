@@ -0,0 +1,145 @@
+package machine
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rancher/apiserver/pkg/types"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	"github.com/rancher/rancher/pkg/provisioningv2/machinelog"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	provisionLogTimeout int64 = 15 * 60
+)
+
+var provisionLogUpgrader = websocket.Upgrader{
+	HandshakeTimeout: 5 * time.Second,
+	CheckOrigin:      func(r *http.Request) bool { return true },
+	Error:            onError,
+}
+
+// provisionLog streams the stored provisioning job logs of the infrastructure machine of a machine, followed by the
+// logs of attempts that finish while the connection is open.
+type provisionLog struct {
+	machines capicontrollers.MachineCache
+	k8s      kubernetes.Interface
+}
+
+func (p *provisionLog) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	apiRequest := types.GetAPIContext(req.Context())
+	if err := apiRequest.AccessControl.CanUpdate(apiRequest, types.APIObject{}, apiRequest.Schema); err != nil {
+		apiRequest.WriteError(err)
+		return
+	}
+
+	if err := p.printLog(apiRequest); err != nil {
+		logrus.Infof("Error while handling machine provisioning log: %v", err)
+	}
+}
+
+func (p *provisionLog) printLog(apiRequest *types.APIRequest) error {
+	machine, err := p.machines.Get(apiRequest.Namespace, apiRequest.Name)
+	if err != nil {
+		apiRequest.WriteError(err)
+		return err
+	}
+
+	conn, err := provisionLogUpgrader.Upgrade(apiRequest.Response, apiRequest.Request, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	w, err := p.k8s.CoreV1().Secrets(machine.Namespace).Watch(apiRequest.Context(), metav1.ListOptions{
+		TimeoutSeconds: &provisionLogTimeout,
+		FieldSelector:  "metadata.name=" + machinelog.SecretName(machine.Spec.InfrastructureRef.Name),
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+
+	printed := map[string]bool{}
+	for event := range w.ResultChan() {
+		switch event.Type {
+		case watch.Added:
+		case watch.Modified:
+		case watch.Deleted:
+			return nil
+		default:
+			continue
+		}
+		secret, ok := event.Object.(*corev1.Secret)
+		if !ok {
+			continue
+		}
+
+		attempts, err := machinelog.Decode(secret)
+		if err != nil {
+			return err
+		}
+		for _, attempt := range attempts {
+			if printed[attempt.PodUID] {
+				continue
+			}
+			if err := printAttempt(attempt, conn); err != nil {
+				return err
+			}
+			printed[attempt.PodUID] = true
+		}
+	}
+
+	return nil
+}
+
+func printAttempt(attempt machinelog.Attempt, conn *websocket.Conn) error {
+	result := "failed"
+	if attempt.Succeeded {
+		result = "succeeded"
+	}
+	header := fmt.Sprintf("==> %s attempt %s %s", attempt.Operation, attempt.PodName, result)
+	if attempt.FinishTime != nil {
+		header += " at " + attempt.FinishTime.UTC().Format(time.RFC3339)
+	}
+	if attempt.Truncated {
+		header += " (truncated)"
+	}
+	if err := printMessage(header, conn); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(attempt.Log))
+	scanner.Buffer(make([]byte, 0, 64*1024), machinelog.MaxLogBytes)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if err := printMessage(line, conn); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func printMessage(msg string, conn *websocket.Conn) error {
+	writer, err := conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write([]byte(base64.StdEncoding.EncodeToString([]byte(msg)))); err != nil {
+		return err
+	}
+	return writer.Close()
+}
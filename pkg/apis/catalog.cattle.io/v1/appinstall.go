@@ -0,0 +1,78 @@
+package v1
+
+import (
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// AppInstallInstalled is true once the release matches the desired state of the AppInstall.
+	AppInstallInstalled condition.Cond = "Installed"
+	// AppInstallDrifted is true while the release differs from the desired state of the AppInstall, with the reason
+	// in its message.
+	AppInstallDrifted condition.Cond = "Drifted"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AppInstall is the desired state of a Helm release installed from a ClusterRepo. The release is installed in the
+// namespace of the AppInstall and is upgraded through helm operations whenever it drifts from the desired state.
+type AppInstall struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AppInstallSpec   `json:"spec"`
+	Status AppInstallStatus `json:"status,omitempty"`
+}
+
+type AppInstallSpec struct {
+	// RepoName is the name of the ClusterRepo the chart is installed from.
+	RepoName string `json:"repoName"`
+	// Chart is the name of the chart.
+	Chart string `json:"chart"`
+	// Version is the exact version of the chart or a semver constraint, like "~1.2" or ">=1.0.0 <2.0.0". Defaults to
+	// the newest version of the chart.
+	Version string `json:"version,omitempty"`
+	// AutoUpgrade upgrades the release to the newest version of the chart matching Version whenever the index of the
+	// ClusterRepo is refreshed. Otherwise, the version resolved when the release was installed is kept as long as it
+	// matches Version.
+	AutoUpgrade bool `json:"autoUpgrade,omitempty"`
+	// ReleaseName is the name of the Helm release. Defaults to the name of the AppInstall.
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Values are the values of the release. They override the values taken from ValuesFrom.
+	Values v3.MapStringInterface `json:"values,omitempty"`
+	// ValuesFrom are YAML values documents taken from Secrets or ConfigMaps in the namespace of the AppInstall, merged in
+	// order.
+	ValuesFrom []AppInstallValuesSource `json:"valuesFrom,omitempty"`
+	// ServiceAccountName is the service account in the namespace of the AppInstall whose permissions are used to run
+	// the helm operations. Defaults to the "default" service account.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// Timeout of the helm operations. Defaults to 5 minutes.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// UninstallOnDelete uninstalls the release when the AppInstall is deleted.
+	UninstallOnDelete bool `json:"uninstallOnDelete,omitempty"`
+}
+
+// AppInstallValuesSource references a key of a Secret or a ConfigMap holding a YAML values document. Exactly one of
+// SecretKeyRef and ConfigMapKeyRef must be set.
+type AppInstallValuesSource struct {
+	SecretKeyRef    *corev1.SecretKeySelector    `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+}
+
+type AppInstallStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration"`
+	// Version is the version of the chart the release is converging to.
+	Version string `json:"version,omitempty"`
+	// OperationName is the name of the last helm operation started to converge the release.
+	OperationName string `json:"operationName,omitempty"`
+	// OperationHash identifies the chart, version and values applied by the last helm operation.
+	OperationHash string `json:"operationHash,omitempty"`
+	// Conditions are the Installed and Drifted conditions of the AppInstall.
+	Conditions []genericcondition.GenericCondition `json:"conditions,omitempty"`
+}
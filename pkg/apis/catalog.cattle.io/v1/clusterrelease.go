@@ -0,0 +1,65 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ClusterReleaseClusterLabel is set on ClusterReleases to the name of the cluster running the release.
+	ClusterReleaseClusterLabel = "catalog.cattle.io/cluster-name"
+	// ClusterReleaseChartLabel is set on ClusterReleases to the name of the chart of the release.
+	ClusterReleaseChartLabel = "catalog.cattle.io/chart-name"
+)
+
+// +genclient
+// +kubebuilder:skipversion
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterRelease is the summary of a Helm release of a downstream cluster, collected in the namespace of the cluster in
+// the management cluster to make the releases of all the clusters searchable from a single place.
+type ClusterRelease struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterReleaseSpec   `json:"spec"`
+	Status ClusterReleaseStatus `json:"status,omitempty"`
+}
+
+// ClusterReleaseSpec is the summary of the App of a release, as found in the downstream cluster.
+type ClusterReleaseSpec struct {
+	// ClusterName is the name of the cluster running the release.
+	ClusterName string `json:"clusterName"`
+	// ReleaseName is the name of the release.
+	ReleaseName string `json:"releaseName"`
+	// ReleaseNamespace is the namespace of the release.
+	ReleaseNamespace string `json:"releaseNamespace"`
+	// Revision is the revision of the release.
+	Revision int `json:"revision,omitempty"`
+	// Status is the status of the release, like deployed or failed.
+	Status Status `json:"status,omitempty"`
+	// LastDeployed is when the release was last deployed.
+	LastDeployed *metav1.Time `json:"lastDeployed,omitempty"`
+	// Chart is the name of the chart of the release.
+	Chart string `json:"chart"`
+	// Version is the version of the chart of the release.
+	Version string `json:"version"`
+	// AppVersion is the version of the application of the chart.
+	AppVersion string `json:"appVersion,omitempty"`
+	// RepoName is the name of the ClusterRepo the chart was installed from, if known.
+	RepoName string `json:"repoName,omitempty"`
+	// Deprecated is set if the chart of the release was marked deprecated when it was installed.
+	Deprecated bool `json:"deprecated,omitempty"`
+}
+
+// ClusterReleaseStatus compares the chart of a release with the charts of the ClusterRepos of the management cluster.
+type ClusterReleaseStatus struct {
+	// LatestVersion is the newest version of the chart available in any ClusterRepo.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// LatestVersionRepoName is the name of the ClusterRepo providing the newest version of the chart.
+	LatestVersionRepoName string `json:"latestVersionRepoName,omitempty"`
+	// Outdated is set if the version of the chart of the release is older than LatestVersion.
+	Outdated bool `json:"outdated,omitempty"`
+	// Deprecated is set if the chart of the release, or its version in the ClusterRepo it was installed from, is
+	// deprecated.
+	Deprecated bool `json:"deprecated,omitempty"`
+}
@@ -44,6 +44,23 @@ type UIPluginEntry struct {
 	NoAuth bool `json:"noAuth,omitempty"`
 	// Metadata of the plugin.
 	Metadata map[string]string `json:"metadata,omitempty"`
+	// Integrity pins the expected content of the plugin files. Files that don't match are not cached and the plugin
+	// isn't served. Plugins with pinned integrity are only served once their files are verified and cached, so it
+	// can't be set along with noCache.
+	// +optional
+	Integrity *UIPluginIntegrity `json:"integrity,omitempty"`
+}
+
+// UIPluginIntegrity holds the digests the files of a plugin are verified against before being cached. Digests use the
+// Subresource Integrity format, like sha384-<base64 digest>, with the sha256, sha384 or sha512 algorithms.
+type UIPluginIntegrity struct {
+	// ManifestDigest is the digest of the manifest of the plugin files: one "<hex sha256 digest>  <file>" line for
+	// each file listed in files.txt, in the same order, as printed by sha256sum.
+	// +optional
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+	// Files maps the files listed in files.txt to their digest. When set, every file must have a digest.
+	// +optional
+	Files map[string]string `json:"files,omitempty"`
 }
 
 type UIPluginStatus struct {
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"github.com/rancher/wrangler/v3/pkg/condition"
 	"github.com/rancher/wrangler/v3/pkg/genericcondition"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -8,6 +9,16 @@
 
 var ClusterRepoNameLabel = "catalog.cattle.io/cluster-repo-name"
 
+const (
+	// ChartVerificationAnnotation is set on the chart versions of the index of repositories with a verification policy
+	// to the result of the last verification of the chart: "verified", "failed" or "unverified" if the chart was not
+	// verified yet.
+	ChartVerificationAnnotation = "catalog.cattle.io/verification"
+	// ChartVerificationMessageAnnotation is set to the signer of a verified chart or to the reason of a failed
+	// verification.
+	ChartVerificationMessageAnnotation = "catalog.cattle.io/verification-message"
+)
+
 // +genclient
 // +genclient:nonNamespaced
 // +kubebuilder:resource:scope=Cluster,path=clusterrepos
@@ -110,6 +121,116 @@ type RepoSpec struct {
 	// Defaults to false, which keeps the SameOrigin check enabled. Setting this to true is not recommended
 	// in production environments due to the security implications.
 	DisableSameOriginCheck bool `json:"disableSameOriginCheck,omitempty"`
+
+	// Verification is an opt-in policy to verify the charts of the repository before they are installed or upgraded.
+	// Charts of HTTP repositories are verified with their Helm provenance file and charts of OCI repositories with
+	// their cosign signature or attestation. Unsigned charts and charts failing the verification are refused.
+	Verification *ChartVerification `json:"verification,omitempty"`
+
+	// Mirror copies the charts of the repository, and the images referenced by their values, to an OCI registry
+	// reachable from air-gapped clusters. The mirror is synchronized whenever the index of the repository changes and
+	// at every refresh interval.
+	Mirror *RepoMirror `json:"mirror,omitempty"`
+
+	// Webhook enables refreshing the repository when its git repository or OCI registry notifies a push to the
+	// /v1-catalog/webhook endpoint of Rancher. GitHub, GitLab and Gitea push events, and Harbor and distribution
+	// registry notifications are supported. Harbor notifications carry no header identifying them, so they must be sent
+	// to /v1-catalog/webhook?provider=harbor.
+	Webhook *RepoWebhook `json:"webhook,omitempty"`
+
+	// Policy restricts the values, namespaces and versions of the charts of the repository installed or upgraded
+	// through Rancher. Install and upgrade requests violating the policy are refused before their operation is created.
+	Policy *RepoPolicy `json:"policy,omitempty"`
+}
+
+// RepoPolicy restricts the charts of a Helm repository installed or upgraded through Rancher.
+type RepoPolicy struct {
+	// ChartPolicyRules apply to every chart of the repository.
+	ChartPolicyRules `json:",inline"`
+
+	// Charts are rules applying to a single chart, in addition to the rules of the repository.
+	Charts []ChartPolicy `json:"charts,omitempty"`
+}
+
+// ChartPolicy restricts a single chart of a Helm repository.
+type ChartPolicy struct {
+	// Name of the chart.
+	Name string `json:"name"`
+
+	ChartPolicyRules `json:",inline"`
+}
+
+// ChartPolicyRules are the restrictions of a chart policy.
+type ChartPolicyRules struct {
+	// ForbiddenValues are the dot separated paths of the values, like "global.systemDefaultRegistry", which must not
+	// be set.
+	ForbiddenValues []string `json:"forbiddenValues,omitempty"`
+
+	// RequiredValues are the dot separated paths of the values which must be set to a non-empty value.
+	RequiredValues []string `json:"requiredValues,omitempty"`
+
+	// AllowedNamespaces are the namespaces the charts can be installed in. Any namespace is allowed if empty.
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+
+	// MaxVersion is the highest version of the charts which can be installed.
+	MaxVersion string `json:"maxVersion,omitempty"`
+}
+
+// RepoWebhook configures the push notifications refreshing a Helm repository.
+type RepoWebhook struct {
+	// Secret is the secret holding, in its "secret" key, the secret shared with the sender of the notifications. It is
+	// the HMAC key of the signature of GitHub and Gitea push events, the token of GitLab push events and the value of
+	// the Authorization header of Harbor and distribution registry notifications.
+	Secret SecretReference `json:"secret"`
+}
+
+// RepoMirror configures the mirroring of the charts of a Helm repository to an OCI registry.
+type RepoMirror struct {
+	// URL is the OCI URL the charts are pushed to, like oci://registry.example.com/charts. Each chart version is pushed
+	// to <URL>/<chart name>:<version>, so that a ClusterRepo with this URL serves the mirrored charts.
+	URL string `json:"url"`
+
+	// ClientSecret is the "kubernetes.io/basic-auth" secret used to push the charts and the images to the registry.
+	ClientSecret *SecretReference `json:"clientSecret,omitempty"`
+
+	// InsecurePlainHTTP allows pushing to the registry without TLS.
+	InsecurePlainHTTP bool `json:"insecurePlainHttp,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle which will be used to validate the registry's certificate.
+	CABundle []byte `json:"caBundle,omitempty"`
+
+	// InsecureSkipTLSverify disables the TLS verification of the registry.
+	InsecureSkipTLSverify bool `json:"insecureSkipTLSVerify,omitempty"`
+
+	// Charts select the chart versions that are mirrored. All the chart versions of the repository are mirrored if empty.
+	Charts []MirroredChart `json:"charts,omitempty"`
+
+	// ImageRegistry is the registry the images referenced by the mirrored charts are copied to, like
+	// registry.example.com/mirror. The image rancher/shell:v0.1.0 is copied to
+	// registry.example.com/mirror/rancher/shell:v0.1.0. If empty, the images are only written to the push list of
+	// the mirror status.
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+}
+
+// MirroredChart selects the versions of a chart to mirror.
+type MirroredChart struct {
+	// Name of the chart.
+	Name string `json:"name"`
+
+	// Versions is the semver constraint, like ">= 1.2.0 < 2.0.0", the mirrored versions of the chart must satisfy.
+	// All the versions of the chart are mirrored if empty.
+	Versions string `json:"versions,omitempty"`
+}
+
+// ChartVerification configures the keys used to verify the charts of a Helm repository.
+type ChartVerification struct {
+	// KeyringSecret is the secret holding the binary or ASCII armored PGP public keyring, in its "keyring" key,
+	// used to verify the provenance files of the charts of HTTP Helm repositories.
+	KeyringSecret *SecretReference `json:"keyringSecret,omitempty"`
+
+	// PublicKeys are the PEM encoded public keys used to verify the cosign signatures and attestations of the charts
+	// of OCI Helm repositories.
+	PublicKeys string `json:"publicKeys,omitempty"`
 }
 
 type RepoCondition string
@@ -118,6 +239,7 @@ type RepoSpec struct {
 	RepoDownloaded         RepoCondition = "Downloaded"
 	FollowerRepoDownloaded RepoCondition = "FollowerDownloaded"
 	OCIDownloaded          RepoCondition = "OCIDownloaded"
+	RepoMirrored           RepoCondition = "Mirrored"
 )
 
 // RepoStatus contains details of the Helm repository that is currently being used in the cluster.
@@ -158,6 +280,104 @@ type RepoStatus struct {
 
 	// If the handler should be skipped or not
 	ShouldNotSkip bool `json:"shouldNotSkip,omitempty"`
+
+	// Mirror is the status of the mirroring of the repository to an OCI registry.
+	Mirror *RepoMirrorStatus `json:"mirror,omitempty"`
+
+	// WebhookDelivery is the last push notification delivered to the webhook of the repository.
+	WebhookDelivery *RepoWebhookDelivery `json:"webhookDelivery,omitempty"`
+
+	// Verifications are the results of the verification of the chart versions of a repository with a verification
+	// policy. Chart versions which failed the verification are refused by every Rancher replica until they or the
+	// keys of the policy change.
+	Verifications []ChartVerificationStatus `json:"verifications,omitempty"`
+}
+
+// ChartVerificationStatus is the result of the last verification of a chart version.
+type ChartVerificationStatus struct {
+	// Name of the chart.
+	Name string `json:"name"`
+
+	// Version of the chart.
+	Version string `json:"version"`
+
+	// Digest of the chart version in the index of the repository when it was verified.
+	Digest string `json:"digest,omitempty"`
+
+	// ManifestDigest is the digest of the verified OCI manifest of the charts of OCI repositories.
+	ManifestDigest string `json:"manifestDigest,omitempty"`
+
+	// KeysDigest is the digest of the keys the chart version was verified with.
+	KeysDigest string `json:"keysDigest"`
+
+	// State is "verified" or "failed".
+	State string `json:"state"`
+
+	// Message is the signer of a verified chart or the reason the verification failed.
+	Message string `json:"message,omitempty"`
+
+	// Time of the verification.
+	Time metav1.Time `json:"time"`
+}
+
+// RepoWebhookDelivery is the result of the delivery of a push notification to the webhook of a Helm repository.
+type RepoWebhookDelivery struct {
+	// Time the notification was received.
+	Time metav1.Time `json:"time"`
+
+	// Provider that sent the notification, one of github, gitlab, gitea, harbor or distribution.
+	Provider string `json:"provider"`
+
+	// Event is the pushed git reference, like refs/heads/main, or the pushed OCI artifact, like charts/app:1.0.0.
+	Event string `json:"event,omitempty"`
+
+	// Accepted is true if the notification was authenticated and the repository refreshed.
+	Accepted bool `json:"accepted"`
+
+	// Message is the reason the notification was rejected.
+	Message string `json:"message,omitempty"`
+}
+
+// RepoMirrorStatus is the result of the last synchronization of the mirror of a Helm repository.
+type RepoMirrorStatus struct {
+	// ObservedGeneration is the generation of the ClusterRepo the mirror was last synchronized for.
+	ObservedGeneration int64 `json:"observedGeneration"`
+
+	// IndexConfigMapResourceVersion is the resource version of the index the mirror was last synchronized with.
+	IndexConfigMapResourceVersion string `json:"indexConfigMapResourceVersion,omitempty"`
+
+	// SyncTime is the time of the last synchronization.
+	SyncTime metav1.Time `json:"syncTime,omitempty"`
+
+	// URL and ImageRegistry are the OCI URL and the image registry the charts and the images were mirrored to. The
+	// mirrored charts are pushed again if either changes.
+	URL           string `json:"url,omitempty"`
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+
+	// Charts are the mirrored chart versions. They are not pushed again unless their digest changes.
+	Charts []MirroredChartStatus `json:"charts,omitempty"`
+
+	// Images is the number of images referenced by the mirrored charts.
+	Images int `json:"images,omitempty"`
+
+	// ImageListConfigMapName and ImageListConfigMapNamespace locate the ConfigMap holding the push list of the images
+	// referenced by the mirrored charts, one per line in its "images.txt" key.
+	ImageListConfigMapName      string `json:"imageListConfigMapName,omitempty"`
+	ImageListConfigMapNamespace string `json:"imageListConfigMapNamespace,omitempty"`
+}
+
+// MirroredChartStatus is a chart version pushed to the OCI registry of a mirror, along with the images it references.
+type MirroredChartStatus struct {
+	// Name and Version of the chart.
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// Digest is the SHA-256 digest of the chart archive.
+	Digest string `json:"digest"`
+
+	// Images are the images referenced by the values of the chart, which were copied to the image registry of the
+	// mirror, if any.
+	Images []string `json:"images,omitempty"`
 }
 
 // +genclient
@@ -187,4 +407,86 @@ type OperationStatus struct {
 	Conditions             []genericcondition.GenericCondition `json:"conditions,omitempty"`
 	AutomaticCPTolerations bool                                `json:"automaticCPTolerations,omitempty"`
 	Tolerations            []corev1.Toleration                 `json:"tolerations,omitempty"`
+	// DryRun is true if the operation only renders the charts with helm --dry-run, without changing the releases.
+	DryRun bool `json:"dryRun,omitempty"`
+	// Diff lists the changes the operation would make to the resources of the releases. It is only set for dry-run
+	// operations, once they completed.
+	Diff []ResourceDiff `json:"diff,omitempty"`
+	// HealthCheck verifies the health of the release once it is upgraded, and rolls it back if it isn't healthy.
+	HealthCheck *OperationHealthCheck `json:"healthCheck,omitempty"`
+	// Queue is the state of the operation in the queue of the operations waiting to run. Operations leave the queue
+	// once no other operation on one of their releases is running and the maximum number of concurrent operations
+	// isn't reached.
+	Queue *OperationQueueStatus `json:"queue,omitempty"`
+}
+
+const (
+	// OperationQueued is the state of an operation waiting in the queue.
+	OperationQueued = "queued"
+	// OperationStarted is the state of an operation which left the queue and whose pod was created.
+	OperationStarted = "started"
+	// OperationCancelled is the state of an operation which was cancelled while waiting in the queue.
+	OperationCancelled = "cancelled"
+	// OperationFailed is the state of an operation which left the queue but whose pod couldn't be created.
+	OperationFailed = "failed"
+)
+
+// OperationQueueStatus is the state of an operation in the queue of the operations waiting to run.
+type OperationQueueStatus struct {
+	// State is queued, started, cancelled or failed.
+	State string `json:"state"`
+	// Position is the position of the operation in the queue, starting at 1, while it is queued.
+	Position int `json:"position,omitempty"`
+	// Reason is why the operation is waiting.
+	Reason string `json:"reason,omitempty"`
+	// QueueTime is when the operation was queued.
+	QueueTime metav1.Time `json:"queueTime,omitempty"`
+	// StartTime is when the operation left the queue, when it was started, cancelled or failed to start.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// Releases are the releases the operation acts on, as <namespace>/<name>. Operations on the same release run one
+	// at a time.
+	Releases []string `json:"releases,omitempty"`
+}
+
+const (
+	// OperationHealthy is true once the workloads of the release upgraded by an operation with a health check are rolled
+	// out, and false if they weren't rolled out within the timeout of the health check.
+	OperationHealthy condition.Cond = "Healthy"
+	// OperationRolledBack is true once a release which failed the health check of its upgrade is rolled back to its
+	// previous revision.
+	OperationRolledBack condition.Cond = "RolledBack"
+)
+
+// OperationHealthCheck is the verification of the health of the release upgraded by an operation.
+type OperationHealthCheck struct {
+	// Timeout within which the workloads of the release must be rolled out once helm completed the upgrade.
+	Timeout metav1.Duration `json:"timeout"`
+	// PreviousRevision is the revision of the release before the upgrade, which it is rolled back to if it isn't
+	// healthy. Releases which were installed by the operation are not rolled back. The rollback is run as the identity
+	// the upgrade was run as, which is kept with the revision in a Secret of the system namespace.
+	PreviousRevision int `json:"previousRevision,omitempty"`
+	// StartTime is when helm completed the upgrade and the verification started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// RollbackOperationName is the name of the operation rolling back the release.
+	RollbackOperationName string `json:"rollbackOperationName,omitempty"`
+}
+
+const (
+	ResourceAdded     = "Added"
+	ResourceRemoved   = "Removed"
+	ResourceModified  = "Modified"
+	ResourceUnchanged = "Unchanged"
+)
+
+// ResourceDiff is the change of a resource between the manifest of the current release and the manifest rendered by
+// a dry-run operation.
+type ResourceDiff struct {
+	ReleaseResource `json:",inline"`
+	// Release is the name of the release rendering the resource.
+	Release string `json:"release,omitempty"`
+	// Change is either Added, Removed, Modified or Unchanged.
+	Change string `json:"change"`
+	// Diff is the unified diff of the YAML of the resource, with the values of Secrets redacted. It is omitted once the
+	// diffs of the operation grow too large to be stored in its status.
+	Diff string `json:"diff,omitempty"`
 }
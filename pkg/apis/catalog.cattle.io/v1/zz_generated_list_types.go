@@ -43,6 +43,40 @@ func NewApp(namespace, name string, obj App) *App {
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
+// AppInstallList is a list of AppInstall resources
+type AppInstallList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []AppInstall `json:"items"`
+}
+
+func NewAppInstall(namespace, name string, obj AppInstall) *AppInstall {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("AppInstall").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterReleaseList is a list of ClusterRelease resources
+type ClusterReleaseList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []ClusterRelease `json:"items"`
+}
+
+func NewClusterRelease(namespace, name string, obj ClusterRelease) *ClusterRelease {
+	obj.APIVersion, obj.Kind = SchemeGroupVersion.WithKind("ClusterRelease").ToAPIVersionAndKind()
+	obj.Name = name
+	obj.Namespace = namespace
+	return &obj
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
 // ClusterRepoList is a list of ClusterRepo resources
 type ClusterRepoList struct {
 	metav1.TypeMeta `json:",inline"`
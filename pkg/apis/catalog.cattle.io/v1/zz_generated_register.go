@@ -28,10 +28,12 @@
 )
 
 var (
-	AppResourceName         = "apps"
-	ClusterRepoResourceName = "clusterrepos"
-	OperationResourceName   = "operations"
-	UIPluginResourceName    = "uiplugins"
+	AppResourceName            = "apps"
+	AppInstallResourceName     = "appinstalls"
+	ClusterReleaseResourceName = "clusterreleases"
+	ClusterRepoResourceName    = "clusterrepos"
+	OperationResourceName      = "operations"
+	UIPluginResourceName       = "uiplugins"
 )
 
 // SchemeGroupVersion is group version used to register these objects
@@ -57,6 +59,10 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&App{},
 		&AppList{},
+		&AppInstall{},
+		&AppInstallList{},
+		&ClusterRelease{},
+		&ClusterReleaseList{},
 		&ClusterRepo{},
 		&ClusterRepoList{},
 		&Operation{},
@@ -28,6 +28,8 @@ type KontainerDriverStatus struct {
 	ExecutablePath string      `json:"executablePath"`
 	Conditions     []Condition `json:"conditions"`
 	DisplayName    string      `json:"displayName"`
+	// SignatureVerification is the result of the verification of the signature of the last downloaded driver binary.
+	SignatureVerification *DriverSignatureVerification `json:"signatureVerification,omitempty"`
 }
 
 type KontainerDriverSpec struct {
@@ -37,6 +39,8 @@ type KontainerDriverSpec struct {
 	Active           bool     `json:"active"`
 	UIURL            string   `json:"uiUrl"`
 	WhitelistDomains []string `json:"whitelistDomains,omitempty"`
+	// Signature configures the verification of the signature of the driver binary before it is installed.
+	Signature *DriverSignature `json:"signature,omitempty"`
 }
 
 var (
@@ -291,6 +291,8 @@ type NodeDriverStatus struct {
 	AppliedURL                  string      `json:"appliedURL"`
 	AppliedChecksum             string      `json:"appliedChecksum"`
 	AppliedDockerMachineVersion string      `json:"appliedDockerMachineVersion"`
+	// SignatureVerification is the result of the verification of the signature of the last downloaded driver binary.
+	SignatureVerification *DriverSignatureVerification `json:"signatureVerification,omitempty"`
 }
 
 var (
@@ -328,6 +330,29 @@ type NodeDriverSpec struct {
 	Checksum           string   `json:"checksum"`
 	UIURL              string   `json:"uiUrl"`
 	WhitelistDomains   []string `json:"whitelistDomains,omitempty"`
+	// Signature configures the verification of the signature of the driver binary before it is installed.
+	Signature *DriverSignature `json:"signature,omitempty"`
+}
+
+// DriverSignature configures the verification of a detached signature of a downloaded driver binary, either a cosign
+// signature created with "cosign sign-blob --key" or a minisign signature.
+type DriverSignature struct {
+	// URL of the signature. Defaults to the URL of the driver with the ".sig" suffix.
+	URL string `json:"url,omitempty"`
+	// PublicKey is a PEM encoded cosign public key or a minisign public key. If empty, the keys of the
+	// driver-signature-public-keys setting are used.
+	PublicKey string `json:"publicKey,omitempty"`
+}
+
+type DriverSignatureVerification struct {
+	Verified bool `json:"verified"`
+	// Format is either "cosign" or "minisign".
+	Format string `json:"format,omitempty"`
+	KeyID  string `json:"keyId,omitempty"`
+	// URL is the URL of the driver binary that was verified.
+	URL     string `json:"url,omitempty"`
+	Message string `json:"message,omitempty"`
+	Time    string `json:"time,omitempty"`
 }
 
 type PublicEndpoint struct {
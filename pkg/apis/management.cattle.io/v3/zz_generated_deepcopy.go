@@ -2014,6 +2014,38 @@ func (in *DockerInfo) DeepCopy() *DockerInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverSignature) DeepCopyInto(out *DriverSignature) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverSignature.
+func (in *DriverSignature) DeepCopy() *DriverSignature {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverSignature)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriverSignatureVerification) DeepCopyInto(out *DriverSignatureVerification) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriverSignatureVerification.
+func (in *DriverSignatureVerification) DeepCopy() *DriverSignatureVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(DriverSignatureVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DynamicSchema) DeepCopyInto(out *DynamicSchema) {
 	*out = *in
@@ -3739,6 +3771,11 @@ func (in *KontainerDriverSpec) DeepCopyInto(out *KontainerDriverSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(DriverSignature)
+		**out = **in
+	}
 	return
 }
 
@@ -3760,6 +3797,11 @@ func (in *KontainerDriverStatus) DeepCopyInto(out *KontainerDriverStatus) {
 		*out = make([]Condition, len(*in))
 		copy(*out, *in)
 	}
+	if in.SignatureVerification != nil {
+		in, out := &in.SignatureVerification, &out.SignatureVerification
+		*out = new(DriverSignatureVerification)
+		**out = **in
+	}
 	return
 }
 
@@ -4439,6 +4481,11 @@ func (in *NodeDriverSpec) DeepCopyInto(out *NodeDriverSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Signature != nil {
+		in, out := &in.Signature, &out.Signature
+		*out = new(DriverSignature)
+		**out = **in
+	}
 	return
 }
 
@@ -4460,6 +4507,11 @@ func (in *NodeDriverStatus) DeepCopyInto(out *NodeDriverStatus) {
 		*out = make([]Condition, len(*in))
 		copy(*out, *in)
 	}
+	if in.SignatureVerification != nil {
+		in, out := &in.SignatureVerification, &out.SignatureVerification
+		*out = new(DriverSignatureVerification)
+		**out = **in
+	}
 	return
 }
 
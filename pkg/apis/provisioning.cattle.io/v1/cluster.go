@@ -66,6 +66,8 @@ type ClusterStatus struct {
 	AgentDeployed      bool                                `json:"agentDeployed,omitempty"`
 	ObservedGeneration int64                               `json:"observedGeneration"`
 	Conditions         []genericcondition.GenericCondition `json:"conditions,omitempty"`
+	Autoscaling        *AutoscalingStatus                  `json:"autoscaling,omitempty"`
+	MachinePools       []MachinePoolStatus                 `json:"machinePools,omitempty"`
 }
 
 type ImportedConfig struct {
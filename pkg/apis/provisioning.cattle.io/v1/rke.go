@@ -2,6 +2,7 @@
 
 import (
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -30,6 +31,59 @@ type RKEMachinePool struct {
 	MachineOS                    string                       `json:"machineOS,omitempty"`
 	DynamicSchemaSpec            string                       `json:"dynamicSchemaSpec,omitempty"`
 	HostnameLengthLimit          int                          `json:"hostnameLengthLimit,omitempty"`
+
+	// MinSize is the minimum number of machines the cluster autoscaler may scale the pool down to. Defaults to 1.
+	MinSize *int32 `json:"minSize,omitempty"`
+	// MaxSize is the maximum number of machines the cluster autoscaler may scale the pool up to. The pool is only
+	// autoscaled if autoscaling is enabled for the cluster and MaxSize is set, in which case Quantity is ignored.
+	MaxSize *int32 `json:"maxSize,omitempty"`
+
+	// RetryPolicy re-runs the create job of a machine of the pool in place when it fails, instead of replacing the
+	// machine right away. The machine is only marked as failed once it is not retried anymore.
+	RetryPolicy *RKEMachinePoolRetryPolicy `json:"retryPolicy,omitempty"`
+
+	// MachineConfigVariants are alternative machine configs of the same kind as the machine config of the pool, such as
+	// other instance types or availability zones. If set, the fields of the variant assigned to a machine override the
+	// machine config of the pool when the machine is created. New machines are spread across the variants by weight,
+	// and a machine whose create job fails with a capacity error falls back to the next variant by priority. The machine
+	// is only marked as failed once it has no variant left to fall back to.
+	MachineConfigVariants []RKEMachineConfigVariant `json:"machineConfigVariants,omitempty"`
+	// MachineConfigFallbackFailures are regular expressions matched against the failure message of the create job to
+	// detect capacity errors that fall back to the next machine config variant. Defaults to the capacity and quota
+	// errors of the common cloud providers.
+	MachineConfigFallbackFailures []string `json:"machineConfigFallbackFailures,omitempty"`
+}
+
+type RKEMachineConfigVariant struct {
+	// Name identifies the variant and is recorded on the machines that use it.
+	Name string `json:"name,omitempty" wrangler:"required"`
+	// NodeConfig references the machine config of the variant. The kind defaults to the kind of the machine config of
+	// the pool.
+	NodeConfig *corev1.ObjectReference `json:"machineConfigRef,omitempty" wrangler:"required"`
+	// Weight is the share of the machines of the pool created with the variant. Defaults to 1. Variants with a weight of
+	// 0 are only used as a fallback.
+	Weight *int `json:"weight,omitempty"`
+	// Priority orders the variants a failed machine falls back to, lowest first. Variants with the same priority are
+	// tried in the order they are listed.
+	Priority int `json:"priority,omitempty"`
+}
+
+type RKEMachinePoolRetryPolicy struct {
+	// MaxAttempts is the number of times the create job of a machine is run before the machine is replaced. Defaults
+	// to 3.
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+	// Backoff is the delay before the first retry of a machine, doubled for every following retry. Defaults to 30s.
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
+	// MaxBackoff caps the delay between retries. Defaults to 10m.
+	MaxBackoff *metav1.Duration `json:"maxBackoff,omitempty"`
+	// RetryableFailures are regular expressions matched against the failure message of the create job, such as
+	// "(?i)quota" or "(?i)timeout". Failures that do not match any of them replace the machine without retrying. If
+	// empty, every failure is retryable.
+	RetryableFailures []string `json:"retryableFailures,omitempty"`
+	// FailureBudget is the number of failed create jobs tolerated in the pool since a machine of the pool was last
+	// provisioned successfully. Once it is used up, failed machines are neither retried nor replaced, and the pool
+	// reports the FailureBudgetExhausted condition. If unset, the budget is unlimited.
+	FailureBudget *int `json:"failureBudget,omitempty"`
 }
 
 type RKEMachinePoolRollingUpdate struct {
@@ -77,12 +131,64 @@ type RKEConfig struct {
 	ETCDSnapshotRestore  *rkev1.ETCDSnapshotRestore  `json:"etcdSnapshotRestore,omitempty"`
 	RotateCertificates   *rkev1.RotateCertificates   `json:"rotateCertificates,omitempty"`
 	RotateEncryptionKeys *rkev1.RotateEncryptionKeys `json:"rotateEncryptionKeys,omitempty"`
+	ETCDMaintenance      *rkev1.ETCDMaintenance      `json:"etcdMaintenance,omitempty"`
+	NodeMaintenance      *rkev1.NodeMaintenance      `json:"nodeMaintenance,omitempty"`
 
 	MachinePools        []RKEMachinePool        `json:"machinePools,omitempty"`
 	MachinePoolDefaults RKEMachinePoolDefaults  `json:"machinePoolDefaults,omitempty"`
 	InfrastructureRef   *corev1.ObjectReference `json:"infrastructureRef,omitempty"`
+
+	// Autoscaling configures the cluster autoscaler that Rancher deploys into the cluster to scale its machine pools.
+	Autoscaling *RKEAutoscaling `json:"autoscaling,omitempty"`
+}
+
+type RKEAutoscaling struct {
+	// Enabled deploys the cluster autoscaler into the cluster, managing every machine pool that has a MaxSize set.
+	Enabled bool `json:"enabled,omitempty"`
+	// ExtraArgs are additional arguments passed to the cluster autoscaler, such as "--scale-down-unneeded-time=5m".
+	ExtraArgs []string `json:"extraArgs,omitempty"`
 }
 
 type RKEMachinePoolDefaults struct {
 	HostnameLengthLimit int `json:"hostnameLengthLimit,omitempty"`
 }
+
+// AutoscalingStatus reports the bounds and the recent scaling activity of the autoscaled machine pools of a cluster.
+type AutoscalingStatus struct {
+	MachinePools []MachinePoolAutoscalingStatus `json:"machinePools,omitempty"`
+	// ScaleEvents are the most recent changes made to the size of the autoscaled machine pools, oldest first.
+	ScaleEvents []AutoscalingScaleEvent `json:"scaleEvents,omitempty"`
+}
+
+type MachinePoolAutoscalingStatus struct {
+	Name     string `json:"name,omitempty"`
+	MinSize  int32  `json:"minSize"`
+	MaxSize  int32  `json:"maxSize"`
+	Replicas int32  `json:"replicas"`
+}
+
+// MachinePoolStatus reports the provisioning failures of a machine pool that has a retry policy.
+type MachinePoolStatus struct {
+	Name string `json:"name,omitempty"`
+	// ProvisioningFailures is the number of failed create jobs since a machine of the pool was last provisioned
+	// successfully.
+	ProvisioningFailures int `json:"provisioningFailures,omitempty"`
+	// LastFailures are the most recent provisioning failures, oldest first.
+	LastFailures []MachineProvisioningFailure        `json:"lastFailures,omitempty"`
+	Conditions   []genericcondition.GenericCondition `json:"conditions,omitempty"`
+}
+
+type MachineProvisioningFailure struct {
+	Time    metav1.Time `json:"time,omitempty"`
+	Machine string      `json:"machine,omitempty"`
+	Attempt int         `json:"attempt,omitempty"`
+	Variant string      `json:"variant,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+type AutoscalingScaleEvent struct {
+	Time        metav1.Time `json:"time,omitempty"`
+	MachinePool string      `json:"machinePool,omitempty"`
+	From        int32       `json:"from"`
+	To          int32       `json:"to"`
+}
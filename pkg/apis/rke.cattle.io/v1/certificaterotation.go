@@ -1,6 +1,50 @@
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
 type RotateCertificates struct {
 	Generation int64    `json:"generation,omitempty"`
 	Services   []string `json:"services,omitempty"`
 }
+
+// CertificateRotationPolicy configures the automatic rotation of certificates that are about to expire.
+type CertificateRotationPolicy struct {
+	// Enabled turns on automatic certificate rotation.
+	Enabled bool `json:"enabled,omitempty"`
+	// ExpiryThresholdDays is the number of days before expiry at which a certificate is rotated. Defaults to 30.
+	ExpiryThresholdDays int `json:"expiryThresholdDays,omitempty"`
+	// MaintenanceWindows restricts automatic certificate rotation to the given windows. If no windows are specified,
+	// certificates are rotated as soon as they fall within the expiry threshold.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindow is a recurring window of time during which disruptive operations may be performed.
+type MaintenanceWindow struct {
+	// ScheduleCron is the cron schedule on which the window opens.
+	ScheduleCron string `json:"scheduleCron,omitempty"`
+	// Duration is how long the window stays open once it has opened.
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+// CertificateExpiryStatus contains the expiry of the certificates found on each machine of the cluster.
+type CertificateExpiryStatus struct {
+	Machines []MachineCertificateExpiry `json:"machines,omitempty"`
+}
+
+type MachineCertificateExpiry struct {
+	MachineName string `json:"machineName,omitempty"`
+	NodeName    string `json:"nodeName,omitempty"`
+	// ObservedTime is the time the certificates were last read from the machine.
+	ObservedTime *metav1.Time        `json:"observedTime,omitempty"`
+	Certificates []CertificateExpiry `json:"certificates,omitempty"`
+}
+
+type CertificateExpiry struct {
+	// Name is the name of the certificate file, without the extension.
+	Name string `json:"name,omitempty"`
+	// Service is the service the certificate is rotated with, as accepted by RotateCertificates.
+	Service        string      `json:"service,omitempty"`
+	ExpirationDate metav1.Time `json:"expirationDate,omitempty"`
+}
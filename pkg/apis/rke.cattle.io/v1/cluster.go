@@ -73,6 +73,18 @@ type RKEClusterSpecCommon struct {
 	Registries            *Registry              `json:"registries,omitempty"`
 	ETCD                  *ETCD                  `json:"etcd,omitempty"`
 
+	// MachineSelectorLifecycleHooks are run by the system-agent on the selected machines during planner operations.
+	MachineSelectorLifecycleHooks []RKELifecycleHook `json:"machineSelectorLifecycleHooks,omitempty"`
+
+	// CertificateRotationPolicy configures the automatic rotation of certificates that are about to expire.
+	CertificateRotationPolicy *CertificateRotationPolicy `json:"certificateRotationPolicy,omitempty"`
+
+	// EncryptionKeyRotationSchedule configures the periodic rotation of the secrets encryption keys.
+	EncryptionKeyRotationSchedule *EncryptionKeyRotationSchedule `json:"encryptionKeyRotationSchedule,omitempty"`
+
+	// PreflightChecks configures the checks run on custom machines before they join the cluster.
+	PreflightChecks *PreflightChecks `json:"preflightChecks,omitempty"`
+
 	// Networking contains information regarding the desired and actual networking stack of the cluster.
 	Networking *Networking `json:"networking,omitempty"`
 
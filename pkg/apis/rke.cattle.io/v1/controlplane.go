@@ -30,6 +30,8 @@ type RKEControlPlaneSpec struct {
 	ETCDSnapshotRestore      *ETCDSnapshotRestore     `json:"etcdSnapshotRestore,omitempty"`
 	RotateCertificates       *RotateCertificates      `json:"rotateCertificates,omitempty"`
 	RotateEncryptionKeys     *RotateEncryptionKeys    `json:"rotateEncryptionKeys,omitempty"`
+	ETCDMaintenance          *ETCDMaintenance         `json:"etcdMaintenance,omitempty"`
+	NodeMaintenance          *NodeMaintenance         `json:"nodeMaintenance,omitempty"`
 	KubernetesVersion        string                   `json:"kubernetesVersion,omitempty"`
 	ClusterName              string                   `json:"clusterName,omitempty" wrangler:"required"`
 	ManagementClusterName    string                   `json:"managementClusterName,omitempty" wrangler:"required"`
@@ -42,13 +44,25 @@ type RKEControlPlaneStatus struct {
 	Ready                         bool                                `json:"ready,omitempty"`
 	ObservedGeneration            int64                               `json:"observedGeneration"`
 	CertificateRotationGeneration int64                               `json:"certificateRotationGeneration"`
+	CertificateRotationTime       *metav1.Time                        `json:"certificateRotationTime,omitempty"`
+	CertificateExpiry             *CertificateExpiryStatus            `json:"certificateExpiry,omitempty"`
 	RotateEncryptionKeys          *RotateEncryptionKeys               `json:"rotateEncryptionKeys,omitempty"`
 	RotateEncryptionKeysPhase     RotateEncryptionKeysPhase           `json:"rotateEncryptionKeysPhase,omitempty"`
 	RotateEncryptionKeysLeader    string                              `json:"rotateEncryptionKeysLeader,omitempty"`
+	EncryptionKeyRotationTime     *metav1.Time                        `json:"encryptionKeyRotationTime,omitempty"`
+	EncryptionKeyAgeDays          int64                               `json:"encryptionKeyAgeDays,omitempty"`
 	ETCDSnapshotRestore           *ETCDSnapshotRestore                `json:"etcdSnapshotRestore,omitempty"`
 	ETCDSnapshotRestorePhase      ETCDSnapshotPhase                   `json:"etcdSnapshotRestorePhase,omitempty"`
 	ETCDSnapshotCreate            *ETCDSnapshotCreate                 `json:"etcdSnapshotCreate,omitempty"`
 	ETCDSnapshotCreatePhase       ETCDSnapshotPhase                   `json:"etcdSnapshotCreatePhase,omitempty"`
+	ETCDSnapshotVerificationTime  *metav1.Time                        `json:"etcdSnapshotVerificationTime,omitempty"`
+	ETCDMaintenance               *ETCDMaintenance                    `json:"etcdMaintenance,omitempty"`
+	ETCDMaintenancePhase          ETCDMaintenancePhase                `json:"etcdMaintenancePhase,omitempty"`
+	ETCDMaintenanceStatus         *ETCDMaintenanceStatus              `json:"etcdMaintenanceStatus,omitempty"`
+	NodeMaintenance               *NodeMaintenance                    `json:"nodeMaintenance,omitempty"`
+	NodeMaintenancePhase          NodeMaintenancePhase                `json:"nodeMaintenancePhase,omitempty"`
+	NodeMaintenanceStatus         *NodeMaintenanceStatus              `json:"nodeMaintenanceStatus,omitempty"`
+	ETCDMemberHealth              *ETCDMemberHealthStatus             `json:"etcdMemberHealth,omitempty"`
 	ConfigGeneration              int64                               `json:"configGeneration,omitempty"`
 	Initialized                   bool                                `json:"initialized,omitempty"`
 	AgentConnected                bool                                `json:"agentConnected,omitempty"`
@@ -16,3 +16,12 @@
 type RotateEncryptionKeys struct {
 	Generation int64 `json:"generation,omitempty"`
 }
+
+// EncryptionKeyRotationSchedule configures the periodic rotation of the secrets encryption keys.
+type EncryptionKeyRotationSchedule struct {
+	// Enabled turns on scheduled encryption key rotation.
+	Enabled bool `json:"enabled,omitempty"`
+	// IntervalDays is the number of days after the last successful rotation, or the creation of the cluster if the keys
+	// have never been rotated, at which the keys are rotated again. Defaults to 90.
+	IntervalDays int `json:"intervalDays,omitempty"`
+}
@@ -71,11 +71,55 @@ type ETCDSnapshotFile struct {
 
 type ETCDSnapshotStatus struct {
 	Missing bool `json:"missing"`
+
+	// Verification contains the result of the most recent integrity verification of the snapshot file, if any.
+	Verification *ETCDSnapshotVerificationStatus `json:"verification,omitempty"`
+}
+
+type ETCDSnapshotVerificationStatus struct {
+	// VerifiedAt is the time at which the verification finished.
+	VerifiedAt *metav1.Time `json:"verifiedAt,omitempty"`
+	// Verified is true if every integrity check passed.
+	Verified bool `json:"verified"`
+	// Message contains the reason the verification failed.
+	Message string `json:"message,omitempty"`
+	// SHA256 is the checksum of the snapshot database.
+	SHA256 string `json:"sha256,omitempty"`
+	// HashChecked is true if the snapshot contained an embedded checksum that was compared against the database.
+	HashChecked bool `json:"hashChecked,omitempty"`
+	// Revision is the latest etcd revision found in the snapshot.
+	Revision int64 `json:"revision,omitempty"`
+	// KeyCount is the total number of keys across all buckets of the snapshot database.
+	KeyCount int64 `json:"keyCount,omitempty"`
+	// DBSize is the size of the snapshot database in bytes.
+	DBSize int64 `json:"dbSize,omitempty"`
 }
 
 type ETCD struct {
-	DisableSnapshots     bool            `json:"disableSnapshots,omitempty"`
-	SnapshotScheduleCron string          `json:"snapshotScheduleCron,omitempty"`
-	SnapshotRetention    int             `json:"snapshotRetention,omitempty"`
-	S3                   *ETCDSnapshotS3 `json:"s3,omitempty"`
+	DisableSnapshots     bool                      `json:"disableSnapshots,omitempty"`
+	SnapshotScheduleCron string                    `json:"snapshotScheduleCron,omitempty"`
+	SnapshotRetention    int                       `json:"snapshotRetention,omitempty"`
+	S3                   *ETCDSnapshotS3           `json:"s3,omitempty"`
+	SnapshotVerification *ETCDSnapshotVerification `json:"snapshotVerification,omitempty"`
+	MaintenanceSchedule  *ETCDMaintenanceSchedule  `json:"maintenanceSchedule,omitempty"`
+}
+
+type ETCDSnapshotVerificationStrategy string
+
+const (
+	// ETCDSnapshotVerificationStrategyLatest verifies the most recently created snapshot.
+	ETCDSnapshotVerificationStrategyLatest ETCDSnapshotVerificationStrategy = "latest"
+	// ETCDSnapshotVerificationStrategySampled verifies a randomly selected snapshot.
+	ETCDSnapshotVerificationStrategySampled ETCDSnapshotVerificationStrategy = "sampled"
+)
+
+// ETCDSnapshotVerification configures the scheduled integrity verification of etcd snapshots stored in S3. Snapshots
+// are downloaded with the S3 cloud credential of the cluster, snapshots without one are reported as unverifiable.
+type ETCDSnapshotVerification struct {
+	// Enabled turns on scheduled snapshot verification for the cluster.
+	Enabled bool `json:"enabled,omitempty"`
+	// ScheduleCron is the cron schedule on which a snapshot is verified. Defaults to once a day.
+	ScheduleCron string `json:"scheduleCron,omitempty"`
+	// Strategy determines which snapshot is verified, either "latest" (default) or "sampled".
+	Strategy ETCDSnapshotVerificationStrategy `json:"strategy,omitempty"`
 }
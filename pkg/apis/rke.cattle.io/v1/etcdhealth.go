@@ -0,0 +1,39 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ETCDMemberHealthStatus summarizes the health of the etcd cluster, as reported by the most recent etcd member health
+// check run on the etcd machines.
+type ETCDMemberHealthStatus struct {
+	// ObservedTime is the time the health check that the status was built from last ran successfully.
+	ObservedTime *metav1.Time `json:"observedTime,omitempty"`
+	// ObservedBy is the name of the machine that reported the health of the etcd cluster.
+	ObservedBy string             `json:"observedBy,omitempty"`
+	Members    []ETCDMemberHealth `json:"members,omitempty"`
+	// Quorum is the number of healthy voting members required for the etcd cluster to remain available.
+	Quorum         int `json:"quorum,omitempty"`
+	HealthyMembers int `json:"healthyMembers,omitempty"`
+	// FaultTolerance is the number of healthy voting members that can be lost before quorum is lost. A negative value
+	// indicates that quorum has already been lost.
+	FaultTolerance int `json:"faultTolerance"`
+}
+
+type ETCDMemberHealth struct {
+	Name        string `json:"name,omitempty"`
+	MemberID    string `json:"memberID,omitempty"`
+	MachineName string `json:"machineName,omitempty"`
+	NodeName    string `json:"nodeName,omitempty"`
+	Leader      bool   `json:"leader,omitempty"`
+	Learner     bool   `json:"learner,omitempty"`
+	// Healthy is true if the member responded to the health check without errors.
+	Healthy     bool  `json:"healthy,omitempty"`
+	DBSize      int64 `json:"dbSize,omitempty"`
+	DBSizeInUse int64 `json:"dbSizeInUse,omitempty"`
+	RaftIndex   int64 `json:"raftIndex,omitempty"`
+	// RaftIndexLag is the number of raft entries the member is behind the leader.
+	RaftIndexLag int64    `json:"raftIndexLag,omitempty"`
+	Alarms       []string `json:"alarms,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+}
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type ETCDMaintenancePhase string
+
+const (
+	ETCDMaintenancePhaseStarted      ETCDMaintenancePhase = "Started"
+	ETCDMaintenancePhaseCompact      ETCDMaintenancePhase = "Compact"
+	ETCDMaintenancePhaseDefragment   ETCDMaintenancePhase = "Defragment"
+	ETCDMaintenancePhaseDisarmAlarms ETCDMaintenancePhase = "DisarmAlarms"
+	ETCDMaintenancePhaseFinished     ETCDMaintenancePhase = "Finished"
+	ETCDMaintenancePhaseFailed       ETCDMaintenancePhase = "Failed"
+)
+
+type ETCDMaintenance struct {
+	// Changing the Generation is the only thing required to initiate an etcd maintenance operation.
+	Generation int64 `json:"generation,omitempty"`
+	// Compact compacts the etcd keyspace to the current revision before the members are defragmented.
+	Compact bool `json:"compact,omitempty"`
+	// DisarmAlarms disarms any NOSPACE alarms once the members have been defragmented.
+	DisarmAlarms bool `json:"disarmAlarms,omitempty"`
+}
+
+// ETCDMaintenanceSchedule configures etcd maintenance to run periodically, in addition to on demand runs requested
+// through ETCDMaintenance.
+type ETCDMaintenanceSchedule struct {
+	// ScheduleCron is the cron schedule on which etcd maintenance is run.
+	ScheduleCron string `json:"scheduleCron,omitempty"`
+	// Compact compacts the etcd keyspace to the current revision before the members are defragmented.
+	Compact bool `json:"compact,omitempty"`
+	// DisarmAlarms disarms any NOSPACE alarms once the members have been defragmented.
+	DisarmAlarms bool `json:"disarmAlarms,omitempty"`
+}
+
+// ETCDMaintenanceStatus contains the progress and results of the current or most recent etcd maintenance run.
+type ETCDMaintenanceStatus struct {
+	// Scheduled is true if the run was started by the maintenance schedule rather than on demand.
+	Scheduled    bool         `json:"scheduled,omitempty"`
+	Compact      bool         `json:"compact,omitempty"`
+	DisarmAlarms bool         `json:"disarmAlarms,omitempty"`
+	StartTime    *metav1.Time `json:"startTime,omitempty"`
+	FinishTime   *metav1.Time `json:"finishTime,omitempty"`
+	Message      string       `json:"message,omitempty"`
+	// Members are ordered in the order they are defragmented, with the leader last.
+	Members []ETCDMemberMaintenanceStatus `json:"members,omitempty"`
+}
+
+type ETCDMemberMaintenanceStatus struct {
+	MachineName  string `json:"machineName,omitempty"`
+	NodeName     string `json:"nodeName,omitempty"`
+	MemberID     string `json:"memberID,omitempty"`
+	Leader       bool   `json:"leader,omitempty"`
+	Defragmented bool   `json:"defragmented,omitempty"`
+	DBSizeBefore int64  `json:"dbSizeBefore,omitempty"`
+	DBSizeAfter  int64  `json:"dbSizeAfter,omitempty"`
+}
@@ -0,0 +1,42 @@
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+// LifecycleHookPoint is a point in the lifecycle of a machine at which lifecycle hooks are run.
+type LifecycleHookPoint string
+
+const (
+	// LifecycleHookPreUpgrade hooks run before the plan that restarts the distro on a machine is applied, e.g. for a
+	// Kubernetes version upgrade or a configuration change. They run after the machine has been drained.
+	LifecycleHookPreUpgrade LifecycleHookPoint = "pre-upgrade"
+	// LifecycleHookPostUpgrade hooks run once the upgraded machine is healthy again, before it is uncordoned.
+	LifecycleHookPostUpgrade LifecycleHookPoint = "post-upgrade"
+	// LifecycleHookPreRestore hooks run before the distro is stopped on a machine to restore an etcd snapshot.
+	LifecycleHookPreRestore LifecycleHookPoint = "pre-restore"
+	// LifecycleHookPostJoin hooks run once a new machine has joined the cluster and is healthy.
+	LifecycleHookPostJoin LifecycleHookPoint = "post-join"
+	// LifecycleHookPreDelete hooks run before a deleting machine is drained.
+	LifecycleHookPreDelete LifecycleHookPoint = "pre-delete"
+)
+
+// RKELifecycleHook is a script or a command from an image that the system-agent runs on the selected machines at the
+// given points of their lifecycle. The planner waits for the hooks to succeed before it proceeds, and their output is
+// saved in the machine plan secret. Lifecycle hooks are not run on Windows machines.
+type RKELifecycleHook struct {
+	// Name identifies the hook in the plan and its output.
+	Name string `json:"name"`
+	// MachineLabelSelector selects the machines the hook runs on. All machines are selected if it is not set.
+	MachineLabelSelector *metav1.LabelSelector `json:"machineLabelSelector,omitempty"`
+	// Points are the points in the lifecycle of the machine at which the hook runs.
+	Points []LifecycleHookPoint `json:"points"`
+	// Script is a shell script run with /bin/sh. Either Script or Command must be set.
+	Script string `json:"script,omitempty"`
+	// Image is extracted by the system-agent before Command is run in its directory.
+	Image string `json:"image,omitempty"`
+	// Command is the command to run, with Args as its arguments.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Env are environment variables set for the hook, in addition to CATTLE_LIFECYCLE_HOOK with the point the hook is
+	// run at and CATTLE_KUBERNETES_VERSION.
+	Env []EnvVar `json:"env,omitempty"`
+}
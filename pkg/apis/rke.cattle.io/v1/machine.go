@@ -23,6 +23,21 @@ type RKEMachineStatus struct {
 	FailureReason             string                              `json:"failureReason,omitempty"`
 	FailureMessage            string                              `json:"failureMessage,omitempty"`
 	Addresses                 []capi.MachineAddress               `json:"addresses,omitempty"`
+	// CreateAttempts is the number of times the create job of the machine has been run, if it has been retried.
+	CreateAttempts int `json:"createAttempts,omitempty"`
+	// NextRetryTime is when the failed create job of the machine is retried.
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+	// CreateFailureMessage is the failure of the last create attempt of a machine that may be created again, which is
+	// only reported through FailureReason and FailureMessage once the machine is not retried anymore.
+	CreateFailureMessage string `json:"createFailureMessage,omitempty"`
+	// FailureCountedAttempt is the last create attempt of the machine whose failure was counted against the failure
+	// budget of its machine pool.
+	FailureCountedAttempt int `json:"failureCountedAttempt,omitempty"`
+	// MachineConfigVariant is the machine config variant of the machine pool the machine is created with.
+	MachineConfigVariant string `json:"machineConfigVariant,omitempty"`
+	// FailedMachineConfigVariants are the machine config variants the create job of the machine failed with before
+	// falling back to another variant.
+	FailedMachineConfigVariants []string `json:"failedMachineConfigVariants,omitempty"`
 }
 
 // +genclient
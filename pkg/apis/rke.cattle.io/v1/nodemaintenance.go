@@ -0,0 +1,80 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type NodeMaintenancePhase string
+
+const (
+	NodeMaintenancePhaseStarted  NodeMaintenancePhase = "Started"
+	NodeMaintenancePhaseRunning  NodeMaintenancePhase = "Running"
+	NodeMaintenancePhaseFinished NodeMaintenancePhase = "Finished"
+	NodeMaintenancePhaseFailed   NodeMaintenancePhase = "Failed"
+)
+
+type NodeMaintenanceNodePhase string
+
+const (
+	NodeMaintenanceNodePhasePending          NodeMaintenanceNodePhase = "Pending"
+	NodeMaintenanceNodePhaseDraining         NodeMaintenanceNodePhase = "Draining"
+	NodeMaintenanceNodePhaseRunning          NodeMaintenanceNodePhase = "Running"
+	NodeMaintenanceNodePhaseRebooting        NodeMaintenanceNodePhase = "Rebooting"
+	NodeMaintenanceNodePhaseWaitingForProbes NodeMaintenanceNodePhase = "WaitingForProbes"
+	NodeMaintenanceNodePhaseUncordoning      NodeMaintenanceNodePhase = "Uncordoning"
+	NodeMaintenanceNodePhaseDone             NodeMaintenanceNodePhase = "Done"
+	NodeMaintenanceNodePhaseFailed           NodeMaintenanceNodePhase = "Failed"
+	// NodeMaintenanceNodePhaseSkipped is set on machines that were deleted during the run.
+	NodeMaintenanceNodePhaseSkipped NodeMaintenanceNodePhase = "Skipped"
+)
+
+// NodeMaintenance rolls through the selected machines, draining each node, running an instruction such as an OS package
+// upgrade, optionally rebooting the machine, and uncordoning the node once its probes are healthy again. Machines with
+// the etcd or controlplane role are maintained one at a time, ahead of the remaining machines. Windows machines are not
+// maintained.
+type NodeMaintenance struct {
+	// Changing the Generation is the only thing required to initiate a node maintenance operation.
+	Generation int64 `json:"generation,omitempty"`
+	// MachineLabelSelector selects the machines to maintain. All machines are selected if it is not set.
+	MachineLabelSelector *metav1.LabelSelector `json:"machineLabelSelector,omitempty"`
+	// Concurrency is the number or percentage of the selected machines that may be unavailable at a time. It defaults
+	// to 1 and does not apply to machines with the etcd or controlplane role.
+	Concurrency string `json:"concurrency,omitempty"`
+	// DrainOptions are used to drain the nodes. The drain options of the upgrade strategy for the role of the machine
+	// are used if they are not set. Nodes are always cordoned, even if draining is not enabled.
+	DrainOptions *DrainOptions `json:"drainOptions,omitempty"`
+	// Script is a shell script run with /bin/sh on each machine once its node is drained. At most one of Script and
+	// Command may be set.
+	Script string `json:"script,omitempty"`
+	// Image is extracted by the system-agent before Command is run in its directory.
+	Image string `json:"image,omitempty"`
+	// Command is the command to run, with Args as its arguments.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Env are environment variables set for the script or command.
+	Env []EnvVar `json:"env,omitempty"`
+	// Reboot reboots each machine after the instruction has succeeded, and waits for it to come back before its probes
+	// are checked.
+	Reboot bool `json:"reboot,omitempty"`
+}
+
+// NodeMaintenanceStatus contains the progress of the current or most recent node maintenance run.
+type NodeMaintenanceStatus struct {
+	StartTime  *metav1.Time `json:"startTime,omitempty"`
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+	Message    string       `json:"message,omitempty"`
+	// Nodes are ordered in the order they are maintained.
+	Nodes []NodeMaintenanceNodeStatus `json:"nodes,omitempty"`
+}
+
+type NodeMaintenanceNodeStatus struct {
+	MachineName string                   `json:"machineName,omitempty"`
+	NodeName    string                   `json:"nodeName,omitempty"`
+	Phase       NodeMaintenanceNodePhase `json:"phase,omitempty"`
+	// BootID is the boot ID of the machine before it was rebooted.
+	BootID             string       `json:"bootID,omitempty"`
+	StartTime          *metav1.Time `json:"startTime,omitempty"`
+	FinishTime         *metav1.Time `json:"finishTime,omitempty"`
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	Message            string       `json:"message,omitempty"`
+}
@@ -0,0 +1,31 @@
+package v1
+
+type PreflightMode string
+
+const (
+	// PreflightModeBlock holds a machine that failed a pre-flight check back from joining the cluster, and runs the
+	// checks again periodically until they pass.
+	PreflightModeBlock PreflightMode = "block"
+	// PreflightModeWarn reports the failed pre-flight checks of a machine on its conditions, but lets it join the cluster.
+	PreflightModeWarn PreflightMode = "warn"
+)
+
+// PreflightChecks configures the checks that are run on custom machines before they are given the plan that joins them
+// to the cluster.
+type PreflightChecks struct {
+	// Enabled turns on the pre-flight checks.
+	Enabled bool `json:"enabled,omitempty"`
+	// Mode is either "block" or "warn". Defaults to "block".
+	Mode PreflightMode `json:"mode,omitempty"`
+	// MinDataDirFreeGiB is the free disk space required for the distro data directory. Defaults to 10.
+	MinDataDirFreeGiB int `json:"minDataDirFreeGiB,omitempty"`
+	// Sysctls are kernel parameters that must be set to the given values.
+	Sysctls map[string]string `json:"sysctls,omitempty"`
+	// KernelModules are kernel modules that must be loaded or loadable. Defaults to overlay and br_netfilter.
+	KernelModules []string `json:"kernelModules,omitempty"`
+	// Registries are the registry hosts that must be reachable. Defaults to the registry of the system agent installer
+	// image.
+	Registries []string `json:"registries,omitempty"`
+	// Skip lists checks that are not run: ports, disk, sysctls, modules, timesync or registry.
+	Skip []string `json:"skip,omitempty"`
+}
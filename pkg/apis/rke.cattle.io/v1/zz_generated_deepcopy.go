@@ -45,6 +45,67 @@ func (in *AuthConfig) DeepCopy() *AuthConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateExpiry) DeepCopyInto(out *CertificateExpiry) {
+	*out = *in
+	in.ExpirationDate.DeepCopyInto(&out.ExpirationDate)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateExpiry.
+func (in *CertificateExpiry) DeepCopy() *CertificateExpiry {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateExpiry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateExpiryStatus) DeepCopyInto(out *CertificateExpiryStatus) {
+	*out = *in
+	if in.Machines != nil {
+		in, out := &in.Machines, &out.Machines
+		*out = make([]MachineCertificateExpiry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateExpiryStatus.
+func (in *CertificateExpiryStatus) DeepCopy() *CertificateExpiryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateExpiryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateRotationPolicy) DeepCopyInto(out *CertificateRotationPolicy) {
+	*out = *in
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateRotationPolicy.
+func (in *CertificateRotationPolicy) DeepCopy() *CertificateRotationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateRotationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterUpgradeStrategy) DeepCopyInto(out *ClusterUpgradeStrategy) {
 	*out = *in
@@ -237,6 +298,16 @@ func (in *ETCD) DeepCopyInto(out *ETCD) {
 		*out = new(ETCDSnapshotS3)
 		**out = **in
 	}
+	if in.SnapshotVerification != nil {
+		in, out := &in.SnapshotVerification, &out.SnapshotVerification
+		*out = new(ETCDSnapshotVerification)
+		**out = **in
+	}
+	if in.MaintenanceSchedule != nil {
+		in, out := &in.MaintenanceSchedule, &out.MaintenanceSchedule
+		*out = new(ETCDMaintenanceSchedule)
+		**out = **in
+	}
 	return
 }
 
@@ -250,6 +321,136 @@ func (in *ETCD) DeepCopy() *ETCD {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMaintenance) DeepCopyInto(out *ETCDMaintenance) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMaintenance.
+func (in *ETCDMaintenance) DeepCopy() *ETCDMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMaintenanceSchedule) DeepCopyInto(out *ETCDMaintenanceSchedule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMaintenanceSchedule.
+func (in *ETCDMaintenanceSchedule) DeepCopy() *ETCDMaintenanceSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMaintenanceSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMaintenanceStatus) DeepCopyInto(out *ETCDMaintenanceStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FinishTime != nil {
+		in, out := &in.FinishTime, &out.FinishTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]ETCDMemberMaintenanceStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMaintenanceStatus.
+func (in *ETCDMaintenanceStatus) DeepCopy() *ETCDMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMemberHealth) DeepCopyInto(out *ETCDMemberHealth) {
+	*out = *in
+	if in.Alarms != nil {
+		in, out := &in.Alarms, &out.Alarms
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMemberHealth.
+func (in *ETCDMemberHealth) DeepCopy() *ETCDMemberHealth {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMemberHealth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMemberHealthStatus) DeepCopyInto(out *ETCDMemberHealthStatus) {
+	*out = *in
+	if in.ObservedTime != nil {
+		in, out := &in.ObservedTime, &out.ObservedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Members != nil {
+		in, out := &in.Members, &out.Members
+		*out = make([]ETCDMemberHealth, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMemberHealthStatus.
+func (in *ETCDMemberHealthStatus) DeepCopy() *ETCDMemberHealthStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMemberHealthStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDMemberMaintenanceStatus) DeepCopyInto(out *ETCDMemberMaintenanceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDMemberMaintenanceStatus.
+func (in *ETCDMemberMaintenanceStatus) DeepCopy() *ETCDMemberMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDMemberMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ETCDSnapshot) DeepCopyInto(out *ETCDSnapshot) {
 	*out = *in
@@ -257,7 +458,7 @@ func (in *ETCDSnapshot) DeepCopyInto(out *ETCDSnapshot) {
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
 	in.SnapshotFile.DeepCopyInto(&out.SnapshotFile)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -404,6 +605,11 @@ func (in *ETCDSnapshotSpec) DeepCopy() *ETCDSnapshotSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ETCDSnapshotStatus) DeepCopyInto(out *ETCDSnapshotStatus) {
 	*out = *in
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(ETCDSnapshotVerificationStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -417,6 +623,58 @@ func (in *ETCDSnapshotStatus) DeepCopy() *ETCDSnapshotStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotVerification) DeepCopyInto(out *ETCDSnapshotVerification) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotVerification.
+func (in *ETCDSnapshotVerification) DeepCopy() *ETCDSnapshotVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ETCDSnapshotVerificationStatus) DeepCopyInto(out *ETCDSnapshotVerificationStatus) {
+	*out = *in
+	if in.VerifiedAt != nil {
+		in, out := &in.VerifiedAt, &out.VerifiedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ETCDSnapshotVerificationStatus.
+func (in *ETCDSnapshotVerificationStatus) DeepCopy() *ETCDSnapshotVerificationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ETCDSnapshotVerificationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EncryptionKeyRotationSchedule) DeepCopyInto(out *EncryptionKeyRotationSchedule) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EncryptionKeyRotationSchedule.
+func (in *EncryptionKeyRotationSchedule) DeepCopy() *EncryptionKeyRotationSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(EncryptionKeyRotationSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EnvVar) DeepCopyInto(out *EnvVar) {
 	*out = *in
@@ -496,6 +754,50 @@ func (in *LocalClusterAuthEndpoint) DeepCopy() *LocalClusterAuthEndpoint {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MachineCertificateExpiry) DeepCopyInto(out *MachineCertificateExpiry) {
+	*out = *in
+	if in.ObservedTime != nil {
+		in, out := &in.ObservedTime, &out.ObservedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Certificates != nil {
+		in, out := &in.Certificates, &out.Certificates
+		*out = make([]CertificateExpiry, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MachineCertificateExpiry.
+func (in *MachineCertificateExpiry) DeepCopy() *MachineCertificateExpiry {
+	if in == nil {
+		return nil
+	}
+	out := new(MachineCertificateExpiry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Mirror) DeepCopyInto(out *Mirror) {
 	*out = *in
@@ -540,6 +842,139 @@ func (in *Networking) DeepCopy() *Networking {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMaintenance) DeepCopyInto(out *NodeMaintenance) {
+	*out = *in
+	if in.MachineLabelSelector != nil {
+		in, out := &in.MachineLabelSelector, &out.MachineLabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DrainOptions != nil {
+		in, out := &in.DrainOptions, &out.DrainOptions
+		*out = new(DrainOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]EnvVar, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenance.
+func (in *NodeMaintenance) DeepCopy() *NodeMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMaintenanceNodeStatus) DeepCopyInto(out *NodeMaintenanceNodeStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FinishTime != nil {
+		in, out := &in.FinishTime, &out.FinishTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastTransitionTime != nil {
+		in, out := &in.LastTransitionTime, &out.LastTransitionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceNodeStatus.
+func (in *NodeMaintenanceNodeStatus) DeepCopy() *NodeMaintenanceNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMaintenanceNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeMaintenanceStatus) DeepCopyInto(out *NodeMaintenanceStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FinishTime != nil {
+		in, out := &in.FinishTime, &out.FinishTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]NodeMaintenanceNodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeMaintenanceStatus.
+func (in *NodeMaintenanceStatus) DeepCopy() *NodeMaintenanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeMaintenanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightChecks) DeepCopyInto(out *PreflightChecks) {
+	*out = *in
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.KernelModules != nil {
+		in, out := &in.KernelModules, &out.KernelModules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Registries != nil {
+		in, out := &in.Registries, &out.Registries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Skip != nil {
+		in, out := &in.Skip, &out.Skip
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightChecks.
+func (in *PreflightChecks) DeepCopy() *PreflightChecks {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightChecks)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProvisioningFileSource) DeepCopyInto(out *ProvisioningFileSource) {
 	*out = *in
@@ -845,6 +1280,28 @@ func (in *RKEClusterSpecCommon) DeepCopyInto(out *RKEClusterSpecCommon) {
 		*out = new(ETCD)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.MachineSelectorLifecycleHooks != nil {
+		in, out := &in.MachineSelectorLifecycleHooks, &out.MachineSelectorLifecycleHooks
+		*out = make([]RKELifecycleHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CertificateRotationPolicy != nil {
+		in, out := &in.CertificateRotationPolicy, &out.CertificateRotationPolicy
+		*out = new(CertificateRotationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EncryptionKeyRotationSchedule != nil {
+		in, out := &in.EncryptionKeyRotationSchedule, &out.EncryptionKeyRotationSchedule
+		*out = new(EncryptionKeyRotationSchedule)
+		**out = **in
+	}
+	if in.PreflightChecks != nil {
+		in, out := &in.PreflightChecks, &out.PreflightChecks
+		*out = new(PreflightChecks)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Networking != nil {
 		in, out := &in.Networking, &out.Networking
 		*out = new(Networking)
@@ -1006,6 +1463,16 @@ func (in *RKEControlPlaneSpec) DeepCopyInto(out *RKEControlPlaneSpec) {
 		*out = new(RotateEncryptionKeys)
 		**out = **in
 	}
+	if in.ETCDMaintenance != nil {
+		in, out := &in.ETCDMaintenance, &out.ETCDMaintenance
+		*out = new(ETCDMaintenance)
+		**out = **in
+	}
+	if in.NodeMaintenance != nil {
+		in, out := &in.NodeMaintenance, &out.NodeMaintenance
+		*out = new(NodeMaintenance)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1032,11 +1499,24 @@ func (in *RKEControlPlaneStatus) DeepCopyInto(out *RKEControlPlaneStatus) {
 		*out = make([]genericcondition.GenericCondition, len(*in))
 		copy(*out, *in)
 	}
+	if in.CertificateRotationTime != nil {
+		in, out := &in.CertificateRotationTime, &out.CertificateRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CertificateExpiry != nil {
+		in, out := &in.CertificateExpiry, &out.CertificateExpiry
+		*out = new(CertificateExpiryStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.RotateEncryptionKeys != nil {
 		in, out := &in.RotateEncryptionKeys, &out.RotateEncryptionKeys
 		*out = new(RotateEncryptionKeys)
 		**out = **in
 	}
+	if in.EncryptionKeyRotationTime != nil {
+		in, out := &in.EncryptionKeyRotationTime, &out.EncryptionKeyRotationTime
+		*out = (*in).DeepCopy()
+	}
 	if in.ETCDSnapshotRestore != nil {
 		in, out := &in.ETCDSnapshotRestore, &out.ETCDSnapshotRestore
 		*out = new(ETCDSnapshotRestore)
@@ -1047,6 +1527,35 @@ func (in *RKEControlPlaneStatus) DeepCopyInto(out *RKEControlPlaneStatus) {
 		*out = new(ETCDSnapshotCreate)
 		**out = **in
 	}
+	if in.ETCDSnapshotVerificationTime != nil {
+		in, out := &in.ETCDSnapshotVerificationTime, &out.ETCDSnapshotVerificationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ETCDMaintenance != nil {
+		in, out := &in.ETCDMaintenance, &out.ETCDMaintenance
+		*out = new(ETCDMaintenance)
+		**out = **in
+	}
+	if in.ETCDMaintenanceStatus != nil {
+		in, out := &in.ETCDMaintenanceStatus, &out.ETCDMaintenanceStatus
+		*out = new(ETCDMaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeMaintenance != nil {
+		in, out := &in.NodeMaintenance, &out.NodeMaintenance
+		*out = new(NodeMaintenance)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NodeMaintenanceStatus != nil {
+		in, out := &in.NodeMaintenanceStatus, &out.NodeMaintenanceStatus
+		*out = new(NodeMaintenanceStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ETCDMemberHealth != nil {
+		in, out := &in.ETCDMemberHealth, &out.ETCDMemberHealth
+		*out = new(ETCDMemberHealthStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -1060,6 +1569,42 @@ func (in *RKEControlPlaneStatus) DeepCopy() *RKEControlPlaneStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RKELifecycleHook) DeepCopyInto(out *RKELifecycleHook) {
+	*out = *in
+	if in.MachineLabelSelector != nil {
+		in, out := &in.MachineLabelSelector, &out.MachineLabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Points != nil {
+		in, out := &in.Points, &out.Points
+		*out = make([]LifecycleHookPoint, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]EnvVar, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RKELifecycleHook.
+func (in *RKELifecycleHook) DeepCopy() *RKELifecycleHook {
+	if in == nil {
+		return nil
+	}
+	out := new(RKELifecycleHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RKEMachineStatus) DeepCopyInto(out *RKEMachineStatus) {
 	*out = *in
@@ -1073,6 +1618,15 @@ func (in *RKEMachineStatus) DeepCopyInto(out *RKEMachineStatus) {
 		*out = make([]v1beta1.MachineAddress, len(*in))
 		copy(*out, *in)
 	}
+	if in.NextRetryTime != nil {
+		in, out := &in.NextRetryTime, &out.NextRetryTime
+		*out = (*in).DeepCopy()
+	}
+	if in.FailedMachineConfigVariants != nil {
+		in, out := &in.FailedMachineConfigVariants, &out.FailedMachineConfigVariants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -27,6 +27,7 @@
 	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
 	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/serviceaccounttoken"
+	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/wrangler/v3/pkg/condition"
 	"github.com/rancher/wrangler/v3/pkg/data"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
@@ -62,6 +63,7 @@
 	JoinURLAnnotation                          = "rke.cattle.io/join-url"
 	JoinedToAnnotation                         = "rke.cattle.io/joined-to"
 	LabelsAnnotation                           = "rke.cattle.io/labels"
+	LifecycleHookAnnotation                    = "rke.cattle.io/lifecycle-hook"
 	MachineIDLabel                             = "rke.cattle.io/machine-id"
 	MachineNameLabel                           = "rke.cattle.io/machine-name"
 	MachineTemplateHashLabel                   = "rke.cattle.io/machine-template-hash"
@@ -111,6 +113,11 @@
 	InfrastructureReady          = condition.Cond(capi.InfrastructureReadyCondition)
 	SystemUpgradeControllerReady = condition.Cond("SystemUpgradeControllerReady")
 	Bootstrapped                 = condition.Cond("Bootstrapped")
+	ETCDSnapshotVerified         = condition.Cond("ETCDSnapshotVerified")
+	ETCDQuorumAvailable          = condition.Cond("ETCDQuorumAvailable")
+	ETCDFaultTolerant            = condition.Cond("ETCDFaultTolerant") // ETCDFaultTolerant is false if losing one more etcd member would break quorum
+	PreflightChecked             = condition.Cond("PreflightChecked")  // PreflightChecked reports the pre-flight checks of a custom machine that has not joined the cluster yet
+	LifecycleHooksRun            = condition.Cond("LifecycleHooksRun") // LifecycleHooksRun reports the lifecycle hooks that last ran on a machine
 
 	RuntimeK3S  = "k3s"
 	RuntimeRKE2 = "rke2"
@@ -271,6 +278,44 @@ func GetSystemAgentDataDir(spec *rkev1.RKEClusterSpecCommon) string {
 	return "/var/lib/rancher/agent"
 }
 
+// GetAgentServerURLAndCACerts returns the URL of rancher that agents connect to and the CA certificates they trust it
+// with. host is the host rancher was reached at, used when the server-url setting is not set, and internal is true when
+// rancher was reached through its internal API.
+func GetAgentServerURLAndCACerts(host string, internal bool) (string, []byte) {
+	var ca []byte
+	url, pem := settings.ServerURL.Get(), settings.CACerts.Get()
+	if strings.TrimSpace(pem) != "" {
+		ca = []byte(pem)
+	}
+
+	if url == "" {
+		pem = settings.InternalCACerts.Get()
+		url = fmt.Sprintf("https://%s", host)
+		if strings.TrimSpace(pem) != "" {
+			ca = []byte(pem)
+		}
+	} else if internal {
+		pem = settings.InternalCACerts.Get()
+		if strings.TrimSpace(pem) != "" {
+			ca = []byte(pem)
+		}
+	}
+	return url, ca
+}
+
+// GetMachinePoolAutoscalingBounds returns the minimum and maximum size of the machine pool, and whether the machine pool
+// is managed by the cluster autoscaler.
+func GetMachinePoolAutoscalingBounds(rkeConfig *provv1.RKEConfig, machinePool provv1.RKEMachinePool) (int32, int32, bool) {
+	if rkeConfig == nil || rkeConfig.Autoscaling == nil || !rkeConfig.Autoscaling.Enabled || machinePool.MaxSize == nil {
+		return 0, 0, false
+	}
+	minSize := int32(1)
+	if machinePool.MinSize != nil {
+		minSize = *machinePool.MinSize
+	}
+	return minSize, *machinePool.MaxSize, true
+}
+
 func IsOwnedByMachine(bootstrapCache rkecontroller.RKEBootstrapCache, machineName string, sa *corev1.ServiceAccount) (bool, error) {
 	for _, owner := range sa.OwnerReferences {
 		if owner.Kind == "RKEBootstrap" {
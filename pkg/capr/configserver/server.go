@@ -18,7 +18,6 @@
 	rkecontroller "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
 	v1 "github.com/rancher/rancher/pkg/generated/norman/core/v1"
 	"github.com/rancher/rancher/pkg/serviceaccounttoken"
-	"github.com/rancher/rancher/pkg/settings"
 	"github.com/rancher/rancher/pkg/tls"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
@@ -122,24 +121,8 @@ func (r *RKE2ConfigServer) ServeHTTP(rw http.ResponseWriter, req *http.Request)
 }
 
 func (r *RKE2ConfigServer) connectAgent(planSecret string, secret *v1.Secret, rw http.ResponseWriter, req *http.Request) {
-	var ca []byte
-	url, pem := settings.ServerURL.Get(), settings.CACerts.Get()
-	if strings.TrimSpace(pem) != "" {
-		ca = []byte(pem)
-	}
-
-	if url == "" {
-		pem = settings.InternalCACerts.Get()
-		url = fmt.Sprintf("https://%s", req.Host)
-		if strings.TrimSpace(pem) != "" {
-			ca = []byte(pem)
-		}
-	} else if v, ok := req.Context().Value(tls.InternalAPI).(bool); ok && v {
-		pem = settings.InternalCACerts.Get()
-		if strings.TrimSpace(pem) != "" {
-			ca = []byte(pem)
-		}
-	}
+	internal, _ := req.Context().Value(tls.InternalAPI).(bool)
+	url, ca := capr.GetAgentServerURLAndCACerts(req.Host, internal)
 
 	kubeConfig, err := clientcmd.Write(clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{
@@ -0,0 +1,203 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	certificateExpiryInstructionName = "certificate-expiry"
+	certificateExpiryPeriodSeconds   = 3600
+	certificateExpiryHeaderPrefix    = "# "
+
+	// certificateExpiryScript prints the leaf certificate of every non-CA certificate file in the distro certificate
+	// directories, each preceded by a header line containing the path of the file.
+	certificateExpiryScript = `
+dataDir=$1
+for f in "$dataDir"/server/tls/*.crt "$dataDir"/server/tls/etcd/*.crt "$dataDir"/server/tls/kube-controller-manager/*.crt "$dataDir"/server/tls/kube-scheduler/*.crt "$dataDir"/agent/*.crt; do
+	[ -f "$f" ] || continue
+	case "$f" in
+		*-ca.crt) continue ;;
+	esac
+	echo "# $f"
+	sed -n '1,/-----END CERTIFICATE-----/p' "$f"
+done
+`
+)
+
+// addCertificateExpiryPeriodicInstruction adds a periodic instruction that reads the certificates on the node, so that
+// their expiry can be reported on the control plane status of every cluster. The instruction is a minor periodic
+// instruction, so adding it neither drains nor restarts the node.
+func (p *Planner) addCertificateExpiryPeriodicInstruction(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane) (plan.NodePlan, error) {
+	nodePlan.PeriodicInstructions = append(nodePlan.PeriodicInstructions, plan.PeriodicInstruction{
+		Name:    certificateExpiryInstructionName,
+		Command: "sh",
+		Args: []string{
+			"-c",
+			certificateExpiryScript,
+			certificateExpiryInstructionName,
+			capr.GetDistroDataDir(controlPlane),
+		},
+		PeriodSeconds: certificateExpiryPeriodSeconds,
+	})
+	return nodePlan, nil
+}
+
+// reconcileCertificateExpiry records the expiry of the certificates reported by each machine on the status, whether or
+// not the certificates are rotated automatically by the CertificateRotationPolicy. Certificates that were read before
+// the most recent certificate rotation are ignored, as they may since have been rotated.
+func (p *Planner) reconcileCertificateExpiry(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) rkev1.RKEControlPlaneStatus {
+	runtime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+
+	var machines []rkev1.MachineCertificateExpiry
+	for _, entry := range collect(clusterPlan, isNotDeleting) {
+		if entry.Plan == nil {
+			continue
+		}
+		output, ok := entry.Plan.PeriodicOutput[certificateExpiryInstructionName]
+		if !ok || output.ExitCode != 0 || output.LastSuccessfulRunTime == "" {
+			continue
+		}
+		runTime, err := time.Parse(time.UnixDate, output.LastSuccessfulRunTime)
+		if err != nil {
+			logrus.Debugf("[planner] rkecluster %s/%s: unable to parse certificate expiry run time for machine %s: %v", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name, err)
+			continue
+		}
+		if status.CertificateRotationTime != nil && runTime.Before(status.CertificateRotationTime.Time) {
+			continue
+		}
+		certificates, err := parseCertificateExpiry(runtime, output.Stdout)
+		if err != nil {
+			logrus.Debugf("[planner] rkecluster %s/%s: unable to parse certificate expiry for machine %s: %v", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name, err)
+			continue
+		}
+		machine := rkev1.MachineCertificateExpiry{
+			MachineName:  entry.Machine.Name,
+			ObservedTime: &metav1.Time{Time: runTime},
+			Certificates: certificates,
+		}
+		if entry.Machine.Status.NodeRef != nil {
+			machine.NodeName = entry.Machine.Status.NodeRef.Name
+		}
+		machines = append(machines, machine)
+	}
+
+	if len(machines) == 0 {
+		status.CertificateExpiry = nil
+		return status
+	}
+	status.CertificateExpiry = &rkev1.CertificateExpiryStatus{Machines: machines}
+	return status
+}
+
+// parseCertificateExpiry parses the output of the certificate expiry periodic instruction. Certificates that fail to parse
+// are skipped, as the file may have been in the middle of being rewritten.
+func parseCertificateExpiry(runtime string, output []byte) ([]rkev1.CertificateExpiry, error) {
+	var (
+		certificates []rkev1.CertificateExpiry
+		file         string
+		block        bytes.Buffer
+	)
+
+	flush := func() {
+		if file == "" {
+			return
+		}
+		defer block.Reset()
+		p, _ := pem.Decode(block.Bytes())
+		if p == nil || p.Type != "CERTIFICATE" {
+			return
+		}
+		cert, err := x509.ParseCertificate(p.Bytes)
+		if err != nil {
+			return
+		}
+		certificates = append(certificates, rkev1.CertificateExpiry{
+			Name:           certificateName(file),
+			Service:        certificateService(runtime, file),
+			ExpirationDate: metav1.Time{Time: cert.NotAfter},
+		})
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, certificateExpiryHeaderPrefix) {
+			flush()
+			file = strings.TrimPrefix(line, certificateExpiryHeaderPrefix)
+			continue
+		}
+		block.WriteString(line)
+		block.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return certificates, nil
+}
+
+// certificateName returns the name of the certificate file without its extension, prefixed by the directory the file
+// is in when it is not one of the top level certificate directories.
+func certificateName(file string) string {
+	name := strings.TrimSuffix(path.Base(file), ".crt")
+	switch dir := path.Base(path.Dir(file)); dir {
+	case "tls", "agent":
+		return name
+	default:
+		return dir + "/" + name
+	}
+}
+
+// certificateService returns the service that must be passed to `certificate rotate` in order to rotate the given
+// certificate file, or an empty string if the certificate is not rotated by a specific service.
+func certificateService(runtime, file string) string {
+	switch path.Base(path.Dir(file)) {
+	case "etcd":
+		return "etcd"
+	case "kube-controller-manager":
+		return "controller-manager"
+	case "kube-scheduler":
+		return "scheduler"
+	}
+
+	switch strings.TrimSuffix(path.Base(file), ".crt") {
+	case "client-admin":
+		return "admin"
+	case "client-auth-proxy":
+		return "auth-proxy"
+	case "client-controller":
+		return "controller-manager"
+	case "client-scheduler":
+		return "scheduler"
+	case "client-kube-apiserver", "serving-kube-apiserver":
+		return "api-server"
+	case "client-kube-proxy":
+		return "kube-proxy"
+	case "client-kubelet", "serving-kubelet":
+		return "kubelet"
+	case "client-supervisor":
+		return runtime + "-server"
+	case "client-" + runtime + "-controller":
+		return runtime + "-controller"
+	case "client-" + runtime + "-cloud-controller":
+		return "cloud-controller"
+	}
+	return ""
+}
@@ -0,0 +1,135 @@
+package planner
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func testCertificatePEM(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestParseCertificateExpiry(t *testing.T) {
+	apiserverExpiry := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	etcdExpiry := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	kubeletExpiry := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	output := strings.Join([]string{
+		"# /var/lib/rancher/rke2/server/tls/serving-kube-apiserver.crt",
+		testCertificatePEM(t, apiserverExpiry),
+		"# /var/lib/rancher/rke2/server/tls/etcd/server-client.crt",
+		testCertificatePEM(t, etcdExpiry),
+		"# /var/lib/rancher/rke2/server/tls/broken.crt",
+		"not a certificate",
+		"# /var/lib/rancher/rke2/agent/serving-kubelet.crt",
+		testCertificatePEM(t, kubeletExpiry),
+	}, "\n")
+
+	certificates, err := parseCertificateExpiry("rke2", []byte(output))
+	require.NoError(t, err)
+	require.Len(t, certificates, 3)
+
+	assert.Equal(t, "serving-kube-apiserver", certificates[0].Name)
+	assert.Equal(t, "api-server", certificates[0].Service)
+	assert.True(t, apiserverExpiry.Equal(certificates[0].ExpirationDate.Time))
+
+	assert.Equal(t, "etcd/server-client", certificates[1].Name)
+	assert.Equal(t, "etcd", certificates[1].Service)
+	assert.True(t, etcdExpiry.Equal(certificates[1].ExpirationDate.Time))
+
+	assert.Equal(t, "serving-kubelet", certificates[2].Name)
+	assert.Equal(t, "kubelet", certificates[2].Service)
+	assert.True(t, kubeletExpiry.Equal(certificates[2].ExpirationDate.Time))
+
+	_, err = parseCertificateExpiry("rke2", []byte(""))
+	assert.ErrorContains(t, err, "no certificates found")
+}
+
+func TestCertificateService(t *testing.T) {
+	tests := []struct {
+		runtime  string
+		file     string
+		expected string
+	}{
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/client-admin.crt", expected: "admin"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/client-controller.crt", expected: "controller-manager"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/client-rke2-controller.crt", expected: "rke2-controller"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/client-rke2-cloud-controller.crt", expected: "cloud-controller"},
+		{runtime: "k3s", file: "/var/lib/rancher/k3s/server/tls/client-k3s-controller.crt", expected: "k3s-controller"},
+		{runtime: "k3s", file: "/var/lib/rancher/k3s/server/tls/client-supervisor.crt", expected: "k3s-server"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/kube-scheduler/kube-scheduler.crt", expected: "scheduler"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/agent/client-kube-proxy.crt", expected: "kube-proxy"},
+		{runtime: "rke2", file: "/var/lib/rancher/rke2/server/tls/unknown.crt", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.expected, certificateService(tt.runtime, tt.file))
+		})
+	}
+}
+
+func TestReconcileCertificateExpiryWithoutRotationPolicy(t *testing.T) {
+	mp := newMockPlanner(t, InfoFunctions{})
+	expiry := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runTime := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{
+			"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+		},
+		Nodes: map[string]*plan.Node{
+			"a": {PeriodicOutput: map[string]plan.PeriodicInstructionOutput{
+				certificateExpiryInstructionName: {
+					Stdout:                []byte("# /var/lib/rancher/rke2/server/tls/serving-kube-apiserver.crt\n" + testCertificatePEM(t, expiry)),
+					LastSuccessfulRunTime: runTime.Format(time.UnixDate),
+				},
+			}},
+		},
+	}
+
+	// the expiry is reported whether or not the certificates are rotated automatically
+	for _, policy := range []*rkev1.CertificateRotationPolicy{nil, {Enabled: false}, {Enabled: true}} {
+		cp := &rkev1.RKEControlPlane{Spec: rkev1.RKEControlPlaneSpec{
+			RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{CertificateRotationPolicy: policy},
+			KubernetesVersion:    "v1.30.1+rke2r1",
+		}}
+		status := mp.planner.reconcileCertificateExpiry(cp, rkev1.RKEControlPlaneStatus{}, clusterPlan)
+		require.NotNil(t, status.CertificateExpiry)
+		require.Len(t, status.CertificateExpiry.Machines, 1)
+		assert.Equal(t, "a", status.CertificateExpiry.Machines[0].MachineName)
+		require.Len(t, status.CertificateExpiry.Machines[0].Certificates, 1)
+		assert.Equal(t, expiry, status.CertificateExpiry.Machines[0].Certificates[0].ExpirationDate.Time.UTC())
+	}
+}
+
+func TestMinorPlanChangeDetectedCertificateExpiry(t *testing.T) {
+	expiry := plan.PeriodicInstruction{Name: certificateExpiryInstructionName, Command: "sh"}
+	assert.True(t, minorPlanChangeDetected(plan.NodePlan{}, plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{expiry}}), "adding the certificate expiry check is minor")
+	assert.True(t, minorPlanChangeDetected(plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{expiry}}, plan.NodePlan{}), "removing the certificate expiry check is minor")
+}
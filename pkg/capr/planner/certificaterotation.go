@@ -5,16 +5,19 @@
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
 	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // rotateCertificates checks if there is a need to rotate any certificates and updates the plan accordingly.
 func (p *Planner) rotateCertificates(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
 	if !shouldRotate(controlPlane) {
+		observeCertificateRotation(controlPlane, false, nil)
 		return status, nil
 	}
 
@@ -43,6 +46,7 @@ func (p *Planner) rotateCertificates(controlPlane *rkev1.RKEControlPlane, status
 
 		err = assignAndCheckPlan(p.store, fmt.Sprintf("[%s] certificate rotation", node.Machine.Name), node, rotatePlan, joinedServer, 0, 0)
 		if err != nil {
+			observeCertificateRotation(controlPlane, true, err)
 			// Ensure the CAPI cluster is paused if we have assigned and are checking a plan.
 			if pauseErr := p.pauseCAPICluster(controlPlane, true); pauseErr != nil {
 				return status, pauseErr
@@ -56,6 +60,8 @@ func (p *Planner) rotateCertificates(controlPlane *rkev1.RKEControlPlane, status
 	}
 
 	status.CertificateRotationGeneration = controlPlane.Spec.RotateCertificates.Generation
+	status.CertificateRotationTime = &metav1.Time{Time: time.Now()}
+	observeCertificateRotation(controlPlane, true, nil)
 	return status, errWaiting("certificate rotation done")
 }
 
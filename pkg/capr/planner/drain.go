@@ -68,6 +68,12 @@ func (p *Planner) drain(oldPlan *plan.NodePlan, newPlan plan.NodePlan, entry *pl
 		return true, nil
 	}
 
+	return p.drainNode(entry, clusterPlan, options)
+}
+
+// drainNode cordons and, if enabled, drains the node of the given entry with the given options through the machine plan
+// secret annotations. It returns true once the node has been drained.
+func (p *Planner) drainNode(entry *planEntry, clusterPlan *plan.Plan, options rkev1.DrainOptions) (bool, error) {
 	// Don't drain a single node cluster, but still run the hooks
 	optionString, err := optionsToString(options, len(clusterPlan.Machines) == 1)
 	if err != nil {
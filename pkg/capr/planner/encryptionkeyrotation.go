@@ -6,6 +6,7 @@
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
@@ -15,6 +16,7 @@
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -209,12 +211,26 @@ func (p *Planner) rotateEncryptionKeys(controlPlane *rkev1.RKEControlPlane, stat
 			return status, errWaiting("unpausing CAPI cluster")
 		}
 		status.RotateEncryptionKeysLeader = ""
+		status.EncryptionKeyRotationTime = &metav1.Time{Time: time.Now()}
 		return p.setEncryptionKeyRotateState(status, controlPlane.Spec.RotateEncryptionKeys, rkev1.RotateEncryptionKeysPhaseDone)
 	}
 
 	return status, fmt.Errorf("encountered unknown encryption key rotation phase: %s", controlPlane.Status.RotateEncryptionKeysPhase)
 }
 
+// encryptionKeyAgeDays returns the number of whole days since the encryption keys were last rotated. Keys that have
+// never been rotated are as old as the control plane.
+func encryptionKeyAgeDays(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, now time.Time) int64 {
+	created := controlPlane.CreationTimestamp.Time
+	if status.EncryptionKeyRotationTime != nil {
+		created = status.EncryptionKeyRotationTime.Time
+	}
+	if created.IsZero() || now.Before(created) {
+		return 0
+	}
+	return int64(now.Sub(created) / (24 * time.Hour))
+}
+
 // encryptionKeyRotationSupported returns a boolean indicating whether encryption key rotation is supported by the release,
 // and an error if one was encountered.
 func encryptionKeyRotationSupported(releaseData *model.Release) (bool, error) {
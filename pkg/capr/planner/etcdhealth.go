@@ -0,0 +1,214 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/controllers/capr/etcdmgmt"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	etcdMemberHealthInstructionName = "etcd-member-health"
+	etcdMemberHealthPeriodSeconds   = 300
+
+	// etcdMemberHealthStaleAfter is the duration after which the etcd member health is no longer trusted, which allows
+	// for a couple of missed health checks.
+	etcdMemberHealthStaleAfter = 3 * etcdMemberHealthPeriodSeconds * time.Second
+
+	// etcdMemberHealthScript prints the etcd member list, the endpoint status of every member and the active alarms as
+	// JSON documents, in that order. Members that do not respond are omitted from the endpoint status rather than
+	// failing the health check, so that they can be reported as unhealthy.
+	etcdMemberHealthScript = `
+dataDir=$1
+tlsDir="$dataDir/server/tls/etcd"
+` + etcdctlRunFunction + `
+etcdctl_run member list -w json || exit 1
+status=$(etcdctl_run endpoint status --cluster -w json 2>/dev/null)
+echo "${status:-[]}"
+etcdctl_run alarm list -w json || exit 1
+`
+)
+
+// addEtcdMemberHealthPeriodicInstruction adds a periodic instruction that reports the health of every member of the etcd
+// cluster, as seen by the local etcd member. The instruction relies on etcdctl_run, so it is only added for rke2.
+func (p *Planner) addEtcdMemberHealthPeriodicInstruction(nodePlan plan.NodePlan, controlPlane *rkev1.RKEControlPlane) (plan.NodePlan, error) {
+	nodePlan.PeriodicInstructions = append(nodePlan.PeriodicInstructions, plan.PeriodicInstruction{
+		Name:    etcdMemberHealthInstructionName,
+		Command: "sh",
+		Args: []string{
+			"-c",
+			etcdMemberHealthScript,
+			etcdMemberHealthInstructionName,
+			capr.GetDistroDataDir(controlPlane),
+		},
+		PeriodSeconds: etcdMemberHealthPeriodSeconds,
+	})
+	return nodePlan, nil
+}
+
+// reconcileEtcdMemberHealth summarizes the most recent etcd member health reported by the etcd machines onto the status,
+// and sets the etcd quorum conditions. Removal of deleting etcd machines is refused while it would break quorum, which
+// is enforced by the rkebootstrap controller before the etcd member is removed.
+func (p *Planner) reconcileEtcdMemberHealth(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) rkev1.RKEControlPlaneStatus {
+	entries := collect(clusterPlan, isEtcd)
+	if len(entries) == 0 || capr.GetRuntime(controlPlane.Spec.KubernetesVersion) == capr.RuntimeK3S {
+		status.ETCDMemberHealth = nil
+		status.Conditions = removeConditions(status.Conditions, capr.ETCDQuorumAvailable, capr.ETCDFaultTolerant)
+		return status
+	}
+
+	health, err := etcdMemberHealth(entries, time.Now())
+	if err != nil {
+		logrus.Debugf("[planner] rkecluster %s/%s: %v", controlPlane.Namespace, controlPlane.Name, err)
+		status.ETCDMemberHealth = nil
+		capr.ETCDQuorumAvailable.Unknown(&status)
+		capr.ETCDQuorumAvailable.Message(&status, err.Error())
+		capr.ETCDFaultTolerant.Unknown(&status)
+		capr.ETCDFaultTolerant.Message(&status, err.Error())
+		return status
+	}
+	status.ETCDMemberHealth = health
+
+	if health.HealthyMembers < health.Quorum {
+		capr.ETCDQuorumAvailable.False(&status)
+		capr.ETCDQuorumAvailable.Reason(&status, "QuorumLost")
+		capr.ETCDQuorumAvailable.Message(&status, fmt.Sprintf("%d etcd members are healthy, %d are required for quorum", health.HealthyMembers, health.Quorum))
+	} else {
+		capr.ETCDQuorumAvailable.True(&status)
+		capr.ETCDQuorumAvailable.Reason(&status, "")
+		capr.ETCDQuorumAvailable.Message(&status, "")
+	}
+
+	var deleting []string
+	for _, entry := range collect(clusterPlan, roleAnd(isEtcd, isDeleting)) {
+		deleting = append(deleting, entry.Machine.Name)
+	}
+
+	switch {
+	case etcdmgmt.RemovalViolatesQuorum(health, deleting):
+		logrus.Warnf("[planner] rkecluster %s/%s: refusing to remove etcd machines %s as the remaining etcd members would not have quorum", controlPlane.Namespace, controlPlane.Name, strings.Join(deleting, ","))
+		capr.ETCDFaultTolerant.False(&status)
+		capr.ETCDFaultTolerant.Reason(&status, "ScaleDownRefused")
+		capr.ETCDFaultTolerant.Message(&status, fmt.Sprintf("removal of etcd machines [%s] is refused as the remaining etcd members would not have quorum; annotate the rkebootstrap with %s=true to remove them anyway", strings.Join(deleting, ", "), capr.ForceRemoveEtcdAnnotation))
+	case health.FaultTolerance <= 0:
+		capr.ETCDFaultTolerant.False(&status)
+		capr.ETCDFaultTolerant.Reason(&status, "QuorumAtRisk")
+		capr.ETCDFaultTolerant.Message(&status, "losing one more healthy etcd member would break quorum")
+	default:
+		capr.ETCDFaultTolerant.True(&status)
+		capr.ETCDFaultTolerant.Reason(&status, "")
+		capr.ETCDFaultTolerant.Message(&status, "")
+	}
+	return status
+}
+
+// removeConditions returns the given conditions without the conditions of the given types.
+func removeConditions(conditions []genericcondition.GenericCondition, conds ...condition.Cond) []genericcondition.GenericCondition {
+	var result []genericcondition.GenericCondition
+	for _, c := range conditions {
+		keep := true
+		for _, cond := range conds {
+			if c.Type == string(cond) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// etcdMemberHealth builds the etcd member health from the most recent successful health check reported by the given etcd
+// machines. Every machine reports the health of the entire etcd cluster, but the local member ID reported by each machine
+// is used to map members to machines.
+func etcdMemberHealth(entries []*planEntry, now time.Time) (*rkev1.ETCDMemberHealthStatus, error) {
+	var (
+		latest         *etcdmgmt.MemberHealth
+		latestTime     time.Time
+		observedBy     string
+		memberMachines = map[string]*planEntry{}
+	)
+
+	for _, entry := range entries {
+		if entry.Plan == nil {
+			continue
+		}
+		output, ok := entry.Plan.PeriodicOutput[etcdMemberHealthInstructionName]
+		if !ok || output.ExitCode != 0 || output.LastSuccessfulRunTime == "" {
+			continue
+		}
+		runTime, err := time.Parse(time.UnixDate, output.LastSuccessfulRunTime)
+		if err != nil {
+			logrus.Debugf("[planner] unable to parse etcd member health run time for machine %s: %v", entry.Machine.Name, err)
+			continue
+		}
+		health, err := etcdmgmt.ParseMemberHealth(output.Stdout)
+		if err != nil {
+			logrus.Debugf("[planner] unable to parse etcd member health for machine %s: %v", entry.Machine.Name, err)
+			continue
+		}
+		memberMachines[health.LocalMemberID()] = entry
+		if latest == nil || runTime.After(latestTime) {
+			latest, latestTime, observedBy = health, runTime, entry.Machine.Name
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("etcd member health has not been reported by any etcd machine")
+	}
+	if now.Sub(latestTime) > etcdMemberHealthStaleAfter {
+		return nil, fmt.Errorf("etcd member health was last reported at %s", latestTime.UTC().Format(time.RFC3339))
+	}
+
+	members := latest.Members()
+	for i := range members {
+		entry, ok := memberMachines[members[i].MemberID]
+		if !ok {
+			entry = etcdMemberEntryByName(entries, members[i].Name)
+		}
+		if entry == nil {
+			continue
+		}
+		members[i].MachineName = entry.Machine.Name
+		if entry.Machine.Status.NodeRef != nil {
+			members[i].NodeName = entry.Machine.Status.NodeRef.Name
+		}
+	}
+
+	quorum, healthy := etcdmgmt.Quorum(members)
+	return &rkev1.ETCDMemberHealthStatus{
+		ObservedTime:   &metav1.Time{Time: latestTime},
+		ObservedBy:     observedBy,
+		Members:        members,
+		Quorum:         quorum,
+		HealthyMembers: healthy,
+		FaultTolerance: healthy - quorum,
+	}, nil
+}
+
+// etcdMemberEntryByName returns the etcd machine whose node has the given etcd member name. k3s and rke2 name etcd
+// members after the node, followed by a random suffix. This is used for members whose machine has not reported a health
+// check of its own, for instance because etcd is down on that machine.
+func etcdMemberEntryByName(entries []*planEntry, memberName string) *planEntry {
+	i := strings.LastIndex(memberName, "-")
+	if i <= 0 {
+		return nil
+	}
+	nodeName := memberName[:i]
+	for _, entry := range entries {
+		if entry.Machine.Status.NodeRef != nil && entry.Machine.Status.NodeRef.Name == nodeName {
+			return entry
+		}
+	}
+	return nil
+}
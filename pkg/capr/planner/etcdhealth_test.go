@@ -0,0 +1,196 @@
+package planner
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	etcdMemberHealthMemberList = `{"header":{"cluster_id":100,"member_id":%d},"members":[{"ID":1,"name":"node-a-11111111"},{"ID":2,"name":"node-b-22222222"},{"ID":3,"name":"node-c-33333333"}]}`
+	etcdMemberHealthAllHealthy = `[{"Status":{"header":{"member_id":1},"leader":1,"raftIndex":100}},{"Status":{"header":{"member_id":2},"leader":1,"raftIndex":100}},{"Status":{"header":{"member_id":3},"leader":1,"raftIndex":90}}]`
+	etcdMemberHealthOneDown    = `[{"Status":{"header":{"member_id":1},"leader":1,"raftIndex":100}},{"Status":{"header":{"member_id":2},"leader":1,"raftIndex":100}}]`
+	etcdMemberHealthNoAlarms   = `{"header":{}}`
+)
+
+func etcdMemberHealthPlan(outputs map[string]string, runTime time.Time, deleting ...string) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Nodes:    map[string]*plan.Node{},
+		Machines: map[string]*capi.Machine{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		machine := &capi.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: capi.MachineStatus{
+				NodeRef: &corev1.ObjectReference{Name: "node-" + name},
+			},
+		}
+		for _, d := range deleting {
+			if d == name {
+				machine.DeletionTimestamp = &metav1.Time{Time: runTime}
+			}
+		}
+		node := &plan.Node{}
+		if output, ok := outputs[name]; ok {
+			node.PeriodicOutput = map[string]plan.PeriodicInstructionOutput{
+				etcdMemberHealthInstructionName: {
+					Name:                  etcdMemberHealthInstructionName,
+					Stdout:                []byte(output),
+					LastSuccessfulRunTime: runTime.Format(time.UnixDate),
+				},
+			}
+		}
+		clusterPlan.Machines[name] = machine
+		clusterPlan.Nodes[name] = node
+		clusterPlan.Metadata[name] = &plan.Metadata{
+			Labels: map[string]string{capr.EtcdRoleLabel: "true"},
+		}
+	}
+	return clusterPlan
+}
+
+func etcdMemberHealthOutput(memberID int, status string) string {
+	return fmt.Sprintf(etcdMemberHealthMemberList, memberID) + "\n" + status + "\n" + etcdMemberHealthNoAlarms + "\n"
+}
+
+func TestEtcdMemberHealth(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	clusterPlan := etcdMemberHealthPlan(map[string]string{
+		"a": etcdMemberHealthOutput(1, etcdMemberHealthAllHealthy),
+		"b": etcdMemberHealthOutput(2, etcdMemberHealthAllHealthy),
+	}, now)
+
+	health, err := etcdMemberHealth(collect(clusterPlan, isEtcd), now)
+	require.NoError(t, err)
+	require.Len(t, health.Members, 3)
+	assert.Equal(t, 2, health.Quorum)
+	assert.Equal(t, 3, health.HealthyMembers)
+	assert.Equal(t, 1, health.FaultTolerance)
+
+	// members a and b reported their own member IDs, and member c is matched by its node name
+	for i, name := range []string{"a", "b", "c"} {
+		assert.Equal(t, name, health.Members[i].MachineName)
+		assert.Equal(t, "node-"+name, health.Members[i].NodeName)
+	}
+	assert.True(t, health.Members[0].Leader)
+	assert.Equal(t, int64(10), health.Members[2].RaftIndexLag)
+
+	_, err = etcdMemberHealth(collect(clusterPlan, isEtcd), now.Add(time.Hour))
+	assert.ErrorContains(t, err, "last reported")
+
+	_, err = etcdMemberHealth(collect(etcdMemberHealthPlan(nil, now), isEtcd), now)
+	assert.ErrorContains(t, err, "has not been reported")
+}
+
+func TestReconcileEtcdMemberHealth(t *testing.T) {
+	now := time.Now()
+	cp := &rkev1.RKEControlPlane{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"}}
+
+	tests := []struct {
+		name                  string
+		output                string
+		deleting              []string
+		expectQuorumAvailable string
+		expectFaultTolerant   string
+		expectReason          string
+	}{
+		{
+			name:                  "all members healthy",
+			output:                etcdMemberHealthAllHealthy,
+			expectQuorumAvailable: "True",
+			expectFaultTolerant:   "True",
+		},
+		{
+			name:                  "one member down",
+			output:                etcdMemberHealthOneDown,
+			expectQuorumAvailable: "True",
+			expectFaultTolerant:   "False",
+			expectReason:          "QuorumAtRisk",
+		},
+		{
+			name:                  "removing the down member",
+			output:                etcdMemberHealthOneDown,
+			deleting:              []string{"c"},
+			expectQuorumAvailable: "True",
+			expectFaultTolerant:   "False",
+			expectReason:          "QuorumAtRisk",
+		},
+		{
+			name:                  "removing a healthy member while another is down",
+			output:                etcdMemberHealthOneDown,
+			deleting:              []string{"b"},
+			expectQuorumAvailable: "True",
+			expectFaultTolerant:   "False",
+			expectReason:          "ScaleDownRefused",
+		},
+		{
+			name:                  "no health reported",
+			expectQuorumAvailable: "Unknown",
+			expectFaultTolerant:   "Unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs := map[string]string{}
+			if tt.output != "" {
+				outputs["a"] = etcdMemberHealthOutput(1, tt.output)
+			}
+			mp := newMockPlanner(t, InfoFunctions{})
+			status := mp.planner.reconcileEtcdMemberHealth(cp, rkev1.RKEControlPlaneStatus{}, etcdMemberHealthPlan(outputs, now, tt.deleting...))
+			assert.Equal(t, tt.expectQuorumAvailable, capr.ETCDQuorumAvailable.GetStatus(&status))
+			assert.Equal(t, tt.expectFaultTolerant, capr.ETCDFaultTolerant.GetStatus(&status))
+			assert.Equal(t, tt.expectReason, capr.ETCDFaultTolerant.GetReason(&status))
+			if tt.output == "" {
+				assert.Nil(t, status.ETCDMemberHealth)
+			} else {
+				assert.NotNil(t, status.ETCDMemberHealth)
+			}
+		})
+	}
+}
+
+func TestReconcileEtcdMemberHealthK3s(t *testing.T) {
+	now := time.Now()
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec:       rkev1.RKEControlPlaneSpec{KubernetesVersion: "v1.25.7+k3s1"},
+	}
+	status := rkev1.RKEControlPlaneStatus{ETCDMemberHealth: &rkev1.ETCDMemberHealthStatus{}}
+	capr.ETCDQuorumAvailable.True(&status)
+	capr.ETCDFaultTolerant.True(&status)
+
+	mp := newMockPlanner(t, InfoFunctions{})
+	status = mp.planner.reconcileEtcdMemberHealth(cp, status, etcdMemberHealthPlan(nil, now))
+	assert.Nil(t, status.ETCDMemberHealth)
+	assert.Empty(t, capr.ETCDQuorumAvailable.GetStatus(&status))
+	assert.Empty(t, capr.ETCDFaultTolerant.GetStatus(&status))
+}
+
+func TestMinorPlanChangeDetectedEtcdMemberHealth(t *testing.T) {
+	snapshotList := plan.PeriodicInstruction{Name: "etcd-snapshot-list-local", Command: "sh"}
+	health := plan.PeriodicInstruction{Name: etcdMemberHealthInstructionName, Command: "sh", Args: []string{"-c", "status"}}
+	changedHealth := plan.PeriodicInstruction{Name: etcdMemberHealthInstructionName, Command: "sh", Args: []string{"-c", "other status"}}
+
+	old := plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{snapshotList}}
+	assert.True(t, minorPlanChangeDetected(old, plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{snapshotList, health}}), "adding the health check is minor")
+
+	old = plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{snapshotList, health}}
+	assert.True(t, minorPlanChangeDetected(old, plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{snapshotList, changedHealth}}), "changing the health check is minor")
+	assert.False(t, minorPlanChangeDetected(old, plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{snapshotList, health}}), "no change is not a minor change")
+	assert.False(t, minorPlanChangeDetected(old, plan.NodePlan{PeriodicInstructions: []plan.PeriodicInstruction{health}}), "removing other periodic instructions is major")
+	assert.False(t, minorPlanChangeDetected(old, plan.NodePlan{
+		Instructions:         []plan.OneTimeInstruction{{Name: "install"}},
+		PeriodicInstructions: []plan.PeriodicInstruction{snapshotList, changedHealth},
+	}), "other changes alongside the health check are major")
+}
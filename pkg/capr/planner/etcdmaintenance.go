@@ -0,0 +1,411 @@
+package planner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/controllers/capr/etcdmgmt"
+	"github.com/robfig/cron"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	etcdMaintenanceBinPrefix  = "capr/etcd-maintenance/bin"
+	etcdMaintenanceScriptPath = "etcd_maintenance.sh"
+
+	etcdMaintenanceStatusCommand = "etcd-maintenance-status"
+
+	etcdMaintenanceActionStatus  = "status"
+	etcdMaintenanceActionCompact = "compact"
+	etcdMaintenanceActionDefrag  = "defrag"
+	etcdMaintenanceActionDisarm  = "disarm"
+
+	// etcdctlRunFunction defines an etcdctl_run shell function that runs etcdctl against the local etcd member. etcdctl is
+	// used from the host if it is installed, otherwise it is executed within the etcd static pod. k3s embeds etcd in its
+	// own process and ships neither etcdctl nor an etcd pod, so the function must only be used for rke2 clusters. The
+	// calling script must set tlsDir.
+	etcdctlRunFunction = `
+etcdctl_run() {
+	if command -v etcdctl >/dev/null 2>&1; then
+		ETCDCTL_API=3 etcdctl --endpoints=https://127.0.0.1:2379 --cacert="$tlsDir/server-ca.crt" --cert="$tlsDir/server-client.crt" --key="$tlsDir/server-client.key" "$@"
+		return $?
+	fi
+	crictl="$dataDir/bin/crictl"
+	if [ -x "$crictl" ]; then
+		export CRI_CONFIG_FILE="$dataDir/agent/etc/crictl.yaml"
+		container=$("$crictl" ps --label io.kubernetes.container.name=etcd --state running --quiet | head -n 1)
+		if [ -n "$container" ]; then
+			"$crictl" exec "$container" etcdctl --endpoints=https://127.0.0.1:2379 --cacert="$tlsDir/server-ca.crt" --cert="$tlsDir/server-client.crt" --key="$tlsDir/server-client.key" "$@"
+			return $?
+		fi
+	fi
+	echo "unable to find etcdctl or a running etcd container on this node" >&2
+	return 1
+}
+`
+
+	// etcdMaintenanceScript runs the given maintenance action against the local etcd member.
+	etcdMaintenanceScript = `
+#!/bin/sh
+
+dataDir=$1
+action=$2
+tlsDir="$dataDir/server/tls/etcd"
+
+` + etcdctlRunFunction + `
+case "$action" in
+	status)
+		etcdctl_run endpoint status -w json
+		;;
+	compact)
+		revision=$(etcdctl_run endpoint status -w json | grep -o '"revision":[0-9]*' | head -n 1 | cut -d: -f2)
+		if [ -z "$revision" ]; then
+			echo "unable to determine the current etcd revision" >&2
+			exit 1
+		fi
+		output=$(etcdctl_run compact "$revision" --physical 2>&1)
+		if [ $? -ne 0 ]; then
+			echo "$output" | grep -q "required revision has been compacted" && exit 0
+			echo "$output" >&2
+			exit 1
+		fi
+		echo "$output"
+		;;
+	defrag)
+		etcdctl_run defrag --command-timeout=300s
+		;;
+	disarm)
+		alarms=$(etcdctl_run alarm list) || exit 1
+		if echo "$alarms" | grep -q NOSPACE; then
+			etcdctl_run alarm disarm
+		fi
+		;;
+	*)
+		echo "unknown etcd maintenance action $action" >&2
+		exit 1
+		;;
+esac
+`
+)
+
+func (p *Planner) setEtcdMaintenanceState(status rkev1.RKEControlPlaneStatus, maintenance *rkev1.ETCDMaintenance, phase rkev1.ETCDMaintenancePhase) (rkev1.RKEControlPlaneStatus, error) {
+	if status.ETCDMaintenancePhase != phase || !equality.Semantic.DeepEqual(status.ETCDMaintenance, maintenance) {
+		status.ETCDMaintenancePhase = phase
+		status.ETCDMaintenance = maintenance
+		return status, errWaiting("refreshing etcd maintenance state")
+	}
+	return status, nil
+}
+
+// etcdMaintenanceInProgress returns true if the phase indicates that an etcd maintenance run has not yet completed.
+func etcdMaintenanceInProgress(phase rkev1.ETCDMaintenancePhase) bool {
+	return phase == rkev1.ETCDMaintenancePhaseStarted ||
+		phase == rkev1.ETCDMaintenancePhaseCompact ||
+		phase == rkev1.ETCDMaintenancePhaseDefragment ||
+		phase == rkev1.ETCDMaintenancePhaseDisarmAlarms
+}
+
+// startEtcdMaintenance starts an etcd maintenance run if an on demand run was requested through a new
+// ETCDMaintenance generation, or if the maintenance schedule is due.
+func (p *Planner) startEtcdMaintenance(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	maintenance := controlPlane.Spec.ETCDMaintenance
+	if maintenance != nil && !equality.Semantic.DeepEqual(maintenance, status.ETCDMaintenance) {
+		logrus.Infof("[planner] rkecluster %s/%s: starting etcd maintenance for generation %d", controlPlane.Namespace, controlPlane.Name, maintenance.Generation)
+		status.ETCDMaintenanceStatus = &rkev1.ETCDMaintenanceStatus{
+			Compact:      maintenance.Compact,
+			DisarmAlarms: maintenance.DisarmAlarms,
+			StartTime:    &metav1.Time{Time: time.Now()},
+		}
+		return p.setEtcdMaintenanceState(status, maintenance, rkev1.ETCDMaintenancePhaseStarted)
+	}
+
+	if controlPlane.Spec.ETCD == nil || controlPlane.Spec.ETCD.MaintenanceSchedule == nil || controlPlane.Spec.ETCD.MaintenanceSchedule.ScheduleCron == "" {
+		return status, nil
+	}
+	// only start scheduled runs on clusters that are otherwise idle
+	if !capr.Ready.IsTrue(controlPlane) {
+		return status, nil
+	}
+
+	schedule := controlPlane.Spec.ETCD.MaintenanceSchedule
+	cronSchedule, err := cron.ParseStandard(schedule.ScheduleCron)
+	if err != nil {
+		logrus.Errorf("[planner] rkecluster %s/%s: skipping scheduled etcd maintenance as schedule %q is invalid: %v", controlPlane.Namespace, controlPlane.Name, schedule.ScheduleCron, err)
+		return status, nil
+	}
+
+	last := controlPlane.CreationTimestamp.Time
+	if status.ETCDMaintenanceStatus != nil && status.ETCDMaintenanceStatus.StartTime != nil {
+		last = status.ETCDMaintenanceStatus.StartTime.Time
+	}
+	now := time.Now()
+	if next := cronSchedule.Next(last); now.Before(next) {
+		p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, next.Sub(now))
+		return status, nil
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: starting scheduled etcd maintenance", controlPlane.Namespace, controlPlane.Name)
+	status.ETCDMaintenanceStatus = &rkev1.ETCDMaintenanceStatus{
+		Scheduled:    true,
+		Compact:      schedule.Compact,
+		DisarmAlarms: schedule.DisarmAlarms,
+		StartTime:    &metav1.Time{Time: now},
+	}
+	return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseStarted)
+}
+
+// etcdMaintenance walks through the etcd maintenance phases. Members are defragmented one at a time, with the leader
+// defragmented last, optionally after compacting the keyspace. NOSPACE alarms are optionally disarmed once every member
+// has been defragmented.
+func (p *Planner) etcdMaintenance(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	if controlPlane.Spec.ETCDMaintenance == nil && status.ETCDMaintenance != nil && !etcdMaintenanceInProgress(status.ETCDMaintenancePhase) {
+		status.ETCDMaintenance = nil
+		return status, errWaiting("refreshing etcd maintenance state")
+	}
+
+	// Don't run etcd maintenance if the cluster is not initialized or bootstrapped.
+	if !status.Initialized || !capr.Bootstrapped.IsTrue(&status) {
+		return status, nil
+	}
+
+	var err error
+	if !etcdMaintenanceInProgress(status.ETCDMaintenancePhase) {
+		if status, err = p.startEtcdMaintenance(controlPlane, status); err != nil || !etcdMaintenanceInProgress(status.ETCDMaintenancePhase) {
+			return status, err
+		}
+	}
+
+	if status.ETCDMaintenanceStatus == nil || status.ETCDMaintenanceStatus.StartTime == nil {
+		return p.etcdMaintenanceFailed(status, fmt.Errorf("etcd maintenance status was missing"))
+	}
+
+	if capr.GetRuntime(controlPlane.Spec.KubernetesVersion) == capr.RuntimeK3S {
+		return p.etcdMaintenanceFailed(status, fmt.Errorf("etcd maintenance is not supported for k3s clusters, as k3s does not provide etcdctl or an etcd pod to run it in"))
+	}
+
+	found, joinServer, _, err := p.findInitNode(controlPlane, clusterPlan)
+	if err != nil {
+		logrus.Errorf("[planner] rkecluster %s/%s: error encountered while searching for init node during etcd maintenance: %v", controlPlane.Namespace, controlPlane.Name, err)
+		return status, err
+	}
+	if !found || joinServer == "" {
+		logrus.Warnf("[planner] rkecluster %s/%s: skipping etcd maintenance as cluster does not have an init node", controlPlane.Namespace, controlPlane.Name)
+		return status, nil
+	}
+
+	switch status.ETCDMaintenancePhase {
+	case rkev1.ETCDMaintenancePhaseStarted:
+		members, err := p.etcdMaintenanceCollectMembers(controlPlane, status, tokensSecret, clusterPlan, joinServer)
+		if err != nil {
+			return p.etcdMaintenanceCheckError(status, err)
+		}
+		status.ETCDMaintenanceStatus.Members = members
+		if status.ETCDMaintenanceStatus.Compact {
+			return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseCompact)
+		}
+		return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseDefragment)
+	case rkev1.ETCDMaintenancePhaseCompact:
+		leader, err := etcdMaintenanceLeader(status, clusterPlan)
+		if err != nil {
+			return p.etcdMaintenanceFailed(status, err)
+		}
+		if _, err := p.etcdMaintenanceRunAction(controlPlane, status, tokensSecret, leader, joinServer, etcdMaintenanceActionCompact); err != nil {
+			return p.etcdMaintenanceCheckError(status, err)
+		}
+		return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseDefragment)
+	case rkev1.ETCDMaintenancePhaseDefragment:
+		for i, member := range status.ETCDMaintenanceStatus.Members {
+			if member.Defragmented {
+				continue
+			}
+			entry, err := etcdMaintenanceMemberEntry(clusterPlan, member.MachineName)
+			if err != nil {
+				return p.etcdMaintenanceFailed(status, err)
+			}
+			endpointStatus, err := p.etcdMaintenanceRunAction(controlPlane, status, tokensSecret, entry, joinServer, etcdMaintenanceActionDefrag)
+			if err != nil {
+				return p.etcdMaintenanceCheckError(status, err)
+			}
+			status.ETCDMaintenanceStatus.Members[i].Defragmented = true
+			status.ETCDMaintenanceStatus.Members[i].DBSizeAfter = endpointStatus.Status.DBSize
+			return status, errWaitingf("defragmented etcd member on machine %s", member.MachineName)
+		}
+		if status.ETCDMaintenanceStatus.DisarmAlarms {
+			return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseDisarmAlarms)
+		}
+		return p.etcdMaintenanceFinished(status)
+	case rkev1.ETCDMaintenancePhaseDisarmAlarms:
+		leader, err := etcdMaintenanceLeader(status, clusterPlan)
+		if err != nil {
+			return p.etcdMaintenanceFailed(status, err)
+		}
+		if _, err := p.etcdMaintenanceRunAction(controlPlane, status, tokensSecret, leader, joinServer, etcdMaintenanceActionDisarm); err != nil {
+			return p.etcdMaintenanceCheckError(status, err)
+		}
+		return p.etcdMaintenanceFinished(status)
+	}
+
+	return status, fmt.Errorf("encountered unknown etcd maintenance phase: %s", status.ETCDMaintenancePhase)
+}
+
+// etcdMaintenanceCollectMembers retrieves the status of every etcd member and returns the members in the order they
+// should be defragmented, with the leader last.
+func (p *Planner) etcdMaintenanceCollectMembers(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, clusterPlan *plan.Plan, joinServer string) ([]rkev1.ETCDMemberMaintenanceStatus, error) {
+	entries := collect(clusterPlan, roleAnd(isEtcd, isNotDeleting))
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("failed to find etcd members to perform etcd maintenance")
+	}
+
+	var (
+		members []rkev1.ETCDMemberMaintenanceStatus
+		waiting []error
+	)
+	for _, entry := range entries {
+		endpointStatus, err := p.etcdMaintenanceRunAction(controlPlane, status, tokensSecret, entry, joinServer, etcdMaintenanceActionStatus)
+		if err != nil {
+			if !IsErrWaiting(err) {
+				return nil, err
+			}
+			waiting = append(waiting, err)
+			continue
+		}
+		member := rkev1.ETCDMemberMaintenanceStatus{
+			MachineName:  entry.Machine.Name,
+			MemberID:     endpointStatus.MemberID(),
+			Leader:       endpointStatus.IsLeader(),
+			DBSizeBefore: endpointStatus.Status.DBSize,
+		}
+		if entry.Machine.Status.NodeRef != nil {
+			member.NodeName = entry.Machine.Status.NodeRef.Name
+		}
+		members = append(members, member)
+	}
+	if len(waiting) > 0 {
+		return nil, waiting[0]
+	}
+
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Leader != members[j].Leader {
+			return members[j].Leader
+		}
+		return members[i].MachineName < members[j].MachineName
+	})
+	return members, nil
+}
+
+// etcdMaintenanceRunAction delivers a plan to the given etcd member that runs the given maintenance action exactly once
+// for the current run, followed by a status query, and returns the parsed status of the member once the plan has been
+// applied.
+func (p *Planner) etcdMaintenanceRunAction(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, tokensSecret plan.Secret, entry *planEntry, joinServer, action string) (*etcdmgmt.EndpointStatus, error) {
+	nodePlan, joinedServer, err := p.desiredPlan(controlPlane, tokensSecret, entry, joinServer)
+	if err != nil {
+		return nil, err
+	}
+
+	runID := strconv.FormatInt(status.ETCDMaintenanceStatus.StartTime.Unix(), 10)
+	scriptPath := etcdMaintenanceScriptFilePath(controlPlane)
+	dataDir := capr.GetDistroDataDir(controlPlane)
+
+	nodePlan.Files = append(nodePlan.Files, plan.File{
+		Content: base64.StdEncoding.EncodeToString([]byte(etcdMaintenanceScript)),
+		Path:    scriptPath,
+	})
+	if action != etcdMaintenanceActionStatus {
+		nodePlan.Instructions = append(nodePlan.Instructions, idempotentInstruction(
+			controlPlane,
+			fmt.Sprintf("etcd-maintenance/%s", action),
+			runID,
+			"sh",
+			[]string{scriptPath, dataDir, action},
+			[]string{},
+		))
+	}
+	nodePlan.Instructions = append(nodePlan.Instructions, plan.OneTimeInstruction{
+		Name:    etcdMaintenanceStatusCommand,
+		Command: "sh",
+		Args:    []string{scriptPath, dataDir, etcdMaintenanceActionStatus},
+		Env: []string{
+			fmt.Sprintf("ETCD_MAINTENANCE_RUN=%s", runID),
+			fmt.Sprintf("ETCD_MAINTENANCE_PHASE=%s", status.ETCDMaintenancePhase),
+		},
+		SaveOutput: true,
+	})
+
+	msg := fmt.Sprintf("etcd maintenance [%s] on machine %s", action, entry.Machine.Name)
+	if err := assignAndCheckPlan(p.store, msg, entry, nodePlan, joinedServer, 3, 3); err != nil {
+		return nil, err
+	}
+
+	output, ok := entry.Plan.Output[etcdMaintenanceStatusCommand]
+	if !ok {
+		return nil, errWaitingf("waiting for etcd member status from machine %s", entry.Machine.Name)
+	}
+	statuses, err := etcdmgmt.ParseEndpointStatus(output)
+	if err != nil {
+		return nil, fmt.Errorf("machine %s: %w", entry.Machine.Name, err)
+	}
+	return &statuses[0], nil
+}
+
+// etcdMaintenanceLeader returns the plan entry of the member that was the leader when the run started.
+func etcdMaintenanceLeader(status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) (*planEntry, error) {
+	for _, member := range status.ETCDMaintenanceStatus.Members {
+		if member.Leader {
+			return etcdMaintenanceMemberEntry(clusterPlan, member.MachineName)
+		}
+	}
+	return nil, fmt.Errorf("unable to determine the etcd leader")
+}
+
+// etcdMaintenanceMemberEntry returns the plan entry for the etcd member running on the given machine.
+func etcdMaintenanceMemberEntry(clusterPlan *plan.Plan, machineName string) (*planEntry, error) {
+	machine, ok := clusterPlan.Machines[machineName]
+	if !ok {
+		return nil, fmt.Errorf("etcd member machine %s no longer exists", machineName)
+	}
+	entry := &planEntry{
+		Machine:  machine,
+		Plan:     clusterPlan.Nodes[machineName],
+		Metadata: clusterPlan.Metadata[machineName],
+	}
+	if isDeleting(entry) {
+		return nil, fmt.Errorf("etcd member machine %s is being deleted", machineName)
+	}
+	return entry, nil
+}
+
+// etcdMaintenanceCheckError marks the run as failed unless the error indicates the planner is waiting on a plan.
+func (p *Planner) etcdMaintenanceCheckError(status rkev1.RKEControlPlaneStatus, err error) (rkev1.RKEControlPlaneStatus, error) {
+	if IsErrWaiting(err) {
+		return status, err
+	}
+	return p.etcdMaintenanceFailed(status, err)
+}
+
+func (p *Planner) etcdMaintenanceFailed(status rkev1.RKEControlPlaneStatus, err error) (rkev1.RKEControlPlaneStatus, error) {
+	if status.ETCDMaintenanceStatus != nil {
+		status.ETCDMaintenanceStatus.FinishTime = &metav1.Time{Time: time.Now()}
+		status.ETCDMaintenanceStatus.Message = err.Error()
+	}
+	status.ETCDMaintenancePhase = rkev1.ETCDMaintenancePhaseFailed
+	return status, errWaitingf("etcd maintenance failed: %v", err)
+}
+
+func (p *Planner) etcdMaintenanceFinished(status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	status.ETCDMaintenanceStatus.FinishTime = &metav1.Time{Time: time.Now()}
+	status.ETCDMaintenanceStatus.Message = ""
+	return p.setEtcdMaintenanceState(status, status.ETCDMaintenance, rkev1.ETCDMaintenancePhaseFinished)
+}
+
+func etcdMaintenanceScriptFilePath(controlPlane *rkev1.RKEControlPlane) string {
+	return path.Join(capr.GetDistroDataDir(controlPlane), etcdMaintenanceBinPrefix, etcdMaintenanceScriptPath)
+}
@@ -0,0 +1,180 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestStartEtcdMaintenance(t *testing.T) {
+	now := time.Now()
+	hourAgo := metav1.NewTime(now.Add(-time.Hour))
+	twoDaysAgo := metav1.NewTime(now.Add(-48 * time.Hour))
+
+	tests := []struct {
+		name           string
+		spec           *rkev1.ETCDMaintenance
+		schedule       *rkev1.ETCDMaintenanceSchedule
+		ready          bool
+		status         rkev1.RKEControlPlaneStatus
+		expectEnqueue  bool
+		expectStarted  bool
+		expectSchedule bool
+		expectCompact  bool
+	}{
+		{
+			name: "no spec and no schedule",
+		},
+		{
+			name:          "new on demand generation",
+			spec:          &rkev1.ETCDMaintenance{Generation: 1, Compact: true},
+			expectStarted: true,
+			expectCompact: true,
+		},
+		{
+			name: "on demand generation already run",
+			spec: &rkev1.ETCDMaintenance{Generation: 1},
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDMaintenance:      &rkev1.ETCDMaintenance{Generation: 1},
+				ETCDMaintenancePhase: rkev1.ETCDMaintenancePhaseFinished,
+			},
+		},
+		{
+			name:     "schedule not due",
+			schedule: &rkev1.ETCDMaintenanceSchedule{ScheduleCron: "@every 24h"},
+			ready:    true,
+			status: rkev1.RKEControlPlaneStatus{
+				ETCDMaintenanceStatus: &rkev1.ETCDMaintenanceStatus{StartTime: &hourAgo},
+			},
+			expectEnqueue: true,
+		},
+		{
+			name:           "schedule due",
+			schedule:       &rkev1.ETCDMaintenanceSchedule{ScheduleCron: "@every 24h", Compact: true},
+			ready:          true,
+			status:         rkev1.RKEControlPlaneStatus{ETCDMaintenanceStatus: &rkev1.ETCDMaintenanceStatus{StartTime: &twoDaysAgo}},
+			expectStarted:  true,
+			expectSchedule: true,
+			expectCompact:  true,
+		},
+		{
+			name:     "schedule due but cluster not ready",
+			schedule: &rkev1.ETCDMaintenanceSchedule{ScheduleCron: "@every 24h"},
+			status:   rkev1.RKEControlPlaneStatus{ETCDMaintenanceStatus: &rkev1.ETCDMaintenanceStatus{StartTime: &twoDaysAgo}},
+		},
+		{
+			name:     "invalid schedule",
+			schedule: &rkev1.ETCDMaintenanceSchedule{ScheduleCron: "not a schedule"},
+			ready:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mp := newMockPlanner(t, InfoFunctions{})
+			if tt.expectEnqueue {
+				mp.rkeControlPlanes.EXPECT().EnqueueAfter("fleet-default", "test", gomock.Any())
+			} else {
+				mp.rkeControlPlanes.EXPECT().EnqueueAfter(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			}
+
+			cp := &rkev1.RKEControlPlane{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "fleet-default",
+					Name:              "test",
+					CreationTimestamp: twoDaysAgo,
+				},
+				Spec: rkev1.RKEControlPlaneSpec{
+					ETCDMaintenance: tt.spec,
+					RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+						ETCD: &rkev1.ETCD{MaintenanceSchedule: tt.schedule},
+					},
+				},
+			}
+			if tt.ready {
+				capr.Ready.True(cp)
+			}
+
+			status, err := mp.planner.startEtcdMaintenance(cp, tt.status)
+			if !tt.expectStarted {
+				assert.NoError(t, err)
+				assert.False(t, etcdMaintenanceInProgress(status.ETCDMaintenancePhase))
+				return
+			}
+			assert.True(t, IsErrWaiting(err))
+			assert.Equal(t, rkev1.ETCDMaintenancePhaseStarted, status.ETCDMaintenancePhase)
+			require.NotNil(t, status.ETCDMaintenanceStatus)
+			assert.NotNil(t, status.ETCDMaintenanceStatus.StartTime)
+			assert.Equal(t, tt.expectSchedule, status.ETCDMaintenanceStatus.Scheduled)
+			assert.Equal(t, tt.expectCompact, status.ETCDMaintenanceStatus.Compact)
+			assert.Equal(t, tt.spec, status.ETCDMaintenance)
+		})
+	}
+}
+
+func TestEtcdMaintenanceK3s(t *testing.T) {
+	mp := newMockPlanner(t, InfoFunctions{})
+	startTime := metav1.Now()
+	cp := &rkev1.RKEControlPlane{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "fleet-default",
+			Name:      "test",
+		},
+		Spec: rkev1.RKEControlPlaneSpec{
+			KubernetesVersion: "v1.25.7+k3s1",
+			ETCDMaintenance:   &rkev1.ETCDMaintenance{Generation: 1},
+		},
+	}
+	status := rkev1.RKEControlPlaneStatus{
+		Initialized:           true,
+		ETCDMaintenance:       &rkev1.ETCDMaintenance{Generation: 1},
+		ETCDMaintenancePhase:  rkev1.ETCDMaintenancePhaseStarted,
+		ETCDMaintenanceStatus: &rkev1.ETCDMaintenanceStatus{StartTime: &startTime},
+	}
+	capr.Bootstrapped.True(&status)
+
+	status, err := mp.planner.etcdMaintenance(cp, status, plan.Secret{}, &plan.Plan{})
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, rkev1.ETCDMaintenancePhaseFailed, status.ETCDMaintenancePhase)
+	assert.Contains(t, status.ETCDMaintenanceStatus.Message, "not supported for k3s clusters")
+	assert.NotNil(t, status.ETCDMaintenanceStatus.FinishTime)
+}
+
+func TestEtcdMaintenanceLeader(t *testing.T) {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{
+			"a": {ObjectMeta: metav1.ObjectMeta{Name: "a"}},
+			"b": {ObjectMeta: metav1.ObjectMeta{Name: "b"}},
+		},
+		Nodes:    map[string]*plan.Node{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+
+	status := rkev1.RKEControlPlaneStatus{
+		ETCDMaintenanceStatus: &rkev1.ETCDMaintenanceStatus{
+			Members: []rkev1.ETCDMemberMaintenanceStatus{
+				{MachineName: "a"},
+				{MachineName: "b", Leader: true},
+			},
+		},
+	}
+	leader, err := etcdMaintenanceLeader(status, clusterPlan)
+	require.NoError(t, err)
+	assert.Equal(t, "b", leader.Machine.Name)
+
+	status.ETCDMaintenanceStatus.Members = []rkev1.ETCDMemberMaintenanceStatus{{MachineName: "c", Leader: true}}
+	_, err = etcdMaintenanceLeader(status, clusterPlan)
+	assert.ErrorContains(t, err, "no longer exists")
+
+	status.ETCDMaintenanceStatus.Members = []rkev1.ETCDMemberMaintenanceStatus{{MachineName: "a"}}
+	_, err = etcdMaintenanceLeader(status, clusterPlan)
+	assert.ErrorContains(t, err, "unable to determine the etcd leader")
+}
@@ -663,6 +663,11 @@ func (p *Planner) runEtcdRestoreServiceStop(controlPlane *rkev1.RKEControlPlane,
 		if err != nil {
 			return err
 		}
+		preRestore, err := lifecycleHookInstructions(controlPlane, server, rkev1.LifecycleHookPreRestore)
+		if err != nil {
+			return err
+		}
+		stopPlan = addLifecycleHooks(stopPlan, rkev1.LifecycleHookPreRestore, preRestore)
 		// Clean up previous restoration tracking attempts before starting this restoration.
 		stopPlan.Instructions = append(stopPlan.Instructions, generateIdempotencyCleanupInstruction(controlPlane, "etcd-restore"))
 		if isEtcd(server) {
@@ -696,6 +701,14 @@ func (p *Planner) runEtcdRestoreServiceStop(controlPlane *rkev1.RKEControlPlane,
 		}
 	}
 
+	for _, server := range servers {
+		if server.Plan != nil && hasLifecycleHooks(server.Plan.Plan, rkev1.LifecycleHookPreRestore) {
+			if err := p.setLifecycleHookCondition(clusterPlan, server, rkev1.LifecycleHookPreRestore); err != nil {
+				return err
+			}
+		}
+	}
+
 	if len(collect(clusterPlan, roleAnd(isEtcd, roleNot(isDeleting)))) == 0 {
 		return errWaiting("waiting for suitable etcd nodes for etcd restore continuation")
 	}
@@ -0,0 +1,376 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	lifecycleHookInstructionPrefix = "lifecycle-hook-"
+	lifecycleHookPointEnv          = "CATTLE_LIFECYCLE_HOOK"
+
+	// capiMachinePreDrainHookAnnotation holds CAPI back from draining a deleting machine until its pre-delete hooks ran.
+	capiMachinePreDrainHookAnnotation = "pre-drain.delete.hook.machine.cluster.x-k8s.io/rke-lifecycle-hooks"
+	capiMachinePreDrainHookOwner      = "rke-planner"
+
+	// preDeleteHookTimeout is how long the deletion of a machine waits for its pre-delete hooks, so that a machine whose
+	// system-agent is gone or whose hooks keep failing can still be deleted.
+	preDeleteHookTimeout = 10 * time.Minute
+
+	// lifecycleHookOutputLimit is the number of characters of the output of a hook shown on the machine.
+	lifecycleHookOutputLimit = 256
+)
+
+// lifecycleHookInstructions returns the instructions for the lifecycle hooks that select the machine of the entry and
+// run at the given point, in the order they are defined in.
+func lifecycleHookInstructions(controlPlane *rkev1.RKEControlPlane, entry *planEntry, point rkev1.LifecycleHookPoint) ([]plan.OneTimeInstruction, error) {
+	if windows(entry) {
+		return nil, nil
+	}
+
+	var instructions []plan.OneTimeInstruction
+	for _, hook := range controlPlane.Spec.MachineSelectorLifecycleHooks {
+		if !slices.Contains(hook.Points, point) {
+			continue
+		}
+		sel, err := metav1.LabelSelectorAsSelector(hook.MachineLabelSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid machine label selector of lifecycle hook %s: %w", hook.Name, err)
+		}
+		if hook.MachineLabelSelector != nil && !sel.Matches(labels.Set(entry.Machine.Labels)) {
+			continue
+		}
+		if (hook.Script == "") == (hook.Command == "") {
+			return nil, fmt.Errorf("lifecycle hook %s must have either a script or a command", hook.Name)
+		}
+
+		instruction := plan.OneTimeInstruction{
+			Name:       lifecycleHookInstructionName(point, hook.Name),
+			Image:      hook.Image,
+			Command:    hook.Command,
+			Args:       hook.Args,
+			SaveOutput: true,
+			Env: []string{
+				lifecycleHookPointEnv + "=" + string(point),
+				"CATTLE_KUBERNETES_VERSION=" + controlPlane.Spec.KubernetesVersion,
+			},
+		}
+		if hook.Script != "" {
+			instruction.Command = "/bin/sh"
+			instruction.Args = []string{"-c", hook.Script}
+		}
+		for _, env := range hook.Env {
+			instruction.Env = append(instruction.Env, env.Name+"="+env.Value)
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions, nil
+}
+
+func lifecycleHookInstructionName(point rkev1.LifecycleHookPoint, name string) string {
+	return lifecycleHookInstructionPrefix + string(point) + "-" + name
+}
+
+// addLifecycleHooks returns the plan with the given hooks added: post-join and post-upgrade hooks run after the
+// instructions of the plan, the other hooks before them.
+func addLifecycleHooks(nodePlan plan.NodePlan, point rkev1.LifecycleHookPoint, hooks []plan.OneTimeInstruction) plan.NodePlan {
+	if len(hooks) == 0 {
+		return nodePlan
+	}
+	switch point {
+	case rkev1.LifecycleHookPostJoin, rkev1.LifecycleHookPostUpgrade:
+		nodePlan.Instructions = append(append([]plan.OneTimeInstruction{}, nodePlan.Instructions...), hooks...)
+	default:
+		nodePlan.Instructions = append(append([]plan.OneTimeInstruction{}, hooks...), nodePlan.Instructions...)
+	}
+	return nodePlan
+}
+
+// withoutLifecycleHooks returns the plan without the lifecycle hooks that were added to it. The hooks are only added to
+// the plan that is delivered at the point they run at, so they are ignored when comparing the plan of a machine to its
+// desired plan.
+func withoutLifecycleHooks(nodePlan plan.NodePlan) plan.NodePlan {
+	if len(lifecycleHookPoints(nodePlan)) == 0 {
+		return nodePlan
+	}
+	var instructions []plan.OneTimeInstruction
+	for _, instruction := range nodePlan.Instructions {
+		if !strings.HasPrefix(instruction.Name, lifecycleHookInstructionPrefix) {
+			instructions = append(instructions, instruction)
+		}
+	}
+	nodePlan.Instructions = instructions
+	return nodePlan
+}
+
+// lifecycleHookPoints returns the points of the lifecycle hooks in the plan.
+func lifecycleHookPoints(nodePlan plan.NodePlan) []rkev1.LifecycleHookPoint {
+	var points []rkev1.LifecycleHookPoint
+	for _, point := range []rkev1.LifecycleHookPoint{
+		rkev1.LifecycleHookPreUpgrade,
+		rkev1.LifecycleHookPostUpgrade,
+		rkev1.LifecycleHookPreRestore,
+		rkev1.LifecycleHookPostJoin,
+		rkev1.LifecycleHookPreDelete,
+	} {
+		if hasLifecycleHooks(nodePlan, point) {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+func hasLifecycleHooks(nodePlan plan.NodePlan, point rkev1.LifecycleHookPoint) bool {
+	prefix := lifecycleHookInstructionName(point, "")
+	for _, instruction := range nodePlan.Instructions {
+		if strings.HasPrefix(instruction.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepUnappliedLifecycleHooks returns the desired plan with the lifecycle hooks of the current plan of the entry if the
+// current plan has not been applied yet, so that a minor change does not skip hooks that are about to run.
+func keepUnappliedLifecycleHooks(entry *planEntry, desiredPlan plan.NodePlan) plan.NodePlan {
+	current := entry.Plan.Plan
+	if entry.Plan.AppliedPlan != nil && reflect.DeepEqual(current, *entry.Plan.AppliedPlan) {
+		return desiredPlan
+	}
+	for _, point := range lifecycleHookPoints(current) {
+		prefix := lifecycleHookInstructionName(point, "")
+		var hooks []plan.OneTimeInstruction
+		for _, instruction := range current.Instructions {
+			if strings.HasPrefix(instruction.Name, prefix) {
+				hooks = append(hooks, instruction)
+			}
+		}
+		desiredPlan = addLifecycleHooks(desiredPlan, point, hooks)
+	}
+	return desiredPlan
+}
+
+// upgradePlan returns the plan that upgrades the machine of the entry: the desired plan with the pre-upgrade hooks of
+// the machine. If the machine has post-upgrade hooks, they are marked as pending on the plan secret so that they run
+// once the machine is healthy again.
+func upgradePlan(controlPlane *rkev1.RKEControlPlane, entry *planEntry, desiredPlan plan.NodePlan) (plan.NodePlan, error) {
+	if !shouldDrain(entry.Plan.AppliedPlan, desiredPlan) {
+		return desiredPlan, nil
+	}
+	preUpgrade, err := lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPreUpgrade)
+	if err != nil {
+		return desiredPlan, err
+	}
+	postUpgrade, err := lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPostUpgrade)
+	if err != nil {
+		return desiredPlan, err
+	}
+	if len(postUpgrade) > 0 {
+		entry.Metadata.Annotations[capr.LifecycleHookAnnotation] = string(rkev1.LifecycleHookPostUpgrade)
+	}
+	return addLifecycleHooks(desiredPlan, rkev1.LifecycleHookPreUpgrade, preUpgrade), nil
+}
+
+// markPostJoinHooks marks the post-join hooks of a machine that is given its initial plan as pending.
+func markPostJoinHooks(controlPlane *rkev1.RKEControlPlane, entry *planEntry) error {
+	postJoin, err := lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPostJoin)
+	if err != nil || len(postJoin) == 0 {
+		return err
+	}
+	entry.Metadata.Annotations[capr.LifecycleHookAnnotation] = string(rkev1.LifecycleHookPostJoin)
+	return nil
+}
+
+// runPendingLifecycleHooks delivers the pending post-join or post-upgrade hooks of a machine whose plan is in sync. It
+// returns true once there are no pending hooks. The hooks have run once the plan that includes them is in sync.
+func (p *Planner) runPendingLifecycleHooks(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, entry *planEntry, maxFailures, failureThreshold int) (bool, error) {
+	point := rkev1.LifecycleHookPoint(entry.Metadata.Annotations[capr.LifecycleHookAnnotation])
+	if point == "" {
+		return true, nil
+	}
+
+	if hasLifecycleHooks(entry.Plan.Plan, point) {
+		if err := p.setLifecycleHookCondition(clusterPlan, entry, point); err != nil {
+			return false, err
+		}
+		entry.Metadata.Annotations[capr.LifecycleHookAnnotation] = ""
+		return false, p.store.updatePlanSecretLabelsAndAnnotations(entry)
+	}
+
+	hooks, err := lifecycleHookInstructions(controlPlane, entry, point)
+	if err != nil {
+		return false, err
+	}
+	if len(hooks) == 0 {
+		entry.Metadata.Annotations[capr.LifecycleHookAnnotation] = ""
+		return false, p.store.updatePlanSecretLabelsAndAnnotations(entry)
+	}
+
+	logrus.Infof("[planner] rkecluster %s/%s: running %s lifecycle hooks on machine %s", controlPlane.Namespace, controlPlane.Name, point, entry.Machine.Name)
+	return false, p.store.UpdatePlan(entry, addLifecycleHooks(withoutLifecycleHooks(entry.Plan.Plan), point, hooks), "", maxFailures, failureThreshold)
+}
+
+// refreshFailedLifecycleHooks delivers the plan of a machine again with its lifecycle hooks rendered from the current
+// spec if the plan failed to apply, so that a failing hook can be fixed without waiting for the next upgrade.
+func (p *Planner) refreshFailedLifecycleHooks(controlPlane *rkev1.RKEControlPlane, entry *planEntry, maxFailures, failureThreshold int) error {
+	if entry.Plan == nil || !entry.Plan.Failed {
+		return nil
+	}
+	points := lifecycleHookPoints(entry.Plan.Plan)
+	if len(points) == 0 {
+		return nil
+	}
+
+	nodePlan := withoutLifecycleHooks(entry.Plan.Plan)
+	for _, point := range points {
+		hooks, err := lifecycleHookInstructions(controlPlane, entry, point)
+		if err != nil {
+			return err
+		}
+		nodePlan = addLifecycleHooks(nodePlan, point, hooks)
+	}
+	if equality.Semantic.DeepEqual(entry.Plan.Plan, nodePlan) {
+		return nil
+	}
+	return p.store.UpdatePlan(entry, nodePlan, "", maxFailures, failureThreshold)
+}
+
+// reconcilePreDeleteHooks adds a CAPI pre-drain hook to the machines that have pre-delete lifecycle hooks, and runs the
+// pre-delete hooks of the deleting machines before releasing the pre-drain hook. Errors for one machine do not hold
+// back the other machines.
+func (p *Planner) reconcilePreDeleteHooks(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	var firstErr error
+	for _, entry := range collect(clusterPlan, anyRole) {
+		if err := p.reconcilePreDeleteHook(controlPlane, clusterPlan, entry); err != nil {
+			logrus.Errorf("[planner] rkecluster %s/%s: error reconciling pre-delete lifecycle hooks of machine %s: %v", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (p *Planner) reconcilePreDeleteHook(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, entry *planEntry) error {
+	hooks, err := lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPreDelete)
+	if err != nil {
+		return err
+	}
+	_, annotated := entry.Machine.Annotations[capiMachinePreDrainHookAnnotation]
+
+	// The hooks can only run on machines whose system-agent has applied a plan.
+	runnable := len(hooks) > 0 && entry.Plan != nil && entry.Plan.AppliedPlan != nil
+	if !isDeleting(entry) {
+		if runnable != annotated {
+			return p.setPreDrainHook(clusterPlan, entry, runnable)
+		}
+		return nil
+	}
+	if !annotated {
+		return nil
+	}
+
+	if !runnable {
+		return p.setPreDrainHook(clusterPlan, entry, false)
+	}
+	if deadline := entry.Machine.DeletionTimestamp.Add(preDeleteHookTimeout); time.Now().After(deadline) {
+		logrus.Warnf("[planner] rkecluster %s/%s: pre-delete lifecycle hooks of machine %s did not succeed within %s, proceeding with deletion", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name, preDeleteHookTimeout)
+		return p.setPreDrainHook(clusterPlan, entry, false)
+	}
+
+	if !hasLifecycleHooks(entry.Plan.Plan, rkev1.LifecycleHookPreDelete) {
+		logrus.Infof("[planner] rkecluster %s/%s: running pre-delete lifecycle hooks on machine %s", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name)
+		return p.store.UpdatePlan(entry, addLifecycleHooks(withoutLifecycleHooks(entry.Plan.Plan), rkev1.LifecycleHookPreDelete, hooks), "", 0, 0)
+	}
+	if entry.Plan.AppliedPlan == nil || !reflect.DeepEqual(entry.Plan.Plan, *entry.Plan.AppliedPlan) {
+		p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, time.Until(entry.Machine.DeletionTimestamp.Add(preDeleteHookTimeout)))
+		return nil
+	}
+
+	if err := p.setLifecycleHookCondition(clusterPlan, entry, rkev1.LifecycleHookPreDelete); err != nil {
+		return err
+	}
+	return p.setPreDrainHook(clusterPlan, entry, false)
+}
+
+// setPreDrainHook adds or removes the CAPI pre-drain hook of the planner on the machine of the entry.
+func (p *Planner) setPreDrainHook(clusterPlan *plan.Plan, entry *planEntry, set bool) error {
+	machine := entry.Machine.DeepCopy()
+	if set {
+		if machine.Annotations == nil {
+			machine.Annotations = map[string]string{}
+		}
+		machine.Annotations[capiMachinePreDrainHookAnnotation] = capiMachinePreDrainHookOwner
+	} else {
+		delete(machine.Annotations, capiMachinePreDrainHookAnnotation)
+	}
+	updated, err := p.machines.Update(machine)
+	if err != nil {
+		return err
+	}
+	entry.Machine = updated
+	clusterPlan.Machines[updated.Name] = updated
+	return nil
+}
+
+// setLifecycleHookCondition records the hooks that ran at the given point on the LifecycleHooksRun condition of the
+// machine, with the last line of the output of each hook.
+func (p *Planner) setLifecycleHookCondition(clusterPlan *plan.Plan, entry *planEntry, point rkev1.LifecycleHookPoint) error {
+	machine := entry.Machine.DeepCopy()
+	conditionType := capi.ConditionType(capr.LifecycleHooksRun)
+
+	conditions.Set(machine, &capi.Condition{
+		Type:    conditionType,
+		Status:  corev1.ConditionTrue,
+		Reason:  string(point),
+		Message: lifecycleHookMessage(entry.Plan.Plan, entry.Plan.Output, point),
+	})
+
+	if equality.Semantic.DeepEqual(conditions.Get(machine, conditionType), conditions.Get(entry.Machine, conditionType)) {
+		return nil
+	}
+	updated, err := p.machines.UpdateStatus(machine)
+	if err != nil {
+		return err
+	}
+	entry.Machine = updated
+	clusterPlan.Machines[updated.Name] = updated
+	return nil
+}
+
+// lifecycleHookMessage returns a message listing the hooks of the given point in the plan, each with the last line of
+// its output.
+func lifecycleHookMessage(nodePlan plan.NodePlan, output map[string][]byte, point rkev1.LifecycleHookPoint) string {
+	prefix := lifecycleHookInstructionName(point, "")
+	var hooks []string
+	for _, instruction := range nodePlan.Instructions {
+		name, ok := strings.CutPrefix(instruction.Name, prefix)
+		if !ok {
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(string(output[instruction.Name])), "\n")
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if len(last) > lifecycleHookOutputLimit {
+			last = last[:lifecycleHookOutputLimit] + "..."
+		}
+		if last != "" {
+			name += ": " + last
+		}
+		hooks = append(hooks, name)
+	}
+	return fmt.Sprintf("%s hooks ran: %s", point, strings.Join(hooks, ", "))
+}
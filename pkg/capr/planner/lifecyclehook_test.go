@@ -0,0 +1,144 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLifecycleHookInstructions(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	controlPlane.Spec.MachineSelectorLifecycleHooks = []rkev1.RKELifecycleHook{
+		{
+			Name:   "stop-services",
+			Points: []rkev1.LifecycleHookPoint{rkev1.LifecycleHookPreUpgrade, rkev1.LifecycleHookPreDelete},
+			Script: "systemctl stop local-cache",
+			Env:    []rkev1.EnvVar{{Name: "CACHE", Value: "local"}},
+		},
+		{
+			Name:                 "flush",
+			MachineLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "cache"}},
+			Points:               []rkev1.LifecycleHookPoint{rkev1.LifecycleHookPreUpgrade},
+			Image:                "example.com/flush:v1",
+			Command:              "./flush.sh",
+			Args:                 []string{"--all"},
+		},
+	}
+	entry := createTestPlanEntry("linux")
+
+	instructions, err := lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPreUpgrade)
+	assert.NoError(t, err)
+	assert.Equal(t, []plan.OneTimeInstruction{{
+		Name:       "lifecycle-hook-pre-upgrade-stop-services",
+		Command:    "/bin/sh",
+		Args:       []string{"-c", "systemctl stop local-cache"},
+		SaveOutput: true,
+		Env:        []string{"CATTLE_LIFECYCLE_HOOK=pre-upgrade", "CATTLE_KUBERNETES_VERSION=v1.30.4+rke2r1", "CACHE=local"},
+	}}, instructions)
+
+	entry.Machine.Labels["pool"] = "cache"
+	instructions, err = lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPreUpgrade)
+	assert.NoError(t, err)
+	if assert.Len(t, instructions, 2) {
+		assert.Equal(t, "lifecycle-hook-pre-upgrade-flush", instructions[1].Name)
+		assert.Equal(t, "example.com/flush:v1", instructions[1].Image)
+		assert.Equal(t, "./flush.sh", instructions[1].Command)
+		assert.Equal(t, []string{"--all"}, instructions[1].Args)
+	}
+
+	instructions, err = lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPostJoin)
+	assert.NoError(t, err)
+	assert.Empty(t, instructions)
+
+	instructions, err = lifecycleHookInstructions(controlPlane, createTestPlanEntry(capr.WindowsMachineOS), rkev1.LifecycleHookPreUpgrade)
+	assert.NoError(t, err)
+	assert.Empty(t, instructions)
+
+	controlPlane.Spec.MachineSelectorLifecycleHooks[0].Command = "systemctl"
+	_, err = lifecycleHookInstructions(controlPlane, entry, rkev1.LifecycleHookPreUpgrade)
+	assert.Error(t, err)
+}
+
+func TestAddLifecycleHooks(t *testing.T) {
+	install := plan.OneTimeInstruction{Name: "install"}
+	preUpgrade := plan.OneTimeInstruction{Name: lifecycleHookInstructionName(rkev1.LifecycleHookPreUpgrade, "stop")}
+	postUpgrade := plan.OneTimeInstruction{Name: lifecycleHookInstructionName(rkev1.LifecycleHookPostUpgrade, "start")}
+	nodePlan := plan.NodePlan{Instructions: []plan.OneTimeInstruction{install}}
+
+	withHooks := addLifecycleHooks(nodePlan, rkev1.LifecycleHookPreUpgrade, []plan.OneTimeInstruction{preUpgrade})
+	withHooks = addLifecycleHooks(withHooks, rkev1.LifecycleHookPostUpgrade, []plan.OneTimeInstruction{postUpgrade})
+	assert.Equal(t, []plan.OneTimeInstruction{preUpgrade, install, postUpgrade}, withHooks.Instructions)
+	assert.Equal(t, []plan.OneTimeInstruction{install}, nodePlan.Instructions)
+	assert.Equal(t, []rkev1.LifecycleHookPoint{rkev1.LifecycleHookPreUpgrade, rkev1.LifecycleHookPostUpgrade}, lifecycleHookPoints(withHooks))
+
+	assert.Equal(t, nodePlan, withoutLifecycleHooks(withHooks))
+	assert.Equal(t, nodePlan, withoutLifecycleHooks(nodePlan))
+	assert.Empty(t, lifecycleHookPoints(nodePlan))
+}
+
+func TestKeepUnappliedLifecycleHooks(t *testing.T) {
+	preUpgrade := plan.OneTimeInstruction{Name: lifecycleHookInstructionName(rkev1.LifecycleHookPreUpgrade, "stop")}
+	current := plan.NodePlan{
+		Files:        []plan.File{{Path: "/etc/a", Content: "a"}},
+		Instructions: []plan.OneTimeInstruction{preUpgrade, {Name: "install"}},
+	}
+	desired := plan.NodePlan{
+		Files:        []plan.File{{Path: "/etc/a", Content: "b", Minor: true}},
+		Instructions: []plan.OneTimeInstruction{{Name: "install"}},
+	}
+	entry := createTestPlanEntry("linux")
+	entry.Plan = &plan.Node{Plan: current}
+
+	kept := keepUnappliedLifecycleHooks(entry, desired)
+	assert.Equal(t, desired.Files, kept.Files)
+	assert.Equal(t, current.Instructions, kept.Instructions)
+
+	entry.Plan.AppliedPlan = &current
+	assert.Equal(t, desired, keepUnappliedLifecycleHooks(entry, desired))
+}
+
+func TestUpgradePlan(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	controlPlane.Spec.MachineSelectorLifecycleHooks = []rkev1.RKELifecycleHook{
+		{Name: "stop", Points: []rkev1.LifecycleHookPoint{rkev1.LifecycleHookPreUpgrade}, Script: "stop"},
+		{Name: "start", Points: []rkev1.LifecycleHookPoint{rkev1.LifecycleHookPostUpgrade}, Script: "start"},
+	}
+	entry := createTestPlanEntry("linux")
+	entry.Metadata.Annotations = map[string]string{}
+	applied := plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Name: "install", Env: []string{"RESTART_STAMP=a"}}}}
+	entry.Plan = &plan.Node{Plan: applied, AppliedPlan: &applied}
+
+	// a plan that does not restart the distro is not an upgrade
+	desired := plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Name: "install", Env: []string{"RESTART_STAMP=a"}}}, Files: []plan.File{{Path: "/etc/a"}}}
+	result, err := upgradePlan(controlPlane, entry, desired)
+	assert.NoError(t, err)
+	assert.Equal(t, desired, result)
+	assert.Empty(t, entry.Metadata.Annotations[capr.LifecycleHookAnnotation])
+
+	desired = plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Name: "install", Env: []string{"RESTART_STAMP=b"}}}}
+	result, err = upgradePlan(controlPlane, entry, desired)
+	assert.NoError(t, err)
+	if assert.Len(t, result.Instructions, 2) {
+		assert.Equal(t, "lifecycle-hook-pre-upgrade-stop", result.Instructions[0].Name)
+		assert.Equal(t, "install", result.Instructions[1].Name)
+	}
+	assert.Equal(t, string(rkev1.LifecycleHookPostUpgrade), entry.Metadata.Annotations[capr.LifecycleHookAnnotation])
+}
+
+func TestLifecycleHookMessage(t *testing.T) {
+	nodePlan := plan.NodePlan{Instructions: []plan.OneTimeInstruction{
+		{Name: "install"},
+		{Name: lifecycleHookInstructionName(rkev1.LifecycleHookPostJoin, "register")},
+		{Name: lifecycleHookInstructionName(rkev1.LifecycleHookPostJoin, "warm-cache")},
+	}}
+	output := map[string][]byte{
+		"install":                             []byte("installed"),
+		"lifecycle-hook-post-join-register":   []byte("registering\nregistered node-1\n"),
+		"lifecycle-hook-post-join-warm-cache": nil,
+	}
+	assert.Equal(t, "post-join hooks ran: register: registered node-1, warm-cache", lifecycleHookMessage(nodePlan, output, rkev1.LifecycleHookPostJoin))
+}
@@ -0,0 +1,55 @@
+package planner
+
+import (
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/metrics"
+)
+
+const certificateRotationOperation = "rotate-certificates"
+
+// observeMachinesWaiting records the number of machines of the cluster that are being drained, whose plan has not been
+// applied yet, or whose probes have not passed since their plan was applied.
+func observeMachinesWaiting(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) {
+	if controlPlane.Spec.ManagementClusterName == "" {
+		return
+	}
+	drain, probes, applying := countMachinesWaiting(clusterPlan)
+	metrics.SetPlannerMachinesWaiting(controlPlane.Spec.ManagementClusterName, drain, probes, applying)
+}
+
+func countMachinesWaiting(clusterPlan *plan.Plan) (drain, probes, applying int) {
+	for _, entry := range collect(clusterPlan, anyRole) {
+		drainOptions := entry.Metadata.Annotations[capr.DrainAnnotation]
+		switch {
+		case drainOptions != "" && entry.Metadata.Annotations[capr.DrainDoneAnnotation] != drainOptions:
+			drain++
+		case entry.Plan == nil || entry.Plan.Failed:
+		case !entry.Plan.InSync:
+			applying++
+		case !entry.Plan.Healthy:
+			probes++
+		}
+	}
+	return
+}
+
+// observeCertificateRotation records the phase of the certificate rotation from the error returned while rotating:
+// waiting errors mean the rotation is still in progress, and any other error means it failed.
+func observeCertificateRotation(controlPlane *rkev1.RKEControlPlane, rotating bool, err error) {
+	if controlPlane.Spec.ManagementClusterName == "" {
+		return
+	}
+	var phase, result string
+	switch {
+	case !rotating:
+	case IsErrWaiting(err):
+		phase = "Rotating"
+	case err != nil:
+		phase, result = "Failed", metrics.PlannerResultFailure
+	default:
+		phase, result = "Done", metrics.PlannerResultSuccess
+	}
+	metrics.ObservePlannerOperationPhase(controlPlane.Spec.ManagementClusterName, certificateRotationOperation, phase, result)
+}
@@ -0,0 +1,41 @@
+package planner
+
+import (
+	"testing"
+
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestCountMachinesWaiting(t *testing.T) {
+	clusterPlan := &plan.Plan{
+		Nodes:    map[string]*plan.Node{},
+		Machines: map[string]*capi.Machine{},
+		Metadata: map[string]*plan.Metadata{},
+	}
+	add := func(name string, node *plan.Node, annotations map[string]string) {
+		clusterPlan.Machines[name] = &capi.Machine{}
+		clusterPlan.Machines[name].Name = name
+		clusterPlan.Metadata[name] = &plan.Metadata{
+			Labels:      map[string]string{capr.WorkerRoleLabel: "true"},
+			Annotations: annotations,
+		}
+		if node != nil {
+			clusterPlan.Nodes[name] = node
+		}
+	}
+
+	add("draining", &plan.Node{InSync: true, Healthy: true}, map[string]string{capr.DrainAnnotation: "{}"})
+	add("drained", &plan.Node{InSync: true}, map[string]string{capr.DrainAnnotation: "{}", capr.DrainDoneAnnotation: "{}"})
+	add("applying", &plan.Node{}, nil)
+	add("failed", &plan.Node{Failed: true}, nil)
+	add("healthy", &plan.Node{InSync: true, Healthy: true}, nil)
+	add("no-plan", nil, nil)
+
+	drain, probes, applying := countMachinesWaiting(clusterPlan)
+	assert.Equal(t, 1, drain)
+	assert.Equal(t, 1, probes)
+	assert.Equal(t, 1, applying)
+}
@@ -0,0 +1,391 @@
+package planner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	nodeMaintenanceIdentifier       = "node-maintenance"
+	nodeMaintenanceRebootIdentifier = "node-maintenance-reboot"
+	// nodeMaintenanceInstructionPrefix is the name prefix of the idempotent instructions added to the plan of a machine
+	// under maintenance.
+	nodeMaintenanceInstructionPrefix = "idempotent-" + nodeMaintenanceIdentifier + "-"
+
+	nodeMaintenanceRunEnv = "CATTLE_NODE_MAINTENANCE_RUN"
+
+	// nodeMaintenanceRebootScript reboots the machine in the background, giving the system-agent time to report the plan
+	// as applied before the machine goes down.
+	nodeMaintenanceRebootScript  = "(sleep 5; systemctl reboot) >/dev/null 2>&1 &"
+	nodeMaintenanceRebootTimeout = 15 * time.Minute
+)
+
+func (p *Planner) setNodeMaintenanceState(status rkev1.RKEControlPlaneStatus, maintenance *rkev1.NodeMaintenance, phase rkev1.NodeMaintenancePhase) (rkev1.RKEControlPlaneStatus, error) {
+	if status.NodeMaintenancePhase != phase || !equality.Semantic.DeepEqual(status.NodeMaintenance, maintenance) {
+		status.NodeMaintenancePhase = phase
+		status.NodeMaintenance = maintenance
+		return status, errWaiting("refreshing node maintenance state")
+	}
+	return status, nil
+}
+
+// nodeMaintenanceInProgress returns true if the phase indicates that a node maintenance run has not yet completed.
+func nodeMaintenanceInProgress(phase rkev1.NodeMaintenancePhase) bool {
+	return phase == rkev1.NodeMaintenancePhaseStarted ||
+		phase == rkev1.NodeMaintenancePhaseRunning
+}
+
+// nodeMaintenanceNodeInProgress returns true if the machine is being maintained, i.e. it may be cordoned.
+func nodeMaintenanceNodeInProgress(phase rkev1.NodeMaintenanceNodePhase) bool {
+	return phase == rkev1.NodeMaintenanceNodePhaseDraining ||
+		phase == rkev1.NodeMaintenanceNodePhaseRunning ||
+		phase == rkev1.NodeMaintenanceNodePhaseRebooting ||
+		phase == rkev1.NodeMaintenanceNodePhaseWaitingForProbes ||
+		phase == rkev1.NodeMaintenanceNodePhaseUncordoning
+}
+
+// nodeMaintenance rolls a node maintenance run through the selected machines. The machines are collected when the run
+// starts, and every machine is drained, has the maintenance instruction run, is optionally rebooted and is uncordoned
+// once it is healthy again. Machine reconciliation is held off while a run is in progress, so the plans delivered for
+// the maintenance are replaced by the regular plans once the run has completed.
+func (p *Planner) nodeMaintenance(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	if controlPlane.Spec.NodeMaintenance == nil && status.NodeMaintenance != nil && !nodeMaintenanceInProgress(status.NodeMaintenancePhase) {
+		status.NodeMaintenance = nil
+		return status, errWaiting("refreshing node maintenance state")
+	}
+
+	// Don't run node maintenance if the cluster is not initialized or bootstrapped.
+	if !status.Initialized || !capr.Bootstrapped.IsTrue(&status) {
+		return status, nil
+	}
+
+	if !nodeMaintenanceInProgress(status.NodeMaintenancePhase) {
+		maintenance := controlPlane.Spec.NodeMaintenance
+		if maintenance == nil || equality.Semantic.DeepEqual(maintenance, status.NodeMaintenance) {
+			return status, nil
+		}
+		logrus.Infof("[planner] rkecluster %s/%s: starting node maintenance for generation %d", controlPlane.Namespace, controlPlane.Name, maintenance.Generation)
+		status.NodeMaintenanceStatus = &rkev1.NodeMaintenanceStatus{
+			StartTime: &metav1.Time{Time: time.Now()},
+		}
+		return p.setNodeMaintenanceState(status, maintenance, rkev1.NodeMaintenancePhaseStarted)
+	}
+
+	if status.NodeMaintenance == nil || status.NodeMaintenanceStatus == nil || status.NodeMaintenanceStatus.StartTime == nil {
+		return p.nodeMaintenanceFailed(status, fmt.Errorf("node maintenance status was missing"))
+	}
+
+	switch status.NodeMaintenancePhase {
+	case rkev1.NodeMaintenancePhaseStarted:
+		if status.NodeMaintenance.Script != "" && status.NodeMaintenance.Command != "" {
+			return p.nodeMaintenanceFailed(status, fmt.Errorf("only one of script and command may be set"))
+		}
+		nodes, err := nodeMaintenanceCollectNodes(status.NodeMaintenance, clusterPlan)
+		if err != nil {
+			return p.nodeMaintenanceFailed(status, err)
+		}
+		if len(nodes) == 0 {
+			return p.nodeMaintenanceFailed(status, fmt.Errorf("no machines were selected for node maintenance"))
+		}
+		status.NodeMaintenanceStatus.Nodes = nodes
+		return p.setNodeMaintenanceState(status, status.NodeMaintenance, rkev1.NodeMaintenancePhaseRunning)
+	case rkev1.NodeMaintenancePhaseRunning:
+		return p.nodeMaintenanceRun(controlPlane, status, clusterPlan)
+	}
+
+	return status, fmt.Errorf("encountered unknown node maintenance phase: %s", status.NodeMaintenancePhase)
+}
+
+// nodeMaintenanceCollectNodes returns the machines selected for maintenance in the order they are maintained: etcd
+// machines first, followed by controlplane machines and workers. Machines that are deleting, run Windows or have not
+// yet joined the cluster are not maintained.
+func nodeMaintenanceCollectNodes(maintenance *rkev1.NodeMaintenance, clusterPlan *plan.Plan) ([]rkev1.NodeMaintenanceNodeStatus, error) {
+	sel, err := metav1.LabelSelectorAsSelector(maintenance.MachineLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid machine label selector: %w", err)
+	}
+
+	var nodes []rkev1.NodeMaintenanceNodeStatus
+	for _, entry := range collectOrderedCertificateRotationEntries(clusterPlan) {
+		if isDeleting(entry) || windows(entry) || entry.Plan == nil || entry.Machine.Status.NodeRef == nil {
+			continue
+		}
+		if maintenance.MachineLabelSelector != nil && !sel.Matches(labels.Set(entry.Machine.Labels)) {
+			continue
+		}
+		nodes = append(nodes, rkev1.NodeMaintenanceNodeStatus{
+			MachineName: entry.Machine.Name,
+			NodeName:    entry.Machine.Status.NodeRef.Name,
+			Phase:       rkev1.NodeMaintenanceNodePhasePending,
+		})
+	}
+	return nodes, nil
+}
+
+// nodeMaintenanceRun advances every machine under maintenance by at most one phase, and starts maintaining pending
+// machines while the concurrency allows it. Machines with the etcd or controlplane role are maintained one at a time,
+// and the remaining machines are not started until all of them are done.
+func (p *Planner) nodeMaintenanceRun(controlPlane *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, clusterPlan *plan.Plan) (rkev1.RKEControlPlaneStatus, error) {
+	var (
+		maintenance      = status.NodeMaintenance
+		nodes            = status.NodeMaintenanceStatus.Nodes
+		runID            = strconv.FormatInt(status.NodeMaintenanceStatus.StartTime.Unix(), 10)
+		reconcilables    = map[string]*reconcilable{}
+		controlPlaneBusy bool
+		waiting          []string
+	)
+
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Phase != rkev1.NodeMaintenanceNodePhasePending && !nodeMaintenanceNodeInProgress(node.Phase) {
+			continue
+		}
+		entry, err := nodeMaintenanceEntry(clusterPlan, node.MachineName)
+		if err != nil {
+			setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseSkipped)
+			node.FinishTime = node.LastTransitionTime
+			node.Message = err.Error()
+			continue
+		}
+		reconcilables[node.MachineName] = &reconcilable{entry: entry}
+		if !nodeMaintenanceNodeInProgress(node.Phase) {
+			continue
+		}
+		if isEtcd(entry) || isControlPlane(entry) {
+			controlPlaneBusy = true
+		}
+		if err := p.nodeMaintenanceStep(controlPlane, maintenance, runID, node, entry, clusterPlan); err != nil {
+			if !IsErrWaiting(err) {
+				setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseFailed)
+				node.FinishTime = node.LastTransitionTime
+				node.Message = err.Error()
+				return p.nodeMaintenanceFailed(status, fmt.Errorf("machine %s: %w", node.MachineName, err))
+			}
+			node.Message = err.Error()
+			waiting = append(waiting, err.Error())
+		}
+	}
+
+	// a percentage is relative to all the selected machines, so the concurrency doesn't shrink as machines are done
+	var selected []*reconcilable
+	for _, node := range nodes {
+		if r := reconcilables[node.MachineName]; r != nil {
+			selected = append(selected, r)
+		} else if entry, err := nodeMaintenanceEntry(clusterPlan, node.MachineName); err == nil {
+			selected = append(selected, &reconcilable{entry: entry})
+		}
+	}
+	concurrency, unavailable, err := calculateConcurrency(maintenance.Concurrency, selected, isDeleting)
+	if err != nil {
+		return p.nodeMaintenanceFailed(status, err)
+	}
+
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Phase != rkev1.NodeMaintenanceNodePhasePending {
+			continue
+		}
+		r := reconcilables[node.MachineName]
+		if controlPlaneBusy || r == nil {
+			break
+		}
+		// a machine that is already unavailable does not count against the concurrency once it is started
+		self := 0
+		if isUnavailable(r) {
+			self = 1
+		}
+		controlPlaneNode := isEtcd(r.entry) || isControlPlane(r.entry)
+		if !controlPlaneNode && concurrency != 0 && unavailable-self >= concurrency {
+			break
+		}
+		logrus.Infof("[planner] rkecluster %s/%s: starting node maintenance on machine %s", controlPlane.Namespace, controlPlane.Name, node.MachineName)
+		setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseDraining)
+		node.StartTime = node.LastTransitionTime
+		if nodeInfo := r.entry.Machine.Status.NodeInfo; nodeInfo != nil {
+			node.BootID = nodeInfo.BootID
+		}
+		unavailable += 1 - self
+		controlPlaneBusy = controlPlaneNode
+		waiting = append(waiting, fmt.Sprintf("starting node maintenance on machine %s", node.MachineName))
+	}
+
+	for _, node := range nodes {
+		if node.Phase == rkev1.NodeMaintenanceNodePhasePending || nodeMaintenanceNodeInProgress(node.Phase) {
+			if len(waiting) == 0 {
+				return status, errWaiting("node maintenance in progress")
+			}
+			return status, errWaiting(strings.Join(waiting, ", "))
+		}
+	}
+	return p.nodeMaintenanceFinished(status)
+}
+
+// nodeMaintenanceStep advances the maintenance of a single machine by one phase. An error that is not an errWaiting
+// fails the run.
+func (p *Planner) nodeMaintenanceStep(controlPlane *rkev1.RKEControlPlane, maintenance *rkev1.NodeMaintenance, runID string, node *rkev1.NodeMaintenanceNodeStatus, entry *planEntry, clusterPlan *plan.Plan) error {
+	switch node.Phase {
+	case rkev1.NodeMaintenanceNodePhaseDraining:
+		drained, err := p.drainNode(entry, clusterPlan, nodeMaintenanceDrainOptions(controlPlane, maintenance, entry))
+		if err != nil && drained {
+			// drain errors are retried by the drain controller
+			return errWaiting(err.Error())
+		} else if err != nil {
+			return err
+		} else if !drained {
+			return errWaitingf("draining machine %s", entry.Machine.Name)
+		}
+		setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseRunning)
+	case rkev1.NodeMaintenanceNodePhaseRunning:
+		nodePlan := nodeMaintenancePlan(controlPlane, maintenance, runID, entry)
+		if err := assignAndCheckPlan(p.store, fmt.Sprintf("node maintenance on machine %s", entry.Machine.Name), entry, nodePlan, "", 3, 3); err != nil {
+			return err
+		}
+		if maintenance.Reboot {
+			setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseRebooting)
+		} else {
+			setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseWaitingForProbes)
+		}
+	case rkev1.NodeMaintenanceNodePhaseRebooting:
+		// without a boot ID to compare against, the probes are all there is to go on
+		if nodeInfo := entry.Machine.Status.NodeInfo; node.BootID != "" && (nodeInfo == nil || nodeInfo.BootID == node.BootID) {
+			if node.LastTransitionTime != nil {
+				remaining := nodeMaintenanceRebootTimeout - time.Since(node.LastTransitionTime.Time)
+				if remaining <= 0 {
+					return fmt.Errorf("machine did not reboot within %s", nodeMaintenanceRebootTimeout)
+				}
+				p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, remaining)
+			}
+			return errWaitingf("waiting for machine %s to reboot", entry.Machine.Name)
+		}
+		setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseWaitingForProbes)
+	case rkev1.NodeMaintenanceNodePhaseWaitingForProbes:
+		if !entry.Plan.InSync || !entry.Plan.Healthy {
+			return errWaitingf("waiting for probes on machine %s: %s", entry.Machine.Name, probesMessage(entry.Plan))
+		}
+		if !conditions.IsTrue(entry.Machine, capi.MachineNodeHealthyCondition) {
+			return errWaitingf("waiting for node %s to be healthy", node.NodeName)
+		}
+		setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseUncordoning)
+	case rkev1.NodeMaintenanceNodePhaseUncordoning:
+		uncordoned, err := p.undrain(entry)
+		if err != nil && uncordoned {
+			return errWaiting(err.Error())
+		} else if err != nil {
+			return err
+		} else if !uncordoned {
+			return errWaitingf("uncordoning machine %s", entry.Machine.Name)
+		}
+		setNodeMaintenanceNodePhase(node, rkev1.NodeMaintenanceNodePhaseDone)
+		node.FinishTime = node.LastTransitionTime
+	}
+	return nil
+}
+
+// nodeMaintenancePlan returns the current plan of the machine with the maintenance instruction and, if requested, a
+// reboot added. Both run once per run, so a plan that is reapplied doesn't rerun them.
+func nodeMaintenancePlan(controlPlane *rkev1.RKEControlPlane, maintenance *rkev1.NodeMaintenance, runID string, entry *planEntry) plan.NodePlan {
+	nodePlan := withoutNodeMaintenance(withoutLifecycleHooks(entry.Plan.Plan))
+	instructions := append([]plan.OneTimeInstruction{}, nodePlan.Instructions...)
+
+	env := []string{nodeMaintenanceRunEnv + "=" + runID}
+	for _, e := range maintenance.Env {
+		env = append(env, e.Name+"="+e.Value)
+	}
+
+	var instruction plan.OneTimeInstruction
+	switch {
+	case maintenance.Script != "":
+		instruction = idempotentInstruction(controlPlane, nodeMaintenanceIdentifier, runID, "/bin/sh", []string{"-c", maintenance.Script}, env)
+	case maintenance.Command != "":
+		instruction = idempotentInstruction(controlPlane, nodeMaintenanceIdentifier, runID, maintenance.Command, maintenance.Args, env)
+	}
+	if instruction.Name != "" {
+		instruction.Image = maintenance.Image
+		instruction.SaveOutput = true
+		instructions = append(instructions, instruction)
+	}
+
+	if maintenance.Reboot {
+		instructions = append(instructions, idempotentInstruction(controlPlane, nodeMaintenanceRebootIdentifier, runID, "/bin/sh", []string{"-c", nodeMaintenanceRebootScript}, []string{}))
+	}
+
+	nodePlan.Instructions = instructions
+	return nodePlan
+}
+
+// withoutNodeMaintenance returns the plan with the instructions of previous node maintenance runs removed.
+func withoutNodeMaintenance(nodePlan plan.NodePlan) plan.NodePlan {
+	var instructions []plan.OneTimeInstruction
+	for _, instruction := range nodePlan.Instructions {
+		if !strings.HasPrefix(instruction.Name, nodeMaintenanceInstructionPrefix) {
+			instructions = append(instructions, instruction)
+		}
+	}
+	nodePlan.Instructions = instructions
+	return nodePlan
+}
+
+// nodeMaintenanceDrainOptions returns the drain options of the run, falling back to the drain options of the upgrade
+// strategy for the role of the machine.
+func nodeMaintenanceDrainOptions(controlPlane *rkev1.RKEControlPlane, maintenance *rkev1.NodeMaintenance, entry *planEntry) rkev1.DrainOptions {
+	if maintenance.DrainOptions != nil {
+		return *maintenance.DrainOptions
+	}
+	if isEtcd(entry) || isControlPlane(entry) {
+		return controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions
+	}
+	return controlPlane.Spec.UpgradeStrategy.WorkerDrainOptions
+}
+
+// nodeMaintenanceEntry returns the plan entry for the given machine.
+func nodeMaintenanceEntry(clusterPlan *plan.Plan, machineName string) (*planEntry, error) {
+	machine, ok := clusterPlan.Machines[machineName]
+	if !ok {
+		return nil, fmt.Errorf("machine %s no longer exists", machineName)
+	}
+	entry := &planEntry{
+		Machine:  machine,
+		Plan:     clusterPlan.Nodes[machineName],
+		Metadata: clusterPlan.Metadata[machineName],
+	}
+	if isDeleting(entry) {
+		return nil, fmt.Errorf("machine %s is being deleted", machineName)
+	}
+	if entry.Plan == nil || entry.Metadata == nil {
+		return nil, fmt.Errorf("machine %s has no plan", machineName)
+	}
+	return entry, nil
+}
+
+func setNodeMaintenanceNodePhase(node *rkev1.NodeMaintenanceNodeStatus, phase rkev1.NodeMaintenanceNodePhase) {
+	node.Phase = phase
+	node.LastTransitionTime = &metav1.Time{Time: time.Now()}
+	node.Message = ""
+}
+
+func (p *Planner) nodeMaintenanceFailed(status rkev1.RKEControlPlaneStatus, err error) (rkev1.RKEControlPlaneStatus, error) {
+	if status.NodeMaintenanceStatus != nil {
+		status.NodeMaintenanceStatus.FinishTime = &metav1.Time{Time: time.Now()}
+		status.NodeMaintenanceStatus.Message = err.Error()
+	}
+	status.NodeMaintenancePhase = rkev1.NodeMaintenancePhaseFailed
+	return status, errWaitingf("node maintenance failed: %v", err)
+}
+
+func (p *Planner) nodeMaintenanceFinished(status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	status.NodeMaintenanceStatus.FinishTime = &metav1.Time{Time: time.Now()}
+	status.NodeMaintenanceStatus.Message = ""
+	return p.setNodeMaintenanceState(status, status.NodeMaintenance, rkev1.NodeMaintenancePhaseFinished)
+}
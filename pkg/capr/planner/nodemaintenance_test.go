@@ -0,0 +1,203 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func createNodeMaintenanceTestEntry(name string, controlPlane bool) *planEntry {
+	entry := createTestPlanEntry("linux")
+	entry.Machine.Name = name
+	entry.Machine.Status.NodeRef = &corev1.ObjectReference{Name: name + "-node"}
+	entry.Machine.Status.NodeInfo.BootID = name + "-boot"
+	entry.Metadata.Annotations = map[string]string{}
+	entry.Plan = &plan.Node{InSync: true, Healthy: true}
+	if controlPlane {
+		entry.Metadata.Labels[capr.EtcdRoleLabel] = "true"
+		entry.Metadata.Labels[capr.ControlPlaneRoleLabel] = "true"
+	}
+	return entry
+}
+
+func createNodeMaintenanceTestPlan(entries ...*planEntry) *plan.Plan {
+	clusterPlan := &plan.Plan{
+		Machines: map[string]*capi.Machine{},
+		Metadata: map[string]*plan.Metadata{},
+		Nodes:    map[string]*plan.Node{},
+	}
+	for _, entry := range entries {
+		clusterPlan.Machines[entry.Machine.Name] = entry.Machine
+		clusterPlan.Metadata[entry.Machine.Name] = entry.Metadata
+		clusterPlan.Nodes[entry.Machine.Name] = entry.Plan
+	}
+	return clusterPlan
+}
+
+func TestNodeMaintenanceCollectNodes(t *testing.T) {
+	worker := createNodeMaintenanceTestEntry("worker", false)
+	worker.Machine.Labels["pool"] = "apps"
+	server := createNodeMaintenanceTestEntry("server", true)
+	unjoined := createNodeMaintenanceTestEntry("unjoined", false)
+	unjoined.Machine.Status.NodeRef = nil
+	windowsWorker := createNodeMaintenanceTestEntry("windows", false)
+	windowsWorker.Metadata.Labels[capr.CattleOSLabel] = capr.WindowsMachineOS
+	clusterPlan := createNodeMaintenanceTestPlan(worker, server, unjoined, windowsWorker)
+
+	nodes, err := nodeMaintenanceCollectNodes(&rkev1.NodeMaintenance{}, clusterPlan)
+	assert.NoError(t, err)
+	assert.Equal(t, []rkev1.NodeMaintenanceNodeStatus{
+		{MachineName: "server", NodeName: "server-node", Phase: rkev1.NodeMaintenanceNodePhasePending},
+		{MachineName: "worker", NodeName: "worker-node", Phase: rkev1.NodeMaintenanceNodePhasePending},
+	}, nodes)
+
+	nodes, err = nodeMaintenanceCollectNodes(&rkev1.NodeMaintenance{
+		MachineLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"pool": "apps"}},
+	}, clusterPlan)
+	assert.NoError(t, err)
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "worker", nodes[0].MachineName)
+	}
+}
+
+func TestNodeMaintenancePlan(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	entry := createNodeMaintenanceTestEntry("worker", false)
+	install := plan.OneTimeInstruction{Name: "install", Env: []string{"RESTART_STAMP=a"}}
+	entry.Plan.Plan = plan.NodePlan{Instructions: []plan.OneTimeInstruction{install}}
+
+	maintenance := &rkev1.NodeMaintenance{
+		Script: "apt-get upgrade -y",
+		Env:    []rkev1.EnvVar{{Name: "DEBIAN_FRONTEND", Value: "noninteractive"}},
+		Reboot: true,
+	}
+	nodePlan := nodeMaintenancePlan(controlPlane, maintenance, "100", entry)
+	if assert.Len(t, nodePlan.Instructions, 3) {
+		assert.Equal(t, install, nodePlan.Instructions[0])
+		assert.Contains(t, nodePlan.Instructions[1].Args, "apt-get upgrade -y")
+		assert.Equal(t, []string{"CATTLE_NODE_MAINTENANCE_RUN=100", "DEBIAN_FRONTEND=noninteractive"}, nodePlan.Instructions[1].Env)
+		assert.True(t, nodePlan.Instructions[1].SaveOutput)
+		assert.Contains(t, nodePlan.Instructions[2].Args, nodeMaintenanceRebootScript)
+	}
+	// the restart stamp is unchanged, so applying the plan does not restart the distro
+	assert.Equal(t, "a", getRestartStamp(&nodePlan))
+	assert.Equal(t, entry.Plan.Plan, withoutNodeMaintenance(nodePlan))
+
+	// instructions of a previous run are replaced
+	entry.Plan.Plan = nodePlan
+	nodePlan = nodeMaintenancePlan(controlPlane, &rkev1.NodeMaintenance{Command: "./patch", Image: "example.com/patch:v1"}, "200", entry)
+	if assert.Len(t, nodePlan.Instructions, 2) {
+		assert.Equal(t, install, nodePlan.Instructions[0])
+		assert.Equal(t, "example.com/patch:v1", nodePlan.Instructions[1].Image)
+		assert.Contains(t, nodePlan.Instructions[1].Args, "./patch")
+	}
+}
+
+func TestNodeMaintenanceDrainOptions(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	controlPlane.Spec.UpgradeStrategy.ControlPlaneDrainOptions = rkev1.DrainOptions{Enabled: true, Timeout: 60}
+	controlPlane.Spec.UpgradeStrategy.WorkerDrainOptions = rkev1.DrainOptions{Enabled: true, Timeout: 120}
+
+	maintenance := &rkev1.NodeMaintenance{}
+	assert.Equal(t, 60, nodeMaintenanceDrainOptions(controlPlane, maintenance, createNodeMaintenanceTestEntry("server", true)).Timeout)
+	assert.Equal(t, 120, nodeMaintenanceDrainOptions(controlPlane, maintenance, createNodeMaintenanceTestEntry("worker", false)).Timeout)
+
+	maintenance.DrainOptions = &rkev1.DrainOptions{Timeout: 30}
+	assert.Equal(t, 30, nodeMaintenanceDrainOptions(controlPlane, maintenance, createNodeMaintenanceTestEntry("server", true)).Timeout)
+}
+
+func TestNodeMaintenanceRunStartsNodes(t *testing.T) {
+	server := createNodeMaintenanceTestEntry("server", true)
+	workers := []*planEntry{
+		createNodeMaintenanceTestEntry("worker-1", false),
+		createNodeMaintenanceTestEntry("worker-2", false),
+		createNodeMaintenanceTestEntry("worker-3", false),
+	}
+	// an unhealthy worker counts against the concurrency
+	workers[2].Plan.Healthy = false
+	clusterPlan := createNodeMaintenanceTestPlan(append(workers, server)...)
+
+	status := rkev1.RKEControlPlaneStatus{
+		NodeMaintenance:      &rkev1.NodeMaintenance{Concurrency: "2"},
+		NodeMaintenancePhase: rkev1.NodeMaintenancePhaseRunning,
+		NodeMaintenanceStatus: &rkev1.NodeMaintenanceStatus{
+			StartTime: &metav1.Time{Time: time.Now()},
+		},
+	}
+	status.NodeMaintenanceStatus.Nodes, _ = nodeMaintenanceCollectNodes(status.NodeMaintenance, clusterPlan)
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	p := &Planner{}
+
+	// control plane machines are maintained on their own
+	status, err := p.nodeMaintenanceRun(controlPlane, status, clusterPlan)
+	assert.True(t, IsErrWaiting(err))
+	phases := func() (result []rkev1.NodeMaintenanceNodePhase) {
+		for _, node := range status.NodeMaintenanceStatus.Nodes {
+			result = append(result, node.Phase)
+		}
+		return result
+	}
+	assert.Equal(t, []rkev1.NodeMaintenanceNodePhase{
+		rkev1.NodeMaintenanceNodePhaseDraining,
+		rkev1.NodeMaintenanceNodePhasePending,
+		rkev1.NodeMaintenanceNodePhasePending,
+		rkev1.NodeMaintenanceNodePhasePending,
+	}, phases())
+	assert.Equal(t, "server-boot", status.NodeMaintenanceStatus.Nodes[0].BootID)
+	assert.NotNil(t, status.NodeMaintenanceStatus.Nodes[0].StartTime)
+
+	status.NodeMaintenanceStatus.Nodes[0].Phase = rkev1.NodeMaintenanceNodePhaseDone
+	status, err = p.nodeMaintenanceRun(controlPlane, status, clusterPlan)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, []rkev1.NodeMaintenanceNodePhase{
+		rkev1.NodeMaintenanceNodePhaseDone,
+		rkev1.NodeMaintenanceNodePhaseDraining,
+		rkev1.NodeMaintenanceNodePhasePending,
+		rkev1.NodeMaintenanceNodePhasePending,
+	}, phases())
+
+	// deleted machines are skipped, and the run finishes once every machine is done
+	delete(clusterPlan.Machines, "worker-2")
+	for i := range status.NodeMaintenanceStatus.Nodes {
+		if status.NodeMaintenanceStatus.Nodes[i].MachineName != "worker-2" {
+			status.NodeMaintenanceStatus.Nodes[i].Phase = rkev1.NodeMaintenanceNodePhaseDone
+		}
+	}
+	status, err = p.nodeMaintenanceRun(controlPlane, status, clusterPlan)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, rkev1.NodeMaintenanceNodePhaseSkipped, status.NodeMaintenanceStatus.Nodes[2].Phase)
+	assert.Equal(t, rkev1.NodeMaintenancePhaseFinished, status.NodeMaintenancePhase)
+	assert.NotNil(t, status.NodeMaintenanceStatus.FinishTime)
+}
+
+func TestNodeMaintenanceRunPercentageConcurrency(t *testing.T) {
+	var workers []*planEntry
+	for _, name := range []string{"worker-1", "worker-2", "worker-3", "worker-4"} {
+		workers = append(workers, createNodeMaintenanceTestEntry(name, false))
+	}
+	clusterPlan := createNodeMaintenanceTestPlan(workers...)
+
+	status := rkev1.RKEControlPlaneStatus{
+		NodeMaintenance:      &rkev1.NodeMaintenance{Concurrency: "50%"},
+		NodeMaintenancePhase: rkev1.NodeMaintenancePhaseRunning,
+		NodeMaintenanceStatus: &rkev1.NodeMaintenanceStatus{
+			StartTime: &metav1.Time{Time: time.Now()},
+		},
+	}
+	status.NodeMaintenanceStatus.Nodes, _ = nodeMaintenanceCollectNodes(status.NodeMaintenance, clusterPlan)
+	status.NodeMaintenanceStatus.Nodes[0].Phase = rkev1.NodeMaintenanceNodePhaseDone
+	status.NodeMaintenanceStatus.Nodes[1].Phase = rkev1.NodeMaintenanceNodePhaseDone
+
+	// half of the four selected machines may be maintained at once, even though only two are left
+	status, err := (&Planner{}).nodeMaintenanceRun(createTestControlPlane("v1.30.4+rke2r1"), status, clusterPlan)
+	assert.True(t, IsErrWaiting(err))
+	assert.Equal(t, rkev1.NodeMaintenanceNodePhaseDraining, status.NodeMaintenanceStatus.Nodes[2].Phase)
+	assert.Equal(t, rkev1.NodeMaintenanceNodePhaseDraining, status.NodeMaintenanceStatus.Nodes[3].Phase)
+}
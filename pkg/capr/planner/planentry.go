@@ -156,7 +156,7 @@ func windows(entry *planEntry) bool {
 
 func anyPlanDataExists(entry *planEntry) bool {
 	if entry.Plan != nil {
-		return entry.Plan.PlanDataExists
+		return entry.Plan.PlanDataExists && !isPreflightPlan(entry.Plan.Plan)
 	}
 	return false
 }
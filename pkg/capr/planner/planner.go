@@ -12,6 +12,7 @@
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/moby/locker"
@@ -263,6 +264,7 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 	if err != nil {
 		return status, err
 	}
+	observeMachinesWaiting(cp, plan)
 
 	// Check for cluster sanity to ensure we can properly deliver plans to this cluster.
 	if !clusterIsSane(plan) {
@@ -301,6 +303,10 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 	capr.Provisioned.Message(&status, "")
 	capr.Provisioned.Reason(&status, "")
 
+	status = p.reconcileEtcdMemberHealth(cp, status, plan)
+	status = p.reconcileCertificateExpiry(cp, status, plan)
+	status.EncryptionKeyAgeDays = encryptionKeyAgeDays(cp, status, time.Now())
+
 	_, clusterSecretTokens, err := p.ensureRKEStateSecret(cp, !anyPlansDelivered)
 	if err != nil {
 		return status, err
@@ -322,8 +328,12 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		return status, err
 	}
 
+	if status, err = p.etcdMaintenance(cp, status, clusterSecretTokens, plan); err != nil {
+		return status, err
+	}
+
 	// pausing the control plane only affects machine reconciliation: etcd snapshot/restore, encryption key & cert
-	// rotation are not interruptable processes, and therefore must always be completed when requested
+	// rotation and etcd maintenance are not interruptable processes, and therefore must always be completed when requested
 	if capiannotations.IsPaused(capiCluster, cp) {
 		return status, errWaitingf("CAPI cluster or RKEControlPlane is paused")
 	}
@@ -337,6 +347,18 @@ func (p *Planner) Process(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlan
 		return status, errWaiting("rkecontrolplane was already initialized but no etcd machines exist that have plans, indicating the etcd plane has been entirely replaced. Restoration from etcd snapshot is required.")
 	}
 
+	if status, err = p.nodeMaintenance(cp, status, plan); err != nil {
+		return status, err
+	}
+
+	if err := p.reconcilePreflight(cp, plan); err != nil {
+		return status, err
+	}
+
+	if err := p.reconcilePreDeleteHooks(cp, plan); err != nil {
+		return status, err
+	}
+
 	return p.fullReconcile(cp, status, clusterSecretTokens, plan, false)
 }
 
@@ -586,17 +608,36 @@ func calculateConcurrency(maxUnavailable string, reconcilables []*reconcilable,
 	return int(math.Ceil(max)), unavailable, nil
 }
 
+// minorPeriodicInstructions are periodic instructions that only report on the state of the node, so adding, changing
+// or removing them does not require the node to be drained.
+var minorPeriodicInstructions = map[string]bool{
+	etcdMemberHealthInstructionName:  true,
+	certificateExpiryInstructionName: true,
+}
+
+// withoutMinorPeriodicInstructions returns the periodic instructions that are not minor, in their original order.
+func withoutMinorPeriodicInstructions(instructions []plan.PeriodicInstruction) []plan.PeriodicInstruction {
+	var result []plan.PeriodicInstruction
+	for _, instruction := range instructions {
+		if !minorPeriodicInstructions[instruction.Name] {
+			result = append(result, instruction)
+		}
+	}
+	return result
+}
+
 func minorPlanChangeDetected(old, new plan.NodePlan) bool {
 	if !equality.Semantic.DeepEqual(old.Instructions, new.Instructions) ||
-		!equality.Semantic.DeepEqual(old.PeriodicInstructions, new.PeriodicInstructions) ||
+		!equality.Semantic.DeepEqual(withoutMinorPeriodicInstructions(old.PeriodicInstructions), withoutMinorPeriodicInstructions(new.PeriodicInstructions)) ||
 		!equality.Semantic.DeepEqual(old.Probes, new.Probes) ||
 		old.Error != new.Error {
 		return false
 	}
+	minorPeriodicInstructionChange := !equality.Semantic.DeepEqual(old.PeriodicInstructions, new.PeriodicInstructions)
 
 	if len(old.Files) == 0 && len(new.Files) == 0 {
-		// if the old plan had no files and no new files were found, there was no plan change detected
-		return false
+		// if the old plan had no files and no new files were found, the only change can be to minor periodic instructions
+		return minorPeriodicInstructionChange
 	}
 
 	newFiles := make(map[string]plan.File)
@@ -630,7 +671,7 @@ func minorPlanChangeDetected(old, new plan.NodePlan) bool {
 		// There were new files and all were not major
 		return true
 	}
-	return false
+	return minorPeriodicInstructionChange
 }
 
 func kubeletVersionUpToDate(controlPlane *rkev1.RKEControlPlane, machine *capi.Machine) bool {
@@ -871,13 +912,18 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			return err
 		}
 		plan.ResetFailureCountOnSystemAgentRestart = resetFailureCountOnSystemAgentRestart
-		reconcilables = append(reconcilables, &reconcilable{
+		r := &reconcilable{
 			entry:       entry,
 			desiredPlan: plan,
 			joinedURL:   joinedURL,
-			change:      entry.Plan != nil && !equality.Semantic.DeepEqual(entry.Plan.Plan, plan),
-			minorChange: entry.Plan != nil && minorPlanChangeDetected(entry.Plan.Plan, plan),
-		})
+		}
+		if entry.Plan != nil {
+			// lifecycle hooks are only part of the plan that is delivered at the point they run at
+			current := withoutLifecycleHooks(entry.Plan.Plan)
+			r.change = !equality.Semantic.DeepEqual(current, plan)
+			r.minorChange = minorPlanChangeDetected(current, plan)
+		}
+		reconcilables = append(reconcilables, r)
 	}
 
 	concurrency, unavailable, err := calculateConcurrency(maxUnavailable, reconcilables, exclude)
@@ -907,16 +953,25 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			nonReady = append(nonReady, r.entry.Machine.Name)
 		}
 
+		if needsPreflight(controlPlane, r.entry) && !preflightPassed(controlPlane, r.entry.Machine) {
+			outOfSync = append(outOfSync, r.entry.Machine.Name)
+			messages[r.entry.Machine.Name] = append(summary.Message, preflightMessage(r.entry.Machine))
+			continue
+		}
+
 		planStatusMessage := getPlanStatusReasonMessage(r.entry)
 		if planStatusMessage != "" {
 			summary.Message = append(summary.Message, planStatusMessage)
 		}
 		messages[r.entry.Machine.Name] = summary.Message
 
-		if r.entry.Plan == nil {
+		if r.entry.Plan == nil || isPreflightPlan(r.entry.Plan.Plan) {
 			logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - setting initial plan for machine %s/%s", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name)
 			logrus.Tracef("[planner] rkecluster %s/%s reconcile tier %s - initial plan for machine %s/%s new: %+v", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name, r.desiredPlan)
 			outOfSync = append(outOfSync, r.entry.Machine.Name)
+			if err := markPostJoinHooks(controlPlane, r.entry); err != nil {
+				return err
+			}
 			if err := p.store.UpdatePlan(r.entry, r.desiredPlan, r.joinedURL, maxFailures, failureThreshold); err != nil {
 				return err
 			}
@@ -924,7 +979,7 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - minor plan change detected for machine %s/%s, updating plan immediately", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name)
 			logrus.Tracef("[planner] rkecluster %s/%s reconcile tier %s - minor plan change for machine %s/%s old: %+v, new: %+v", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name, r.entry.Plan.Plan, r.desiredPlan)
 			outOfSync = append(outOfSync, r.entry.Machine.Name)
-			if err := p.store.UpdatePlan(r.entry, r.desiredPlan, r.joinedURL, maxFailures, failureThreshold); err != nil {
+			if err := p.store.UpdatePlan(r.entry, keepUnappliedLifecycleHooks(r.entry, r.desiredPlan), r.joinedURL, maxFailures, failureThreshold); err != nil {
 				return err
 			}
 		} else if r.change {
@@ -948,7 +1003,11 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 					// Drain is done (or didn't need to be done) and there are no errors, so the plan should be updated to enact the reason the node was drained.
 					logrus.Debugf("[planner] rkecluster %s/%s reconcile tier %s - major plan change for machine %s/%s", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name)
 					logrus.Tracef("[planner] rkecluster %s/%s reconcile tier %s - major plan change for machine %s/%s old: %+v, new: %+v", controlPlane.Namespace, controlPlane.Name, tierName, r.entry.Machine.Namespace, r.entry.Machine.Name, r.entry.Plan.Plan, r.desiredPlan)
-					if err = p.store.UpdatePlan(r.entry, r.desiredPlan, r.joinedURL, maxFailures, failureThreshold); err != nil {
+					desiredPlan, err := upgradePlan(controlPlane, r.entry, r.desiredPlan)
+					if err != nil {
+						return err
+					}
+					if err = p.store.UpdatePlan(r.entry, desiredPlan, r.joinedURL, maxFailures, failureThreshold); err != nil {
 						return err
 					} else if r.entry.Metadata.Annotations[capr.DrainDoneAnnotation] != "" {
 						messages[r.entry.Machine.Name] = append(messages[r.entry.Machine.Name], "drain completed")
@@ -969,6 +1028,14 @@ func (p *Planner) reconcile(controlPlane *rkev1.RKEControlPlane, tokensSecret pl
 			}
 		} else if planStatusMessage != "" {
 			outOfSync = append(outOfSync, r.entry.Machine.Name)
+			if err := p.refreshFailedLifecycleHooks(controlPlane, r.entry, maxFailures, failureThreshold); err != nil {
+				return err
+			}
+		} else if ok, err := p.runPendingLifecycleHooks(controlPlane, clusterPlan, r.entry, maxFailures, failureThreshold); err != nil {
+			return err
+		} else if !ok {
+			outOfSync = append(outOfSync, r.entry.Machine.Name)
+			messages[r.entry.Machine.Name] = append(messages[r.entry.Machine.Name], "running lifecycle hooks")
 		} else if ok, err := p.undrain(r.entry); !ok && err != nil {
 			return err
 		} else if !ok || err != nil {
@@ -1124,6 +1191,11 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret
 			nodePlan.Files = append(nodePlan.Files, setPermissionsWindowsScriptFile)
 			nodePlan.Instructions = append(nodePlan.Instructions, setPermissionsWindowsScriptInstruction)
 		}
+	} else {
+		nodePlan, err = p.addCertificateExpiryPeriodicInstruction(nodePlan, controlPlane)
+		if err != nil {
+			return nodePlan, joinedTo, err
+		}
 	}
 
 	if isEtcd(entry) {
@@ -1131,6 +1203,12 @@ func (p *Planner) desiredPlan(controlPlane *rkev1.RKEControlPlane, tokensSecret
 		if err != nil {
 			return nodePlan, joinedTo, err
 		}
+		if capr.GetRuntime(controlPlane.Spec.KubernetesVersion) != capr.RuntimeK3S {
+			nodePlan, err = p.addEtcdMemberHealthPeriodicInstruction(nodePlan, controlPlane)
+			if err != nil {
+				return nodePlan, joinedTo, err
+			}
+		}
 		if controlPlane != nil && controlPlane.Spec.ETCD != nil && S3Enabled(controlPlane.Spec.ETCD.S3) && isInitNode(entry) {
 			nodePlan, err = p.addEtcdSnapshotListS3PeriodicInstruction(nodePlan, controlPlane)
 			if err != nil {
@@ -0,0 +1,446 @@
+package planner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	preflightInstructionName = "preflight"
+	preflightRunEnv          = "PREFLIGHT_RUN"
+	preflightFailedReason    = "PreflightFailed"
+	customMachineKind        = "CustomMachine"
+
+	// preflightRetryInterval is how often the checks of a machine that is held back from joining are run again.
+	preflightRetryInterval = 2 * time.Minute
+
+	defaultPreflightMinDataDirFreeGiB = 10
+	defaultPreflightRegistry          = "registry-1.docker.io"
+
+	preflightStatusFail = "fail"
+
+	// preflightScript runs the pre-flight checks configured through the environment, printing a tab separated line with
+	// the check, its status and a message for each check that is run. It always exits successfully, the results are
+	// evaluated by the planner.
+	preflightScript = `
+result() {
+	printf '%s\t%s\t%s\n' "$1" "$2" "$3"
+}
+
+skipped() {
+	case " $PREFLIGHT_SKIP " in
+		*" $1 "*) return 0 ;;
+	esac
+	return 1
+}
+
+listening() {
+	hex=$(printf '%04X' "$1")
+	for f in /proc/net/tcp /proc/net/tcp6; do
+		[ -f "$f" ] || continue
+		awk -v port=":$hex" '$4 == "0A" && substr($2, length($2) - 4) == port { found = 1 } END { exit !found }' "$f" && return 0
+	done
+	return 1
+}
+
+reachable() {
+	code=$(curl -sk -o /dev/null -w '%{http_code}' --connect-timeout 5 --max-time 10 "$1")
+	[ -n "$code" ] && [ "$code" != "000" ]
+}
+
+if ! skipped ports; then
+	message=""
+	for port in $PREFLIGHT_PORTS; do
+		listening "$port" && message="$message port $port is already in use,"
+	done
+	if [ -n "$PREFLIGHT_JOIN_URL" ] && command -v curl >/dev/null 2>&1 && ! reachable "$PREFLIGHT_JOIN_URL"; then
+		message="$message join server $PREFLIGHT_JOIN_URL is not reachable,"
+	fi
+	if [ -n "$message" ]; then
+		message=${message# }
+		result ports fail "${message%,}"
+	else
+		result ports pass ""
+	fi
+fi
+
+if ! skipped disk; then
+	dir="$PREFLIGHT_DATA_DIR"
+	while [ ! -d "$dir" ]; do
+		dir=$(dirname "$dir")
+	done
+	available=$(df -Pk "$dir" | awk 'NR == 2 { print $4 }')
+	required=$((PREFLIGHT_MIN_DISK_GIB * 1024 * 1024))
+	if [ -z "$available" ]; then
+		result disk warn "unable to determine the free disk space of $dir"
+	elif [ "$available" -lt "$required" ]; then
+		result disk fail "$((available / 1024 / 1024))GiB free for $PREFLIGHT_DATA_DIR, ${PREFLIGHT_MIN_DISK_GIB}GiB required"
+	else
+		result disk pass ""
+	fi
+fi
+
+if ! skipped sysctls; then
+	message=""
+	for sysctl in $PREFLIGHT_SYSCTLS; do
+		key=${sysctl%%=*}
+		expected=$(echo "${sysctl#*=}" | tr , ' ')
+		file="/proc/sys/$(echo "$key" | tr . /)"
+		if [ ! -f "$file" ]; then
+			message="$message $key is not available,"
+			continue
+		fi
+		actual=$(tr -s '[:space:]' ' ' < "$file" | sed 's/ $//')
+		[ "$actual" = "$expected" ] || message="$message $key is $actual but $expected is required,"
+	done
+	if [ -n "$message" ]; then
+		message=${message# }
+		result sysctls fail "${message%,}"
+	else
+		result sysctls pass ""
+	fi
+fi
+
+if ! skipped modules; then
+	message=""
+	for module in $PREFLIGHT_MODULES; do
+		grep -q "^$module " /proc/modules 2>/dev/null && continue
+		grep -q "/$module.ko" "/lib/modules/$(uname -r)/modules.builtin" 2>/dev/null && continue
+		modprobe -n "$module" >/dev/null 2>&1 && continue
+		message="$message $module,"
+	done
+	if [ -n "$message" ]; then
+		result modules fail "kernel modules are not available:${message%,}"
+	else
+		result modules pass ""
+	fi
+fi
+
+if ! skipped timesync; then
+	if command -v timedatectl >/dev/null 2>&1 && [ "$(timedatectl show -p NTPSynchronized --value 2>/dev/null)" = "yes" ]; then
+		result timesync pass ""
+	elif command -v chronyc >/dev/null 2>&1 && chronyc tracking 2>/dev/null | grep -q "Leap status *: Normal"; then
+		result timesync pass ""
+	elif command -v ntpstat >/dev/null 2>&1 && ntpstat >/dev/null 2>&1; then
+		result timesync pass ""
+	elif command -v timedatectl >/dev/null 2>&1 || command -v chronyc >/dev/null 2>&1; then
+		result timesync fail "the system clock is not synchronized"
+	else
+		result timesync warn "unable to determine whether the system clock is synchronized"
+	fi
+fi
+
+if ! skipped registry && [ -n "$PREFLIGHT_REGISTRIES" ]; then
+	if ! command -v curl >/dev/null 2>&1; then
+		result registry warn "curl is not installed, unable to check the reachability of the registries"
+	else
+		message=""
+		for registry in $PREFLIGHT_REGISTRIES; do
+			reachable "https://$registry/v2/" || message="$message $registry,"
+		done
+		if [ -n "$message" ]; then
+			result registry fail "registries are not reachable:${message%,}"
+		else
+			result registry pass ""
+		fi
+	fi
+fi
+`
+)
+
+var defaultPreflightKernelModules = []string{"overlay", "br_netfilter"}
+
+type preflightResult struct {
+	Check   string
+	Status  string
+	Message string
+}
+
+// needsPreflight returns true if the machine is a custom machine that has not been given the plan that joins it to the
+// cluster, and pre-flight checks are enabled.
+func needsPreflight(controlPlane *rkev1.RKEControlPlane, entry *planEntry) bool {
+	checks := controlPlane.Spec.PreflightChecks
+	if checks == nil || !checks.Enabled || isDeleting(entry) || windows(entry) {
+		return false
+	}
+	if entry.Machine.Spec.InfrastructureRef.Kind != customMachineKind {
+		return false
+	}
+	return entry.Plan == nil || isPreflightPlan(entry.Plan.Plan)
+}
+
+// isPreflightPlan returns true if the plan only runs the pre-flight checks.
+func isPreflightPlan(nodePlan plan.NodePlan) bool {
+	for _, instruction := range nodePlan.Instructions {
+		if instruction.Name == preflightInstructionName {
+			return true
+		}
+	}
+	return false
+}
+
+// preflightPassed returns true if the machine may join the cluster: either its checks passed, or they failed and the
+// cluster only warns about failed checks.
+func preflightPassed(controlPlane *rkev1.RKEControlPlane, machine *capi.Machine) bool {
+	cond := conditions.Get(machine, capi.ConditionType(capr.PreflightChecked))
+	if cond == nil {
+		return false
+	}
+	if cond.Status == corev1.ConditionTrue {
+		return true
+	}
+	return cond.Status == corev1.ConditionFalse && cond.Reason == preflightFailedReason &&
+		controlPlane.Spec.PreflightChecks.Mode == rkev1.PreflightModeWarn
+}
+
+// preflightMessage returns the message shown on a machine that is waiting for its pre-flight checks.
+func preflightMessage(machine *capi.Machine) string {
+	if cond := conditions.Get(machine, capi.ConditionType(capr.PreflightChecked)); cond != nil && cond.Status == corev1.ConditionFalse {
+		return "pre-flight checks failed: " + cond.Message
+	}
+	return "waiting for pre-flight checks"
+}
+
+// reconcilePreflight delivers the pre-flight plan to the custom machines that have not joined the cluster yet, and
+// records the results on the PreflightChecked condition of the machines. Errors for one machine do not hold back the
+// checks of the other machines.
+func (p *Planner) reconcilePreflight(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan) error {
+	if controlPlane.Spec.PreflightChecks == nil || !controlPlane.Spec.PreflightChecks.Enabled {
+		return nil
+	}
+
+	var joinServer string
+	for _, entry := range collect(clusterPlan, isInitNode) {
+		if url := entry.Metadata.Annotations[capr.JoinURLAnnotation]; url != "" {
+			joinServer = url
+		}
+	}
+
+	var firstErr error
+	for _, entry := range collect(clusterPlan, anyRole) {
+		if !needsPreflight(controlPlane, entry) || preflightPassed(controlPlane, entry.Machine) {
+			continue
+		}
+		server := joinServer
+		if isInitNode(entry) {
+			server = ""
+		}
+		if err := p.runPreflight(controlPlane, clusterPlan, entry, server); err != nil {
+			logrus.Errorf("[planner] rkecluster %s/%s: error running pre-flight checks on machine %s: %v", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+func (p *Planner) runPreflight(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, entry *planEntry, joinServer string) error {
+	runID := strconv.FormatInt(time.Now().Unix(), 10)
+	if entry.Plan != nil {
+		if id := preflightRunID(entry.Plan.Plan); id != "" {
+			runID = id
+		}
+	}
+
+	nodePlan := p.preflightPlan(controlPlane, entry, joinServer, runID)
+	if entry.Plan == nil || !equality.Semantic.DeepEqual(entry.Plan.Plan, nodePlan) {
+		logrus.Infof("[planner] rkecluster %s/%s: running pre-flight checks on machine %s", controlPlane.Namespace, controlPlane.Name, entry.Machine.Name)
+		if err := p.store.UpdatePlan(entry, nodePlan, "", 1, 1); err != nil {
+			return err
+		}
+		return p.setPreflightCondition(controlPlane, clusterPlan, entry, nil)
+	}
+
+	var results []preflightResult
+	if entry.Plan.Failed {
+		results = []preflightResult{{Check: preflightInstructionName, Status: preflightStatusFail, Message: "the pre-flight plan failed to apply"}}
+	} else if output, ok := entry.Plan.Output[preflightInstructionName]; !ok || !entry.Plan.InSync {
+		return nil
+	} else {
+		results = parsePreflightOutput(output)
+	}
+
+	if err := p.setPreflightCondition(controlPlane, clusterPlan, entry, results); err != nil {
+		return err
+	}
+	if preflightPassed(controlPlane, entry.Machine) {
+		return nil
+	}
+
+	// run the checks again once the retry interval has passed, so that a machine whose host has been fixed can join
+	updated, err := time.Parse(time.RFC3339, entry.Metadata.Annotations[capr.PlanUpdatedTimeAnnotation])
+	if err != nil {
+		updated = time.Time{}
+	}
+	if wait := time.Until(updated.Add(preflightRetryInterval)); wait > 0 {
+		p.rkeControlPlanes.EnqueueAfter(controlPlane.Namespace, controlPlane.Name, wait)
+		return nil
+	}
+	return p.store.UpdatePlan(entry, p.preflightPlan(controlPlane, entry, joinServer, strconv.FormatInt(time.Now().Unix(), 10)), "", 1, 1)
+}
+
+// preflightPlan returns the plan that runs the pre-flight checks for the roles of the machine.
+func (p *Planner) preflightPlan(controlPlane *rkev1.RKEControlPlane, entry *planEntry, joinServer, runID string) plan.NodePlan {
+	checks := controlPlane.Spec.PreflightChecks
+	runtime := capr.GetRuntime(controlPlane.Spec.KubernetesVersion)
+
+	minDisk := checks.MinDataDirFreeGiB
+	if minDisk <= 0 {
+		minDisk = defaultPreflightMinDataDirFreeGiB
+	}
+	modules := checks.KernelModules
+	if len(modules) == 0 {
+		modules = defaultPreflightKernelModules
+	}
+	registries := checks.Registries
+	if len(registries) == 0 {
+		registries = []string{imageRegistry(p.getInstallerImage(controlPlane))}
+	}
+	var sysctls []string
+	for key, value := range checks.Sysctls {
+		// multi-value parameters such as net.ipv4.ip_local_port_range are passed comma separated
+		sysctls = append(sysctls, key+"="+strings.Join(strings.Fields(value), ","))
+	}
+	sort.Strings(sysctls)
+
+	var ports []string
+	for _, port := range preflightPorts(runtime, isEtcd(entry), isControlPlane(entry)) {
+		ports = append(ports, strconv.Itoa(port))
+	}
+
+	return plan.NodePlan{
+		Instructions: []plan.OneTimeInstruction{
+			{
+				Name:    preflightInstructionName,
+				Command: "sh",
+				Args:    []string{"-c", preflightScript, preflightInstructionName},
+				Env: []string{
+					preflightRunEnv + "=" + runID,
+					"PREFLIGHT_PORTS=" + strings.Join(ports, " "),
+					"PREFLIGHT_JOIN_URL=" + joinServer,
+					"PREFLIGHT_DATA_DIR=" + capr.GetDistroDataDir(controlPlane),
+					"PREFLIGHT_MIN_DISK_GIB=" + strconv.Itoa(minDisk),
+					"PREFLIGHT_SYSCTLS=" + strings.Join(sysctls, " "),
+					"PREFLIGHT_MODULES=" + strings.Join(modules, " "),
+					"PREFLIGHT_REGISTRIES=" + strings.Join(registries, " "),
+					"PREFLIGHT_SKIP=" + strings.Join(checks.Skip, " "),
+				},
+				SaveOutput: true,
+			},
+		},
+	}
+}
+
+// setPreflightCondition records the results of the pre-flight checks on the PreflightChecked condition of the machine,
+// or marks the checks as running if there are no results yet.
+func (p *Planner) setPreflightCondition(controlPlane *rkev1.RKEControlPlane, clusterPlan *plan.Plan, entry *planEntry, results []preflightResult) error {
+	machine := entry.Machine.DeepCopy()
+	conditionType := capi.ConditionType(capr.PreflightChecked)
+
+	if results == nil {
+		conditions.MarkUnknown(machine, conditionType, "Running", "running pre-flight checks")
+	} else if failed := preflightFailures(results); failed != "" {
+		severity := capi.ConditionSeverityError
+		if controlPlane.Spec.PreflightChecks.Mode == rkev1.PreflightModeWarn {
+			severity = capi.ConditionSeverityWarning
+		}
+		conditions.MarkFalse(machine, conditionType, preflightFailedReason, severity, "%s", failed)
+	} else {
+		conditions.MarkTrue(machine, conditionType)
+	}
+
+	if equality.Semantic.DeepEqual(conditions.Get(machine, conditionType), conditions.Get(entry.Machine, conditionType)) {
+		return nil
+	}
+	updated, err := p.machines.UpdateStatus(machine)
+	if err != nil {
+		return err
+	}
+	entry.Machine = updated
+	clusterPlan.Machines[updated.Name] = updated
+	return nil
+}
+
+// preflightPorts returns the ports that must be free on a machine with the given roles.
+func preflightPorts(runtime string, etcd, controlPlane bool) []int {
+	ports := []int{10250}
+	if etcd {
+		ports = append(ports, 2379, 2380)
+	}
+	if controlPlane {
+		ports = append(ports, 6443, 10257, 10259)
+		if runtime == capr.RuntimeRKE2 {
+			ports = append(ports, 9345)
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// imageRegistry returns the registry host of the given image reference.
+func imageRegistry(image string) string {
+	if i := strings.Index(image, "/"); i > 0 {
+		host := image[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			return host
+		}
+	}
+	return defaultPreflightRegistry
+}
+
+func preflightRunID(nodePlan plan.NodePlan) string {
+	for _, instruction := range nodePlan.Instructions {
+		if instruction.Name != preflightInstructionName {
+			continue
+		}
+		for _, env := range instruction.Env {
+			if value, ok := strings.CutPrefix(env, preflightRunEnv+"="); ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+func parsePreflightOutput(output []byte) []preflightResult {
+	results := []preflightResult{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(parts) < 2 {
+			continue
+		}
+		result := preflightResult{Check: parts[0], Status: parts[1]}
+		if len(parts) == 3 {
+			result.Message = strings.TrimSpace(parts[2])
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// preflightFailures returns a message describing the failed checks, or an empty string if no check failed.
+func preflightFailures(results []preflightResult) string {
+	var failed []string
+	for _, result := range results {
+		if result.Status == preflightStatusFail {
+			failed = append(failed, fmt.Sprintf("%s: %s", result.Check, result.Message))
+		}
+	}
+	return strings.Join(failed, ", ")
+}
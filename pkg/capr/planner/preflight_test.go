@@ -0,0 +1,104 @@
+package planner
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1/plan"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+func TestNeedsPreflight(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	entry := createTestPlanEntry("linux")
+	entry.Machine.Spec.InfrastructureRef.Kind = customMachineKind
+
+	assert.False(t, needsPreflight(controlPlane, entry))
+
+	controlPlane.Spec.PreflightChecks = &rkev1.PreflightChecks{Enabled: true}
+	assert.True(t, needsPreflight(controlPlane, entry))
+
+	entry.Plan = &plan.Node{Plan: plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Name: preflightInstructionName}}}}
+	assert.True(t, needsPreflight(controlPlane, entry))
+
+	entry.Plan = &plan.Node{Plan: plan.NodePlan{Instructions: []plan.OneTimeInstruction{{Name: "install"}}}}
+	assert.False(t, needsPreflight(controlPlane, entry))
+
+	entry.Plan = nil
+	entry.Machine.Spec.InfrastructureRef.Kind = "Amazonec2Machine"
+	assert.False(t, needsPreflight(controlPlane, entry))
+
+	windowsEntry := createTestPlanEntry(capr.WindowsMachineOS)
+	windowsEntry.Machine.Spec.InfrastructureRef.Kind = customMachineKind
+	assert.False(t, needsPreflight(controlPlane, windowsEntry))
+}
+
+func TestPreflightPassed(t *testing.T) {
+	controlPlane := createTestControlPlane("v1.30.4+rke2r1")
+	controlPlane.Spec.PreflightChecks = &rkev1.PreflightChecks{Enabled: true}
+	machine := &capi.Machine{}
+	conditionType := capi.ConditionType(capr.PreflightChecked)
+
+	assert.False(t, preflightPassed(controlPlane, machine))
+	assert.Equal(t, "waiting for pre-flight checks", preflightMessage(machine))
+
+	conditions.MarkUnknown(machine, conditionType, "Running", "running pre-flight checks")
+	assert.False(t, preflightPassed(controlPlane, machine))
+
+	conditions.MarkFalse(machine, conditionType, preflightFailedReason, capi.ConditionSeverityError, "%s", "disk: 2GiB free")
+	assert.False(t, preflightPassed(controlPlane, machine))
+	assert.Equal(t, "pre-flight checks failed: disk: 2GiB free", preflightMessage(machine))
+
+	controlPlane.Spec.PreflightChecks.Mode = rkev1.PreflightModeWarn
+	assert.True(t, preflightPassed(controlPlane, machine))
+
+	controlPlane.Spec.PreflightChecks.Mode = rkev1.PreflightModeBlock
+	conditions.MarkTrue(machine, conditionType)
+	assert.True(t, preflightPassed(controlPlane, machine))
+	assert.Equal(t, corev1.ConditionTrue, conditions.Get(machine, conditionType).Status)
+}
+
+func TestParsePreflightOutput(t *testing.T) {
+	output := "ports\tfail\tport 6443 is already in use\n" +
+		"disk\tpass\t\n" +
+		"garbage\n" +
+		"timesync\twarn\tunable to determine whether the system clock is synchronized\n" +
+		"modules\tfail\tkernel modules are not available: br_netfilter\n"
+
+	results := parsePreflightOutput([]byte(output))
+	assert.Equal(t, []preflightResult{
+		{Check: "ports", Status: "fail", Message: "port 6443 is already in use"},
+		{Check: "disk", Status: "pass"},
+		{Check: "timesync", Status: "warn", Message: "unable to determine whether the system clock is synchronized"},
+		{Check: "modules", Status: "fail", Message: "kernel modules are not available: br_netfilter"},
+	}, results)
+	assert.Equal(t, "ports: port 6443 is already in use, modules: kernel modules are not available: br_netfilter", preflightFailures(results))
+	assert.Empty(t, preflightFailures(results[1:3]))
+	assert.Empty(t, preflightFailures(parsePreflightOutput(nil)))
+}
+
+func TestPreflightPorts(t *testing.T) {
+	assert.Equal(t, []int{10250}, preflightPorts(capr.RuntimeK3S, false, false))
+	assert.Equal(t, []int{2379, 2380, 10250}, preflightPorts(capr.RuntimeRKE2, true, false))
+	assert.Equal(t, []int{6443, 10250, 10257, 10259}, preflightPorts(capr.RuntimeK3S, false, true))
+	assert.Equal(t, []int{2379, 2380, 6443, 9345, 10250, 10257, 10259}, preflightPorts(capr.RuntimeRKE2, true, true))
+}
+
+func TestImageRegistry(t *testing.T) {
+	assert.Equal(t, defaultPreflightRegistry, imageRegistry("rancher/system-agent-installer-rke2:v1.30.4-rke2r1"))
+	assert.Equal(t, "registry.example.com", imageRegistry("registry.example.com/rancher/system-agent-installer-rke2:v1.30.4-rke2r1"))
+	assert.Equal(t, "localhost:5000", imageRegistry("localhost:5000/rancher/system-agent-installer-k3s:v1.30.4-k3s1"))
+}
+
+func TestPreflightRunID(t *testing.T) {
+	nodePlan := plan.NodePlan{Instructions: []plan.OneTimeInstruction{{
+		Name: preflightInstructionName,
+		Env:  []string{"PREFLIGHT_PORTS=10250", preflightRunEnv + "=1700000000"},
+	}}}
+	assert.Equal(t, "1700000000", preflightRunID(nodePlan))
+	assert.Empty(t, preflightRunID(plan.NodePlan{}))
+}
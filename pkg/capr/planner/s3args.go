@@ -48,7 +48,7 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 	}
 
 	var (
-		s3Cred s3Credential
+		s3Cred S3Credential
 	)
 
 	controlPlaneEtcdS3NotNil := controlPlane.Spec.ETCD != nil && controlPlane.Spec.ETCD.S3 != nil
@@ -58,7 +58,7 @@ func (s *s3Args) ToArgs(s3 *rkev1.ETCDSnapshotS3, controlPlane *rkev1.RKEControl
 		credName = controlPlane.Spec.ETCD.S3.CloudCredentialName
 	}
 
-	s3Cred, err = getS3Credential(s.secretCache, controlPlane.Namespace, credName)
+	s3Cred, err = GetS3Credential(s.secretCache, controlPlane.Namespace, credName)
 	if err != nil {
 		return
 	}
@@ -147,7 +147,8 @@ func generateEndpointCAFileIfPathMatches(controlPlane *rkev1.RKEControlPlane, ex
 	return nil
 }
 
-type s3Credential struct {
+// S3Credential contains the S3 configuration stored in an S3 cloud credential secret.
+type S3Credential struct {
 	AccessKey     string
 	SecretKey     string
 	Region        string
@@ -158,7 +159,9 @@ type s3Credential struct {
 	Folder        string
 }
 
-func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name string) (result s3Credential, _ error) {
+// GetS3Credential retrieves the S3 cloud credential with the given name and returns its contents. An empty name results in
+// an empty credential.
+func GetS3Credential(secretCache corecontrollers.SecretCache, namespace, name string) (result S3Credential, _ error) {
 	if name == "" {
 		return result, nil
 	}
@@ -174,7 +177,7 @@ func getS3Credential(secretCache corecontrollers.SecretCache, namespace, name st
 		data[k] = v
 	}
 
-	return s3Credential{
+	return S3Credential{
 		AccessKey:     string(data["accessKey"]),
 		SecretKey:     string(data["secretKey"]),
 		Region:        string(data["defaultRegion"]),
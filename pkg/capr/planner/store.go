@@ -111,7 +111,7 @@ func (p *PlanStore) Load(cluster *capi.Cluster, rkeControlPlane *rkev1.RKEContro
 		if node == nil {
 			continue
 		}
-		if node.PlanDataExists {
+		if node.PlanDataExists && !isPreflightPlan(node.Plan) {
 			anyPlanDelivered = true
 		}
 		if err := p.setMachineJoinURL(&planEntry{Machine: result.Machines[machineName], Metadata: result.Metadata[machineName], Plan: node}, cluster, rkeControlPlane); err != nil {
@@ -59,6 +59,8 @@ type Manager struct {
 	discovery    discovery.DiscoveryInterface        // An interface to the Kubernetes Discovery API. Provides information about the Kubernetes API server.
 	IndexCache   map[string]indexCache               // cache for Helm repository index files. Used to store and retrieve index files for faster access.
 	lock         sync.RWMutex                        // read-write mutex used to ensure that some Manager's operations are thread-safe.
+
+	clusterRepoClient catalogcontrollers.ClusterRepoClient // client recording the results of the verification of charts in the status of ClusterRepos.
 }
 
 // indexCache - used to cache helm chart indexes
@@ -80,13 +82,15 @@ func NewManager(
 	discovery discovery.DiscoveryInterface,
 	configMaps corecontrollers.ConfigMapCache,
 	secrets corecontrollers.SecretCache,
-	clusterRepos catalogcontrollers.ClusterRepoCache) *Manager {
+	clusterRepos catalogcontrollers.ClusterRepoController) *Manager {
 	return &Manager{
 		discovery:    discovery,
 		configMaps:   configMaps,
 		secrets:      secrets,
-		clusterRepos: clusterRepos,
+		clusterRepos: clusterRepos.Cache(),
 		IndexCache:   map[string]indexCache{},
+
+		clusterRepoClient: clusterRepos,
 	}
 }
 
@@ -121,7 +125,7 @@ func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter boo
 	if cache, ok := c.IndexCache[fmt.Sprintf("%s/%s", r.status.IndexConfigMapNamespace, r.status.IndexConfigMapName)]; ok {
 		if cm.ResourceVersion == cache.revision {
 			c.lock.RUnlock()
-			return c.filterReleases(deepCopyIndex(cache.index), k8sVersion, skipFilter), nil
+			return c.annotateVerification(r, c.filterReleases(deepCopyIndex(cache.index), k8sVersion, skipFilter)), nil
 		}
 	}
 	c.lock.RUnlock()
@@ -159,7 +163,7 @@ func (c *Manager) Index(namespace, name, targetK8sVersion string, skipFilter boo
 	}
 	c.lock.Unlock()
 
-	return c.filterReleases(deepCopyIndex(index), k8sVersion, skipFilter), nil
+	return c.annotateVerification(r, c.filterReleases(deepCopyIndex(index), k8sVersion, skipFilter)), nil
 }
 
 // Icon Returns an io.ReadCloser and the icon's MIME type for the chart.
@@ -0,0 +1,258 @@
+package content
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2"
+	helmhttp "github.com/rancher/rancher/pkg/catalogv2/http"
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	verificationVerified   = "verified"
+	verificationFailed     = "failed"
+	verificationUnverified = "unverified"
+
+	// keyringSecretKey is the key of the keyring secret of a verification policy holding the PGP public keyring.
+	keyringSecretKey = "keyring"
+)
+
+// verificationResult is the result of the verification of a chart version.
+type verificationResult struct {
+	manifestDigest string // digest of the verified OCI manifest of the charts of OCI repositories
+	keysDigest     string // digest of the keys the chart was verified with
+	signer         string // signer of the chart
+	err            error  // reason the verification failed
+}
+
+// VerifiedChart retrieves a specific Helm chart from a Helm repository like Chart, but refuses it if the repository
+// has a verification policy and the chart is unsigned or its signature is invalid.
+//
+// The result of the verification is recorded in the status of the ClusterRepo, so that every replica refuses a chart
+// version that failed the verification, and reported in the annotations of the chart version in the index.
+func (c *Manager) VerifiedChart(namespace, name, chartName, version string, skipFilter bool) (io.ReadCloser, error) {
+	repo, err := c.getRepo(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if repo.spec.Verification == nil {
+		return c.Chart(namespace, name, chartName, version, skipFilter)
+	}
+
+	index, err := c.Index(namespace, name, "", skipFilter)
+	if err != nil {
+		return nil, err
+	}
+	chart, err := index.Get(chartName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	data, result, err := c.verifyChart(repo, chart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify chart %s version %s of repository %s: %w", chartName, version, name, err)
+	}
+	if err := c.recordVerification(name, chart, result); err != nil {
+		return nil, err
+	}
+	if result.err != nil {
+		return nil, fmt.Errorf("chart %s version %s of repository %s failed verification: %w", chartName, version, name, result.err)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// verifyChart downloads the chart and verifies it with the verification policy of the repository. It returns the chart
+// tarball and the result of the verification, or an error if the chart could not be verified, e.g. because it could
+// not be downloaded. A chart version that already failed the verification with the same keys is refused without
+// being downloaded again.
+func (c *Manager) verifyChart(r repoDef, chart *repo.ChartVersion) ([]byte, verificationResult, error) {
+	if r.status.Commit != "" {
+		return nil, verificationResult{}, errors.New("verification is not supported for git repositories")
+	}
+	if len(chart.URLs) <= 0 {
+		return nil, verificationResult{}, errors.New("chart has no urls specified")
+	}
+
+	secret, err := catalogv2.GetSecret(c.secrets, r.spec, r.metadata.Namespace)
+	if err != nil {
+		return nil, verificationResult{}, err
+	}
+
+	if registry.IsOCI(chart.URLs[0]) {
+		result := verificationResult{keysDigest: keysDigest([]byte(r.spec.Verification.PublicKeys))}
+		if r.spec.Verification.PublicKeys == "" {
+			result.err = errors.New("no public keys are configured to verify OCI charts")
+			return nil, result, nil
+		}
+
+		// the chart is verified and downloaded by the digest of its manifest, so that it cannot be replaced in between
+		result.manifestDigest, err = oci.ResolveChart(secret, chart, *r.spec)
+		if err != nil {
+			return nil, result, err
+		}
+		if failed := failedVerification(r.status, chart, result); failed != nil {
+			return nil, *failed, nil
+		}
+		result.signer, err = oci.VerifyChart(secret, chart, *r.spec, result.manifestDigest, r.spec.Verification.PublicKeys)
+		if errors.Is(err, oci.ErrUnverified) {
+			result.err = err
+			return nil, result, nil
+		} else if err != nil {
+			return nil, result, err
+		}
+		data, err := readAll(oci.ChartDigest(secret, chart, *r.spec, result.manifestDigest))
+		return data, result, err
+	}
+
+	keyring, err := c.keyring(r.spec.Verification)
+	if err != nil {
+		return nil, verificationResult{}, err
+	}
+	result := verificationResult{keysDigest: keysDigest(keyring)}
+	if failed := failedVerification(r.status, chart, result); failed != nil {
+		return nil, *failed, nil
+	}
+	data, err := readAll(helmhttp.Chart(secret, r.status.URL, r.spec.CABundle, r.spec.InsecureSkipTLSverify, r.spec.DisableSameOriginCheck, chart))
+	if err != nil {
+		return nil, result, err
+	}
+	prov, chartFileName, err := helmhttp.Provenance(secret, r.status.URL, r.spec.CABundle, r.spec.InsecureSkipTLSverify, r.spec.DisableSameOriginCheck, chart)
+	if err == nil {
+		result.signer, err = helmhttp.VerifyProvenance(data, chartFileName, prov, keyring)
+	}
+	if errors.Is(err, helmhttp.ErrUnverified) {
+		result.err = err
+		return nil, result, nil
+	} else if err != nil {
+		return nil, result, err
+	}
+	return data, result, nil
+}
+
+// failedVerification returns the result of the verification of the chart version recorded in the status of the
+// repository if it failed for the same chart and keys.
+func failedVerification(status *v1.RepoStatus, chart *repo.ChartVersion, result verificationResult) *verificationResult {
+	recorded := findVerification(status, chart.Name, chart.Version)
+	if recorded == nil || recorded.State != verificationFailed || recorded.Digest != chart.Digest ||
+		recorded.ManifestDigest != result.manifestDigest || recorded.KeysDigest != result.keysDigest {
+		return nil
+	}
+	result.err = errors.New(recorded.Message)
+	return &result
+}
+
+// keyring returns the PGP public keyring of the verification policy.
+func (c *Manager) keyring(verification *v1.ChartVerification) ([]byte, error) {
+	if verification.KeyringSecret == nil {
+		return nil, errors.New("no keyring secret is configured to verify provenance files")
+	}
+	secret, err := c.secrets.Get(verification.KeyringSecret.Namespace, verification.KeyringSecret.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get keyring secret %s/%s: %w", verification.KeyringSecret.Namespace, verification.KeyringSecret.Name, err)
+	}
+	keyring := secret.Data[keyringSecretKey]
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("keyring secret %s/%s has no %q key", secret.Namespace, secret.Name, keyringSecretKey)
+	}
+	return keyring, nil
+}
+
+// recordVerification records the result of the verification of the chart version in the status of the repository.
+func (c *Manager) recordVerification(name string, chart *repo.ChartVersion, result verificationResult) error {
+	verification := v1.ChartVerificationStatus{
+		Name:           chart.Name,
+		Version:        chart.Version,
+		Digest:         chart.Digest,
+		ManifestDigest: result.manifestDigest,
+		KeysDigest:     result.keysDigest,
+		State:          verificationVerified,
+		Message:        result.signer,
+	}
+	if result.err != nil {
+		verification.State = verificationFailed
+		verification.Message = result.err.Error()
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cr, err := c.clusterRepoClient.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if recorded := findVerification(&cr.Status, chart.Name, chart.Version); recorded != nil {
+			verification.Time = recorded.Time
+			if *recorded == verification {
+				return nil
+			}
+		}
+		verification.Time = metav1.Now()
+
+		cr = cr.DeepCopy()
+		if recorded := findVerification(&cr.Status, chart.Name, chart.Version); recorded != nil {
+			*recorded = verification
+		} else {
+			cr.Status.Verifications = append(cr.Status.Verifications, verification)
+		}
+		_, err = c.clusterRepoClient.UpdateStatus(cr)
+		return err
+	})
+}
+
+// findVerification returns the verification of the chart version recorded in the status of the repository.
+func findVerification(status *v1.RepoStatus, chartName, version string) *v1.ChartVerificationStatus {
+	for i := range status.Verifications {
+		if status.Verifications[i].Name == chartName && status.Verifications[i].Version == version {
+			return &status.Verifications[i]
+		}
+	}
+	return nil
+}
+
+// annotateVerification sets the verification annotations on the chart versions of the index of a repository with a
+// verification policy. The index must be a copy of the cached index.
+func (c *Manager) annotateVerification(r repoDef, index *repo.IndexFile) *repo.IndexFile {
+	if r.spec.Verification == nil {
+		return index
+	}
+
+	for _, versions := range index.Entries {
+		for _, version := range versions {
+			// the annotations are shared with the cached index
+			annotations := make(map[string]string, len(version.Annotations)+2)
+			for k, v := range version.Annotations {
+				annotations[k] = v
+			}
+
+			recorded := findVerification(r.status, version.Name, version.Version)
+			if recorded != nil && recorded.Digest == version.Digest {
+				annotations[v1.ChartVerificationAnnotation] = recorded.State
+				annotations[v1.ChartVerificationMessageAnnotation] = recorded.Message
+			} else {
+				annotations[v1.ChartVerificationAnnotation] = verificationUnverified
+			}
+			version.Annotations = annotations
+		}
+	}
+	return index
+}
+
+// keysDigest returns the digest of the keys of a verification policy.
+func keysDigest(keys []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(keys))
+}
+
+func readAll(rc io.ReadCloser, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,85 @@
+package content
+
+import (
+	"testing"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAnnotateVerification(t *testing.T) {
+	newIndex := func() *repo.IndexFile {
+		return &repo.IndexFile{
+			Entries: map[string]repo.ChartVersions{
+				"test": {
+					{Metadata: &chart.Metadata{Name: "test", Version: "1.0.0", Annotations: map[string]string{"foo": "bar"}}, Digest: "digest-1"},
+					{Metadata: &chart.Metadata{Name: "test", Version: "2.0.0"}, Digest: "digest-2"},
+					{Metadata: &chart.Metadata{Name: "test", Version: "3.0.0"}, Digest: "digest-3"},
+				},
+			},
+		}
+	}
+	r := repoDef{
+		metadata: &metav1.ObjectMeta{Name: "repo"},
+		spec:     &v1.RepoSpec{Verification: &v1.ChartVerification{PublicKeys: "keys"}},
+		status: &v1.RepoStatus{Verifications: []v1.ChartVerificationStatus{
+			{Name: "test", Version: "1.0.0", Digest: "digest-1", State: "verified", Message: "signer"},
+			{Name: "test", Version: "2.0.0", Digest: "digest-2", State: "failed", Message: "no signature"},
+			{Name: "other", Version: "3.0.0", Digest: "digest-3", State: "verified", Message: "signer"},
+		}},
+	}
+
+	m := Manager{}
+	cached := newIndex()
+	index := m.annotateVerification(r, deepCopyIndex(cached))
+	versions := index.Entries["test"]
+	assert.Equal(t, map[string]string{
+		"foo":                                 "bar",
+		v1.ChartVerificationAnnotation:        "verified",
+		v1.ChartVerificationMessageAnnotation: "signer",
+	}, versions[0].Annotations)
+	assert.Equal(t, map[string]string{
+		v1.ChartVerificationAnnotation:        "failed",
+		v1.ChartVerificationMessageAnnotation: "no signature",
+	}, versions[1].Annotations)
+	assert.Equal(t, map[string]string{v1.ChartVerificationAnnotation: "unverified"}, versions[2].Annotations)
+	assert.Equal(t, map[string]string{"foo": "bar"}, cached.Entries["test"][0].Annotations, "cached index must not be modified")
+
+	// a new chart published under the same version is not verified
+	changed := newIndex()
+	changed.Entries["test"][0].Digest = "digest-changed"
+	index = m.annotateVerification(r, changed)
+	assert.Equal(t, "unverified", index.Entries["test"][0].Annotations[v1.ChartVerificationAnnotation])
+
+	// charts of repositories without a verification policy are not annotated
+	r.spec = &v1.RepoSpec{}
+	index = m.annotateVerification(r, newIndex())
+	assert.Equal(t, map[string]string{"foo": "bar"}, index.Entries["test"][0].Annotations)
+	assert.Nil(t, index.Entries["test"][1].Annotations)
+}
+
+func TestFailedVerification(t *testing.T) {
+	version := &repo.ChartVersion{Metadata: &chart.Metadata{Name: "test", Version: "1.0.0"}, Digest: "digest"}
+	status := &v1.RepoStatus{Verifications: []v1.ChartVerificationStatus{
+		{Name: "test", Version: "1.0.0", Digest: "digest", ManifestDigest: "sha256:manifest", KeysDigest: "keys", State: "failed", Message: "no signature"},
+		{Name: "test", Version: "2.0.0", Digest: "digest", KeysDigest: "keys", State: "verified", Message: "signer"},
+	}}
+
+	failed := failedVerification(status, version, verificationResult{manifestDigest: "sha256:manifest", keysDigest: "keys"})
+	if assert.NotNil(t, failed) {
+		assert.EqualError(t, failed.err, "no signature")
+		assert.Equal(t, "sha256:manifest", failed.manifestDigest)
+	}
+
+	assert.Nil(t, failedVerification(status, version, verificationResult{manifestDigest: "sha256:other", keysDigest: "keys"}), "manifest changed")
+	assert.Nil(t, failedVerification(status, version, verificationResult{manifestDigest: "sha256:manifest", keysDigest: "new keys"}), "keys changed")
+
+	version.Digest = "new digest"
+	assert.Nil(t, failedVerification(status, version, verificationResult{manifestDigest: "sha256:manifest", keysDigest: "keys"}), "chart changed")
+
+	version.Version, version.Digest = "2.0.0", "digest"
+	assert.Nil(t, failedVerification(status, version, verificationResult{keysDigest: "keys"}), "verified chart")
+}
@@ -0,0 +1,227 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/pmezard/go-difflib/difflib"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/yaml"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8syaml "sigs.k8s.io/yaml"
+)
+
+const (
+	// maxDiffSize is the total size of the diffs kept for the resources of an operation, so that they fit in its status.
+	maxDiffSize = 512 * 1024
+
+	redacted        = "(redacted)"
+	redactedChanged = "(redacted, changed)"
+
+	// DryRunSecretPrefix prefixes the name of the pod of a dry-run operation to name the Secret, in the namespace of the
+	// releases, the pod writes the rendered releases to.
+	DryRunSecretPrefix = "helm-dry-run-"
+	// DryRunSecretKey is the key of the Secret of a dry-run operation holding the gzipped output of its helm commands.
+	DryRunSecretKey = "releases"
+)
+
+// RenderedRelease is the release printed by helm install or helm upgrade with --output json.
+type RenderedRelease struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Manifest  string `json:"manifest"`
+}
+
+// RenderedReleases reads the output of helm commands run with --output json and returns the releases they printed,
+// in order. Lines which are not a release are skipped.
+func RenderedReleases(output io.Reader) ([]RenderedRelease, error) {
+	var (
+		result []RenderedRelease
+		reader = bufio.NewReader(output)
+	)
+	for {
+		// the chart is part of the release, so a line can be larger than the buffer of a bufio.Scanner
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		line = bytes.TrimSpace(line)
+		if bytes.HasPrefix(line, []byte("{")) {
+			var release RenderedRelease
+			if json.Unmarshal(line, &release) == nil && release.Name != "" {
+				result = append(result, release)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+	}
+}
+
+// ManifestDiff compares the resources of the current manifest of a release with the resources of the manifest
+// rendered for it by a dry-run, and returns the change of each resource, sorted by resource. The values of Secrets are
+// redacted from the diffs.
+func ManifestDiff(release, namespace, current, rendered string, isNamespaced IsNamespaced) ([]v1.ResourceDiff, error) {
+	currentObjs, err := manifestObjects(namespace, current, isNamespaced)
+	if err != nil {
+		return nil, err
+	}
+	renderedObjs, err := manifestObjects(namespace, rendered, isNamespaced)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []v1.ResourceDiff
+	for resource, renderedObj := range renderedObjs {
+		currentObj, ok := currentObjs[resource]
+		diff := v1.ResourceDiff{
+			ReleaseResource: resource,
+			Release:         release,
+			Change:          v1.ResourceAdded,
+		}
+		if ok {
+			diff.Change = v1.ResourceModified
+			if reflect.DeepEqual(currentObj.Object, renderedObj.Object) {
+				diff.Change = v1.ResourceUnchanged
+			}
+		}
+		if diff.Change != v1.ResourceUnchanged {
+			if diff.Diff, err = objectDiff(currentObj, renderedObj); err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, diff)
+	}
+	for resource, currentObj := range currentObjs {
+		if _, ok := renderedObjs[resource]; ok {
+			continue
+		}
+		diff := v1.ResourceDiff{
+			ReleaseResource: resource,
+			Release:         release,
+			Change:          v1.ResourceRemoved,
+		}
+		if diff.Diff, err = objectDiff(currentObj, nil); err != nil {
+			return nil, err
+		}
+		result = append(result, diff)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return resourceKey(result[i].ReleaseResource) < resourceKey(result[j].ReleaseResource)
+	})
+	return result, nil
+}
+
+// TruncateDiffs drops the diffs of the resources once their total size exceeds what can be stored in the status of an
+// operation. The change of each resource is kept.
+func TruncateDiffs(diffs []v1.ResourceDiff) []v1.ResourceDiff {
+	size := 0
+	for i := range diffs {
+		size += len(diffs[i].Diff)
+		if size > maxDiffSize {
+			diffs[i].Diff = ""
+		}
+	}
+	return diffs
+}
+
+// manifestObjects returns the objects of a release manifest by the v1.ReleaseResource identifying them.
+func manifestObjects(namespace, manifest string, isNamespaced IsNamespaced) (map[v1.ReleaseResource]*unstructured.Unstructured, error) {
+	objs, err := yaml.ToObjects(bytes.NewReader([]byte(manifest)))
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[v1.ReleaseResource]*unstructured.Unstructured{}
+	for _, obj := range objs {
+		u, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+		resource, err := releaseResource(namespace, obj, isNamespaced)
+		if err != nil {
+			return nil, err
+		}
+		result[resource] = u
+	}
+	return result, nil
+}
+
+// objectDiff returns the unified diff of the YAML of the current and the rendered object, either of which can be nil.
+func objectDiff(current, rendered *unstructured.Unstructured) (string, error) {
+	current, rendered = redactSecrets(current, rendered)
+
+	currentYAML, err := toYAML(current)
+	if err != nil {
+		return "", err
+	}
+	renderedYAML, err := toYAML(rendered)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(currentYAML),
+		B:        difflib.SplitLines(renderedYAML),
+		FromFile: "current",
+		ToFile:   "rendered",
+		Context:  3,
+	})
+}
+
+// redactSecrets returns copies of the objects with the values of Secrets replaced, so that a diff only shows which keys
+// were added, removed or changed.
+func redactSecrets(current, rendered *unstructured.Unstructured) (*unstructured.Unstructured, *unstructured.Unstructured) {
+	if !isSecret(current) && !isSecret(rendered) {
+		return current, rendered
+	}
+
+	current, rendered = current.DeepCopy(), rendered.DeepCopy()
+	for _, field := range []string{"data", "stringData"} {
+		var currentValues, renderedValues map[string]interface{}
+		if current != nil {
+			currentValues, _, _ = unstructured.NestedMap(current.Object, field)
+		}
+		if rendered != nil {
+			renderedValues, _, _ = unstructured.NestedMap(rendered.Object, field)
+		}
+		for k, v := range renderedValues {
+			if currentValue, ok := currentValues[k]; ok && reflect.DeepEqual(currentValue, v) {
+				renderedValues[k] = redacted
+			} else {
+				renderedValues[k] = redactedChanged
+			}
+		}
+		for k := range currentValues {
+			currentValues[k] = redacted
+		}
+		if currentValues != nil {
+			_ = unstructured.SetNestedMap(current.Object, currentValues, field)
+		}
+		if renderedValues != nil {
+			_ = unstructured.SetNestedMap(rendered.Object, renderedValues, field)
+		}
+	}
+	return current, rendered
+}
+
+func isSecret(obj *unstructured.Unstructured) bool {
+	return obj != nil && obj.GetKind() == "Secret" && obj.GetAPIVersion() == "v1"
+}
+
+func toYAML(obj *unstructured.Unstructured) (string, error) {
+	if obj == nil {
+		return "", nil
+	}
+	data, err := k8syaml.Marshal(obj.Object)
+	return string(data), err
+}
+
+func resourceKey(r v1.ReleaseResource) string {
+	return r.Namespace + "/" + r.APIVersion + "/" + r.Kind + "/" + r.Name
+}
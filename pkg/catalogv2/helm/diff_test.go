@@ -0,0 +1,144 @@
+package helm
+
+import (
+	"strings"
+	"testing"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const currentManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  replicas: "1"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+data:
+  password: b2xk
+  user: YWRtaW4=
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: ClusterIP
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: web
+rules: []
+`
+
+const renderedManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+data:
+  replicas: "3"
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: credentials
+data:
+  password: bmV3
+  user: YWRtaW4=
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: ClusterIP
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`
+
+func TestManifestDiff(t *testing.T) {
+	isNamespaced := func(gvk schema.GroupVersionKind) bool {
+		return gvk.Kind != "ClusterRole"
+	}
+
+	diffs, err := ManifestDiff("web", "default", currentManifest, renderedManifest, isNamespaced)
+	require.NoError(t, err)
+
+	changes := map[string]string{}
+	for _, diff := range diffs {
+		assert.Equal(t, "web", diff.Release)
+		changes[diff.Namespace+"/"+diff.Kind+"/"+diff.Name] = diff.Change
+	}
+	assert.Equal(t, map[string]string{
+		"default/ConfigMap/config":   v1.ResourceModified,
+		"default/Secret/credentials": v1.ResourceModified,
+		"default/Service/web":        v1.ResourceUnchanged,
+		"default/Deployment/web":     v1.ResourceAdded,
+		"/ClusterRole/web":           v1.ResourceRemoved,
+	}, changes)
+
+	for _, diff := range diffs {
+		switch diff.Kind {
+		case "ConfigMap":
+			assert.Contains(t, diff.Diff, `-  replicas: "1"`)
+			assert.Contains(t, diff.Diff, `+  replicas: "3"`)
+		case "Secret":
+			assert.NotContains(t, diff.Diff, "bmV3")
+			assert.NotContains(t, diff.Diff, "b2xk")
+			assert.NotContains(t, diff.Diff, "YWRtaW4=")
+			assert.Contains(t, diff.Diff, "-  password: (redacted)")
+			assert.Contains(t, diff.Diff, "+  password: (redacted, changed)")
+			assert.Contains(t, diff.Diff, "   user: (redacted)")
+		case "Service":
+			assert.Empty(t, diff.Diff)
+		case "Deployment":
+			assert.Contains(t, diff.Diff, "+  replicas: 3")
+		case "ClusterRole":
+			assert.Contains(t, diff.Diff, "-kind: ClusterRole")
+		}
+	}
+}
+
+func TestRenderedReleases(t *testing.T) {
+	output := strings.Join([]string{
+		"helm upgrade --dry-run=server --install web /home/shell/helm/web-1.0.0.tgz",
+		`{"name":"web-crd","namespace":"default","manifest":"---\napiVersion: v1\n","version":1}`,
+		`{"not":"a release"}`,
+		`{"name":"web","namespace":"default","manifest":"---\nkind: ConfigMap\n","version":2}`,
+	}, "\n")
+
+	releases, err := RenderedReleases(strings.NewReader(output))
+	require.NoError(t, err)
+	assert.Equal(t, []RenderedRelease{
+		{Name: "web-crd", Namespace: "default", Manifest: "---\napiVersion: v1\n"},
+		{Name: "web", Namespace: "default", Manifest: "---\nkind: ConfigMap\n"},
+	}, releases)
+}
+
+func TestTruncateDiffs(t *testing.T) {
+	large := strings.Repeat("x", maxDiffSize/2+1)
+	diffs := TruncateDiffs([]v1.ResourceDiff{
+		{Change: v1.ResourceModified, Diff: large},
+		{Change: v1.ResourceModified, Diff: large},
+		{Change: v1.ResourceAdded, Diff: "small"},
+	})
+	assert.Equal(t, large, diffs[0].Diff)
+	assert.Empty(t, diffs[1].Diff)
+	assert.Equal(t, v1.ResourceModified, diffs[1].Change)
+	assert.Empty(t, diffs[2].Diff)
+}
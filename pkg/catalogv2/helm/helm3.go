@@ -12,7 +12,9 @@
 	"io/ioutil"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 
@@ -37,6 +39,44 @@ func fromHelm3Data(data string, isNamespaced IsNamespaced) (*v1.ReleaseSpec, err
 	return fromHelm3ReleaseToRelease(release, isNamespaced)
 }
 
+// ReleaseConfig returns the values supplied by the user to the helm3 release stored in the given runtime.Object, which
+// can be an unstructured.Unstructured, corev1.ConfigMap or a corev1.Secret. The values are not part of the
+// v1.ReleaseSpec as they can hold credentials.
+func ReleaseConfig(obj runtime.Object) (map[string]interface{}, error) {
+	release, err := helm3Release(obj)
+	if err != nil {
+		return nil, err
+	}
+	return release.Config, nil
+}
+
+// ReleaseManifest returns the rendered manifest of the helm3 release stored in the given runtime.Object, which can be
+// an unstructured.Unstructured, corev1.ConfigMap or a corev1.Secret.
+func ReleaseManifest(obj runtime.Object) (string, error) {
+	release, err := helm3Release(obj)
+	if err != nil {
+		return "", err
+	}
+	return release.Manifest, nil
+}
+
+// helm3Release decodes the helm3 release stored in the given runtime.Object.
+func helm3Release(obj runtime.Object) (*release.Release, error) {
+	meta, err := meta.Accessor(obj)
+	if err != nil {
+		return nil, err
+	}
+	if !isHelm3(meta.GetLabels()) {
+		return nil, ErrNotHelmRelease
+	}
+
+	releaseData, err := getReleaseDataAndKind(obj)
+	if err != nil {
+		return nil, err
+	}
+	return decodeHelm3(releaseData)
+}
+
 // fromHelm3ReleaseToRelease receives a helm3 release struct.
 // Returns a pointer to a rancher v1.ReleaseSpec struct constructed from the helm3 release struct.
 func fromHelm3ReleaseToRelease(release *release.Release, isNamespaced IsNamespaced) (*v1.ReleaseSpec, error) {
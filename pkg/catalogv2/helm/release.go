@@ -130,21 +130,31 @@ func resourcesFromManifest(namespace string, manifest string, isNamespaced IsNam
 	}
 
 	for _, obj := range objs {
-		meta, err := meta2.Accessor(obj)
+		r, err := releaseResource(namespace, obj, isNamespaced)
 		if err != nil {
 			return nil, err
 		}
-		r := v1.ReleaseResource{
-			Name:      meta.GetName(),
-			Namespace: meta.GetNamespace(),
-		}
-		gvk := obj.GetObjectKind().GroupVersionKind()
-		if isNamespaced != nil && isNamespaced(gvk) && r.Namespace == "" {
-			r.Namespace = namespace
-		}
-		r.APIVersion, r.Kind = gvk.ToAPIVersionAndKind()
 		result = append(result, r)
 	}
 
 	return result, nil
 }
+
+// releaseResource returns the v1.ReleaseResource identifying the object of a release manifest. Namespaced objects
+// without a namespace are set in the namespace of the release.
+func releaseResource(namespace string, obj runtime.Object, isNamespaced IsNamespaced) (v1.ReleaseResource, error) {
+	meta, err := meta2.Accessor(obj)
+	if err != nil {
+		return v1.ReleaseResource{}, err
+	}
+	r := v1.ReleaseResource{
+		Name:      meta.GetName(),
+		Namespace: meta.GetNamespace(),
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if isNamespaced != nil && isNamespaced(gvk) && r.Namespace == "" {
+		r.Namespace = namespace
+	}
+	r.APIVersion, r.Kind = gvk.ToAPIVersionAndKind()
+	return r, nil
+}
@@ -0,0 +1,119 @@
+package helm
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// rolloutKinds are the kinds of the resources of a release whose rollout is verified by RolloutStatus.
+var rolloutKinds = map[schema.GroupKind]bool{
+	{Group: "apps", Kind: "Deployment"}:  true,
+	{Group: "apps", Kind: "StatefulSet"}: true,
+	{Group: "apps", Kind: "DaemonSet"}:   true,
+}
+
+// HasRolloutStatus returns whether the rollout of resources of the given kind is verified by RolloutStatus.
+func HasRolloutStatus(gvk schema.GroupVersionKind) bool {
+	return rolloutKinds[gvk.GroupKind()]
+}
+
+// RolloutStatus returns whether the rollout of a Deployment, StatefulSet or DaemonSet completed, following the rules
+// of kubectl rollout status, and a message describing the rollout if it did not. An error is returned if the rollout
+// can't complete.
+func RolloutStatus(obj *unstructured.Unstructured) (bool, string, error) {
+	switch obj.GetKind() {
+	case "Deployment":
+		return deploymentRolloutStatus(obj)
+	case "StatefulSet":
+		return statefulSetRolloutStatus(obj)
+	case "DaemonSet":
+		return daemonSetRolloutStatus(obj)
+	}
+	return true, "", nil
+}
+
+func deploymentRolloutStatus(obj *unstructured.Unstructured) (bool, string, error) {
+	if obj.GetGeneration() > nestedInt64(obj, "status", "observedGeneration") {
+		return false, "waiting for the deployment spec update to be observed", nil
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		if condition["type"] == "Progressing" && condition["reason"] == "ProgressDeadlineExceeded" {
+			return false, "", errors.New("deployment exceeded its progress deadline")
+		}
+	}
+
+	replicas := int64(1)
+	if specReplicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); ok {
+		replicas = specReplicas
+	}
+	updated := nestedInt64(obj, "status", "updatedReplicas")
+	if updated < replicas {
+		return false, fmt.Sprintf("%d out of %d new replicas have been updated", updated, replicas), nil
+	}
+	if current := nestedInt64(obj, "status", "replicas"); current > updated {
+		return false, fmt.Sprintf("%d old replicas are pending termination", current-updated), nil
+	}
+	if available := nestedInt64(obj, "status", "availableReplicas"); available < updated {
+		return false, fmt.Sprintf("%d of %d updated replicas are available", available, updated), nil
+	}
+	return true, "", nil
+}
+
+func statefulSetRolloutStatus(obj *unstructured.Unstructured) (bool, string, error) {
+	observed := nestedInt64(obj, "status", "observedGeneration")
+	if observed == 0 || obj.GetGeneration() > observed {
+		return false, "waiting for the statefulset spec update to be observed", nil
+	}
+	if strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type"); strategy != "" && strategy != "RollingUpdate" {
+		return true, "", nil
+	}
+
+	replicas := int64(1)
+	if specReplicas, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas"); ok {
+		replicas = specReplicas
+	}
+	if ready := nestedInt64(obj, "status", "readyReplicas"); ready < replicas {
+		return false, fmt.Sprintf("%d of %d pods are ready", ready, replicas), nil
+	}
+	if partition, ok, _ := unstructured.NestedInt64(obj.Object, "spec", "updateStrategy", "rollingUpdate", "partition"); ok && partition > 0 {
+		if updated := nestedInt64(obj, "status", "updatedReplicas"); updated < replicas-partition {
+			return false, fmt.Sprintf("%d of %d pods have been updated", updated, replicas-partition), nil
+		}
+		return true, "", nil
+	}
+	updateRevision, _, _ := unstructured.NestedString(obj.Object, "status", "updateRevision")
+	currentRevision, _, _ := unstructured.NestedString(obj.Object, "status", "currentRevision")
+	if updateRevision != currentRevision {
+		return false, fmt.Sprintf("%d pods are at revision %s", nestedInt64(obj, "status", "updatedReplicas"), updateRevision), nil
+	}
+	return true, "", nil
+}
+
+func daemonSetRolloutStatus(obj *unstructured.Unstructured) (bool, string, error) {
+	if strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "updateStrategy", "type"); strategy != "" && strategy != "RollingUpdate" {
+		return true, "", nil
+	}
+	if obj.GetGeneration() > nestedInt64(obj, "status", "observedGeneration") {
+		return false, "waiting for the daemonset spec update to be observed", nil
+	}
+
+	desired := nestedInt64(obj, "status", "desiredNumberScheduled")
+	if updated := nestedInt64(obj, "status", "updatedNumberScheduled"); updated < desired {
+		return false, fmt.Sprintf("%d out of %d new pods have been updated", updated, desired), nil
+	}
+	if available := nestedInt64(obj, "status", "numberAvailable"); available < desired {
+		return false, fmt.Sprintf("%d of %d updated pods are available", available, desired), nil
+	}
+	return true, "", nil
+}
+
+func nestedInt64(obj *unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return v
+}
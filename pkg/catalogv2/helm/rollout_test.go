@@ -0,0 +1,131 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRolloutStatus(t *testing.T) {
+	newObj := func(kind string, generation int64, spec, status map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       kind,
+			"metadata": map[string]interface{}{
+				"name":       "test",
+				"generation": generation,
+			},
+			"spec":   spec,
+			"status": status,
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		obj       *unstructured.Unstructured
+		ready     bool
+		message   string
+		expectErr bool
+	}{
+		{
+			name: "deployment rolled out",
+			obj: newObj("Deployment", 2, map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+			ready: true,
+		},
+		{
+			name: "deployment update not observed",
+			obj: newObj("Deployment", 3, map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(2),
+			}),
+			message: "waiting for the deployment spec update to be observed",
+		},
+		{
+			name: "deployment with crash looping replicas",
+			obj: newObj("Deployment", 2, map[string]interface{}{"replicas": int64(2)}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(2), "availableReplicas": int64(0),
+			}),
+			message: "0 of 2 updated replicas are available",
+		},
+		{
+			name: "deployment with old replicas",
+			obj: newObj("Deployment", 2, map[string]interface{}{}, map[string]interface{}{
+				"observedGeneration": int64(2), "replicas": int64(2), "updatedReplicas": int64(1), "availableReplicas": int64(1),
+			}),
+			message: "1 old replicas are pending termination",
+		},
+		{
+			name: "deployment exceeded its progress deadline",
+			obj: newObj("Deployment", 2, map[string]interface{}{}, map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"},
+				},
+			}),
+			expectErr: true,
+		},
+		{
+			name: "statefulset rolled out",
+			obj: newObj("StatefulSet", 1, map[string]interface{}{"replicas": int64(3)}, map[string]interface{}{
+				"observedGeneration": int64(1), "readyReplicas": int64(3), "currentRevision": "web-1", "updateRevision": "web-1",
+			}),
+			ready: true,
+		},
+		{
+			name: "statefulset updating",
+			obj: newObj("StatefulSet", 2, map[string]interface{}{"replicas": int64(3)}, map[string]interface{}{
+				"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(1), "currentRevision": "web-1", "updateRevision": "web-2",
+			}),
+			message: "1 pods are at revision web-2",
+		},
+		{
+			name: "statefulset with partition",
+			obj: newObj("StatefulSet", 2, map[string]interface{}{
+				"replicas":       int64(3),
+				"updateStrategy": map[string]interface{}{"type": "RollingUpdate", "rollingUpdate": map[string]interface{}{"partition": int64(2)}},
+			}, map[string]interface{}{
+				"observedGeneration": int64(2), "readyReplicas": int64(3), "updatedReplicas": int64(1), "currentRevision": "web-1", "updateRevision": "web-2",
+			}),
+			ready: true,
+		},
+		{
+			name: "statefulset with OnDelete strategy",
+			obj: newObj("StatefulSet", 2, map[string]interface{}{
+				"updateStrategy": map[string]interface{}{"type": "OnDelete"},
+			}, map[string]interface{}{"observedGeneration": int64(2)}),
+			ready: true,
+		},
+		{
+			name: "daemonset rolled out",
+			obj: newObj("DaemonSet", 1, map[string]interface{}{}, map[string]interface{}{
+				"observedGeneration": int64(1), "desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(3), "numberAvailable": int64(3),
+			}),
+			ready: true,
+		},
+		{
+			name: "daemonset updating",
+			obj: newObj("DaemonSet", 1, map[string]interface{}{}, map[string]interface{}{
+				"observedGeneration": int64(1), "desiredNumberScheduled": int64(3), "updatedNumberScheduled": int64(1), "numberAvailable": int64(3),
+			}),
+			message: "1 out of 3 new pods have been updated",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, message, err := RolloutStatus(tt.obj)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.ready, ready)
+			assert.Equal(t, tt.message, message)
+		})
+	}
+
+	assert.True(t, HasRolloutStatus(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}))
+	assert.False(t, HasRolloutStatus(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}))
+}
@@ -18,15 +18,18 @@
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unicode/utf8"
 
+	"github.com/rancher/apiserver/pkg/apierror"
 	"github.com/rancher/apiserver/pkg/types"
 	types2 "github.com/rancher/rancher/pkg/api/steve/catalog/types"
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
 	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/catalogv2/content"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
 	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	namespaces "github.com/rancher/rancher/pkg/namespace"
 	"github.com/rancher/rancher/pkg/settings"
@@ -41,6 +44,7 @@
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -68,6 +72,27 @@
 	}
 )
 
+// dryRunArgs are the arguments of install and upgrade commands in dry-run operations. The dry-run is run server-side so
+// that lookups in the templates are resolved, and the rendered releases are printed as JSON so that the manifests can be
+// compared with the current releases once the operation completed.
+var dryRunArgs = map[string]interface{}{
+	"dryRun": "server",
+	"output": "json",
+}
+
+// dryRunPath is where the helm container of a dry-run operation writes the output of its helm commands.
+const dryRunPath = "/home/shell/helm-dry-run"
+
+// dryRunScript runs the helm commands of a dry-run operation and writes their output, which holds the rendered releases
+// along with the values of their Secrets, to a Secret in the namespace of the releases instead of the logs of the
+// operation. The Secret is created with the permissions of the user, who must be able to create the Secrets of the
+// releases anyway, and is deleted by the operation controller once it compared the releases.
+var dryRunScript = fmt.Sprintf(`set -e
+helm-cmd > %[1]s/%[2]s
+gzip %[1]s/%[2]s
+kubectl create secret generic "%[3]s${POD_NAME}" --namespace="${RELEASE_NAMESPACE}" --from-file=%[2]s=%[1]s/%[2]s.gz >/dev/null
+echo "rendered releases written to secret ${RELEASE_NAMESPACE}/%[3]s${POD_NAME}"`, dryRunPath, helm.DryRunSecretKey, helm.DryRunSecretPrefix)
+
 var (
 	podOptionsScheme = runtime.NewScheme()
 	podOptionsCodec  = runtime.NewParameterCodec(podOptionsScheme)
@@ -156,6 +181,7 @@ type Operations struct {
 	apps           catalogcontrollers.AppClient        // client for apps custom resource
 	roles          rbacv1controllers.RoleClient        // client for role kubernetes resource
 	roleBindings   rbacv1controllers.RoleBindingClient // client for rolebinding kubernetes resource
+	secrets        corev1controllers.SecretClient      // client for the rollback and queue secrets of operations
 	cg             proxy.ClientGetter                  // dynamic kubernetes client factory
 }
 
@@ -166,7 +192,8 @@ func NewOperations(
 	rbac rbacv1controllers.Interface,
 	contentManager *content.Manager,
 	pods corev1controllers.PodClient,
-	nodes corev1controllers.NodeClient) *Operations {
+	nodes corev1controllers.NodeClient,
+	secrets corev1controllers.SecretClient) *Operations {
 	return &Operations{
 		cg:             cg,
 		contentManager: contentManager,
@@ -179,6 +206,7 @@ func NewOperations(
 		roleBindings:   rbac.RoleBinding(),
 		roles:          rbac.Role(),
 		nodes:          nodes,
+		secrets:        secrets,
 	}
 }
 
@@ -225,7 +253,32 @@ func (s *Operations) Upgrade(ctx context.Context, user user.Info, namespace, nam
 		return nil, err
 	}
 
-	return s.createOperation(ctx, user, status, cmds, imageOverride)
+	op, err := s.createOperation(ctx, user, status, cmds, imageOverride)
+	if err != nil || status.HealthCheck == nil {
+		return op, err
+	}
+	// the identity the release is rolled back with is not kept in the status of the operation
+	return op, s.createRollbackSecret(op, user, status)
+}
+
+// Rollback creates an operation rolling back the release in the given namespace to the given revision, run as the
+// given user. Releases failing the health check of their upgrade are rolled back with it.
+func (s *Operations) Rollback(ctx context.Context, user user.Info, namespace, releaseName string, revision int, tolerations []corev1.Toleration) (*catalog.Operation, error) {
+	cmd := Command{
+		Operation:        "rollback",
+		ReleaseName:      releaseName,
+		ReleaseNamespace: namespace,
+		Revision:         revision,
+	}
+
+	status := catalog.OperationStatus{
+		Action:      cmd.Operation,
+		Release:     releaseName,
+		Namespace:   namespace,
+		Tolerations: tolerations,
+	}
+
+	return s.createOperation(ctx, user, status, Commands{cmd}, "")
 }
 
 // Install gets the install commands using the given namespace, name and options and gets the user using the isApp flag as false.
@@ -430,11 +483,13 @@ func (s *Operations) getUninstallArgs(appNamespace, appName string, body io.Read
 }
 
 // getUpgradeCommand receives the repository namespace and name and body of the request.
-// Returns the status of the operation that will be created and a list of Command to upgrade the charts received in the request
+// Returns the status of the operation that will be created and a list of Command to upgrade the charts received in the request,
+// once validated against their values schema and the policy of the repository.
 func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.Reader) (catalog.OperationStatus, Commands, error) {
 	var (
 		upgradeArgs = &types2.ChartUpgradeAction{}
 		commands    Commands
+		charts      []chartRequest
 	)
 	err := json.NewDecoder(body).Decode(upgradeArgs)
 	if err != nil {
@@ -451,9 +506,10 @@ func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.R
 		Namespace:              namespace(upgradeArgs.Namespace),
 		Tolerations:            upgradeArgs.OperationTolerations,
 		AutomaticCPTolerations: upgradeArgs.AutomaticCPTolerations,
+		DryRun:                 upgradeArgs.DryRun,
 	}
 
-	for _, chartUpgrade := range upgradeArgs.Charts {
+	for i, chartUpgrade := range upgradeArgs.Charts {
 		cmd, err := s.getChartCommand(repoNamespace, repoName, chartUpgrade.ChartName, chartUpgrade.Version, true, chartUpgrade.Annotations, chartUpgrade.Values)
 		if err != nil {
 			return status, nil, err
@@ -470,13 +526,82 @@ func (s *Operations) getUpgradeCommand(repoNamespace, repoName string, body io.R
 			"labels": fmt.Sprintf("%s=%s", catalog.ClusterRepoNameLabel, repoName),
 		})
 
+		if upgradeArgs.DryRun {
+			cmd.ArgObjects = append(cmd.ArgObjects, dryRunArgs)
+		}
+
 		status.Release = chartUpgrade.ReleaseName
 		commands = append(commands, cmd)
+		charts = append(charts, chartRequest{
+			index:       i,
+			name:        chartUpgrade.ChartName,
+			chart:       cmd.Chart,
+			values:      chartUpgrade.Values,
+			reuseValues: len(chartUpgrade.Values) == 0 && !chartUpgrade.ResetValues,
+		})
+	}
+
+	if err := s.validateCharts(repoNamespace, repoName, status.Namespace, charts); err != nil {
+		return status, nil, err
+	}
+	if upgradeArgs.DryRun {
+		if err := validateDryRun(status.Namespace, commands, s.releaseInstalled); err != nil {
+			return status, nil, err
+		}
+	}
+
+	// the health of the main chart, which is the last one, is verified
+	if upgradeArgs.HealthCheckTimeout != nil && !upgradeArgs.DryRun && status.Release != "" {
+		previousRevision, err := s.releaseRevision(status.Namespace, status.Release)
+		if err != nil {
+			return status, nil, err
+		}
+		status.HealthCheck = &catalog.OperationHealthCheck{
+			Timeout:          *upgradeArgs.HealthCheckTimeout,
+			PreviousRevision: previousRevision,
+		}
 	}
 
 	return status, commands, nil
 }
 
+// releaseRevision returns the current revision of the release, or 0 if it isn't installed.
+func (s *Operations) releaseRevision(namespace, releaseName string) (int, error) {
+	app, err := s.apps.Get(namespace, releaseName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return app.Spec.Version, nil
+}
+
+// releaseInstalled returns true if the release is installed.
+func (s *Operations) releaseInstalled(namespace, releaseName string) (bool, error) {
+	revision, err := s.releaseRevision(namespace, releaseName)
+	return revision > 0, err
+}
+
+// validateDryRun refuses the dry-run of chart sets whose first charts, which provide the CRDs of the main chart, aren't
+// installed yet. Those charts are only dry-run too, so the main chart can't be checked server-side without its CRDs.
+func validateDryRun(namespace string, cmds Commands, installed func(namespace, releaseName string) (bool, error)) error {
+	if len(cmds) < 2 {
+		return nil
+	}
+	mainChart := len(cmds) - 1
+	for _, cmd := range cmds[:mainChart] {
+		ok, err := installed(namespace, cmd.ReleaseName)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apierror.NewFieldAPIError(validation.InvalidBodyContent, fmt.Sprintf("charts[%d]", mainChart),
+				fmt.Sprintf("release %s can not be checked with a dry-run before release %s/%s, which provides its CRDs, is installed", cmds[mainChart].ReleaseName, namespace, cmd.ReleaseName))
+		}
+	}
+	return nil
+}
+
 // Command represents a command that will be run inside a helm operation
 type Command struct {
 	Operation        string        // type of operation, eg upgrade, install, uninstall
@@ -487,6 +612,7 @@ type Command struct {
 	Chart            []byte        // content of the chart file
 	ReleaseName      string        // name of the release
 	ReleaseNamespace string        // namespace of the release
+	Revision         int           // revision of the release to roll back to
 	Kustomize        bool          // flag to inform if it should use kustomize.sh
 }
 
@@ -581,6 +707,7 @@ func (c Command) renderArgs() ([]string, error) {
 	delete(dataMap, "projectId")
 	delete(dataMap, "operationTolerations")
 	delete(dataMap, "automaticCPTolerations")
+	delete(dataMap, "healthCheckTimeout")
 	if v, ok := dataMap["disableOpenAPIValidation"]; ok {
 		delete(dataMap, "disableOpenAPIValidation")
 		dataMap["disableOpenapiValidation"] = v
@@ -618,6 +745,9 @@ func (c Command) renderArgs() ([]string, error) {
 	if c.ReleaseName != "" {
 		args = append(args, c.ReleaseName)
 	}
+	if c.Revision > 0 {
+		args = append(args, strconv.Itoa(c.Revision))
+	}
 	if len(c.Chart) > 0 {
 		args = append(args, filepath.Join(runPath, c.ChartFile))
 	}
@@ -735,7 +865,8 @@ func (s *Operations) enableKustomize(annotations map[string]string, upgrade bool
 // and then creates and return a Command containing the name of the values file, name of the chart file, the chart data
 // and if the command should use kustomize.sh
 func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion string, upgrade bool, annotations map[string]string, values map[string]interface{}) (Command, error) {
-	chart, err := s.contentManager.Chart(namespace, name, chartName, chartVersion, true)
+	// charts of repositories with a verification policy are refused if they are unsigned or their signature is invalid
+	chart, err := s.contentManager.VerifiedChart(namespace, name, chartName, chartVersion, true)
 	if err != nil {
 		return Command{}, err
 	}
@@ -773,7 +904,8 @@ func (s *Operations) getChartCommand(namespace, name, chartName, chartVersion st
 // getInstallCommand receives the repository namespace, name, and body of the request.
 // It decodes the request to get chart information for creating the `helm install` command
 // along with args. It returns the catalog.OperationStatus struct and a slice of commands
-// to install the charts received in the body of the request.
+// to install the charts received in the body of the request. The charts are validated against their values schema
+// and the policy of the repository.
 func (s *Operations) getInstallCommand(repoNamespace, repoName string, body io.Reader) (catalog.OperationStatus, Commands, error) {
 	installArgs := &types2.ChartInstallAction{}
 	err := json.NewDecoder(body).Decode(installArgs)
@@ -782,6 +914,7 @@ func (s *Operations) getInstallCommand(repoNamespace, repoName string, body io.R
 	}
 	var (
 		cmds   []Command
+		charts []chartRequest
 		status = catalog.OperationStatus{
 			Action: "install",
 		}
@@ -789,7 +922,7 @@ func (s *Operations) getInstallCommand(repoNamespace, repoName string, body io.R
 	// Sometimes there are two charts to be installed. First one being the CRD chart
 	// and then the actual helm chart. So, we need a for loop and the last index of the array
 	// would be the main chart.
-	for _, chartInstall := range installArgs.Charts {
+	for i, chartInstall := range installArgs.Charts {
 		cmd, err := s.getChartCommand(repoNamespace, repoName, chartInstall.ChartName, chartInstall.Version, false, chartInstall.Annotations, chartInstall.Values)
 		if err != nil {
 			return status, nil, err
@@ -818,15 +951,34 @@ func (s *Operations) getInstallCommand(repoNamespace, repoName string, body io.R
 			"labels": fmt.Sprintf("%s=%s", catalog.ClusterRepoNameLabel, repoName),
 		})
 
+		if installArgs.DryRun {
+			cmd.ArgObjects = append(cmd.ArgObjects, dryRunArgs)
+		}
+
 		status.Release = chartInstall.ReleaseName
 
 		cmds = append(cmds, cmd)
+		charts = append(charts, chartRequest{
+			index:  i,
+			name:   chartInstall.ChartName,
+			chart:  cmd.Chart,
+			values: chartInstall.Values,
+		})
 	}
 
 	status.Namespace = namespace(installArgs.Namespace)
+	if err := s.validateCharts(repoNamespace, repoName, status.Namespace, charts); err != nil {
+		return status, nil, err
+	}
+	if installArgs.DryRun {
+		if err := validateDryRun(status.Namespace, cmds, s.releaseInstalled); err != nil {
+			return status, nil, err
+		}
+	}
 	status.ProjectID = installArgs.ProjectID
 	status.Tolerations = installArgs.OperationTolerations
 	status.AutomaticCPTolerations = installArgs.AutomaticCPTolerations
+	status.DryRun = installArgs.DryRun
 
 	return status, cmds, err
 }
@@ -839,11 +991,18 @@ func namespace(ns string) string {
 	return ns
 }
 
-// createOperation creates an operation and its pod, along with its roles and roleBinding.
-// Uses the Operations.Impersonator and Operations.ops to do it.
+// createOperation creates an operation, along with its roles and roleBinding, in the queue of the operations waiting
+// to run. Its pod is created by StartQueued once no other operation on one of its releases is running and the
+// maximum number of concurrent operations isn't reached.
+// Uses the Operations.ops and Operations.secrets to do it.
 // Returns the created catalog.Operation struct
 func (s *Operations) createOperation(ctx context.Context, user user.Info, status catalog.OperationStatus, cmds Commands, imageOverride string) (*catalog.Operation, error) {
-	if status.Action != "uninstall" {
+	if status.DryRun {
+		// dry-runs don't change the cluster, so the namespace of the releases isn't created
+		if err := s.checkNamespace(ctx, status.Namespace); err != nil {
+			return nil, err
+		}
+	} else if status.Action != "uninstall" && status.Action != "rollback" {
 		_, err := s.createNamespace(ctx, status.Namespace, status.ProjectID)
 		if err != nil {
 			return nil, err
@@ -863,39 +1022,38 @@ func (s *Operations) createOperation(ctx context.Context, user user.Info, status
 		kustomize = true
 		break
 	}
-	pod, podOptions := s.createPod(secretData, kustomize, imageOverride, status.Tolerations)
-	pod, err = s.Impersonator.CreatePod(ctx, user, pod, podOptions)
-	if err != nil {
-		return nil, err
-	}
 
 	status.Command, err = cmds.CommandArgs()
 	if err != nil {
 		return nil, err
 	}
 
-	status.Token = pod.Labels[podimpersonation.TokenLabel]
-	status.PodName = pod.Name
-	status.PodNamespace = pod.Namespace
+	return s.queueOperation(user, status, queuedPod{
+		Namespace:     status.Namespace,
+		SecretData:    secretData,
+		Kustomize:     kustomize,
+		ImageOverride: imageOverride,
+		Tolerations:   status.Tolerations,
+		DryRun:        status.DryRun,
+	}, releaseKeys(status.Namespace, cmds))
+}
 
-	op := &catalog.Operation{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:            pod.Name,
-			Namespace:       status.Namespace,
-			OwnerReferences: pod.OwnerReferences,
-		},
+// createOperationPod creates the pod running the commands of an operation as the user, and records it in the status
+// of the operation.
+func (s *Operations) createOperationPod(ctx context.Context, user user.Info, status *catalog.OperationStatus, secretData map[string][]byte, kustomize bool, imageOverride string) (*v1.Pod, error) {
+	pod, podOptions := s.createPod(secretData, kustomize, imageOverride, status.Tolerations)
+	if status.DryRun {
+		dryRunPod(pod, status.Namespace)
 	}
-	op, err = s.ops.Create(op)
+	pod, err := s.Impersonator.CreatePod(ctx, user, pod, podOptions)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.createRoleAndRoleBindings(op, user.GetName()); err != nil {
-		return nil, err
-	}
-
-	op.Status = status
-	return s.ops.UpdateStatus(op)
+	status.Token = pod.Labels[podimpersonation.TokenLabel]
+	status.PodName = pod.Name
+	status.PodNamespace = pod.Namespace
+	return pod, nil
 }
 
 // createRoleAndRoleBindings creates a role that applies to the given catalog.Operation and
@@ -954,6 +1112,20 @@ func (s *Operations) createRoleAndRoleBindings(op *catalog.Operation, user strin
 	return nil
 }
 
+// checkNamespace returns an error if the namespace doesn't exist. The namespace is retrieved as the user, users who
+// aren't allowed to get it are not told whether it exists.
+func (s *Operations) checkNamespace(ctx context.Context, namespace string) error {
+	client, err := s.cg.K8sInterface(types.GetAPIContext(ctx))
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return apierror.NewAPIError(validation.InvalidBodyContent, fmt.Sprintf("namespace %s does not exist, dry-run operations do not create it", namespace))
+	}
+	return nil
+}
+
 // createNamespace creates a new k8s namespace and returns its object.
 // It can also set the field.cattle.io/projectId annotation on the namespace if a non-empty projectID is provided.
 // It creates a watch on the namespace and waits for the v3.ProjectConditionInitialRolesPopulated condition
@@ -1147,6 +1319,36 @@ func (s *Operations) createPod(secretData map[string][]byte, kustomize bool, ima
 	}
 }
 
+// dryRunPod makes the helm container of the pod run the helm commands with dryRunScript, so that the releases rendered
+// by the dry-run are not printed in the logs of the operation.
+func dryRunPod(pod *v1.Pod, namespace string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, v1.Volume{
+		Name: "helm-dry-run",
+		VolumeSource: v1.VolumeSource{
+			EmptyDir: &v1.EmptyDirVolumeSource{},
+		},
+	})
+
+	container := &pod.Spec.Containers[0]
+	container.Command = []string{"/bin/sh", "-c", dryRunScript}
+	container.Env = append(container.Env,
+		v1.EnvVar{
+			Name: "POD_NAME",
+			ValueFrom: &v1.EnvVarSource{
+				FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.name"},
+			},
+		},
+		v1.EnvVar{
+			Name:  "RELEASE_NAMESPACE",
+			Value: namespace,
+		},
+	)
+	container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+		Name:      "helm-dry-run",
+		MountPath: dryRunPath,
+	})
+}
+
 // AddCpTaintsToTolerations gets the list of control plane nodes and adds their taints to the given tolerations.
 func (s *Operations) AddCpTaintsToTolerations(tolerations []corev1.Toleration) ([]corev1.Toleration, error) {
 	cpList, err := s.nodes.List(metav1.ListOptions{LabelSelector: "node-role.kubernetes.io/control-plane=true"})
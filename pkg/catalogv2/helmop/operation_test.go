@@ -156,6 +156,40 @@ func Test_Render(t *testing.T) {
 			},
 			failMsg: "operation toleration test case failed",
 		},
+		{
+			commands: Commands{
+				Command{
+					Operation:   "upgrade",
+					ChartFile:   "test-chart-v1.1.0.tgz",
+					Chart:       []byte("test-chart"),
+					Kustomize:   false,
+					ReleaseName: "test7",
+					ArgObjects: []interface{}{
+						types.ChartUpgradeAction{DryRun: true},
+						dryRunArgs,
+					},
+				},
+			},
+			expected: map[string][]byte{
+				"operation000":          []byte(strings.Join([]string{"upgrade", "--dry-run=server", "--output=json", "test7", "/home/shell/helm/test-chart-v1.1.0.tgz"}, "\x00")),
+				"test-chart-v1.1.0.tgz": []byte("test-chart"),
+			},
+			failMsg: "dry-run test case failed",
+		},
+		{
+			commands: Commands{
+				Command{
+					Operation:        "rollback",
+					ReleaseName:      "test8",
+					ReleaseNamespace: "test-ns",
+					Revision:         3,
+				},
+			},
+			expected: map[string][]byte{
+				"operation000": []byte(strings.Join([]string{"rollback", "--namespace=test-ns", "test8", "3"}, "\x00")),
+			},
+			failMsg: "rollback test case failed",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -267,6 +301,21 @@ func Test_CreatePod(t *testing.T) {
 	}
 }
 
+func Test_dryRunPod(t *testing.T) {
+	asserts := assert.New(t)
+	operation := Operations{namespace: "test-ns"}
+	pod, _ := operation.createPod(nil, false, "", nil)
+
+	dryRunPod(pod, "release-ns")
+
+	container := pod.Spec.Containers[0]
+	asserts.Equal([]string{"/bin/sh", "-c", dryRunScript}, container.Command)
+	asserts.Contains(dryRunScript, "helm-cmd > /home/shell/helm-dry-run/releases\n", "the output of the helm commands must not be printed in the logs")
+	asserts.Contains(container.Env, corev1.EnvVar{Name: "RELEASE_NAMESPACE", Value: "release-ns"})
+	asserts.Contains(container.VolumeMounts, corev1.VolumeMount{Name: "helm-dry-run", MountPath: dryRunPath})
+	asserts.Len(pod.Spec.Volumes, 2)
+}
+
 func Test_mergeTolerations(t *testing.T) {
 	asserts := assert.New(t)
 	testCases := []mergeTolerationsTestCase{
@@ -327,3 +376,40 @@ func Test_mergeTolerations(t *testing.T) {
 		asserts.ElementsMatch(resp, t.expected, t.name)
 	}
 }
+
+func Test_validateDryRun(t *testing.T) {
+	crdChart := Command{ReleaseName: "app-crd"}
+	mainChart := Command{ReleaseName: "app"}
+	installed := func(releases ...string) func(namespace, releaseName string) (bool, error) {
+		return func(namespace, releaseName string) (bool, error) {
+			for _, release := range releases {
+				if namespace+"/"+releaseName == release {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		cmds      Commands
+		installed []string
+		expectErr bool
+	}{
+		{name: "single chart", cmds: Commands{mainChart}},
+		{name: "CRD chart not installed", cmds: Commands{crdChart, mainChart}, expectErr: true},
+		{name: "CRD chart installed in another namespace", cmds: Commands{crdChart, mainChart}, installed: []string{"other/app-crd"}, expectErr: true},
+		{name: "CRD chart installed", cmds: Commands{crdChart, mainChart}, installed: []string{"default/app-crd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDryRun("default", tt.cmds, installed(tt.installed...))
+			if tt.expectErr {
+				assert.ErrorContains(t, err, "release app can not be checked with a dry-run before release default/app-crd")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
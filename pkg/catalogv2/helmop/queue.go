@@ -0,0 +1,343 @@
+package helmop
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/rancher/apiserver/pkg/apierror"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	namespaces "github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/wrangler/v3/pkg/kstatus"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/util/retry"
+)
+
+// queueSecretKey is the key of the queue Secret of an operation holding its queuedPod.
+const queueSecretKey = "operation"
+
+// queuedPod is what the pod of a queued operation is created from once it leaves the queue. It is kept in a Secret in
+// the namespace of the operation pods rather than in the status of the operation, which is writable by the users
+// allowed to update operations.
+type queuedPod struct {
+	// Operation is the operation, as <namespace>/<name>.
+	Operation string `json:"operation"`
+	// User, UID, Groups and Extra are the identity the operation is run as.
+	User   string              `json:"user"`
+	UID    string              `json:"uid,omitempty"`
+	Groups []string            `json:"groups,omitempty"`
+	Extra  map[string][]string `json:"extra,omitempty"`
+	// Namespace is the namespace of the releases of the operation.
+	Namespace     string              `json:"namespace"`
+	SecretData    map[string][]byte   `json:"secretData"`
+	Kustomize     bool                `json:"kustomize,omitempty"`
+	ImageOverride string              `json:"imageOverride,omitempty"`
+	Tolerations   []corev1.Toleration `json:"tolerations,omitempty"`
+	DryRun        bool                `json:"dryRun,omitempty"`
+}
+
+// userInfo returns the identity the operation is run as.
+func (p *queuedPod) userInfo() user.Info {
+	return &user.DefaultInfo{
+		Name:   p.User,
+		UID:    p.UID,
+		Groups: p.Groups,
+		Extra:  p.Extra,
+	}
+}
+
+// QueueSecretName returns the name of the Secret holding the pod of the queued operation, in the system namespace.
+func QueueSecretName(namespace, name string) string {
+	digest := sha256.Sum256([]byte(namespace + "/" + name))
+	return "helm-queue-" + hex.EncodeToString(digest[:8])
+}
+
+// releaseKeys returns the releases the commands of an operation act on, as <namespace>/<name>.
+func releaseKeys(namespace string, cmds Commands) []string {
+	var result []string
+	seen := map[string]bool{}
+	for _, cmd := range cmds {
+		if cmd.ReleaseName == "" {
+			continue
+		}
+		ns := cmd.ReleaseNamespace
+		if ns == "" {
+			ns = namespace
+		}
+		key := ns + "/" + cmd.ReleaseName
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// queueOperation creates the operation in the queued state, along with the Secret its pod is created from once it
+// leaves the queue. Queued operations are started by the operation queue controller, with StartQueued.
+func (s *Operations) queueOperation(user user.Info, status catalog.OperationStatus, pod queuedPod, releases []string) (*catalog.Operation, error) {
+	name := names.SimpleNameGenerator.GenerateName("helm-operation-")
+	pod.Operation = status.Namespace + "/" + name
+	pod.User = user.GetName()
+	pod.UID = user.GetUID()
+	pod.Groups = user.GetGroups()
+	pod.Extra = user.GetExtra()
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.secrets.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      QueueSecretName(status.Namespace, name),
+			Namespace: namespaces.System,
+		},
+		Data: map[string][]byte{
+			queueSecretKey: data,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := s.ops.Create(&catalog.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: status.Namespace,
+		},
+	})
+	if err != nil {
+		s.deleteQueueSecret(status.Namespace, name)
+		return nil, err
+	}
+	if err := s.createRoleAndRoleBindings(op, user.GetName()); err != nil {
+		s.deleteQueuedOperation(op)
+		return nil, err
+	}
+
+	status.Queue = &catalog.OperationQueueStatus{
+		State:     catalog.OperationQueued,
+		QueueTime: metav1.Now(),
+		Releases:  releases,
+	}
+	kstatus.SetTransitioning(&status, "waiting in the queue of operations")
+	op.Status = status
+	result, err := s.ops.UpdateStatus(op)
+	if err != nil {
+		s.deleteQueuedOperation(op)
+		return nil, err
+	}
+	return result, nil
+}
+
+// deleteQueuedOperation removes an operation which couldn't be queued.
+func (s *Operations) deleteQueuedOperation(op *catalog.Operation) {
+	if err := s.ops.Delete(op.Namespace, op.Name, &metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		logrus.Errorf("failed to delete helm operation %s/%s: %v", op.Namespace, op.Name, err)
+	}
+	s.deleteQueueSecret(op.Namespace, op.Name)
+}
+
+// getQueuedPod returns what the pod of the queued operation is created from.
+func (s *Operations) getQueuedPod(namespace, name string) (*queuedPod, error) {
+	secret, err := s.secrets.Get(namespaces.System, QueueSecretName(namespace, name), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("the pod of operation %s/%s is not recorded", namespace, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	pod := &queuedPod{}
+	if err := json.Unmarshal(secret.Data[queueSecretKey], pod); err != nil {
+		return nil, fmt.Errorf("invalid pod of operation %s/%s: %w", namespace, name, err)
+	}
+	if pod.Operation != namespace+"/"+name {
+		return nil, fmt.Errorf("the pod of operation %s/%s is recorded for operation %s", namespace, name, pod.Operation)
+	}
+	return pod, nil
+}
+
+// deleteQueueSecret deletes the Secret the pod of the operation is created from, once the operation left the queue.
+func (s *Operations) deleteQueueSecret(namespace, name string) {
+	err := s.secrets.Delete(namespaces.System, QueueSecretName(namespace, name), &metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.Errorf("failed to delete the queue secret of helm operation %s/%s: %v", namespace, name, err)
+	}
+}
+
+// StartQueued creates the pod of a queued operation leaving the queue. The operation is marked as started before its
+// pod is created, so that the pod is created once even if the operation is dispatched again, and as failed if its pod
+// can't be created. Operations which aren't queued anymore are left as is.
+func (s *Operations) StartQueued(ctx context.Context, namespace, name string) error {
+	op, err := s.ops.Get(namespace, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if op.Status.Queue == nil || op.Status.Queue.State != catalog.OperationQueued {
+		return nil
+	}
+
+	op.Status.Queue.State = catalog.OperationStarted
+	op.Status.Queue.Position = 0
+	op.Status.Queue.Reason = ""
+	op.Status.Queue.StartTime = &metav1.Time{Time: time.Now()}
+	kstatus.SetTransitioning(&op.Status, "waiting to run operation")
+	op, err = s.ops.UpdateStatus(op)
+	if apierrors.IsConflict(err) || apierrors.IsNotFound(err) {
+		// the operation was cancelled or dispatched again, it is looked at again once the change is observed
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer s.deleteQueueSecret(namespace, name)
+
+	pod, err := s.createQueuedPod(ctx, op)
+	if err != nil {
+		s.updateOperationStatus(namespace, name, func(status *catalog.OperationStatus) {
+			if status.Queue == nil {
+				status.Queue = &catalog.OperationQueueStatus{}
+			}
+			status.Queue.State = catalog.OperationFailed
+			kstatus.SetError(status, fmt.Sprintf("failed to start operation: %v", err))
+		})
+		return nil
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := s.ops.Get(namespace, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// the operation is removed with its pod
+		current.OwnerReferences = pod.OwnerReferences
+		current, err = s.ops.Update(current)
+		if err != nil {
+			return err
+		}
+
+		current.Status.Token = op.Status.Token
+		current.Status.PodName = op.Status.PodName
+		current.Status.PodNamespace = op.Status.PodNamespace
+		_, err = s.ops.UpdateStatus(current)
+		return err
+	})
+}
+
+// createQueuedPod creates the pod of the started operation from its queue Secret, and records it in the status of
+// the operation.
+func (s *Operations) createQueuedPod(ctx context.Context, op *catalog.Operation) (*corev1.Pod, error) {
+	queued, err := s.getQueuedPod(op.Namespace, op.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the pod is only created from what was recorded when the operation was queued
+	status := op.Status.DeepCopy()
+	status.Namespace = queued.Namespace
+	status.Tolerations = queued.Tolerations
+	status.DryRun = queued.DryRun
+	pod, err := s.createOperationPod(ctx, queued.userInfo(), status, queued.SecretData, queued.Kustomize, queued.ImageOverride)
+	if err != nil {
+		return nil, err
+	}
+	op.Status.Token = status.Token
+	op.Status.PodName = status.PodName
+	op.Status.PodNamespace = status.PodNamespace
+	return pod, nil
+}
+
+// updateOperationStatus updates the status of an operation, retrying on conflicts.
+func (s *Operations) updateOperationStatus(namespace, name string, update func(status *catalog.OperationStatus)) {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		op, err := s.ops.Get(namespace, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		update(&op.Status)
+		_, err = s.ops.UpdateStatus(op)
+		return err
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		logrus.Errorf("failed to update the status of helm operation %s/%s: %v", namespace, name, err)
+	}
+}
+
+// Cancel removes an operation from the queue of the operations waiting to run. Only queued operations can be
+// cancelled, by the user who requested them or by users allowed to update operations.
+func (s *Operations) Cancel(user user.Info, namespace, name string, canUpdate bool) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		op, err := s.ops.Get(namespace, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if op.Status.Queue == nil || op.Status.Queue.State != catalog.OperationQueued {
+			return apierror.NewAPIError(validation.Conflict, fmt.Sprintf("operation %s/%s is not queued", namespace, name))
+		}
+		if !canUpdate {
+			queued, err := s.getQueuedPod(namespace, name)
+			if err != nil {
+				return err
+			}
+			if queued.User != user.GetName() {
+				return apierror.NewAPIError(validation.PermissionDenied, fmt.Sprintf("can not cancel operation %s/%s", namespace, name))
+			}
+		}
+
+		op.Status.Queue.State = catalog.OperationCancelled
+		op.Status.Queue.Position = 0
+		op.Status.Queue.Reason = fmt.Sprintf("cancelled by %s", user.GetName())
+		op.Status.Queue.StartTime = &metav1.Time{Time: time.Now()}
+		kstatus.SetError(&op.Status, "operation was cancelled")
+		_, err = s.ops.UpdateStatus(op)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.deleteQueueSecret(namespace, name)
+	return nil
+}
+
+// WaitStarted waits for a queued operation to leave the queue, and returns it once its pod was created. Operations
+// which aren't queued are returned as is.
+func (s *Operations) WaitStarted(ctx context.Context, op *catalog.Operation) (*catalog.Operation, error) {
+	if op.Status.Queue == nil || op.Status.Queue.State != catalog.OperationQueued {
+		return op, nil
+	}
+
+	// the pod of the operation is created by the operation queue controller once it leaves the queue
+	var result *catalog.Operation
+	err := wait.PollUntilContextCancel(ctx, time.Second, true, func(context.Context) (bool, error) {
+		current, err := s.ops.Get(op.Namespace, op.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		result = current
+		queue := current.Status.Queue
+		switch {
+		case queue == nil:
+			return current.Status.PodName != "", nil
+		case queue.State == catalog.OperationCancelled:
+			return false, fmt.Errorf("operation %s/%s was cancelled", op.Namespace, op.Name)
+		case queue.State == catalog.OperationFailed:
+			return false, fmt.Errorf("operation %s/%s failed to start: %s", op.Namespace, op.Name, kstatus.Stalled.GetMessage(current))
+		case queue.State == catalog.OperationStarted:
+			return current.Status.PodName != "", nil
+		}
+		return false, nil
+	})
+	return result, err
+}
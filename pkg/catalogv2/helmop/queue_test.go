@@ -0,0 +1,17 @@
+package helmop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_releaseKeys(t *testing.T) {
+	cmds := Commands{
+		{ReleaseName: "app-crd"},
+		{ReleaseName: "app", ReleaseNamespace: "apps"},
+		{ReleaseName: "app-crd"},
+		{},
+	}
+	assert.Equal(t, []string{"default/app-crd", "apps/app"}, releaseKeys("default", cmds))
+}
@@ -0,0 +1,96 @@
+package helmop
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	namespaces "github.com/rancher/rancher/pkg/namespace"
+	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// rollbackSecretKey is the key of the rollback Secret of an operation holding its HealthCheckRollback.
+const rollbackSecretKey = "rollback"
+
+// HealthCheckRollback is how the release upgraded by an operation with a health check is rolled back if it isn't
+// healthy. It is kept in a Secret in the namespace of the operation pods rather than in the status of the operation,
+// which is writable by the users allowed to update operations.
+type HealthCheckRollback struct {
+	// Operation is the operation, as <namespace>/<name>.
+	Operation string `json:"operation"`
+	// Namespace and Release are the upgraded release.
+	Namespace string `json:"namespace"`
+	Release   string `json:"release"`
+	// PreviousRevision is the revision of the release before the upgrade.
+	PreviousRevision int `json:"previousRevision"`
+	// User and Groups are the identity the upgrade was run as, which the rollback is run as.
+	User   string   `json:"user"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// UserInfo returns the identity the rollback is run as.
+func (r *HealthCheckRollback) UserInfo() user.Info {
+	return &user.DefaultInfo{
+		Name:   r.User,
+		Groups: r.Groups,
+	}
+}
+
+// RollbackSecretName returns the name of the Secret holding the HealthCheckRollback of the operation, in the system
+// namespace.
+func RollbackSecretName(namespace, name string) string {
+	digest := sha256.Sum256([]byte(namespace + "/" + name))
+	return "helm-rollback-" + hex.EncodeToString(digest[:8])
+}
+
+// createRollbackSecret records how the release upgraded by the operation is rolled back if it fails its health check.
+// The Secret is deleted by the operation queue controller along with the operation.
+func (s *Operations) createRollbackSecret(op *catalog.Operation, user user.Info, status catalog.OperationStatus) error {
+	data, err := json.Marshal(HealthCheckRollback{
+		Operation:        op.Namespace + "/" + op.Name,
+		Namespace:        status.Namespace,
+		Release:          status.Release,
+		PreviousRevision: status.HealthCheck.PreviousRevision,
+		User:             user.GetName(),
+		Groups:           user.GetGroups(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.secrets.Create(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RollbackSecretName(op.Namespace, op.Name),
+			Namespace: namespaces.System,
+		},
+		Data: map[string][]byte{
+			rollbackSecretKey: data,
+		},
+	})
+	return err
+}
+
+// GetHealthCheckRollback returns how the release upgraded by the operation is rolled back.
+func GetHealthCheckRollback(secrets corev1controllers.SecretCache, op *catalog.Operation) (*HealthCheckRollback, error) {
+	secret, err := secrets.Get(namespaces.System, RollbackSecretName(op.Namespace, op.Name))
+	if apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("the rollback of operation %s/%s is not recorded", op.Namespace, op.Name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	rollback := &HealthCheckRollback{}
+	if err := json.Unmarshal(secret.Data[rollbackSecretKey], rollback); err != nil {
+		return nil, fmt.Errorf("invalid rollback of operation %s/%s: %w", op.Namespace, op.Name, err)
+	}
+	if rollback.Operation != op.Namespace+"/"+op.Name {
+		return nil, fmt.Errorf("the rollback of operation %s/%s is recorded for operation %s", op.Namespace, op.Name, rollback.Operation)
+	}
+	return rollback, nil
+}
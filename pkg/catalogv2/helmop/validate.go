@@ -0,0 +1,183 @@
+package helmop
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/apiserver/pkg/apierror"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/schemas/validation"
+	"github.com/xeipuuv/gojsonschema"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// chartRequest is a chart of an install or upgrade request, as validated before the operation is created.
+type chartRequest struct {
+	index  int                    // index of the chart in the charts of the request
+	name   string                 // name of the chart
+	chart  []byte                 // content of the chart tar
+	values map[string]interface{} // values of the request
+	// reuseValues is set for upgrades without values, for which helm reuses the values of the release.
+	reuseValues bool
+}
+
+// fieldError is a field of a request violating the values schema of a chart or the policy of its repository.
+type fieldError struct {
+	field   string
+	message string
+}
+
+// validateCharts validates the values of the charts against their values.schema.json and the charts against the
+// policy of the repository. The request is refused with the fields in error, so that it fails before the operation
+// pod is created rather than when helm runs.
+func (s *Operations) validateCharts(repoNamespace, repoName, releaseNamespace string, charts []chartRequest) error {
+	repoSpec, err := s.getSpec(repoNamespace, repoName, false)
+	if err != nil {
+		return err
+	}
+
+	var errs []fieldError
+	for _, c := range charts {
+		errs = append(errs, validateChart(c, releaseNamespace, repoSpec.Policy)...)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, fmt.Sprintf("%s: %s", e.field, e.message))
+	}
+	return apierror.NewFieldAPIError(validation.InvalidBodyContent, errs[0].field, strings.Join(messages, "; "))
+}
+
+// validateChart returns the fields of the chart request violating the values schema of the chart or the policy.
+func validateChart(c chartRequest, releaseNamespace string, policy *catalog.RepoPolicy) []fieldError {
+	field := fmt.Sprintf("charts[%d]", c.index)
+	chrt, err := loader.LoadArchive(bytes.NewReader(c.chart))
+	if err != nil {
+		return []fieldError{{field: field, message: fmt.Sprintf("failed to load chart %s: %v", c.name, err)}}
+	}
+
+	var errs []fieldError
+	if !c.reuseValues {
+		// values are validated the way helm does: against the schemas of the chart and of its enabled dependencies,
+		// once merged with their default values
+		values := chartutil.Values(c.values)
+		if err := chartutil.ProcessDependenciesWithMerge(chrt, values); err != nil {
+			return []fieldError{{field: field + ".values", message: err.Error()}}
+		}
+		coalesced, err := chartutil.CoalesceValues(chrt, values)
+		if err != nil {
+			return []fieldError{{field: field + ".values", message: err.Error()}}
+		}
+		errs = append(errs, validateSchema(chrt, coalesced, field+".values")...)
+	}
+
+	if policy == nil {
+		return errs
+	}
+	rules := []catalog.ChartPolicyRules{policy.ChartPolicyRules}
+	for _, chartPolicy := range policy.Charts {
+		if chartPolicy.Name == c.name {
+			rules = append(rules, chartPolicy.ChartPolicyRules)
+		}
+	}
+	for _, r := range rules {
+		errs = append(errs, validatePolicyRules(r, c, chrt.Metadata.Version, releaseNamespace, field)...)
+	}
+	return errs
+}
+
+// validateSchema returns the values violating the values.schema.json of the chart or of its dependencies.
+func validateSchema(chrt *chart.Chart, values map[string]interface{}, field string) []fieldError {
+	var errs []fieldError
+	if len(chrt.Schema) > 0 {
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return []fieldError{{field: field, message: err.Error()}}
+		}
+		if bytes.Equal(valuesJSON, []byte("null")) {
+			valuesJSON = []byte("{}")
+		}
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(chrt.Schema), gojsonschema.NewBytesLoader(valuesJSON))
+		if err != nil {
+			return []fieldError{{field: field, message: fmt.Sprintf("invalid values.schema.json of chart %s: %v", chrt.Name(), err)}}
+		}
+		for _, resultErr := range result.Errors() {
+			path := field
+			if resultErr.Field() != gojsonschema.STRING_CONTEXT_ROOT {
+				path += "." + resultErr.Field()
+			}
+			if property, ok := resultErr.Details()["property"].(string); ok && resultErr.Type() == "required" {
+				path += "." + property
+			}
+			errs = append(errs, fieldError{field: path, message: resultErr.Description()})
+		}
+	}
+
+	for _, dependency := range chrt.Dependencies() {
+		dependencyValues, _ := values[dependency.Name()].(map[string]interface{})
+		errs = append(errs, validateSchema(dependency, dependencyValues, field+"."+dependency.Name())...)
+	}
+	return errs
+}
+
+// validatePolicyRules returns the fields of the chart request violating the rules of a policy.
+func validatePolicyRules(rules catalog.ChartPolicyRules, c chartRequest, version, releaseNamespace, field string) []fieldError {
+	var errs []fieldError
+
+	if len(rules.AllowedNamespaces) > 0 && !slices.Contains(rules.AllowedNamespaces, releaseNamespace) {
+		errs = append(errs, fieldError{
+			field:   "namespace",
+			message: fmt.Sprintf("chart %s can't be installed in namespace %s, allowed namespaces are %s", c.name, releaseNamespace, strings.Join(rules.AllowedNamespaces, ", ")),
+		})
+	}
+
+	if rules.MaxVersion != "" {
+		maxVersion, err := semver.NewVersion(rules.MaxVersion)
+		if err != nil {
+			errs = append(errs, fieldError{field: field + ".version", message: fmt.Sprintf("invalid maxVersion %s of the repository policy: %v", rules.MaxVersion, err)})
+		} else if chartVersion, err := semver.NewVersion(version); err != nil {
+			errs = append(errs, fieldError{field: field + ".version", message: fmt.Sprintf("invalid version %s of chart %s: %v", version, c.name, err)})
+		} else if chartVersion.GreaterThan(maxVersion) {
+			errs = append(errs, fieldError{field: field + ".version", message: fmt.Sprintf("version %s of chart %s is higher than the maximum version %s", version, c.name, rules.MaxVersion)})
+		}
+	}
+
+	if c.reuseValues {
+		return errs
+	}
+	for _, path := range rules.ForbiddenValues {
+		if _, ok := lookupValue(c.values, path); ok {
+			errs = append(errs, fieldError{field: field + ".values." + path, message: "value is forbidden by the repository policy"})
+		}
+	}
+	for _, path := range rules.RequiredValues {
+		if value, ok := lookupValue(c.values, path); !ok || value == nil || value == "" {
+			errs = append(errs, fieldError{field: field + ".values." + path, message: "value is required by the repository policy"})
+		}
+	}
+	return errs
+}
+
+// lookupValue returns the value at the dot separated path of the values, and whether it is set.
+func lookupValue(values map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
@@ -0,0 +1,149 @@
+package helmop
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testValuesSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "replicas": {"type": "integer", "minimum": 1},
+    "image": {
+      "type": "object",
+      "required": ["repository"],
+      "properties": {"repository": {"type": "string"}, "tag": {"type": "string"}}
+    }
+  }
+}`
+
+// chartArchive returns the tar of a chart with the given files.
+func chartArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "app/" + name, Mode: 0644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func Test_validateChart(t *testing.T) {
+	chart := chartArchive(t, map[string]string{
+		"Chart.yaml":         "apiVersion: v2\nname: app\nversion: 1.2.0\n",
+		"values.yaml":        "replicas: 1\nimage:\n  repository: rancher/app\n  tag: v1.0.0\n",
+		"values.schema.json": testValuesSchema,
+	})
+
+	tests := []struct {
+		name             string
+		values           map[string]interface{}
+		reuseValues      bool
+		releaseNamespace string
+		policy           *catalog.RepoPolicy
+		expected         []fieldError
+	}{
+		{
+			name:   "valid values",
+			values: map[string]interface{}{"replicas": 3, "image": map[string]interface{}{"tag": "v1.1.0"}},
+		},
+		{
+			name:   "values violating the schema",
+			values: map[string]interface{}{"replicas": 0, "image": map[string]interface{}{"repository": nil, "tag": 1}},
+			expected: []fieldError{
+				{field: "charts[0].values.image.repository", message: "repository is required"},
+				{field: "charts[0].values.image.tag", message: "Invalid type. Expected: string, given: integer"},
+				{field: "charts[0].values.replicas", message: "Must be greater than or equal to 1"},
+			},
+		},
+		{
+			name:        "reused values aren't validated",
+			values:      map[string]interface{}{"replicas": "three"},
+			reuseValues: true,
+			policy: &catalog.RepoPolicy{ChartPolicyRules: catalog.ChartPolicyRules{
+				RequiredValues: []string{"global.clusterId"},
+			}},
+		},
+		{
+			name:   "values violating the policy",
+			values: map[string]interface{}{"global": map[string]interface{}{"systemDefaultRegistry": "registry.example.com", "clusterId": ""}},
+			policy: &catalog.RepoPolicy{ChartPolicyRules: catalog.ChartPolicyRules{
+				ForbiddenValues: []string{"global.systemDefaultRegistry", "hostNetwork"},
+				RequiredValues:  []string{"global.clusterId", "global.projectId"},
+			}},
+			expected: []fieldError{
+				{field: "charts[0].values.global.systemDefaultRegistry", message: "value is forbidden by the repository policy"},
+				{field: "charts[0].values.global.clusterId", message: "value is required by the repository policy"},
+				{field: "charts[0].values.global.projectId", message: "value is required by the repository policy"},
+			},
+		},
+		{
+			name:             "namespace and version allowed by the policy",
+			releaseNamespace: "apps",
+			policy: &catalog.RepoPolicy{
+				ChartPolicyRules: catalog.ChartPolicyRules{AllowedNamespaces: []string{"default", "apps"}, MaxVersion: "2.0.0"},
+				Charts:           []catalog.ChartPolicy{{Name: "other", ChartPolicyRules: catalog.ChartPolicyRules{MaxVersion: "1.0.0"}}},
+			},
+		},
+		{
+			name:             "namespace and version refused by the policy of the chart",
+			releaseNamespace: "kube-system",
+			policy: &catalog.RepoPolicy{
+				ChartPolicyRules: catalog.ChartPolicyRules{MaxVersion: "2.0.0"},
+				Charts:           []catalog.ChartPolicy{{Name: "app", ChartPolicyRules: catalog.ChartPolicyRules{AllowedNamespaces: []string{"apps"}, MaxVersion: "1.1.x"}}},
+			},
+			expected: []fieldError{
+				{field: "namespace", message: "chart app can't be installed in namespace kube-system, allowed namespaces are apps"},
+				{field: "charts[0].version", message: "invalid maxVersion 1.1.x of the repository policy: Invalid Semantic Version"},
+			},
+		},
+		{
+			name: "version higher than the maximum version",
+			policy: &catalog.RepoPolicy{
+				ChartPolicyRules: catalog.ChartPolicyRules{MaxVersion: "1.1.0"},
+			},
+			expected: []fieldError{
+				{field: "charts[0].version", message: "version 1.2.0 of chart app is higher than the maximum version 1.1.0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := chartRequest{name: "app", chart: chart, values: tt.values, reuseValues: tt.reuseValues}
+			actual := validateChart(c, tt.releaseNamespace, tt.policy)
+			assert.ElementsMatch(t, tt.expected, actual)
+		})
+	}
+}
+
+func Test_validateChartDependencies(t *testing.T) {
+	chart := chartArchive(t, map[string]string{
+		"Chart.yaml":                          "apiVersion: v2\nname: app\nversion: 1.0.0\ndependencies:\n- name: db\n  version: 1.0.0\n  condition: db.enabled\n",
+		"charts/db/Chart.yaml":                "apiVersion: v2\nname: db\nversion: 1.0.0\n",
+		"charts/db/values.yaml":               "enabled: true\nport: 5432\n",
+		"charts/db/values.schema.json":        `{"type": "object", "properties": {"port": {"type": "integer"}}}`,
+		"charts/db/templates/deployment.yaml": "",
+	})
+
+	actual := validateChart(chartRequest{index: 1, name: "app", chart: chart, values: map[string]interface{}{"db": map[string]interface{}{"port": "5432"}}}, "default", nil)
+	assert.Equal(t, []fieldError{{field: "charts[1].values.db.port", message: "Invalid type. Expected: integer, given: string"}}, actual)
+
+	// the schemas of disabled dependencies are ignored
+	actual = validateChart(chartRequest{index: 1, name: "app", chart: chart, values: map[string]interface{}{"db": map[string]interface{}{"enabled": false, "port": "5432"}}}, "default", nil)
+	assert.Empty(t, actual)
+
+	actual = validateChart(chartRequest{name: "app", chart: []byte("not a chart")}, "default", nil)
+	require.Len(t, actual, 1)
+	assert.Equal(t, "charts[0]", actual[0].field)
+}
@@ -78,6 +78,23 @@ func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipT
 	}
 	defer client.CloseIdleConnections()
 
+	u, err := chartURL(repoURL, chart)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	return ioutil.NopCloser(bytes.NewBuffer(data)), err
+}
+
+// chartURL returns the URL of the chart tarball, resolving relative chart URLs against the repository URL.
+func chartURL(repoURL string, chart *repo.ChartVersion) (*url.URL, error) {
 	u, err := url.Parse(chart.URLs[0])
 	if err != nil {
 		return nil, err
@@ -95,15 +112,43 @@ func Chart(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipT
 		// contain an access credential.
 		u.RawQuery = base.RawQuery
 	}
+	return u, nil
+}
+
+// Provenance returns the Helm provenance file of the chart, which is published next to the chart tarball with the
+// ".prov" suffix, along with the file name of the chart tarball that the provenance file refers to.
+func Provenance(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool, chart *repo.ChartVersion) ([]byte, string, error) {
+	if len(chart.URLs) == 0 {
+		return nil, "", fmt.Errorf("failed to find chartName %s version %s: %w", chart.Name, chart.Version, validation.NotFound)
+	}
+
+	client, err := HelmClient(secret, caBundle, insecureSkipTLSVerify, disableSameOriginCheck, repoURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer client.CloseIdleConnections()
+
+	u, err := chartURL(repoURL, chart)
+	if err != nil {
+		return nil, "", err
+	}
+	chartFileName := path.Base(u.Path)
+	u.Path += ".prov"
 
 	resp, err := client.Get(u.String())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
-	data, err := ioutil.ReadAll(resp.Body)
-	return ioutil.NopCloser(bytes.NewBuffer(data)), err
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%w: chart %s version %s has no provenance file", ErrUnverified, chart.Name, chart.Version)
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to download the provenance file of chart %s version %s: %s", chart.Name, chart.Version, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxProvenanceSize))
+	return data, chartFileName, err
 }
 
 func DownloadIndex(secret *corev1.Secret, repoURL string, caBundle []byte, insecureSkipTLSVerify bool, disableSameOriginCheck bool) (*repo.IndexFile, error) {
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/openpgp" //nolint
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// maxProvenanceSize bounds the size of a downloaded provenance file.
+const maxProvenanceSize = 1024 * 1024
+
+// ErrUnverified is wrapped by the errors of charts which have no provenance file or whose provenance file is invalid.
+var ErrUnverified = errors.New("chart provenance verification failed")
+
+// VerifyProvenance verifies the provenance file of the chart tarball with the given PGP public keyring, which can be
+// binary or ASCII armored. The chart file name must be the name the provenance file records the digest of the chart
+// tarball under. It returns the identity of the signer.
+func VerifyProvenance(chartData []byte, chartFileName string, prov, keyring []byte) (string, error) {
+	var (
+		keys openpgp.EntityList
+		err  error
+	)
+	if bytes.Contains(keyring, []byte("-----BEGIN PGP")) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(keyring))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(keyring))
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to read PGP keyring: %w", ErrUnverified, err)
+	}
+
+	// the provenance package only verifies files
+	dir, err := os.MkdirTemp("", "chart-provenance-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	chartPath := filepath.Join(dir, filepath.Base(chartFileName))
+	provPath := chartPath + ".prov"
+	if err := os.WriteFile(chartPath, chartData, 0600); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(provPath, prov, 0600); err != nil {
+		return "", err
+	}
+
+	verification, err := (&provenance.Signatory{KeyRing: keys}).Verify(chartPath, provPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnverified, err)
+	}
+
+	var identities []string
+	for name := range verification.SignedBy.Identities {
+		identities = append(identities, name)
+	}
+	sort.Strings(identities)
+	if len(identities) == 0 {
+		return fmt.Sprintf("%X", verification.SignedBy.PrimaryKey.Fingerprint), nil
+	}
+	return identities[0], nil
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp" //nolint
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+func TestVerifyProvenance(t *testing.T) {
+	signer, err := openpgp.NewEntity("Chart Signer", "", "signer@example.com", nil)
+	require.NoError(t, err)
+	other, err := openpgp.NewEntity("Other", "", "other@example.com", nil)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	chartPath, err := chartutil.Save(&chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "test", Version: "1.0.0"},
+	}, dir)
+	require.NoError(t, err)
+	chartData, err := os.ReadFile(chartPath)
+	require.NoError(t, err)
+	prov, err := (&provenance.Signatory{Entity: signer}).ClearSign(chartPath)
+	require.NoError(t, err)
+
+	keyring := func(entity *openpgp.Entity) []byte {
+		buf := &bytes.Buffer{}
+		require.NoError(t, entity.Serialize(buf))
+		return buf.Bytes()
+	}
+
+	signedBy, err := VerifyProvenance(chartData, filepath.Base(chartPath), []byte(prov), keyring(signer))
+	assert.NoError(t, err)
+	assert.Equal(t, "Chart Signer <signer@example.com>", signedBy)
+
+	_, err = VerifyProvenance(chartData, filepath.Base(chartPath), []byte(prov), keyring(other))
+	assert.ErrorIs(t, err, ErrUnverified, "signed by a key that is not in the keyring")
+
+	_, err = VerifyProvenance(append(chartData, 0), filepath.Base(chartPath), []byte(prov), keyring(signer))
+	assert.ErrorIs(t, err, ErrUnverified, "tampered chart")
+
+	_, err = VerifyProvenance(chartData, "renamed-1.0.0.tgz", []byte(prov), keyring(signer))
+	assert.ErrorIs(t, err, ErrUnverified, "chart file name not in the provenance file")
+}
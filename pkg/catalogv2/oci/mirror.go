@@ -0,0 +1,177 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/distribution/reference"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	helmregistry "helm.sh/helm/v3/pkg/registry"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// dockerHubRegistry is the host serving the images of the docker.io domain.
+const dockerHubRegistry = "registry-1.docker.io"
+
+// ChartTag returns the tag of a chart version in an OCI registry. OCI tags can't contain "+", which helm replaces
+// with "_" when pushing charts.
+func ChartTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// PushChart pushes the chart tarball to the repository and tag of the client, as a Helm chart OCI artifact. Nothing is
+// pushed if the tag already references the same chart tarball.
+func (o *Client) PushChart(ctx context.Context, chartTar []byte) error {
+	if int64(len(chartTar)) > maxHelmChartTarSize {
+		return fmt.Errorf("the chart has size more than %d which is not supported", maxHelmChartTarSize)
+	}
+	chart, err := loader.LoadArchive(bytes.NewReader(chartTar))
+	if err != nil {
+		return fmt.Errorf("failed to load the chart: %w", err)
+	}
+	config, err := json.Marshal(chart.Metadata)
+	if err != nil {
+		return err
+	}
+
+	repository, err := o.GetOrasRepository()
+	if err != nil {
+		return fmt.Errorf("failed to create an OCI repository for %s/%s: %w", o.registry, o.repository, err)
+	}
+
+	configDesc := content.NewDescriptorFromBytes(helmregistry.ConfigMediaType, config)
+	chartDesc := content.NewDescriptorFromBytes(helmregistry.ChartLayerMediaType, chartTar)
+	if pushed, err := o.chartPushed(ctx, repository, chartDesc); err != nil || pushed {
+		return err
+	}
+
+	for _, blob := range []struct {
+		desc ocispecv1.Descriptor
+		data []byte
+	}{{configDesc, config}, {chartDesc, chartTar}} {
+		exists, err := repository.Exists(ctx, blob.desc)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if err := repository.Push(ctx, blob.desc, bytes.NewReader(blob.data)); err != nil {
+			return fmt.Errorf("failed to push blob %s to %s/%s: %w", blob.desc.Digest, o.registry, o.repository, err)
+		}
+	}
+
+	manifest, err := oras.PackManifest(ctx, repository, oras.PackManifestVersion1_1_RC4, "", oras.PackManifestOptions{
+		ConfigDescriptor: &configDesc,
+		Layers:           []ocispecv1.Descriptor{chartDesc},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push the manifest of %s/%s:%s: %w", o.registry, o.repository, o.tag, err)
+	}
+	return repository.Tag(ctx, manifest, o.tag)
+}
+
+// chartPushed returns whether the manifest referenced by the tag of the client contains the chart layer.
+func (o *Client) chartPushed(ctx context.Context, repository *remote.Repository, chartDesc ocispecv1.Descriptor) (bool, error) {
+	desc, err := repository.Resolve(ctx, o.tag)
+	if err != nil {
+		if errors.Is(err, errdef.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if desc.MediaType != ocispecv1.MediaTypeImageManifest {
+		return false, nil
+	}
+	manifestBlob, err := content.FetchAll(ctx, repository, desc)
+	if err != nil {
+		return false, err
+	}
+	var manifest ocispecv1.Manifest
+	if err := json.Unmarshal(manifestBlob, &manifest); err != nil {
+		return false, err
+	}
+	for _, layer := range manifest.Layers {
+		if layer.Digest == chartDesc.Digest {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MirroredImage returns the reference of the image once copied to the registry, which is the path of the image in
+// its original registry prefixed by the registry, like registry.example.com/mirror/rancher/shell:v0.1.0 for the image
+// rancher/shell:v0.1.0 and the registry registry.example.com/mirror.
+func MirroredImage(registry, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse image %s: %w", image, err)
+	}
+	named = reference.TagNameOnly(named)
+	mirrored := strings.TrimSuffix(strings.TrimPrefix(registry, "oci://"), "/") + "/" + reference.Path(named)
+	if tagged, ok := named.(reference.Tagged); ok {
+		mirrored += ":" + tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		mirrored += "@" + digested.Digest().String()
+	}
+	return mirrored, nil
+}
+
+// CopyImage copies the image, with the manifests of all its platforms, to the repository and tag of the client. The
+// image is pulled anonymously from its registry.
+func (o *Client) CopyImage(ctx context.Context, image string) error {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return fmt.Errorf("failed to parse image %s: %w", image, err)
+	}
+	named = reference.TagNameOnly(named)
+
+	host := reference.Domain(named)
+	if host == "docker.io" {
+		host = dockerHubRegistry
+	}
+	source, err := remote.NewRepository(host + "/" + reference.Path(named))
+	if err != nil {
+		return err
+	}
+	source.Client = auth.DefaultClient
+
+	sourceRef := ""
+	if tagged, ok := named.(reference.Tagged); ok {
+		sourceRef = tagged.Tag()
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		sourceRef = digested.Digest().String()
+	}
+	desc, err := source.Resolve(ctx, sourceRef)
+	if err != nil {
+		return fmt.Errorf("failed to resolve image %s: %w", image, err)
+	}
+
+	target, err := o.GetOrasRepository()
+	if err != nil {
+		return fmt.Errorf("failed to create an OCI repository for %s/%s: %w", o.registry, o.repository, err)
+	}
+	targetRef := o.tag
+	if targetRef == "" {
+		targetRef = desc.Digest.String()
+	}
+	if existing, err := target.Resolve(ctx, targetRef); err == nil && existing.Digest == desc.Digest {
+		return nil
+	}
+
+	if _, err := oras.Copy(ctx, source, sourceRef, target, targetRef, oras.DefaultCopyOptions); err != nil {
+		return fmt.Errorf("failed to copy image %s to %s/%s: %w", image, o.registry, o.repository, err)
+	}
+	return nil
+}
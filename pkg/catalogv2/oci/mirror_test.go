@@ -0,0 +1,134 @@
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// fakeRegistry is a minimal OCI distribution registry storing the blobs and manifests pushed to a single repository.
+type fakeRegistry struct {
+	sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	pushes    int
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/testrepo/")
+	switch {
+	case r.URL.Path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+	case strings.HasPrefix(path, "blobs/uploads/") && r.Method == http.MethodPost:
+		w.Header().Set("Location", "/v2/testrepo/blobs/uploads/upload")
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasPrefix(path, "blobs/uploads/") && r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.blobs[r.URL.Query().Get("digest")] = data
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "blobs/"):
+		data, ok := f.blobs[strings.TrimPrefix(path, "blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case strings.HasPrefix(path, "manifests/") && r.Method == http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.manifests[strings.TrimPrefix(path, "manifests/")] = data
+		f.manifests[digest.FromBytes(data).String()] = data
+		f.pushes++
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+		w.WriteHeader(http.StatusCreated)
+	case strings.HasPrefix(path, "manifests/"):
+		data, ok := f.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPushChart(t *testing.T) {
+	chartTar, err := os.ReadFile("../../../tests/testdata/testingchart-0.1.0.tgz")
+	require.NoError(t, err)
+
+	fake := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	url := fmt.Sprintf("oci://%s/testrepo:%s", strings.TrimPrefix(server.URL, "http://"), ChartTag("0.1.0+up1"))
+	client, err := NewClient(url, v1.RepoSpec{InsecurePlainHTTP: true}, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, client.PushChart(context.Background(), chartTar))
+	manifestBlob, ok := fake.manifests["0.1.0_up1"]
+	require.True(t, ok, "the chart version should be tagged")
+
+	var manifest ocispec.Manifest
+	require.NoError(t, json.Unmarshal(manifestBlob, &manifest))
+	assert.Equal(t, registry.ConfigMediaType, manifest.Config.MediaType)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, registry.ChartLayerMediaType, manifest.Layers[0].MediaType)
+	assert.Equal(t, chartTar, fake.blobs[manifest.Layers[0].Digest.String()])
+
+	// pushing the same chart again is a no-op
+	pushes := fake.pushes
+	require.NoError(t, client.PushChart(context.Background(), chartTar))
+	assert.Equal(t, pushes, fake.pushes)
+
+	assert.Error(t, client.PushChart(context.Background(), []byte("not a chart")))
+}
+
+func TestMirroredImage(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected string
+	}{
+		{"rancher/shell:v0.1.0", "registry.example.com/mirror/rancher/shell:v0.1.0"},
+		{"nginx", "registry.example.com/mirror/library/nginx:latest"},
+		{"quay.io/prometheus/node-exporter:v1.7.0", "registry.example.com/mirror/prometheus/node-exporter:v1.7.0"},
+		{
+			"rancher/shell@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+			"registry.example.com/mirror/rancher/shell@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+	}
+	for _, tt := range tests {
+		actual, err := MirroredImage("oci://registry.example.com/mirror/", tt.image)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, actual)
+	}
+
+	_, err := MirroredImage("registry.example.com", "Invalid Image")
+	assert.Error(t, err)
+}
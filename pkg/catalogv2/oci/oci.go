@@ -11,6 +11,7 @@
 	"strings"
 
 	"github.com/hashicorp/go-version"
+	"github.com/opencontainers/go-digest"
 	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -37,23 +38,79 @@ func Chart(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRep
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	ociClient, orasRepository, err := chartRepository(credentialSecret, chart, clusterRepoSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	chartTar, _, err := fetchChart(ctx, orasRepository, ociClient.tag, chart.URLs[0])
+	return chartTar, err
+}
+
+// ChartDigest returns an io.ReadCloser of the chart tar of the OCI artifact with the given manifest digest, like Chart.
+// It fails if the downloaded manifest does not have this digest, so that the chart cannot be replaced after the
+// manifest was verified.
+func ChartDigest(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRepoSpec v1.RepoSpec, manifestDigest string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, orasRepository, err := chartRepository(credentialSecret, chart, clusterRepoSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	chartTar, manifest, err := fetchChart(ctx, orasRepository, manifestDigest, chart.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Digest.String() != manifestDigest {
+		chartTar.Close()
+		return nil, fmt.Errorf("the manifest of %s has digest %s instead of %s", chart.URLs[0], manifest.Digest, manifestDigest)
+	}
+	return chartTar, nil
+}
+
+// ResolveChart returns the digest of the manifest the tag of the chart refers to.
+func ResolveChart(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRepoSpec v1.RepoSpec) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ociClient, orasRepository, err := chartRepository(credentialSecret, chart, clusterRepoSpec)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := orasRepository.Resolve(ctx, ociClient.tag)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the OCI artifact %s: %w", chart.URLs[0], err)
+	}
+	return manifest.Digest.String(), nil
+}
+
+// chartRepository creates the OCI client and the oras repository of the chart.
+func chartRepository(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRepoSpec v1.RepoSpec) (*Client, *remote.Repository, error) {
 	chartURL := chart.URLs[0]
 
 	// Create a new OCIClient
 	ociClient, err := NewClient(chartURL, clusterRepoSpec, credentialSecret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create an OCI client for url %s: %w", chartURL, err)
+		return nil, nil, fmt.Errorf("failed to create an OCI client for url %s: %w", chartURL, err)
 	}
 
 	// Create an oras repository
 	orasRepository, err := ociClient.GetOrasRepository()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create an OCI repository for url %s: %w", chartURL, err)
+		return nil, nil, fmt.Errorf("failed to create an OCI repository for url %s: %w", chartURL, err)
 	}
+	return ociClient, orasRepository, nil
+}
 
+// fetchChart downloads the OCI artifact with the given reference, a tag or a manifest digest, and returns its chart
+// tar layer along with the descriptor of its manifest.
+func fetchChart(ctx context.Context, orasRepository oras.ReadOnlyTarget, reference, chartURL string) (io.ReadCloser, ocispecv1.Descriptor, error) {
 	// Download the oci artifact manifest
 	memoryStore := memory.New()
-	manifest, err := oras.Copy(ctx, orasRepository, ociClient.tag, memoryStore, "", oras.CopyOptions{
+	manifest, err := oras.Copy(ctx, orasRepository, reference, memoryStore, "", oras.CopyOptions{
 		CopyGraphOptions: oras.CopyGraphOptions{
 			PreCopy: func(ctx context.Context, desc ocispecv1.Descriptor) error {
 				// Download only helm chart related descriptors.
@@ -73,17 +130,17 @@ func Chart(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRep
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("unable to oras copy the remote OCI artifact %s: %w", chartURL, err)
+		return nil, manifest, fmt.Errorf("unable to oras copy the remote OCI artifact %s: %w", chartURL, err)
 	}
 	// Fetch the manifest blob of the oci artifact
 	manifestBlob, err := content.FetchAll(ctx, memoryStore, manifest)
 	if err != nil {
-		return nil, fmt.Errorf("unable to fetch the manifest blob of %s: %w", chartURL, err)
+		return nil, manifest, fmt.Errorf("unable to fetch the manifest blob of %s: %w", chartURL, err)
 	}
 	var manifestJSON ocispecv1.Manifest
 	err = json.Unmarshal(manifestBlob, &manifestJSON)
 	if err != nil {
-		return nil, fmt.Errorf("unable to unmarshal manifest blob of %s: %w", chartURL, err)
+		return nil, manifest, fmt.Errorf("unable to unmarshal manifest blob of %s: %w", chartURL, err)
 	}
 
 	// Check if the oci artifact is of type helm config ?
@@ -93,15 +150,53 @@ func Chart(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRep
 			if layer.MediaType == registry.ChartLayerMediaType {
 				chartTar, err := content.FetchAll(ctx, memoryStore, layer)
 				if err != nil {
-					return nil, err
+					return nil, manifest, err
 				}
 
-				return io.NopCloser(bytes.NewBuffer(chartTar)), nil
+				return io.NopCloser(bytes.NewBuffer(chartTar)), manifest, nil
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("unable to find the required chart tar file for %s", chartURL)
+	return nil, manifest, fmt.Errorf("unable to find the required chart tar file for %s", chartURL)
+}
+
+// VerifyChart verifies the cosign signature of the OCI artifact of the chart with the given manifest digest with the
+// given PEM encoded public keys, falling back to its cosign attestations if it is not signed. It returns the ID of the
+// key that signed the chart. Errors wrapping ErrUnverified mean that the chart is unsigned or that its signatures are
+// invalid, other errors that the signatures could not be downloaded.
+func VerifyChart(credentialSecret *corev1.Secret, chart *repo.ChartVersion, clusterRepoSpec v1.RepoSpec, manifestDigest, publicKeys string) (string, error) {
+	keys, err := parsePublicKeys(publicKeys)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnverified, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, orasRepository, err := chartRepository(credentialSecret, chart, clusterRepoSpec)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := digest.Parse(manifestDigest)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrUnverified, err)
+	}
+
+	var messages []string
+	for _, suffix := range []string{"sig", "att"} {
+		tag := fmt.Sprintf("%s-%s.%s", manifest.Algorithm(), manifest.Encoded(), suffix)
+		keyID, err := verifyCosignManifest(ctx, orasRepository, tag, manifestDigest, keys)
+		if err == nil {
+			return keyID, nil
+		}
+		if !errors.Is(err, ErrUnverified) {
+			return "", fmt.Errorf("failed to download the signature of %s: %w", chart.URLs[0], err)
+		}
+		messages = append(messages, err.Error())
+	}
+	return "", fmt.Errorf("%w: failed to verify the signature of %s: %s", ErrUnverified, chart.URLs[0], strings.Join(messages, "; "))
 }
 
 // GenerateIndex creates a Helm repo index from the OCI url provided
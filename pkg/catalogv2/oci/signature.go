@@ -0,0 +1,225 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/errdef"
+)
+
+const (
+	// cosignSignatureAnnotation holds the base64 encoded signature of a layer of a cosign signature manifest.
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	// cosignSimpleSigningMediaType is the media type of the layers of cosign signature manifests.
+	cosignSimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	// dsseEnvelopeMediaType is the media type of the layers of cosign attestation manifests.
+	dsseEnvelopeMediaType = "application/vnd.dsse.envelope.v1+json"
+
+	// maxSignatureLayerSize bounds the size of the signature and attestation layers that are downloaded.
+	maxSignatureLayerSize int64 = 1024 * 1024
+)
+
+// ErrUnverified is wrapped by the errors of charts which are not signed or whose signatures are invalid.
+var ErrUnverified = errors.New("chart signature verification failed")
+
+// publicKey is a public key used to verify cosign signatures, along with its ID.
+type publicKey struct {
+	id  string
+	key crypto.PublicKey
+}
+
+// parsePublicKeys parses the PEM encoded public keys. The ID of a key is derived from the digest of its DER encoding.
+func parsePublicKeys(keys string) ([]publicKey, error) {
+	var result []publicKey
+	rest := []byte(keys)
+	for {
+		block, remaining := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		rest = remaining
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		digest := sha256.Sum256(block.Bytes)
+		result = append(result, publicKey{
+			id:  hex.EncodeToString(digest[:8]),
+			key: key,
+		})
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no PEM encoded public key is configured")
+	}
+	return result, nil
+}
+
+// verifySignature returns the ID of the key that signed the message with the signature, as created by cosign.
+func verifySignature(keys []publicKey, message, signature []byte) (string, bool) {
+	digest := sha256.Sum256(message)
+	for _, key := range keys {
+		verified := false
+		switch pub := key.key.(type) {
+		case *ecdsa.PublicKey:
+			verified = ecdsa.VerifyASN1(pub, digest[:], signature)
+		case *rsa.PublicKey:
+			verified = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+		case ed25519.PublicKey:
+			verified = ed25519.Verify(pub, message, signature)
+		}
+		if verified {
+			return key.id, true
+		}
+	}
+	return "", false
+}
+
+// simpleSigningPayload is the payload signed by "cosign sign", which binds the signature to the manifest digest.
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+	} `json:"critical"`
+}
+
+// verifySimpleSigning verifies a layer of a cosign signature manifest for the manifest with the given digest. It
+// returns the ID of the key that signed it.
+func verifySimpleSigning(keys []publicKey, payload []byte, encodedSignature, manifestDigest string) (string, error) {
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return "", fmt.Errorf("invalid cosign signature: %w", err)
+	}
+	keyID, ok := verifySignature(keys, payload, signature)
+	if !ok {
+		return "", fmt.Errorf("cosign signature does not match any of the configured public keys")
+	}
+
+	var signed simpleSigningPayload
+	if err := json.Unmarshal(payload, &signed); err != nil {
+		return "", fmt.Errorf("invalid cosign signature payload: %w", err)
+	}
+	if signed.Critical.Image.DockerManifestDigest != manifestDigest {
+		return "", fmt.Errorf("cosign signature is for manifest %s instead of %s", signed.Critical.Image.DockerManifestDigest, manifestDigest)
+	}
+	return keyID, nil
+}
+
+// dsseEnvelope is the envelope of the in-toto statements attached by "cosign attest".
+type dsseEnvelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		Sig string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// inTotoStatement is the part of an in-toto statement that binds it to the manifest digest.
+type inTotoStatement struct {
+	Subject []struct {
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+}
+
+// verifyAttestation verifies a layer of a cosign attestation manifest for the manifest with the given digest. It
+// returns the ID of the key that signed it.
+func verifyAttestation(keys []publicKey, envelope []byte, manifestDigest string) (string, error) {
+	var env dsseEnvelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return "", fmt.Errorf("invalid attestation envelope: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return "", fmt.Errorf("invalid attestation payload: %w", err)
+	}
+
+	// the signatures are computed over the pre-authentication encoding of the payload
+	pae := []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(env.PayloadType), env.PayloadType, len(payload), payload))
+	keyID := ""
+	for _, sig := range env.Signatures {
+		signature, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		if id, ok := verifySignature(keys, pae, signature); ok {
+			keyID = id
+			break
+		}
+	}
+	if keyID == "" {
+		return "", fmt.Errorf("attestation is not signed by any of the configured public keys")
+	}
+
+	var statement inTotoStatement
+	if err := json.Unmarshal(payload, &statement); err != nil {
+		return "", fmt.Errorf("invalid attestation statement: %w", err)
+	}
+	algorithm, encoded, _ := strings.Cut(manifestDigest, ":")
+	for _, subject := range statement.Subject {
+		if subject.Digest[algorithm] == encoded {
+			return keyID, nil
+		}
+	}
+	return "", fmt.Errorf("attestation is not for manifest %s", manifestDigest)
+}
+
+// verifyCosignManifest verifies the cosign signature or attestation manifest with the given tag, which cosign derives
+// from the digest of the signed manifest. It returns the ID of the key that signed the first valid layer.
+func verifyCosignManifest(ctx context.Context, target oras.ReadOnlyTarget, tag, manifestDigest string, keys []publicKey) (string, error) {
+	_, manifestBlob, err := oras.FetchBytes(ctx, target, tag, oras.FetchBytesOptions{MaxBytes: maxSignatureLayerSize})
+	if errors.Is(err, errdef.ErrNotFound) {
+		return "", fmt.Errorf("%w: %s does not exist", ErrUnverified, tag)
+	} else if err != nil {
+		return "", err
+	}
+	var manifest ocispecv1.Manifest
+	if err := json.Unmarshal(manifestBlob, &manifest); err != nil {
+		return "", fmt.Errorf("%w: unable to unmarshal manifest %s: %w", ErrUnverified, tag, err)
+	}
+
+	var lastErr error
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != cosignSimpleSigningMediaType && layer.MediaType != dsseEnvelopeMediaType {
+			continue
+		}
+		if layer.Size > maxSignatureLayerSize {
+			lastErr = fmt.Errorf("layer %s of %s has size more than %d which is not supported", layer.Digest, tag, maxSignatureLayerSize)
+			continue
+		}
+		blob, err := content.FetchAll(ctx, target, layer)
+		if err != nil {
+			return "", err
+		}
+
+		var keyID string
+		if layer.MediaType == cosignSimpleSigningMediaType {
+			keyID, err = verifySimpleSigning(keys, blob, layer.Annotations[cosignSignatureAnnotation], manifestDigest)
+		} else {
+			keyID, err = verifyAttestation(keys, blob, manifestDigest)
+		}
+		if err == nil {
+			return keyID, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return "", fmt.Errorf("%w: %s has no cosign signature or attestation", ErrUnverified, tag)
+	}
+	return "", fmt.Errorf("%w: %w", ErrUnverified, lastErr)
+}
@@ -0,0 +1,108 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/memory"
+)
+
+func TestVerifyCosignManifest(t *testing.T) {
+	ctx := context.Background()
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	publicKeys := func(key *ecdsa.PrivateKey) []publicKey {
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		require.NoError(t, err)
+		keys, err := parsePublicKeys(string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+		require.NoError(t, err)
+		return keys
+	}
+	sign := func(message []byte) string {
+		digest := sha256.Sum256(message)
+		signature, err := ecdsa.SignASN1(rand.Reader, signer, digest[:])
+		require.NoError(t, err)
+		return base64.StdEncoding.EncodeToString(signature)
+	}
+	push := func(store *memory.Store, tag, mediaType string, layer []byte, annotations map[string]string) {
+		layerDesc := content.NewDescriptorFromBytes(mediaType, layer)
+		layerDesc.Annotations = annotations
+		require.NoError(t, store.Push(ctx, layerDesc, bytes.NewReader(layer)))
+		config := []byte("{}")
+		configDesc := content.NewDescriptorFromBytes(ocispecv1.MediaTypeImageConfig, config)
+		if exists, _ := store.Exists(ctx, configDesc); !exists {
+			require.NoError(t, store.Push(ctx, configDesc, bytes.NewReader(config)))
+		}
+		manifest, err := json.Marshal(ocispecv1.Manifest{
+			Versioned: specs.Versioned{SchemaVersion: 2},
+			MediaType: ocispecv1.MediaTypeImageManifest,
+			Config:    configDesc,
+			Layers:    []ocispecv1.Descriptor{layerDesc},
+		})
+		require.NoError(t, err)
+		manifestDesc := content.NewDescriptorFromBytes(ocispecv1.MediaTypeImageManifest, manifest)
+		require.NoError(t, store.Push(ctx, manifestDesc, bytes.NewReader(manifest)))
+		require.NoError(t, store.Tag(ctx, manifestDesc, tag))
+	}
+
+	chartDigest := digest.FromString("chart manifest")
+	signatureTag := fmt.Sprintf("%s-%s.sig", chartDigest.Algorithm(), chartDigest.Encoded())
+	attestationTag := fmt.Sprintf("%s-%s.att", chartDigest.Algorithm(), chartDigest.Encoded())
+
+	store := memory.New()
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"example.com/charts/test"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"}}`, chartDigest))
+	push(store, signatureTag, cosignSimpleSigningMediaType, payload, map[string]string{cosignSignatureAnnotation: sign(payload)})
+
+	statement := []byte(fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v0.1","subject":[{"name":"example.com/charts/test","digest":{"sha256":"%s"}}]}`, chartDigest.Encoded()))
+	payloadType := "application/vnd.in-toto+json"
+	envelope, err := json.Marshal(map[string]any{
+		"payloadType": payloadType,
+		"payload":     base64.StdEncoding.EncodeToString(statement),
+		"signatures": []map[string]string{
+			{"sig": sign([]byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(statement), statement)))},
+		},
+	})
+	require.NoError(t, err)
+	push(store, attestationTag, dsseEnvelopeMediaType, envelope, nil)
+
+	expectedKeyID := publicKeys(signer)[0].id
+
+	keyID, err := verifyCosignManifest(ctx, store, signatureTag, chartDigest.String(), publicKeys(signer))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedKeyID, keyID)
+
+	keyID, err = verifyCosignManifest(ctx, store, attestationTag, chartDigest.String(), publicKeys(signer))
+	assert.NoError(t, err)
+	assert.Equal(t, expectedKeyID, keyID)
+
+	_, err = verifyCosignManifest(ctx, store, signatureTag, chartDigest.String(), publicKeys(other))
+	assert.ErrorIs(t, err, ErrUnverified, "signed by a key that is not configured")
+
+	otherDigest := digest.FromString("other manifest").String()
+	_, err = verifyCosignManifest(ctx, store, signatureTag, otherDigest, publicKeys(signer))
+	assert.ErrorIs(t, err, ErrUnverified, "signature for another manifest")
+	_, err = verifyCosignManifest(ctx, store, attestationTag, otherDigest, publicKeys(signer))
+	assert.ErrorIs(t, err, ErrUnverified, "attestation for another manifest")
+
+	_, err = verifyCosignManifest(ctx, store, "sha256-missing.sig", chartDigest.String(), publicKeys(signer))
+	assert.ErrorIs(t, err, ErrUnverified, "unsigned chart")
+}
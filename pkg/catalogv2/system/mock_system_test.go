@@ -129,6 +129,21 @@ func (mr *MockOperationClientMockRecorder) Upgrade(arg0, arg1, arg2, arg3, arg4,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upgrade", reflect.TypeOf((*MockOperationClient)(nil).Upgrade), arg0, arg1, arg2, arg3, arg4, arg5)
 }
 
+// WaitStarted mocks base method.
+func (m *MockOperationClient) WaitStarted(arg0 context.Context, arg1 *v1.Operation) (*v1.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitStarted", arg0, arg1)
+	ret0, _ := ret[0].(*v1.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitStarted indicates an expected call of WaitStarted.
+func (mr *MockOperationClientMockRecorder) WaitStarted(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitStarted", reflect.TypeOf((*MockOperationClient)(nil).WaitStarted), arg0, arg1)
+}
+
 // MockHelmClient is a mock of HelmClient interface.
 type MockHelmClient struct {
 	ctrl     *gomock.Controller
@@ -99,6 +99,32 @@ func (_m *OperationClient) Upgrade(ctx context.Context, _a1 user.Info, namespace
 	return r0, r1
 }
 
+// WaitStarted provides a mock function with given fields: ctx, op
+func (_m *OperationClient) WaitStarted(ctx context.Context, op *catalog_cattle_iov1.Operation) (*catalog_cattle_iov1.Operation, error) {
+	ret := _m.Called(ctx, op)
+
+	var r0 *catalog_cattle_iov1.Operation
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *catalog_cattle_iov1.Operation) (*catalog_cattle_iov1.Operation, error)); ok {
+		return rf(ctx, op)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *catalog_cattle_iov1.Operation) *catalog_cattle_iov1.Operation); ok {
+		r0 = rf(ctx, op)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*catalog_cattle_iov1.Operation)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *catalog_cattle_iov1.Operation) error); ok {
+		r1 = rf(ctx, op)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewOperationClient creates a new instance of OperationClient. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewOperationClient(t interface {
@@ -68,6 +68,8 @@ type OperationClient interface {
 	Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader, imageOverride string) (*catalog.Operation, error)
 	// AddCpTaintsToTolerations gets the list of control plane nodes and adds their taints to the given tolerations.
 	AddCpTaintsToTolerations(tolerations []v1.Toleration) ([]v1.Toleration, error)
+	// WaitStarted waits for a queued operation to leave the queue, and returns it once its pod was created.
+	WaitStarted(ctx context.Context, op *catalog.Operation) (*catalog.Operation, error)
 }
 
 type ContentClient interface {
@@ -368,6 +370,15 @@ func (m *Manager) install(namespace, name, minVersion, exactVersion string, valu
 // returns nil if it is. If not, creates a watch for the pod with a timeout of 300 seconds
 // that will check if the pod is done and return nil. If the watch timeouts, it returns an error.
 func (m *Manager) waitPodDone(op *catalog.Operation) error {
+	// operations waiting in the queue of the helm operations have no pod yet
+	if op.Status.Queue != nil && op.Status.Queue.State == catalog.OperationQueued {
+		var err error
+		op, err = m.operation.WaitStarted(m.ctx, op)
+		if err != nil {
+			return err
+		}
+	}
+
 	pod, err := m.pods.Get(op.Status.PodNamespace, op.Status.PodName, metav1.GetOptions{})
 	if err != nil {
 		return err
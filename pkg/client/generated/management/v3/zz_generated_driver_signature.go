@@ -0,0 +1,12 @@
+package client
+
+const (
+	DriverSignatureType           = "driverSignature"
+	DriverSignatureFieldPublicKey = "publicKey"
+	DriverSignatureFieldURL       = "url"
+)
+
+type DriverSignature struct {
+	PublicKey string `json:"publicKey,omitempty" yaml:"publicKey,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+}
@@ -0,0 +1,20 @@
+package client
+
+const (
+	DriverSignatureVerificationType          = "driverSignatureVerification"
+	DriverSignatureVerificationFieldFormat   = "format"
+	DriverSignatureVerificationFieldKeyID    = "keyId"
+	DriverSignatureVerificationFieldMessage  = "message"
+	DriverSignatureVerificationFieldTime     = "time"
+	DriverSignatureVerificationFieldURL      = "url"
+	DriverSignatureVerificationFieldVerified = "verified"
+)
+
+type DriverSignatureVerification struct {
+	Format   string `json:"format,omitempty" yaml:"format,omitempty"`
+	KeyID    string `json:"keyId,omitempty" yaml:"keyId,omitempty"`
+	Message  string `json:"message,omitempty" yaml:"message,omitempty"`
+	Time     string `json:"time,omitempty" yaml:"time,omitempty"`
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`
+	Verified bool   `json:"verified,omitempty" yaml:"verified,omitempty"`
+}
@@ -19,6 +19,7 @@
 	KontainerDriverFieldName                 = "name"
 	KontainerDriverFieldOwnerReferences      = "ownerReferences"
 	KontainerDriverFieldRemoved              = "removed"
+	KontainerDriverFieldSignature            = "signature"
 	KontainerDriverFieldState                = "state"
 	KontainerDriverFieldTransitioning        = "transitioning"
 	KontainerDriverFieldTransitioningMessage = "transitioningMessage"
@@ -43,6 +44,7 @@ type KontainerDriver struct {
 	Name                 string            `json:"name,omitempty" yaml:"name,omitempty"`
 	OwnerReferences      []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
 	Removed              string            `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Signature            *DriverSignature  `json:"signature,omitempty" yaml:"signature,omitempty"`
 	State                string            `json:"state,omitempty" yaml:"state,omitempty"`
 	Transitioning        string            `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
 	TransitioningMessage string            `json:"transitioningMessage,omitempty" yaml:"transitioningMessage,omitempty"`
@@ -19,6 +19,7 @@
 	NodeDriverFieldName                 = "name"
 	NodeDriverFieldOwnerReferences      = "ownerReferences"
 	NodeDriverFieldRemoved              = "removed"
+	NodeDriverFieldSignature            = "signature"
 	NodeDriverFieldState                = "state"
 	NodeDriverFieldStatus               = "status"
 	NodeDriverFieldTransitioning        = "transitioning"
@@ -44,6 +45,7 @@ type NodeDriver struct {
 	Name                 string            `json:"name,omitempty" yaml:"name,omitempty"`
 	OwnerReferences      []OwnerReference  `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
 	Removed              string            `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Signature            *DriverSignature  `json:"signature,omitempty" yaml:"signature,omitempty"`
 	State                string            `json:"state,omitempty" yaml:"state,omitempty"`
 	Status               *NodeDriverStatus `json:"status,omitempty" yaml:"status,omitempty"`
 	Transitioning        string            `json:"transitioning,omitempty" yaml:"transitioning,omitempty"`
@@ -6,11 +6,13 @@
 	NodeDriverStatusFieldAppliedDockerMachineVersion = "appliedDockerMachineVersion"
 	NodeDriverStatusFieldAppliedURL                  = "appliedURL"
 	NodeDriverStatusFieldConditions                  = "conditions"
+	NodeDriverStatusFieldSignatureVerification       = "signatureVerification"
 )
 
 type NodeDriverStatus struct {
-	AppliedChecksum             string      `json:"appliedChecksum,omitempty" yaml:"appliedChecksum,omitempty"`
-	AppliedDockerMachineVersion string      `json:"appliedDockerMachineVersion,omitempty" yaml:"appliedDockerMachineVersion,omitempty"`
-	AppliedURL                  string      `json:"appliedURL,omitempty" yaml:"appliedURL,omitempty"`
-	Conditions                  []Condition `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	AppliedChecksum             string                       `json:"appliedChecksum,omitempty" yaml:"appliedChecksum,omitempty"`
+	AppliedDockerMachineVersion string                       `json:"appliedDockerMachineVersion,omitempty" yaml:"appliedDockerMachineVersion,omitempty"`
+	AppliedURL                  string                       `json:"appliedURL,omitempty" yaml:"appliedURL,omitempty"`
+	Conditions                  []Condition                  `json:"conditions,omitempty" yaml:"conditions,omitempty"`
+	SignatureVerification       *DriverSignatureVerification `json:"signatureVerification,omitempty" yaml:"signatureVerification,omitempty"`
 }
@@ -0,0 +1,452 @@
+package autoscaler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	rocontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningv2/kubeconfig"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+const (
+	downstreamSetID = "cluster-autoscaler"
+	// maxScaleEvents is the number of scale events retained on the cluster status.
+	maxScaleEvents = 20
+	// serviceAccountWait is how long to wait for the management cluster service account to be created.
+	serviceAccountWait = 5 * time.Second
+	// managementTokenTTL is how long the token of the kubeconfig handed to the downstream cluster is valid for. The token
+	// is rotated once half of it elapsed.
+	managementTokenTTL = 2 * time.Hour
+)
+
+type handler struct {
+	clusters               rocontrollers.ClusterController
+	machineDeploymentCache capicontrollers.MachineDeploymentCache
+	machineCache           capicontrollers.MachineCache
+	serviceAccountCache    corecontrollers.ServiceAccountCache
+	k8s                    kubernetes.Interface
+	kubeconfigManager      *kubeconfig.Manager
+
+	// deployed holds the hash of the objects last applied to each downstream cluster, to avoid contacting the
+	// downstream cluster every time the provisioning cluster changes.
+	deployed map[string]string
+	// tokens holds the token last handed to each downstream cluster, until it is rotated.
+	tokens       map[string]managementToken
+	deployedLock sync.Mutex
+}
+
+// managementToken is a short-lived token of the service account of a cluster in the management cluster.
+type managementToken struct {
+	token    string
+	rotateAt time.Time
+}
+
+// Register sets up the controller that deploys the cluster autoscaler into provisioning clusters with autoscaling
+// enabled. The autoscaler runs in the downstream cluster, and scales the cluster's machine deployments in the
+// management cluster through a kubeconfig that may only modify the cluster's own machine deployments and machines.
+func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager *kubeconfig.Manager) {
+	h := &handler{
+		clusters:               clients.Provisioning.Cluster(),
+		machineDeploymentCache: clients.CAPI.MachineDeployment().Cache(),
+		machineCache:           clients.CAPI.Machine().Cache(),
+		serviceAccountCache:    clients.Core.ServiceAccount().Cache(),
+		k8s:                    clients.K8s,
+		kubeconfigManager:      kubeconfigManager,
+		deployed:               map[string]string{},
+		tokens:                 map[string]managementToken{},
+	}
+
+	rocontrollers.RegisterClusterGeneratingHandler(ctx, clients.Provisioning.Cluster(),
+		clients.Apply.
+			WithCacheTypes(clients.Core.ServiceAccount(),
+				clients.RBAC.Role(),
+				clients.RBAC.RoleBinding()),
+		"", "cluster-autoscaler", h.OnChange, nil)
+
+	relatedresource.Watch(ctx, "cluster-autoscaler-trigger", func(namespace, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+		if md, ok := obj.(*capi.MachineDeployment); ok && md.Spec.ClusterName != "" {
+			return []relatedresource.Key{{
+				Namespace: md.Namespace,
+				Name:      md.Spec.ClusterName,
+			}}, nil
+		}
+		// the role of the autoscaler names the machines of the cluster, so it is updated as machines come and go
+		if machine, ok := obj.(*capi.Machine); ok && machine.Spec.ClusterName != "" {
+			return []relatedresource.Key{{
+				Namespace: machine.Namespace,
+				Name:      machine.Spec.ClusterName,
+			}}, nil
+		}
+		if sa, ok := obj.(*corev1.ServiceAccount); ok {
+			if clusterName, ok := sa.Labels[capr.ClusterNameLabel]; ok && sa.Name == serviceAccountName(clusterName) {
+				return []relatedresource.Key{{
+					Namespace: sa.Namespace,
+					Name:      clusterName,
+				}}, nil
+			}
+		}
+		return nil, nil
+	}, clients.Provisioning.Cluster(), clients.CAPI.MachineDeployment(), clients.CAPI.Machine(), clients.Core.ServiceAccount())
+}
+
+func (h *handler) OnChange(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) ([]runtime.Object, rancherv1.ClusterStatus, error) {
+	if cluster.Spec.RKEConfig == nil || cluster.Spec.RKEConfig.Autoscaling == nil || !cluster.Spec.RKEConfig.Autoscaling.Enabled {
+		if status.Autoscaling == nil {
+			return nil, status, nil
+		}
+		if err := h.removeDownstream(cluster, status); err != nil {
+			return nil, status, err
+		}
+		status.Autoscaling = nil
+		return nil, status, nil
+	}
+
+	machineDeployments, err := h.machineDeployments(cluster)
+	if err != nil {
+		return nil, status, err
+	}
+	status.Autoscaling = newAutoscalingStatus(cluster.Spec.RKEConfig, status.Autoscaling, machineDeployments, time.Now())
+
+	machines, err := h.machineCache.List(cluster.Namespace, labels.SelectorFromSet(map[string]string{
+		capi.ClusterNameLabel: cluster.Name,
+	}))
+	if err != nil {
+		return nil, status, err
+	}
+	objs := managementObjects(cluster, machineDeployments, machines)
+	if !status.Ready || status.ClusterName == "" {
+		return objs, status, nil
+	}
+
+	if err := h.deployDownstream(cluster, status); err != nil {
+		return objs, status, err
+	}
+	return objs, status, nil
+}
+
+// machineDeployments returns the machine deployments of the machine pools of the cluster that exist, by machine pool name.
+func (h *handler) machineDeployments(cluster *rancherv1.Cluster) (map[string]*capi.MachineDeployment, error) {
+	machineDeployments := map[string]*capi.MachineDeployment{}
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		md, err := h.machineDeploymentCache.Get(cluster.Namespace, name.SafeConcatName(cluster.Name, machinePool.Name))
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		machineDeployments[machinePool.Name] = md
+	}
+	return machineDeployments, nil
+}
+
+// newAutoscalingStatus returns the bounds and replicas of the autoscaled machine pools of the cluster, recording a scale
+// event for every machine pool whose replicas changed since the previous status.
+func newAutoscalingStatus(rkeConfig *rancherv1.RKEConfig, previous *rancherv1.AutoscalingStatus, machineDeployments map[string]*capi.MachineDeployment, now time.Time) *rancherv1.AutoscalingStatus {
+	previousReplicas := map[string]int32{}
+	result := &rancherv1.AutoscalingStatus{}
+	if previous != nil {
+		for _, pool := range previous.MachinePools {
+			previousReplicas[pool.Name] = pool.Replicas
+		}
+		result.ScaleEvents = append(result.ScaleEvents, previous.ScaleEvents...)
+	}
+
+	for _, machinePool := range rkeConfig.MachinePools {
+		minSize, maxSize, ok := capr.GetMachinePoolAutoscalingBounds(rkeConfig, machinePool)
+		if !ok {
+			continue
+		}
+		poolStatus := rancherv1.MachinePoolAutoscalingStatus{
+			Name:    machinePool.Name,
+			MinSize: minSize,
+			MaxSize: maxSize,
+		}
+		md := machineDeployments[machinePool.Name]
+		if md == nil || md.Spec.Replicas == nil {
+			// the machine deployment has not been created or defaulted yet
+			result.MachinePools = append(result.MachinePools, poolStatus)
+			continue
+		}
+		poolStatus.Replicas = *md.Spec.Replicas
+		if from, ok := previousReplicas[machinePool.Name]; ok && from != 0 && from != poolStatus.Replicas {
+			result.ScaleEvents = append(result.ScaleEvents, rancherv1.AutoscalingScaleEvent{
+				Time:        metav1.NewTime(now),
+				MachinePool: machinePool.Name,
+				From:        from,
+				To:          poolStatus.Replicas,
+			})
+		}
+		result.MachinePools = append(result.MachinePools, poolStatus)
+	}
+
+	if len(result.ScaleEvents) > maxScaleEvents {
+		result.ScaleEvents = result.ScaleEvents[len(result.ScaleEvents)-maxScaleEvents:]
+	}
+	return result
+}
+
+// deployDownstream applies the cluster autoscaler to the downstream cluster, along with a kubeconfig for the service
+// account of the cluster in the management cluster. The token of the kubeconfig is short-lived, and is rotated by
+// re-applying the kubeconfig before it expires.
+func (h *handler) deployDownstream(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) error {
+	if settings.ServerURL.Get() == "" {
+		return fmt.Errorf("server-url setting must be set to deploy the cluster autoscaler")
+	}
+	serverURL, ca := capr.GetAgentServerURLAndCACerts("", false)
+
+	sa, err := h.serviceAccountCache.Get(cluster.Namespace, serviceAccountName(cluster.Name))
+	if apierrors.IsNotFound(err) {
+		h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, serviceAccountWait)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	h.deployedLock.Lock()
+	defer h.deployedLock.Unlock()
+
+	token, err := h.managementToken(cluster, sa, time.Now())
+	if err != nil {
+		return err
+	}
+
+	managementKubeconfig, err := clientcmd.Write(clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"management": {
+				Server:                   serverURL,
+				CertificateAuthorityData: ca,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"management": {
+				// the token is read from its own file, as client-go reloads token files when they change
+				TokenFile: path.Join(managementKubeconfigMountPath, managementTokenKey),
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"management": {
+				Cluster:   "management",
+				AuthInfo:  "management",
+				Namespace: cluster.Namespace,
+			},
+		},
+		CurrentContext: "management",
+	})
+	if err != nil {
+		return err
+	}
+
+	objs := downstreamObjects(cluster, managementKubeconfig, []byte(token.token))
+	hash, err := objectsHash(objs)
+	if err != nil {
+		return err
+	}
+
+	if h.deployed[string(cluster.UID)] != hash {
+		if err := h.applyDownstream(cluster, status, objs...); err != nil {
+			return err
+		}
+		logrus.Infof("[autoscaler] cluster %s/%s: deployed cluster autoscaler", cluster.Namespace, cluster.Name)
+		h.deployed[string(cluster.UID)] = hash
+	}
+	h.clusters.EnqueueAfter(cluster.Namespace, cluster.Name, time.Until(token.rotateAt))
+	return nil
+}
+
+// managementToken returns the token of the service account of the cluster to hand to the downstream cluster, requesting
+// a new one when there is none yet or the current one is due for rotation.
+func (h *handler) managementToken(cluster *rancherv1.Cluster, sa *corev1.ServiceAccount, now time.Time) (managementToken, error) {
+	if token, ok := h.tokens[string(cluster.UID)]; ok && now.Before(token.rotateAt) {
+		return token, nil
+	}
+
+	tokenRequest, err := h.k8s.CoreV1().ServiceAccounts(sa.Namespace).CreateToken(context.Background(), sa.Name, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: &[]int64{int64(managementTokenTTL.Seconds())}[0],
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return managementToken{}, fmt.Errorf("creating token for service account %s/%s: %w", sa.Namespace, sa.Name, err)
+	}
+
+	token := managementToken{
+		token:    tokenRequest.Status.Token,
+		rotateAt: now.Add(tokenRequest.Status.ExpirationTimestamp.Sub(now) / 2),
+	}
+	h.tokens[string(cluster.UID)] = token
+	return token, nil
+}
+
+func (h *handler) removeDownstream(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus) error {
+	h.deployedLock.Lock()
+	defer h.deployedLock.Unlock()
+	delete(h.deployed, string(cluster.UID))
+	delete(h.tokens, string(cluster.UID))
+
+	if !status.Ready || status.ClusterName == "" {
+		return nil
+	}
+	if err := h.applyDownstream(cluster, status); err != nil {
+		return err
+	}
+	logrus.Infof("[autoscaler] cluster %s/%s: removed cluster autoscaler", cluster.Namespace, cluster.Name)
+	return nil
+}
+
+func (h *handler) applyDownstream(cluster *rancherv1.Cluster, status rancherv1.ClusterStatus, objs ...runtime.Object) error {
+	config, err := h.kubeconfigManager.GetRESTConfig(cluster, status)
+	if err != nil {
+		return err
+	}
+	downstreamApply, err := apply.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	err = downstreamApply.
+		WithDynamicLookup().
+		WithSetID(downstreamSetID).
+		ApplyObjects(objs...)
+	if err != nil {
+		return fmt.Errorf("applying cluster autoscaler to cluster %s/%s: %w", cluster.Namespace, cluster.Name, err)
+	}
+	return nil
+}
+
+func objectsHash(objs []runtime.Object) (string, error) {
+	digest := sha256.New()
+	for _, obj := range objs {
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return "", err
+		}
+		digest.Write(b)
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+func serviceAccountName(clusterName string) string {
+	return name.SafeConcatName(clusterName, "cluster", "autoscaler")
+}
+
+// managementObjects returns the service account that the cluster autoscaler uses to access the management cluster.
+// Short-lived tokens of the service account are handed to the downstream cluster, so it may only modify the autoscaled
+// machine deployments of the cluster and the machines of the cluster, and only read the machine templates of those
+// machine deployments. The CAPI objects of the namespace can still be listed, as the autoscaler discovers its node
+// groups by listing them and list requests can't be restricted to named objects.
+func managementObjects(cluster *rancherv1.Cluster, machineDeployments map[string]*capi.MachineDeployment, machines []*capi.Machine) []runtime.Object {
+	saName := serviceAccountName(cluster.Name)
+
+	var machineDeploymentNames, templateNames, machineNames []string
+	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
+		if _, _, ok := capr.GetMachinePoolAutoscalingBounds(cluster.Spec.RKEConfig, machinePool); !ok {
+			continue
+		}
+		machineDeploymentNames = append(machineDeploymentNames, name.SafeConcatName(cluster.Name, machinePool.Name))
+		if md := machineDeployments[machinePool.Name]; md != nil && md.Spec.Template.Spec.InfrastructureRef.Name != "" {
+			templateNames = append(templateNames, md.Spec.Template.Spec.InfrastructureRef.Name)
+		}
+	}
+	for _, machine := range machines {
+		if machine.Spec.ClusterName == cluster.Name {
+			machineNames = append(machineNames, machine.Name)
+		}
+	}
+	sort.Strings(machineNames)
+
+	rules := []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{capi.GroupVersion.Group},
+			Resources: []string{"machinedeployments", "machinesets", "machines", "machinepools"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+	}
+	if len(machineDeploymentNames) > 0 {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups:     []string{capi.GroupVersion.Group},
+			Resources:     []string{"machinedeployments", "machinedeployments/scale"},
+			ResourceNames: machineDeploymentNames,
+			Verbs:         []string{"get", "update", "patch"},
+		})
+	}
+	if len(machineNames) > 0 {
+		// the autoscaler marks the machines to remove before scaling down
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups:     []string{capi.GroupVersion.Group},
+			Resources:     []string{"machines"},
+			ResourceNames: machineNames,
+			Verbs:         []string{"update", "patch"},
+		})
+	}
+	if len(templateNames) > 0 {
+		// the autoscaler reads the machine templates to scale machine pools up from zero
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups:     []string{"rke-machine.cattle.io"},
+			Resources:     []string{"*"},
+			ResourceNames: templateNames,
+			Verbs:         []string{"get"},
+		})
+	}
+
+	return []runtime.Object{
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      saName,
+				Namespace: cluster.Namespace,
+				Labels: map[string]string{
+					capr.ClusterNameLabel: cluster.Name,
+				},
+			},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      saName,
+				Namespace: cluster.Namespace,
+			},
+			Rules: rules,
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      saName,
+				Namespace: cluster.Namespace,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      "ServiceAccount",
+				Name:      saName,
+				Namespace: cluster.Namespace,
+			}},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     saName,
+			},
+		},
+	}
+}
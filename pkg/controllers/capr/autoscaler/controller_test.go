@@ -0,0 +1,199 @@
+package autoscaler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestNewAutoscalingStatus(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	int32Ptr := func(i int32) *int32 { return &i }
+	machineDeployment := func(replicas int32) *capi.MachineDeployment {
+		return &capi.MachineDeployment{Spec: capi.MachineDeploymentSpec{Replicas: int32Ptr(replicas)}}
+	}
+
+	rkeConfig := &rancherv1.RKEConfig{
+		Autoscaling: &rancherv1.RKEAutoscaling{Enabled: true},
+		MachinePools: []rancherv1.RKEMachinePool{
+			{Name: "control-plane", Quantity: int32Ptr(3)},
+			{Name: "workers", MinSize: int32Ptr(2), MaxSize: int32Ptr(10)},
+			{Name: "gpu", MaxSize: int32Ptr(4)},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		previous           *rancherv1.AutoscalingStatus
+		machineDeployments map[string]*capi.MachineDeployment
+		expected           *rancherv1.AutoscalingStatus
+	}{
+		{
+			name: "first observation records no events",
+			machineDeployments: map[string]*capi.MachineDeployment{
+				"workers": machineDeployment(2),
+			},
+			expected: &rancherv1.AutoscalingStatus{
+				MachinePools: []rancherv1.MachinePoolAutoscalingStatus{
+					{Name: "workers", MinSize: 2, MaxSize: 10, Replicas: 2},
+					{Name: "gpu", MinSize: 1, MaxSize: 4},
+				},
+			},
+		},
+		{
+			name: "scaling records an event",
+			previous: &rancherv1.AutoscalingStatus{
+				MachinePools: []rancherv1.MachinePoolAutoscalingStatus{
+					{Name: "workers", MinSize: 2, MaxSize: 10, Replicas: 2},
+					{Name: "gpu", MinSize: 1, MaxSize: 4},
+				},
+			},
+			machineDeployments: map[string]*capi.MachineDeployment{
+				"workers": machineDeployment(5),
+				"gpu":     machineDeployment(1),
+			},
+			expected: &rancherv1.AutoscalingStatus{
+				MachinePools: []rancherv1.MachinePoolAutoscalingStatus{
+					{Name: "workers", MinSize: 2, MaxSize: 10, Replicas: 5},
+					{Name: "gpu", MinSize: 1, MaxSize: 4, Replicas: 1},
+				},
+				ScaleEvents: []rancherv1.AutoscalingScaleEvent{
+					{Time: metav1.NewTime(now), MachinePool: "workers", From: 2, To: 5},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, newAutoscalingStatus(rkeConfig, tt.previous, tt.machineDeployments, now))
+		})
+	}
+}
+
+func TestNewAutoscalingStatusBoundsEvents(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	maxSize := int32(100)
+	replicas := int32(maxScaleEvents + 5)
+	rkeConfig := &rancherv1.RKEConfig{
+		Autoscaling:  &rancherv1.RKEAutoscaling{Enabled: true},
+		MachinePools: []rancherv1.RKEMachinePool{{Name: "workers", MaxSize: &maxSize}},
+	}
+
+	previous := &rancherv1.AutoscalingStatus{
+		MachinePools: []rancherv1.MachinePoolAutoscalingStatus{{Name: "workers", MinSize: 1, MaxSize: maxSize, Replicas: 1}},
+	}
+	for i := int32(1); i < replicas; i++ {
+		previous.ScaleEvents = append(previous.ScaleEvents, rancherv1.AutoscalingScaleEvent{MachinePool: "workers", From: i, To: i + 1})
+	}
+
+	result := newAutoscalingStatus(rkeConfig, previous, map[string]*capi.MachineDeployment{
+		"workers": {Spec: capi.MachineDeploymentSpec{Replicas: &replicas}},
+	}, now)
+	assert.Len(t, result.ScaleEvents, maxScaleEvents)
+	assert.Equal(t, replicas, result.ScaleEvents[maxScaleEvents-1].To)
+}
+
+func TestManagementObjectsRole(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	cluster := &rancherv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test"},
+		Spec: rancherv1.ClusterSpec{RKEConfig: &rancherv1.RKEConfig{
+			Autoscaling: &rancherv1.RKEAutoscaling{Enabled: true},
+			MachinePools: []rancherv1.RKEMachinePool{
+				{Name: "control-plane", Quantity: int32Ptr(3)},
+				{Name: "workers", MinSize: int32Ptr(2), MaxSize: int32Ptr(10)},
+			},
+		}},
+	}
+	machineDeployments := map[string]*capi.MachineDeployment{
+		"workers": {Spec: capi.MachineDeploymentSpec{Template: capi.MachineTemplateSpec{Spec: capi.MachineSpec{
+			InfrastructureRef: corev1.ObjectReference{Kind: "Amazonec2MachineTemplate", Name: "test-workers-abcde"},
+		}}}},
+	}
+	machines := []*capi.Machine{
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-workers-2"}, Spec: capi.MachineSpec{ClusterName: "test"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test-workers-1"}, Spec: capi.MachineSpec{ClusterName: "test"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-workers-1"}, Spec: capi.MachineSpec{ClusterName: "other"}},
+	}
+
+	var role *rbacv1.Role
+	for _, obj := range managementObjects(cluster, machineDeployments, machines) {
+		if r, ok := obj.(*rbacv1.Role); ok {
+			role = r
+		}
+	}
+	require.NotNil(t, role)
+	assert.Equal(t, []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{capi.GroupVersion.Group},
+			Resources: []string{"machinedeployments", "machinesets", "machines", "machinepools"},
+			Verbs:     []string{"get", "list", "watch"},
+		},
+		{
+			APIGroups:     []string{capi.GroupVersion.Group},
+			Resources:     []string{"machinedeployments", "machinedeployments/scale"},
+			ResourceNames: []string{"test-workers"},
+			Verbs:         []string{"get", "update", "patch"},
+		},
+		{
+			APIGroups:     []string{capi.GroupVersion.Group},
+			Resources:     []string{"machines"},
+			ResourceNames: []string{"test-workers-1", "test-workers-2"},
+			Verbs:         []string{"update", "patch"},
+		},
+		{
+			APIGroups:     []string{"rke-machine.cattle.io"},
+			Resources:     []string{"*"},
+			ResourceNames: []string{"test-workers-abcde"},
+			Verbs:         []string{"get"},
+		},
+	}, role.Rules)
+}
+
+func TestManagementToken(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cluster := &rancherv1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: "test", UID: "uid"}}
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Namespace: "fleet-default", Name: serviceAccountName("test")}}
+
+	k8s := fake.NewSimpleClientset()
+	var requests []*authenticationv1.TokenRequest
+	k8s.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest).DeepCopy()
+		requests = append(requests, request)
+		request.Status.Token = fmt.Sprintf("token-%d", len(requests))
+		request.Status.ExpirationTimestamp = metav1.NewTime(now.Add(time.Duration(*request.Spec.ExpirationSeconds) * time.Second))
+		return true, request, nil
+	})
+	h := &handler{k8s: k8s, tokens: map[string]managementToken{}}
+
+	token, err := h.managementToken(cluster, sa, now)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.token)
+	assert.Equal(t, now.Add(managementTokenTTL/2), token.rotateAt)
+	require.Len(t, requests, 1)
+	assert.Equal(t, int64(managementTokenTTL.Seconds()), *requests[0].Spec.ExpirationSeconds)
+
+	// the token is handed out again until it is due for rotation
+	token, err = h.managementToken(cluster, sa, now.Add(managementTokenTTL/2-time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token.token)
+	assert.Len(t, requests, 1)
+
+	token, err = h.managementToken(cluster, sa, now.Add(managementTokenTTL/2))
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token.token)
+	assert.Len(t, requests, 2)
+}
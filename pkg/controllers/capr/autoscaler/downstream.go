@@ -0,0 +1,210 @@
+package autoscaler
+
+import (
+	"fmt"
+	"path"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	namespaces "github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/provisioningv2/image"
+	"github.com/rancher/rancher/pkg/settings"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	autoscalerName                 = "cluster-autoscaler"
+	managementKubeconfigSecretName = "cluster-autoscaler-management-kubeconfig"
+	managementKubeconfigMountPath  = "/etc/kubernetes/management"
+	managementKubeconfigKey        = "value"
+	managementTokenKey             = "token"
+)
+
+// downstreamObjects returns the cluster autoscaler deployment and its RBAC for the downstream cluster. The autoscaler
+// uses the clusterapi cloud provider, discovering the machine deployments of the cluster in the management cluster
+// through the given kubeconfig and token, and the in-cluster config to access the downstream cluster itself.
+func downstreamObjects(cluster *rancherv1.Cluster, managementKubeconfig, managementToken []byte) []runtime.Object {
+	labels := map[string]string{"app": autoscalerName}
+
+	args := []string{
+		"--cloud-provider=clusterapi",
+		"--cloud-config=" + path.Join(managementKubeconfigMountPath, managementKubeconfigKey),
+		fmt.Sprintf("--node-group-auto-discovery=clusterapi:namespace=%s,clusterName=%s", cluster.Namespace, cluster.Name),
+		"--namespace=" + namespaces.System,
+		"--leader-elect-resource-namespace=" + namespaces.System,
+	}
+	args = append(args, cluster.Spec.RKEConfig.Autoscaling.ExtraArgs...)
+
+	return []runtime.Object{
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      managementKubeconfigSecretName,
+				Namespace: namespaces.System,
+			},
+			Data: map[string][]byte{
+				managementKubeconfigKey: managementKubeconfig,
+				managementTokenKey:      managementToken,
+			},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+			},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: autoscalerName,
+			},
+			Rules: []rbacv1.PolicyRule{
+				{
+					APIGroups: []string{""},
+					Resources: []string{"events", "endpoints"},
+					Verbs:     []string{"create", "patch"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"pods/eviction"},
+					Verbs:     []string{"create"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"pods/status"},
+					Verbs:     []string{"update"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"nodes"},
+					Verbs:     []string{"get", "list", "watch", "update"},
+				},
+				{
+					APIGroups: []string{""},
+					Resources: []string{"namespaces", "pods", "services", "replicationcontrollers", "persistentvolumeclaims", "persistentvolumes", "configmaps"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{"apps"},
+					Resources: []string{"daemonsets", "replicasets", "statefulsets"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{"batch"},
+					Resources: []string{"jobs", "cronjobs"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+				{
+					APIGroups: []string{"policy"},
+					Resources: []string{"poddisruptionbudgets"},
+					Verbs:     []string{"list", "watch"},
+				},
+				{
+					APIGroups: []string{"storage.k8s.io"},
+					Resources: []string{"storageclasses", "csinodes", "csidrivers", "csistoragecapacities"},
+					Verbs:     []string{"get", "list", "watch"},
+				},
+			},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: autoscalerName,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      "ServiceAccount",
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+			}},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     autoscalerName,
+			},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+			},
+			Rules: []rbacv1.PolicyRule{
+				{
+					// status configmap
+					APIGroups: []string{""},
+					Resources: []string{"configmaps"},
+					Verbs:     []string{"create", "update", "patch", "delete"},
+				},
+				{
+					// leader election
+					APIGroups: []string{"coordination.k8s.io"},
+					Resources: []string{"leases"},
+					Verbs:     []string{"get", "create", "update"},
+				},
+			},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      "ServiceAccount",
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+			}},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "Role",
+				Name:     autoscalerName,
+			},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      autoscalerName,
+				Namespace: namespaces.System,
+				Labels:    labels,
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: &[]int32{1}[0],
+				Selector: &metav1.LabelSelector{
+					MatchLabels: labels,
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: labels,
+					},
+					Spec: corev1.PodSpec{
+						ServiceAccountName: autoscalerName,
+						NodeSelector: map[string]string{
+							corev1.LabelOSStable: "linux",
+						},
+						// the autoscaler is allowed to run on any node, including the control plane nodes that it never
+						// scales down.
+						Tolerations: []corev1.Toleration{{
+							Operator: corev1.TolerationOpExists,
+						}},
+						Containers: []corev1.Container{{
+							Name:    autoscalerName,
+							Image:   image.ResolveWithCluster(settings.ClusterAutoscalerImage.Get(), cluster),
+							Command: []string{"/cluster-autoscaler"},
+							Args:    args,
+							VolumeMounts: []corev1.VolumeMount{{
+								Name:      "management-kubeconfig",
+								MountPath: managementKubeconfigMountPath,
+								ReadOnly:  true,
+							}},
+						}},
+						Volumes: []corev1.Volume{{
+							Name: "management-kubeconfig",
+							VolumeSource: corev1.VolumeSource{
+								Secret: &corev1.SecretVolumeSource{
+									SecretName: managementKubeconfigSecretName,
+								},
+							},
+						}},
+					},
+				},
+			},
+		},
+	}
+}
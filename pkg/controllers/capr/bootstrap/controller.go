@@ -534,6 +534,18 @@ func (h *handler) reconcileMachinePreTerminateAnnotation(bootstrap *rkev1.RKEBoo
 		return bootstrap, err
 	}
 
+	// Refuse to remove the etcd member while the remaining members would not have quorum, as reported by the etcd member
+	// health check. The force remove annotation can be used to remove the member regardless.
+	deleting, err := h.deletingEtcdMachineNames(machine.Namespace, bootstrap.Spec.ClusterName)
+	if err != nil {
+		return bootstrap, err
+	}
+	if etcdmgmt.RemovalViolatesQuorum(cp.Status.ETCDMemberHealth, deleting) {
+		logrus.Warnf("[rkebootstrap] %s/%s: refusing to remove etcd member of machine %s/%s as the remaining etcd members would not have quorum", bootstrap.Namespace, bootstrap.Name, machine.Namespace, machine.Name)
+		h.rkeBootstrap.EnqueueAfter(bootstrap.Namespace, bootstrap.Name, 30*time.Second)
+		return bootstrap, generic.ErrSkip
+	}
+
 	removed, err := etcdmgmt.SafelyRemoved(restConfig, capr.GetRuntimeCommand(cp.Spec.KubernetesVersion), machine.Status.NodeRef.Name)
 	if err != nil {
 		return bootstrap, err
@@ -545,6 +557,23 @@ func (h *handler) reconcileMachinePreTerminateAnnotation(bootstrap *rkev1.RKEBoo
 	return h.ensureMachinePreTerminateAnnotationRemoved(bootstrap, machine)
 }
 
+// deletingEtcdMachineNames returns the names of the etcd machines of the given cluster that are deleting.
+func (h *handler) deletingEtcdMachineNames(namespace, clusterName string) ([]string, error) {
+	machines, err := h.machineCache.List(namespace, labels.SelectorFromSet(map[string]string{
+		capi.ClusterNameLabel: clusterName,
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("error listing machines for cluster %s/%s: %w", namespace, clusterName, err)
+	}
+	var names []string
+	for _, machine := range machines {
+		if _, isEtcd := machine.Labels[capr.EtcdRoleLabel]; isEtcd && !machine.DeletionTimestamp.IsZero() {
+			names = append(names, machine.Name)
+		}
+	}
+	return names, nil
+}
+
 // ensureMachinePreTerminateAnnotationRemoved removes the pre-terminate annotation from a CAPI machine when we removing the rkebootstrap, indicating the infrastructure can be deleted.
 func (h *handler) ensureMachinePreTerminateAnnotationRemoved(bootstrap *rkev1.RKEBootstrap, machine *capi.Machine) (*rkev1.RKEBootstrap, error) {
 	if machine == nil || machine.Annotations == nil {
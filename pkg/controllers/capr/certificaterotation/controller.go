@@ -0,0 +1,181 @@
+package certificaterotation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	provcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/robfig/cron"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const defaultExpiryThresholdDays = 30
+
+type handler struct {
+	controlPlanes    rkecontrollers.RKEControlPlaneController
+	provClusterCache provcontrollers.ClusterCache
+	provClusters     provcontrollers.ClusterClient
+}
+
+// Register sets up the automatic certificate rotation controller. When a control plane's certificate rotation policy
+// is enabled, the controller requests a certificate rotation of the services whose certificates expire within the
+// policy's threshold, as reported on the control plane status, during the policy's maintenance windows.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		controlPlanes:    clients.RKE.RKEControlPlane(),
+		provClusterCache: clients.Provisioning.Cluster().Cache(),
+		provClusters:     clients.Provisioning.Cluster(),
+	}
+	clients.RKE.RKEControlPlane().OnChange(ctx, "certificate-rotation-policy", h.OnChange)
+}
+
+func (h *handler) OnChange(_ string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if cp == nil || cp.DeletionTimestamp != nil || cp.Spec.CertificateRotationPolicy == nil || !cp.Spec.CertificateRotationPolicy.Enabled {
+		return cp, nil
+	}
+
+	// never request a rotation while another rotation is in progress, or the cluster is otherwise not ready. The control
+	// plane is re-enqueued when its status changes.
+	if cp.Spec.RotateCertificates != nil && cp.Status.CertificateRotationGeneration != cp.Spec.RotateCertificates.Generation {
+		return cp, nil
+	}
+	if !capr.Ready.IsTrue(cp) {
+		return cp, nil
+	}
+
+	policy := cp.Spec.CertificateRotationPolicy
+	now := time.Now()
+	threshold := time.Duration(defaultExpiryThresholdDays) * 24 * time.Hour
+	if policy.ExpiryThresholdDays > 0 {
+		threshold = time.Duration(policy.ExpiryThresholdDays) * 24 * time.Hour
+	}
+
+	services, all, nextExpiry := expiringServices(cp.Status.CertificateExpiry, now.Add(threshold))
+	if len(services) == 0 && !all {
+		if !nextExpiry.IsZero() {
+			h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, nextExpiry.Add(-threshold).Sub(now))
+		}
+		return cp, nil
+	}
+
+	open, nextOpen, err := inMaintenanceWindow(policy.MaintenanceWindows, now)
+	if err != nil {
+		logrus.Errorf("[certificaterotation] rkecluster %s/%s: skipping automatic certificate rotation: %v", cp.Namespace, cp.Name, err)
+		return cp, nil
+	}
+	if !open {
+		logrus.Debugf("[certificaterotation] rkecluster %s/%s: certificates are expiring, waiting for the maintenance window opening at %s", cp.Namespace, cp.Name, nextOpen.Format(time.RFC3339))
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, nextOpen.Sub(now))
+		return cp, nil
+	}
+
+	cluster, err := h.provClusterCache.Get(cp.Namespace, cp.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return cp, nil
+		}
+		return cp, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return cp, nil
+	}
+
+	// the cluster may have already been updated, in which case the new rotation has not made it to the control plane yet.
+	if rotation := cluster.Spec.RKEConfig.RotateCertificates; rotation != nil && (cp.Spec.RotateCertificates == nil || rotation.Generation != cp.Spec.RotateCertificates.Generation) {
+		return cp, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	rotation := &rkev1.RotateCertificates{Generation: 1}
+	if cluster.Spec.RKEConfig.RotateCertificates != nil {
+		rotation.Generation = cluster.Spec.RKEConfig.RotateCertificates.Generation + 1
+	}
+	if !all {
+		rotation.Services = services
+	}
+	cluster.Spec.RKEConfig.RotateCertificates = rotation
+
+	if all {
+		logrus.Infof("[certificaterotation] rkecluster %s/%s: certificates expire within %s, rotating all certificates", cp.Namespace, cp.Name, threshold)
+	} else {
+		logrus.Infof("[certificaterotation] rkecluster %s/%s: certificates expire within %s, rotating certificates for services [%s]", cp.Namespace, cp.Name, threshold, strings.Join(services, ", "))
+	}
+	_, err = h.provClusters.Update(cluster)
+	return cp, err
+}
+
+// expiringServices returns the sorted services whose certificates expire before the deadline, and whether any of the
+// expiring certificates can only be rotated by rotating all certificates. If no certificates expire before the deadline,
+// the earliest expiry of the reported certificates is returned instead.
+func expiringServices(expiry *rkev1.CertificateExpiryStatus, deadline time.Time) ([]string, bool, time.Time) {
+	if expiry == nil {
+		return nil, false, time.Time{}
+	}
+
+	var (
+		all        bool
+		nextExpiry time.Time
+		services   = map[string]bool{}
+	)
+	for _, machine := range expiry.Machines {
+		for _, certificate := range machine.Certificates {
+			expiration := certificate.ExpirationDate.Time
+			if expiration.Before(deadline) {
+				if certificate.Service == "" {
+					all = true
+				} else {
+					services[certificate.Service] = true
+				}
+				continue
+			}
+			if nextExpiry.IsZero() || expiration.Before(nextExpiry) {
+				nextExpiry = expiration
+			}
+		}
+	}
+
+	if len(services) == 0 && !all {
+		return nil, false, nextExpiry
+	}
+	result := make([]string, 0, len(services))
+	for service := range services {
+		result = append(result, service)
+	}
+	sort.Strings(result)
+	return result, all, time.Time{}
+}
+
+// inMaintenanceWindow returns true if now falls within any of the given maintenance windows, or if no windows are
+// given. Otherwise, the time at which the next window opens is returned.
+func inMaintenanceWindow(windows []rkev1.MaintenanceWindow, now time.Time) (bool, time.Time, error) {
+	if len(windows) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	var nextOpen time.Time
+	for _, window := range windows {
+		schedule, err := cron.ParseStandard(window.ScheduleCron)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid maintenance window schedule %q: %w", window.ScheduleCron, err)
+		}
+		if window.Duration.Duration <= 0 {
+			return false, time.Time{}, fmt.Errorf("maintenance window with schedule %q must have a positive duration", window.ScheduleCron)
+		}
+		// the window is open if it opened at some point within the last duration
+		if !schedule.Next(now.Add(-window.Duration.Duration)).After(now) {
+			return true, time.Time{}, nil
+		}
+		if next := schedule.Next(now); nextOpen.IsZero() || next.Before(nextOpen) {
+			nextOpen = next
+		}
+	}
+	return false, nextOpen, nil
+}
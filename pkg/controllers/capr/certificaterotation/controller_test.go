@@ -0,0 +1,101 @@
+package certificaterotation
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestExpiringServices(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	expiresIn := func(d time.Duration) metav1.Time {
+		return metav1.Time{Time: now.Add(d)}
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name           string
+		expiry         *rkev1.CertificateExpiryStatus
+		expectServices []string
+		expectAll      bool
+		expectNext     time.Time
+	}{
+		{
+			name: "no expiry reported",
+		},
+		{
+			name: "nothing expiring",
+			expiry: &rkev1.CertificateExpiryStatus{Machines: []rkev1.MachineCertificateExpiry{
+				{Certificates: []rkev1.CertificateExpiry{
+					{Service: "api-server", ExpirationDate: expiresIn(200 * day)},
+					{Service: "kubelet", ExpirationDate: expiresIn(100 * day)},
+				}},
+			}},
+			expectNext: now.Add(100 * day),
+		},
+		{
+			name: "services expiring across machines",
+			expiry: &rkev1.CertificateExpiryStatus{Machines: []rkev1.MachineCertificateExpiry{
+				{Certificates: []rkev1.CertificateExpiry{
+					{Service: "kubelet", ExpirationDate: expiresIn(10 * day)},
+					{Service: "api-server", ExpirationDate: expiresIn(200 * day)},
+				}},
+				{Certificates: []rkev1.CertificateExpiry{
+					{Service: "etcd", ExpirationDate: expiresIn(-day)},
+					{Service: "kubelet", ExpirationDate: expiresIn(5 * day)},
+				}},
+			}},
+			expectServices: []string{"etcd", "kubelet"},
+		},
+		{
+			name: "certificate without a service expiring",
+			expiry: &rkev1.CertificateExpiryStatus{Machines: []rkev1.MachineCertificateExpiry{
+				{Certificates: []rkev1.CertificateExpiry{
+					{ExpirationDate: expiresIn(day)},
+				}},
+			}},
+			expectServices: []string{},
+			expectAll:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			services, all, next := expiringServices(tt.expiry, now.Add(30*day))
+			assert.Equal(t, tt.expectServices, services)
+			assert.Equal(t, tt.expectAll, all)
+			assert.Equal(t, tt.expectNext, next)
+		})
+	}
+}
+
+func TestInMaintenanceWindow(t *testing.T) {
+	// Saturday 2024-06-01
+	saturday := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	weekendNights := []rkev1.MaintenanceWindow{
+		{ScheduleCron: "0 2 * * 6,0", Duration: metav1.Duration{Duration: 3 * time.Hour}},
+	}
+
+	open, _, err := inMaintenanceWindow(nil, saturday)
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, _, err = inMaintenanceWindow(weekendNights, saturday.Add(3*time.Hour))
+	require.NoError(t, err)
+	assert.True(t, open)
+
+	open, next, err := inMaintenanceWindow(weekendNights, saturday.Add(6*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, open)
+	assert.Equal(t, saturday.Add(26*time.Hour), next)
+
+	_, _, err = inMaintenanceWindow([]rkev1.MaintenanceWindow{{ScheduleCron: "not a schedule", Duration: metav1.Duration{Duration: time.Hour}}}, saturday)
+	assert.ErrorContains(t, err, "invalid maintenance window schedule")
+
+	_, _, err = inMaintenanceWindow([]rkev1.MaintenanceWindow{{ScheduleCron: "0 2 * * *"}}, saturday)
+	assert.ErrorContains(t, err, "positive duration")
+}
@@ -5,8 +5,12 @@
 
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/capr/planner"
+	"github.com/rancher/rancher/pkg/controllers/capr/autoscaler"
 	"github.com/rancher/rancher/pkg/controllers/capr/bootstrap"
+	"github.com/rancher/rancher/pkg/controllers/capr/certificaterotation"
 	"github.com/rancher/rancher/pkg/controllers/capr/dynamicschema"
+	"github.com/rancher/rancher/pkg/controllers/capr/encryptionkeyrotation"
+	"github.com/rancher/rancher/pkg/controllers/capr/etcdsnapshotverification"
 	"github.com/rancher/rancher/pkg/controllers/capr/machinedrain"
 	"github.com/rancher/rancher/pkg/controllers/capr/machinenodelookup"
 	"github.com/rancher/rancher/pkg/controllers/capr/machineprovision"
@@ -36,6 +40,7 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 	if features.MCM.Enabled() {
 		dynamicschema.Register(ctx, clients)
 		machineprovision.Register(ctx, clients, kubeconfigManager)
+		autoscaler.Register(ctx, clients, kubeconfigManager)
 	}
 	rkecluster.Register(ctx, clients)
 	bootstrap.Register(ctx, clients)
@@ -46,4 +51,7 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 	rkecontrolplane.Register(ctx, clients)
 	managesystemagent.Register(ctx, clients)
 	machinedrain.Register(ctx, clients)
+	etcdsnapshotverification.Register(ctx, clients)
+	certificaterotation.Register(ctx, clients)
+	encryptionkeyrotation.Register(ctx, clients)
 }
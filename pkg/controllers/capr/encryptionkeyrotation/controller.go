@@ -0,0 +1,122 @@
+package encryptionkeyrotation
+
+import (
+	"context"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	provcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const defaultIntervalDays = 90
+
+type handler struct {
+	controlPlanes    rkecontrollers.RKEControlPlaneController
+	provClusterCache provcontrollers.ClusterCache
+	provClusters     provcontrollers.ClusterClient
+	backoff          *flowcontrol.Backoff
+}
+
+// Register sets up the scheduled encryption key rotation controller. When a control plane's encryption key rotation
+// schedule is enabled, the controller requests an encryption key rotation once the configured interval has elapsed
+// since the keys were last rotated.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		controlPlanes:    clients.RKE.RKEControlPlane(),
+		provClusterCache: clients.Provisioning.Cluster().Cache(),
+		provClusters:     clients.Provisioning.Cluster(),
+		backoff:          flowcontrol.NewBackOff(time.Minute, time.Hour),
+	}
+	clients.RKE.RKEControlPlane().OnChange(ctx, "encryption-key-rotation-schedule", h.OnChange)
+}
+
+func (h *handler) OnChange(key string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if cp == nil || cp.DeletionTimestamp != nil || cp.Spec.EncryptionKeyRotationSchedule == nil || !cp.Spec.EncryptionKeyRotationSchedule.Enabled {
+		h.backoff.DeleteEntry(key)
+		return cp, nil
+	}
+
+	// a rotation that has been requested but not yet completed is left to the planner. A failed rotation requires an
+	// etcd restore, so it is never retried automatically.
+	if rotation := cp.Spec.RotateEncryptionKeys; rotation != nil {
+		if cp.Status.RotateEncryptionKeys == nil || cp.Status.RotateEncryptionKeys.Generation != rotation.Generation {
+			return cp, nil
+		}
+		if cp.Status.RotateEncryptionKeysPhase == rkev1.RotateEncryptionKeysPhaseFailed {
+			logrus.Debugf("[encryptionkeyrotation] rkecluster %s/%s: skipping scheduled encryption key rotation as the last rotation failed", cp.Namespace, cp.Name)
+			return cp, nil
+		}
+		if cp.Status.RotateEncryptionKeysPhase != rkev1.RotateEncryptionKeysPhaseDone {
+			return cp, nil
+		}
+	}
+
+	now := time.Now()
+	due := nextRotation(cp, now)
+	if now.Before(due) {
+		h.backoff.DeleteEntry(key)
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, due.Sub(now))
+		return cp, nil
+	}
+
+	if !capr.Ready.IsTrue(cp) {
+		h.backoff.Next(key, now)
+		delay := h.backoff.Get(key)
+		logrus.Infof("[encryptionkeyrotation] rkecluster %s/%s: encryption key rotation is due but the cluster is not ready, retrying in %s", cp.Namespace, cp.Name, delay)
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, delay)
+		return cp, nil
+	}
+
+	cluster, err := h.provClusterCache.Get(cp.Namespace, cp.Name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return cp, nil
+		}
+		return cp, err
+	}
+	if cluster.Spec.RKEConfig == nil {
+		return cp, nil
+	}
+
+	// the cluster may have already been updated, in which case the new rotation has not made it to the control plane yet.
+	if rotation := cluster.Spec.RKEConfig.RotateEncryptionKeys; rotation != nil && (cp.Spec.RotateEncryptionKeys == nil || rotation.Generation != cp.Spec.RotateEncryptionKeys.Generation) {
+		return cp, nil
+	}
+
+	cluster = cluster.DeepCopy()
+	rotation := &rkev1.RotateEncryptionKeys{Generation: 1}
+	if cluster.Spec.RKEConfig.RotateEncryptionKeys != nil {
+		rotation.Generation = cluster.Spec.RKEConfig.RotateEncryptionKeys.Generation + 1
+	}
+	cluster.Spec.RKEConfig.RotateEncryptionKeys = rotation
+
+	logrus.Infof("[encryptionkeyrotation] rkecluster %s/%s: encryption keys were last rotated %d days ago, rotating encryption keys", cp.Namespace, cp.Name, cp.Status.EncryptionKeyAgeDays)
+	if _, err := h.provClusters.Update(cluster); err != nil {
+		return cp, err
+	}
+	h.backoff.DeleteEntry(key)
+	return cp, nil
+}
+
+// nextRotation returns the time at which the encryption keys of the control plane are due to be rotated, based on the
+// last successful rotation or, if the keys have never been rotated, the creation of the control plane.
+func nextRotation(cp *rkev1.RKEControlPlane, now time.Time) time.Time {
+	last := cp.CreationTimestamp.Time
+	if cp.Status.EncryptionKeyRotationTime != nil {
+		last = cp.Status.EncryptionKeyRotationTime.Time
+	}
+	if last.IsZero() {
+		last = now
+	}
+	intervalDays := defaultIntervalDays
+	if cp.Spec.EncryptionKeyRotationSchedule != nil && cp.Spec.EncryptionKeyRotationSchedule.IntervalDays > 0 {
+		intervalDays = cp.Spec.EncryptionKeyRotationSchedule.IntervalDays
+	}
+	return last.Add(time.Duration(intervalDays) * 24 * time.Hour)
+}
@@ -0,0 +1,56 @@
+package encryptionkeyrotation
+
+import (
+	"testing"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNextRotation(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	created := metav1.Time{Time: now.Add(-100 * day)}
+	rotated := metav1.Time{Time: now.Add(-10 * day)}
+
+	tests := []struct {
+		name     string
+		cp       *rkev1.RKEControlPlane
+		expected time.Time
+	}{
+		{
+			name: "never rotated uses the default interval from creation",
+			cp: &rkev1.RKEControlPlane{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: rkev1.RKEControlPlaneSpec{RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+					EncryptionKeyRotationSchedule: &rkev1.EncryptionKeyRotationSchedule{Enabled: true},
+				}},
+			},
+			expected: now.Add(-10 * day),
+		},
+		{
+			name: "rotated uses the configured interval from the last rotation",
+			cp: &rkev1.RKEControlPlane{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: created},
+				Spec: rkev1.RKEControlPlaneSpec{RKEClusterSpecCommon: rkev1.RKEClusterSpecCommon{
+					EncryptionKeyRotationSchedule: &rkev1.EncryptionKeyRotationSchedule{Enabled: true, IntervalDays: 30},
+				}},
+				Status: rkev1.RKEControlPlaneStatus{EncryptionKeyRotationTime: &rotated},
+			},
+			expected: now.Add(20 * day),
+		},
+		{
+			name:     "not yet created",
+			cp:       &rkev1.RKEControlPlane{},
+			expected: now.Add(90 * day),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, nextRotation(tt.cp, now))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package etcdmgmt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// EndpointStatus is the status of a single etcd member, as reported by `etcdctl endpoint status -w json`.
+type EndpointStatus struct {
+	Endpoint string `json:"Endpoint"`
+	Status   struct {
+		Header struct {
+			ClusterID uint64 `json:"cluster_id"`
+			MemberID  uint64 `json:"member_id"`
+			Revision  int64  `json:"revision"`
+			RaftTerm  uint64 `json:"raft_term"`
+		} `json:"header"`
+		Version          string   `json:"version"`
+		DBSize           int64    `json:"dbSize"`
+		DBSizeInUse      int64    `json:"dbSizeInUse"`
+		Leader           uint64   `json:"leader"`
+		RaftIndex        uint64   `json:"raftIndex"`
+		RaftTerm         uint64   `json:"raftTerm"`
+		RaftAppliedIndex uint64   `json:"raftAppliedIndex"`
+		Errors           []string `json:"errors"`
+	} `json:"Status"`
+}
+
+// MemberID returns the member ID of the endpoint in the hex format used by etcdctl.
+func (e EndpointStatus) MemberID() string {
+	return FormatMemberID(e.Status.Header.MemberID)
+}
+
+// IsLeader returns true if the endpoint is the raft leader.
+func (e EndpointStatus) IsLeader() bool {
+	return e.Status.Header.MemberID != 0 && e.Status.Header.MemberID == e.Status.Leader
+}
+
+// FormatMemberID formats an etcd member ID in the hex format used by etcdctl.
+func FormatMemberID(id uint64) string {
+	return strconv.FormatUint(id, 16)
+}
+
+// ParseEndpointStatus parses the output of `etcdctl endpoint status -w json`.
+func ParseEndpointStatus(output []byte) ([]EndpointStatus, error) {
+	var statuses []EndpointStatus
+	if err := json.Unmarshal(output, &statuses); err != nil {
+		return nil, fmt.Errorf("error parsing etcd endpoint status: %w", err)
+	}
+	if len(statuses) == 0 {
+		return nil, fmt.Errorf("etcd endpoint status did not contain any endpoints")
+	}
+	return statuses, nil
+}
@@ -0,0 +1,41 @@
+package etcdmgmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEndpointStatus(t *testing.T) {
+	output := []byte(`[{"Endpoint":"https://127.0.0.1:2379","Status":{"header":{"cluster_id":15793309364733512342,"member_id":12417846373153347478,"revision":84213,"raft_term":4},"version":"3.5.13","dbSize":41435136,"leader":12417846373153347478,"raftIndex":98733,"raftTerm":4,"raftAppliedIndex":98733,"dbSizeInUse":18710528}}]`)
+
+	statuses, err := ParseEndpointStatus(output)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+
+	status := statuses[0]
+	assert.Equal(t, "ac550d7bdb2d9796", status.MemberID())
+	assert.True(t, status.IsLeader())
+	assert.Equal(t, int64(84213), status.Status.Header.Revision)
+	assert.Equal(t, int64(41435136), status.Status.DBSize)
+	assert.Equal(t, int64(18710528), status.Status.DBSizeInUse)
+	assert.Equal(t, uint64(98733), status.Status.RaftIndex)
+}
+
+func TestParseEndpointStatusFollower(t *testing.T) {
+	output := []byte(`[{"Endpoint":"https://127.0.0.1:2379","Status":{"header":{"member_id":2},"leader":1,"dbSize":10}}]`)
+
+	statuses, err := ParseEndpointStatus(output)
+	require.NoError(t, err)
+	assert.False(t, statuses[0].IsLeader())
+	assert.Equal(t, "2", statuses[0].MemberID())
+}
+
+func TestParseEndpointStatusInvalid(t *testing.T) {
+	_, err := ParseEndpointStatus([]byte("Error: context deadline exceeded"))
+	assert.ErrorContains(t, err, "error parsing etcd endpoint status")
+
+	_, err = ParseEndpointStatus([]byte("[]"))
+	assert.ErrorContains(t, err, "did not contain any endpoints")
+}
@@ -0,0 +1,174 @@
+package etcdmgmt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+)
+
+// MemberList is the membership of the etcd cluster, as reported by `etcdctl member list -w json`.
+type MemberList struct {
+	Header struct {
+		ClusterID uint64 `json:"cluster_id"`
+		MemberID  uint64 `json:"member_id"`
+	} `json:"header"`
+	Members []struct {
+		ID         uint64   `json:"ID"`
+		Name       string   `json:"name"`
+		PeerURLs   []string `json:"peerURLs"`
+		ClientURLs []string `json:"clientURLs"`
+		IsLearner  bool     `json:"isLearner"`
+	} `json:"members"`
+}
+
+// AlarmList is the list of active alarms, as reported by `etcdctl alarm list -w json`.
+type AlarmList struct {
+	Alarms []struct {
+		MemberID uint64 `json:"memberID"`
+		Alarm    int    `json:"alarm"`
+	} `json:"alarms"`
+}
+
+// MemberHealth is the output of the etcd member health check, which consists of the member list, the endpoint status of
+// every member of the cluster, and the active alarms, in that order.
+type MemberHealth struct {
+	MemberList MemberList
+	Endpoints  []EndpointStatus
+	Alarms     AlarmList
+}
+
+// LocalMemberID returns the ID of the member that the health check was run against.
+func (m *MemberHealth) LocalMemberID() string {
+	return FormatMemberID(m.MemberList.Header.MemberID)
+}
+
+// ParseMemberHealth parses the output of the etcd member health check.
+func ParseMemberHealth(output []byte) (*MemberHealth, error) {
+	health := &MemberHealth{}
+	decoder := json.NewDecoder(bytes.NewReader(output))
+	if err := decoder.Decode(&health.MemberList); err != nil {
+		return nil, fmt.Errorf("error parsing etcd member list: %w", err)
+	}
+	if err := decoder.Decode(&health.Endpoints); err != nil {
+		return nil, fmt.Errorf("error parsing etcd endpoint status: %w", err)
+	}
+	if err := decoder.Decode(&health.Alarms); err != nil {
+		return nil, fmt.Errorf("error parsing etcd alarm list: %w", err)
+	}
+	if len(health.MemberList.Members) == 0 {
+		return nil, fmt.Errorf("etcd member list did not contain any members")
+	}
+	return health, nil
+}
+
+// Members returns the health of each member of the etcd cluster. Members that did not report an endpoint status are
+// considered unhealthy. The raft index lag of each member is calculated relative to the leader, if the leader reported.
+func (m *MemberHealth) Members() []rkev1.ETCDMemberHealth {
+	endpoints := map[uint64]EndpointStatus{}
+	var leaderRaftIndex uint64
+	for _, endpoint := range m.Endpoints {
+		endpoints[endpoint.Status.Header.MemberID] = endpoint
+		if endpoint.IsLeader() {
+			leaderRaftIndex = endpoint.Status.RaftIndex
+		}
+	}
+
+	alarms := map[uint64][]string{}
+	for _, alarm := range m.Alarms.Alarms {
+		alarms[alarm.MemberID] = append(alarms[alarm.MemberID], alarmName(alarm.Alarm))
+	}
+
+	members := make([]rkev1.ETCDMemberHealth, 0, len(m.MemberList.Members))
+	for _, member := range m.MemberList.Members {
+		health := rkev1.ETCDMemberHealth{
+			Name:     member.Name,
+			MemberID: FormatMemberID(member.ID),
+			Learner:  member.IsLearner,
+			Alarms:   alarms[member.ID],
+		}
+		if endpoint, ok := endpoints[member.ID]; ok {
+			health.Healthy = len(endpoint.Status.Errors) == 0
+			health.Leader = endpoint.IsLeader()
+			health.DBSize = endpoint.Status.DBSize
+			health.DBSizeInUse = endpoint.Status.DBSizeInUse
+			health.RaftIndex = int64(endpoint.Status.RaftIndex)
+			health.Errors = endpoint.Status.Errors
+			if leaderRaftIndex > endpoint.Status.RaftIndex {
+				health.RaftIndexLag = int64(leaderRaftIndex - endpoint.Status.RaftIndex)
+			}
+		} else {
+			health.Errors = []string{"member did not respond to the endpoint status request"}
+		}
+		members = append(members, health)
+	}
+	return members
+}
+
+// alarmName returns the name of an etcd alarm type, as defined by etcdserverpb.AlarmType.
+func alarmName(alarm int) string {
+	switch alarm {
+	case 1:
+		return "NOSPACE"
+	case 2:
+		return "CORRUPT"
+	default:
+		return strconv.Itoa(alarm)
+	}
+}
+
+// Quorum returns the number of healthy voting members required for quorum, along with the number of healthy voting
+// members. Learners are not voting members, and are therefore ignored.
+func Quorum(members []rkev1.ETCDMemberHealth) (quorum, healthy int) {
+	var voting int
+	for _, member := range members {
+		if member.Learner {
+			continue
+		}
+		voting++
+		if member.Healthy {
+			healthy++
+		}
+	}
+	if voting == 0 {
+		return 0, 0
+	}
+	return voting/2 + 1, healthy
+}
+
+// RemovalViolatesQuorum returns true if removing the etcd members running on the given machines would leave the
+// remaining members without quorum. Members that are no longer part of the etcd cluster are ignored. If the health of
+// the etcd cluster is unknown, or every member is being removed, removal is not considered to violate quorum.
+func RemovalViolatesQuorum(health *rkev1.ETCDMemberHealthStatus, machineNames []string) bool {
+	if health == nil || len(machineNames) == 0 {
+		return false
+	}
+
+	removing := map[string]bool{}
+	for _, machineName := range machineNames {
+		removing[machineName] = true
+	}
+
+	var (
+		remaining []rkev1.ETCDMemberHealth
+		removed   bool
+	)
+	for _, member := range health.Members {
+		if member.MachineName != "" && removing[member.MachineName] {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, member)
+	}
+	if !removed {
+		return false
+	}
+
+	quorum, healthy := Quorum(remaining)
+	if quorum == 0 {
+		return false
+	}
+	return healthy < quorum
+}
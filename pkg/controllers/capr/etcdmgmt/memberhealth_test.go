@@ -0,0 +1,157 @@
+package etcdmgmt
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const memberHealthOutput = `{"header":{"cluster_id":100,"member_id":1,"raft_term":3},"members":[{"ID":1,"name":"node-a-11111111","peerURLs":["https://10.0.0.1:2380"],"clientURLs":["https://10.0.0.1:2379"]},{"ID":2,"name":"node-b-22222222","peerURLs":["https://10.0.0.2:2380"],"clientURLs":["https://10.0.0.2:2379"]},{"ID":3,"name":"node-c-33333333","peerURLs":["https://10.0.0.3:2380"],"clientURLs":["https://10.0.0.3:2379"]}]}
+[{"Endpoint":"https://10.0.0.1:2379","Status":{"header":{"member_id":1,"revision":50},"leader":1,"dbSize":2048,"dbSizeInUse":1024,"raftIndex":500}},{"Endpoint":"https://10.0.0.2:2379","Status":{"header":{"member_id":2,"revision":50},"leader":1,"dbSize":4096,"dbSizeInUse":1024,"raftIndex":480}}]
+{"header":{"cluster_id":100,"member_id":1},"alarms":[{"memberID":2,"alarm":1}]}
+`
+
+func TestParseMemberHealth(t *testing.T) {
+	health, err := ParseMemberHealth([]byte(memberHealthOutput))
+	require.NoError(t, err)
+	assert.Equal(t, "1", health.LocalMemberID())
+
+	members := health.Members()
+	require.Len(t, members, 3)
+
+	assert.Equal(t, "node-a-11111111", members[0].Name)
+	assert.True(t, members[0].Healthy)
+	assert.True(t, members[0].Leader)
+	assert.Equal(t, int64(0), members[0].RaftIndexLag)
+
+	assert.True(t, members[1].Healthy)
+	assert.False(t, members[1].Leader)
+	assert.Equal(t, int64(20), members[1].RaftIndexLag)
+	assert.Equal(t, int64(4096), members[1].DBSize)
+	assert.Equal(t, []string{"NOSPACE"}, members[1].Alarms)
+
+	assert.False(t, members[2].Healthy)
+	assert.NotEmpty(t, members[2].Errors)
+
+	quorum, healthy := Quorum(members)
+	assert.Equal(t, 2, quorum)
+	assert.Equal(t, 2, healthy)
+}
+
+func TestParseMemberHealthInvalid(t *testing.T) {
+	_, err := ParseMemberHealth([]byte("Error: context deadline exceeded"))
+	assert.ErrorContains(t, err, "error parsing etcd member list")
+
+	_, err = ParseMemberHealth([]byte(`{"members":[{"ID":1}]}`))
+	assert.ErrorContains(t, err, "error parsing etcd endpoint status")
+
+	_, err = ParseMemberHealth([]byte(`{"members":[]} [] {}`))
+	assert.ErrorContains(t, err, "did not contain any members")
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		name          string
+		members       []rkev1.ETCDMemberHealth
+		expectQuorum  int
+		expectHealthy int
+	}{
+		{
+			name: "no members",
+		},
+		{
+			name:          "single member",
+			members:       []rkev1.ETCDMemberHealth{{Healthy: true}},
+			expectQuorum:  1,
+			expectHealthy: 1,
+		},
+		{
+			name:          "learners are not voting members",
+			members:       []rkev1.ETCDMemberHealth{{Healthy: true}, {Healthy: true}, {Healthy: true, Learner: true}},
+			expectQuorum:  2,
+			expectHealthy: 2,
+		},
+		{
+			name:          "four members with one unhealthy",
+			members:       []rkev1.ETCDMemberHealth{{Healthy: true}, {Healthy: true}, {Healthy: true}, {}},
+			expectQuorum:  3,
+			expectHealthy: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quorum, healthy := Quorum(tt.members)
+			assert.Equal(t, tt.expectQuorum, quorum)
+			assert.Equal(t, tt.expectHealthy, healthy)
+		})
+	}
+}
+
+func TestRemovalViolatesQuorum(t *testing.T) {
+	threeHealthy := &rkev1.ETCDMemberHealthStatus{
+		Members: []rkev1.ETCDMemberHealth{
+			{MachineName: "a", Healthy: true},
+			{MachineName: "b", Healthy: true},
+			{MachineName: "c", Healthy: true},
+		},
+	}
+	oneUnhealthy := &rkev1.ETCDMemberHealthStatus{
+		Members: []rkev1.ETCDMemberHealth{
+			{MachineName: "a", Healthy: true},
+			{MachineName: "b", Healthy: true},
+			{MachineName: "c"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		health   *rkev1.ETCDMemberHealthStatus
+		machines []string
+		expected bool
+	}{
+		{
+			name:     "unknown health",
+			machines: []string{"a"},
+		},
+		{
+			name:     "nothing removed",
+			health:   threeHealthy,
+			machines: []string{"d"},
+		},
+		{
+			name:     "remove one healthy member",
+			health:   threeHealthy,
+			machines: []string{"a"},
+		},
+		{
+			name:     "remove two healthy members",
+			health:   threeHealthy,
+			machines: []string{"a", "b"},
+		},
+		{
+			name:     "remove the unhealthy member",
+			health:   oneUnhealthy,
+			machines: []string{"c"},
+		},
+		{
+			name:     "remove a healthy member while another is unhealthy",
+			health:   oneUnhealthy,
+			machines: []string{"a"},
+			expected: true,
+		},
+		{
+			name:     "remove every member",
+			health:   oneUnhealthy,
+			machines: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, RemovalViolatesQuorum(tt.health, tt.machines))
+		})
+	}
+}
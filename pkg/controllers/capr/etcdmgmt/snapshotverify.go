@@ -0,0 +1,214 @@
+package etcdmgmt
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// etcd appends a sha256 checksum of the database to snapshots that are streamed from a member. Snapshots that contain
+	// the checksum have a size that is a multiple of 512 plus the size of the checksum.
+	snapshotHashAlignment = 512
+
+	keyBucketName = "key"
+	// revisionBytesLen is the length of the revision prefix of keys in the etcd key bucket, which is the big endian
+	// encoded main revision followed by a separator and the big endian encoded sub revision.
+	revisionBytesLen = 8 + 1 + 8
+)
+
+// ErrSnapshotCorrupt is wrapped by the errors of VerifySnapshot that indicate the snapshot was read in full but failed an
+// integrity check, as opposed to errors that occurred while reading it.
+var ErrSnapshotCorrupt = errors.New("etcd snapshot is corrupt")
+
+// corrupt marks err as an integrity failure of the snapshot.
+func corrupt(err error) error {
+	return fmt.Errorf("%w: %w", ErrSnapshotCorrupt, err)
+}
+
+// SnapshotVerification contains the results of verifying the integrity of an etcd snapshot.
+type SnapshotVerification struct {
+	// SHA256 is the hex encoded checksum of the snapshot database.
+	SHA256 string
+	// HashChecked is true if the snapshot contained an embedded checksum that matched the database.
+	HashChecked bool
+	// Revision is the latest main revision found in the key bucket.
+	Revision int64
+	// KeyCount is the total number of keys across all buckets.
+	KeyCount int64
+	// DBSize is the size of the snapshot database, excluding the embedded checksum.
+	DBSize int64
+}
+
+// VerifySnapshot verifies the integrity of the etcd snapshot in the given reader. If compressed is true, the snapshot is
+// expected to be a zip archive containing a single snapshot file, as created by the --etcd-snapshot-compress option.
+// The snapshot is written to a temporary file within tmpDir so the bbolt database can be opened and walked.
+func VerifySnapshot(r io.Reader, compressed bool, tmpDir string) (*SnapshotVerification, error) {
+	f, err := os.CreateTemp(tmpDir, "etcd-snapshot-verify-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	var size int64
+	if compressed {
+		size, err = copyFromZip(f, r, tmpDir)
+	} else {
+		size, err = io.Copy(f, r)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading snapshot: %w", err)
+	}
+
+	result := &SnapshotVerification{
+		DBSize: size,
+	}
+
+	if size%snapshotHashAlignment == sha256.Size {
+		if result.SHA256, err = verifyEmbeddedHash(f, size); err != nil {
+			return result, err
+		}
+		result.HashChecked = true
+		result.DBSize = size - sha256.Size
+		// bbolt does not expect trailing data after the last page, so the checksum is removed before the database is opened.
+		if err := f.Truncate(result.DBSize); err != nil {
+			return result, err
+		}
+	} else {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return result, err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return result, err
+		}
+		result.SHA256 = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if err := f.Close(); err != nil {
+		return result, err
+	}
+
+	if err := walkSnapshotDB(f.Name(), result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// verifyEmbeddedHash compares the checksum at the end of the snapshot with the checksum of the preceding database, and
+// returns the hex encoded checksum if they match.
+func verifyEmbeddedHash(f *os.File, size int64) (string, error) {
+	expected := make([]byte, sha256.Size)
+	if _, err := f.ReadAt(expected, size-sha256.Size); err != nil {
+		return "", fmt.Errorf("error reading embedded snapshot hash: %w", err)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f, 0, size-sha256.Size)); err != nil {
+		return "", fmt.Errorf("error computing snapshot hash: %w", err)
+	}
+	actual := h.Sum(nil)
+	if !bytes.Equal(expected, actual) {
+		return hex.EncodeToString(actual), corrupt(fmt.Errorf("snapshot hash mismatch: expected %s, got %s", hex.EncodeToString(expected), hex.EncodeToString(actual)))
+	}
+	return hex.EncodeToString(actual), nil
+}
+
+// walkSnapshotDB opens the bbolt database at the given path read-only, checks the consistency of every page, and
+// populates the revision and key count of the result.
+func walkSnapshotDB(path string, result *SnapshotVerification) error {
+	db, err := bolt.Open(path, 0400, &bolt.Options{ReadOnly: true, Timeout: 10 * time.Second})
+	if err != nil {
+		return corrupt(fmt.Errorf("error opening snapshot database: %w", err))
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bolt.Tx) error {
+		var errs []error
+		for err := range tx.Check() {
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return corrupt(fmt.Errorf("snapshot database consistency check failed: %w", errors.Join(errs...)))
+		}
+
+		var keyCount int64
+		if err := tx.ForEach(func(_ []byte, b *bolt.Bucket) error {
+			keyCount += int64(b.Stats().KeyN)
+			return nil
+		}); err != nil {
+			return err
+		}
+		result.KeyCount = keyCount
+
+		b := tx.Bucket([]byte(keyBucketName))
+		if b == nil {
+			return corrupt(fmt.Errorf("snapshot database does not contain the %s bucket", keyBucketName))
+		}
+		k, _ := b.Cursor().Last()
+		if k == nil {
+			// an empty key bucket is valid for a cluster that has never had a write, which is unlikely but not corrupt.
+			return nil
+		}
+		if len(k) < revisionBytesLen {
+			return corrupt(fmt.Errorf("snapshot database contains an invalid revision key of length %d", len(k)))
+		}
+		result.Revision = int64(binary.BigEndian.Uint64(k[:8]))
+		return nil
+	})
+}
+
+// copyFromZip copies the single snapshot file contained in the zip archive in the given reader to dst, and returns the
+// number of bytes copied.
+func copyFromZip(dst io.Writer, r io.Reader, tmpDir string) (int64, error) {
+	// zip archives require random access, so the archive is first written to disk.
+	zf, err := os.CreateTemp(tmpDir, "etcd-snapshot-verify-zip-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(zf.Name())
+	defer zf.Close()
+
+	size, err := io.Copy(zf, r)
+	if err != nil {
+		return 0, err
+	}
+
+	zr, err := zip.NewReader(zf, size)
+	if err != nil {
+		return 0, corrupt(fmt.Errorf("error opening compressed snapshot: %w", err))
+	}
+	if len(zr.File) != 1 {
+		return 0, corrupt(fmt.Errorf("compressed snapshot contained %d files, expected 1", len(zr.File)))
+	}
+
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		return 0, corrupt(fmt.Errorf("error opening %s in compressed snapshot: %w", zr.File[0].Name, err))
+	}
+	defer rc.Close()
+
+	n, err := io.Copy(dst, rc)
+	var corruptInput flate.CorruptInputError
+	if errors.Is(err, zip.ErrChecksum) || errors.As(err, &corruptInput) {
+		return n, corrupt(fmt.Errorf("error decompressing %s: %w", zr.File[0].Name, err))
+	}
+	return n, err
+}
+
+// IsCompressedSnapshot returns true if the snapshot file name indicates the snapshot is a zip archive.
+func IsCompressedSnapshot(name string) bool {
+	return strings.EqualFold(filepath.Ext(name), ".zip")
+}
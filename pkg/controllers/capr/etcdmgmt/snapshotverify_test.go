@@ -0,0 +1,165 @@
+package etcdmgmt
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+// newTestSnapshotDB creates a bbolt database laid out like an etcd backend with the given number of revisions and
+// returns its contents.
+func newTestSnapshotDB(t *testing.T, revisions int) []byte {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		keys, err := tx.CreateBucket([]byte(keyBucketName))
+		if err != nil {
+			return err
+		}
+		for i := 1; i <= revisions; i++ {
+			k := make([]byte, revisionBytesLen)
+			binary.BigEndian.PutUint64(k[:8], uint64(i))
+			k[8] = '_'
+			if err := keys.Put(k, []byte("value")); err != nil {
+				return err
+			}
+		}
+		meta, err := tx.CreateBucket([]byte("meta"))
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("consistent_index"), []byte{0, 0, 0, 0, 0, 0, 0, 1})
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
+
+func withHash(db []byte) []byte {
+	sum := sha256.Sum256(db)
+	return append(append([]byte{}, db...), sum[:]...)
+}
+
+func zipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create("etcd-snapshot")
+	require.NoError(t, err)
+	_, err = w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	db := newTestSnapshotDB(t, 5)
+	dbSum := sha256.Sum256(db)
+
+	corruptHash := withHash(db)
+	corruptHash[len(corruptHash)-1] ^= 0xff
+
+	tests := []struct {
+		name        string
+		data        []byte
+		compressed  bool
+		expectErr   string
+		hashChecked bool
+	}{
+		{
+			name: "snapshot without embedded hash",
+			data: db,
+		},
+		{
+			name:        "snapshot with embedded hash",
+			data:        withHash(db),
+			hashChecked: true,
+		},
+		{
+			name:        "compressed snapshot",
+			data:        zipped(t, withHash(db)),
+			compressed:  true,
+			hashChecked: true,
+		},
+		{
+			name:      "embedded hash mismatch",
+			data:      corruptHash,
+			expectErr: "snapshot hash mismatch",
+		},
+		{
+			name:      "not a bbolt database",
+			data:      bytes.Repeat([]byte{1}, 4096),
+			expectErr: "error opening snapshot database",
+		},
+		{
+			name:       "compressed snapshot is not a zip archive",
+			data:       db,
+			compressed: true,
+			expectErr:  "error opening compressed snapshot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifySnapshot(bytes.NewReader(tt.data), tt.compressed, t.TempDir())
+			if tt.expectErr != "" {
+				assert.ErrorContains(t, err, tt.expectErr)
+				assert.ErrorIs(t, err, ErrSnapshotCorrupt)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.hashChecked, result.HashChecked)
+			assert.Equal(t, hex.EncodeToString(dbSum[:]), result.SHA256)
+			assert.Equal(t, int64(len(db)), result.DBSize)
+			assert.Equal(t, int64(5), result.Revision)
+			assert.Equal(t, int64(6), result.KeyCount)
+		})
+	}
+}
+
+func TestVerifySnapshotMissingKeyBucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := bolt.Open(path, 0600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("meta"))
+		return err
+	}))
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = VerifySnapshot(bytes.NewReader(data), false, t.TempDir())
+	assert.ErrorContains(t, err, "does not contain the key bucket")
+	assert.ErrorIs(t, err, ErrSnapshotCorrupt)
+}
+
+func TestVerifySnapshotReadError(t *testing.T) {
+	_, err := VerifySnapshot(iotest.ErrReader(errors.New("connection reset")), false, t.TempDir())
+	assert.ErrorContains(t, err, "connection reset")
+	assert.NotErrorIs(t, err, ErrSnapshotCorrupt)
+}
+
+func TestIsCompressedSnapshot(t *testing.T) {
+	assert.True(t, IsCompressedSnapshot("etcd-snapshot-node1-1700000000.zip"))
+	assert.True(t, IsCompressedSnapshot("etcd-snapshot-node1-1700000000.ZIP"))
+	assert.False(t, IsCompressedSnapshot("etcd-snapshot-node1-1700000000"))
+}
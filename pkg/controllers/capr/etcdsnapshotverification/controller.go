@@ -0,0 +1,324 @@
+package etcdsnapshotverification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/controllers/capr/etcdmgmt"
+	rkecontrollers "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/metrics"
+	"github.com/rancher/rancher/pkg/wrangler"
+	"github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	"github.com/robfig/cron"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	defaultScheduleCron = "0 0 * * *"
+
+	// verificationTimeout bounds the time spent downloading and verifying a single snapshot.
+	verificationTimeout = 15 * time.Minute
+
+	// retryInterval is the time to wait before retrying a verification that failed to download or read the snapshot.
+	retryInterval = 5 * time.Minute
+
+	snapshotStatusFailed = "failed"
+)
+
+// verification is a snapshot verification started by the handler for a control plane.
+type verification struct {
+	snapshot *rkev1.ETCDSnapshot
+	done     bool
+	started  time.Time
+	finished time.Time
+	result   *etcdmgmt.SnapshotVerification
+	err      error
+	// retryAt is set once a verification that failed with a transient error has been recorded on the control plane.
+	retryAt time.Time
+}
+
+type handler struct {
+	ctx               context.Context
+	controlPlanes     rkecontrollers.RKEControlPlaneController
+	etcdSnapshotCache rkecontrollers.ETCDSnapshotCache
+	etcdSnapshots     rkecontrollers.ETCDSnapshotClient
+	secretCache       v1.SecretCache
+	verify            func(ctx context.Context, controlPlane *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) (*etcdmgmt.SnapshotVerification, error)
+
+	lock          sync.Mutex
+	verifications map[string]*verification
+}
+
+// Register sets up the etcd snapshot verification controller. On the schedule configured in the control plane's etcd
+// snapshot verification settings, the controller downloads an S3 etcd snapshot of the cluster, verifies its integrity
+// and records the result on the snapshot, the control plane and in metrics. Snapshots are downloaded in the background
+// so a slow download doesn't hold up the control plane handlers.
+func Register(ctx context.Context, clients *wrangler.Context) {
+	h := &handler{
+		ctx:               ctx,
+		verifications:     map[string]*verification{},
+		controlPlanes:     clients.RKE.RKEControlPlane(),
+		etcdSnapshotCache: clients.RKE.ETCDSnapshot().Cache(),
+		etcdSnapshots:     clients.RKE.ETCDSnapshot(),
+		secretCache:       clients.Core.Secret().Cache(),
+	}
+	h.verify = h.downloadAndVerify
+
+	rkecontrollers.RegisterRKEControlPlaneStatusHandler(ctx, clients.RKE.RKEControlPlane(),
+		"", "etcd-snapshot-verification", h.OnChange)
+}
+
+func (h *handler) OnChange(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus) (rkev1.RKEControlPlaneStatus, error) {
+	if cp == nil || cp.DeletionTimestamp != nil || !status.Initialized {
+		return status, nil
+	}
+
+	key := cp.Namespace + "/" + cp.Name
+	if cp.Spec.ETCD == nil || cp.Spec.ETCD.SnapshotVerification == nil || !cp.Spec.ETCD.SnapshotVerification.Enabled {
+		h.lock.Lock()
+		if v := h.verifications[key]; v != nil && v.done {
+			delete(h.verifications, key)
+		}
+		h.lock.Unlock()
+		if capr.ETCDSnapshotVerified.GetStatus(&status) != "" {
+			// verification was disabled, so the result of a previous verification no longer applies.
+			status.Conditions = removeCondition(status.Conditions, string(capr.ETCDSnapshotVerified))
+		}
+		return status, nil
+	}
+
+	settings := cp.Spec.ETCD.SnapshotVerification
+	scheduleCron := settings.ScheduleCron
+	if scheduleCron == "" {
+		scheduleCron = defaultScheduleCron
+	}
+	schedule, err := cron.ParseStandard(scheduleCron)
+	if err != nil {
+		capr.ETCDSnapshotVerified.SetError(&status, "InvalidSchedule", fmt.Errorf("invalid etcd snapshot verification schedule %q: %w", scheduleCron, err))
+		return status, nil
+	}
+
+	now := time.Now()
+	h.lock.Lock()
+	v := h.verifications[key]
+	switch {
+	case v == nil:
+	case !v.done:
+		// the verification enqueues the control plane once it is done.
+		h.lock.Unlock()
+		return status, nil
+	case v.retryAt.IsZero():
+		status = h.recordVerification(cp, status, v, schedule)
+		if v.err != nil && !errors.Is(v.err, etcdmgmt.ErrSnapshotCorrupt) && !errors.Is(v.err, errNoS3Credentials) {
+			v.retryAt = v.finished.Add(retryInterval)
+		} else {
+			delete(h.verifications, key)
+		}
+		h.lock.Unlock()
+		return status, nil
+	case now.Before(v.retryAt):
+		h.lock.Unlock()
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, v.retryAt.Sub(now))
+		return status, nil
+	default:
+		delete(h.verifications, key)
+	}
+	h.lock.Unlock()
+
+	if status.ETCDSnapshotVerificationTime != nil {
+		if next := schedule.Next(status.ETCDSnapshotVerificationTime.Time); now.Before(next) {
+			h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, next.Sub(now))
+			return status, nil
+		}
+	}
+
+	snapshot, err := h.selectSnapshot(cp, settings.Strategy)
+	if err != nil {
+		return status, err
+	}
+	if snapshot == nil {
+		logrus.Debugf("[etcdsnapshotverification] rkecontrolplane %s/%s: no S3 etcd snapshots available to verify", cp.Namespace, cp.Name)
+		capr.ETCDSnapshotVerified.Unknown(&status)
+		capr.ETCDSnapshotVerified.Reason(&status, "NoSnapshots")
+		capr.ETCDSnapshotVerified.Message(&status, "no successful S3 etcd snapshots are available to verify")
+		status.ETCDSnapshotVerificationTime = &metav1.Time{Time: now}
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, schedule.Next(now).Sub(now))
+		return status, nil
+	}
+
+	logrus.Infof("[etcdsnapshotverification] rkecontrolplane %s/%s: verifying etcd snapshot %s", cp.Namespace, cp.Name, snapshot.Name)
+	v = &verification{
+		snapshot: snapshot.DeepCopy(),
+		started:  now,
+	}
+	h.lock.Lock()
+	h.verifications[key] = v
+	h.lock.Unlock()
+	go h.runVerification(cp.DeepCopy(), v)
+	return status, nil
+}
+
+// runVerification downloads and verifies the snapshot of the verification, records the result on the snapshot and
+// enqueues the control plane so the result is recorded on it too.
+func (h *handler) runVerification(cp *rkev1.RKEControlPlane, v *verification) {
+	ctx, cancel := context.WithTimeout(h.ctx, verificationTimeout)
+	defer cancel()
+	result, verifyErr := h.verify(ctx, cp, v.snapshot)
+	finished := time.Now()
+	if h.ctx.Err() != nil {
+		// rancher is shutting down, the verification is started again by the next leader.
+		return
+	}
+
+	if verifyErr == nil || errors.Is(verifyErr, etcdmgmt.ErrSnapshotCorrupt) {
+		// errors downloading the snapshot say nothing about its integrity, so they are only recorded on the control plane.
+		if err := h.updateSnapshotStatus(v.snapshot, result, verifyErr, finished); err != nil {
+			logrus.Errorf("[etcdsnapshotverification] rkecontrolplane %s/%s: failed to record verification of etcd snapshot %s: %v", cp.Namespace, cp.Name, v.snapshot.Name, err)
+		}
+	}
+
+	h.lock.Lock()
+	v.done = true
+	v.finished = finished
+	v.result = result
+	v.err = verifyErr
+	h.lock.Unlock()
+	h.controlPlanes.Enqueue(cp.Namespace, cp.Name)
+}
+
+// recordVerification records the result of a completed verification in the control plane status and metrics.
+func (h *handler) recordVerification(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, v *verification, schedule cron.Schedule) rkev1.RKEControlPlaneStatus {
+	name := v.snapshot.SnapshotFile.Name
+	switch {
+	case v.err == nil:
+		capr.ETCDSnapshotVerified.True(&status)
+		capr.ETCDSnapshotVerified.Reason(&status, "")
+		capr.ETCDSnapshotVerified.Message(&status, fmt.Sprintf("etcd snapshot %s verified at revision %d", name, v.result.Revision))
+		metrics.SetETCDSnapshotVerification(cp.Spec.ManagementClusterName, true, v.finished, v.finished.Sub(v.started), v.result.Revision, v.result.KeyCount)
+	case errors.Is(v.err, etcdmgmt.ErrSnapshotCorrupt):
+		logrus.Errorf("[etcdsnapshotverification] rkecontrolplane %s/%s: verification of etcd snapshot %s failed: %v", cp.Namespace, cp.Name, v.snapshot.Name, v.err)
+		capr.ETCDSnapshotVerified.False(&status)
+		capr.ETCDSnapshotVerified.Reason(&status, "VerificationFailed")
+		capr.ETCDSnapshotVerified.Message(&status, fmt.Sprintf("etcd snapshot %s failed verification: %v", name, v.err))
+		metrics.SetETCDSnapshotVerification(cp.Spec.ManagementClusterName, false, v.finished, v.finished.Sub(v.started), 0, 0)
+	case errors.Is(v.err, errNoS3Credentials):
+		// retrying doesn't help until credentials are configured, so the verification waits for the next scheduled run.
+		logrus.Infof("[etcdsnapshotverification] rkecontrolplane %s/%s: etcd snapshot %s is not verifiable: %v", cp.Namespace, cp.Name, v.snapshot.Name, v.err)
+		capr.ETCDSnapshotVerified.Unknown(&status)
+		capr.ETCDSnapshotVerified.Reason(&status, "SnapshotUnverifiable")
+		capr.ETCDSnapshotVerified.Message(&status, fmt.Sprintf("etcd snapshot %s is not verifiable: %v", name, v.err))
+	default:
+		// the snapshot couldn't be read, so the result of the last verification is kept and the verification is retried
+		// without waiting for the next scheduled run.
+		logrus.Warnf("[etcdsnapshotverification] rkecontrolplane %s/%s: unable to verify etcd snapshot %s, retrying in %s: %v", cp.Namespace, cp.Name, v.snapshot.Name, retryInterval, v.err)
+		if capr.ETCDSnapshotVerified.GetStatus(&status) == "" {
+			capr.ETCDSnapshotVerified.Unknown(&status)
+		}
+		capr.ETCDSnapshotVerified.Reason(&status, "VerificationError")
+		capr.ETCDSnapshotVerified.Message(&status, fmt.Sprintf("unable to verify etcd snapshot %s: %v", name, v.err))
+		h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, retryInterval)
+		return status
+	}
+
+	status.ETCDSnapshotVerificationTime = &metav1.Time{Time: v.finished}
+	h.controlPlanes.EnqueueAfter(cp.Namespace, cp.Name, schedule.Next(v.finished).Sub(v.finished))
+	return status
+}
+
+// selectSnapshot returns the snapshot to verify according to the given strategy, or nil if the cluster has no
+// successful S3 snapshots.
+func (h *handler) selectSnapshot(cp *rkev1.RKEControlPlane, strategy rkev1.ETCDSnapshotVerificationStrategy) (*rkev1.ETCDSnapshot, error) {
+	snapshots, err := h.etcdSnapshotCache.List(cp.Namespace, labels.SelectorFromSet(map[string]string{
+		capr.ClusterNameLabel: cp.Spec.ClusterName,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*rkev1.ETCDSnapshot
+	for _, snapshot := range snapshots {
+		if snapshot.SnapshotFile.S3 == nil || snapshot.Status.Missing || snapshot.SnapshotFile.Status == snapshotStatusFailed {
+			continue
+		}
+		candidates = append(candidates, snapshot)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	if strategy == rkev1.ETCDSnapshotVerificationStrategySampled {
+		return candidates[rand.Intn(len(candidates))], nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return createdAt(candidates[i]).After(createdAt(candidates[j]))
+	})
+	return candidates[0], nil
+}
+
+func createdAt(snapshot *rkev1.ETCDSnapshot) time.Time {
+	if snapshot.SnapshotFile.CreatedAt != nil {
+		return snapshot.SnapshotFile.CreatedAt.Time
+	}
+	return snapshot.CreationTimestamp.Time
+}
+
+// updateSnapshotStatus records the verification result on the etcd snapshot object.
+func (h *handler) updateSnapshotStatus(snapshot *rkev1.ETCDSnapshot, result *etcdmgmt.SnapshotVerification, verifyErr error, verifiedAt time.Time) error {
+	snapshot = snapshot.DeepCopy()
+	verification := &rkev1.ETCDSnapshotVerificationStatus{
+		VerifiedAt: &metav1.Time{Time: verifiedAt},
+		Verified:   verifyErr == nil,
+	}
+	if verifyErr != nil {
+		verification.Message = verifyErr.Error()
+	}
+	if result != nil {
+		verification.SHA256 = result.SHA256
+		verification.HashChecked = result.HashChecked
+		verification.Revision = result.Revision
+		verification.KeyCount = result.KeyCount
+		verification.DBSize = result.DBSize
+	}
+	snapshot.Status.Verification = verification
+	_, err := h.etcdSnapshots.UpdateStatus(snapshot)
+	return err
+}
+
+// downloadAndVerify downloads the given S3 snapshot and verifies its integrity.
+func (h *handler) downloadAndVerify(ctx context.Context, cp *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) (*etcdmgmt.SnapshotVerification, error) {
+	s3Config, err := h.resolveS3Config(cp, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := s3Config.getObject(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	return etcdmgmt.VerifySnapshot(object, etcdmgmt.IsCompressedSnapshot(snapshot.SnapshotFile.Name), os.TempDir())
+}
+
+// removeCondition returns the given conditions without the condition of the given type.
+func removeCondition(conditions []genericcondition.GenericCondition, conditionType string) []genericcondition.GenericCondition {
+	var result []genericcondition.GenericCondition
+	for _, c := range conditions {
+		if c.Type != conditionType {
+			result = append(result, c)
+		}
+	}
+	return result
+}
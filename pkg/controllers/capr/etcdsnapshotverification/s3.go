@@ -0,0 +1,174 @@
+package etcdsnapshotverification
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr/planner"
+)
+
+const defaultS3Endpoint = "s3.amazonaws.com"
+
+// errNoS3Credentials is returned for snapshots without S3 access credentials. Those snapshots are only readable with
+// the ambient credentials of the nodes that took them, which rancher doesn't use on behalf of clusters.
+var errNoS3Credentials = errors.New("no S3 credentials are configured")
+
+// s3Config contains the resolved settings required to download an etcd snapshot from S3.
+type s3Config struct {
+	endpoint      string
+	endpointCA    []byte
+	skipSSLVerify bool
+	region        string
+	bucket        string
+	key           string
+	accessKey     string
+	secretKey     string
+}
+
+// first returns the first non-blank string of the passed in arguments.
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// resolveS3Config merges the S3 settings recorded on the snapshot with the S3 cloud credential and the control plane's
+// S3 settings, in that order of precedence, mirroring how the planner renders S3 arguments for a snapshot restore.
+func (h *handler) resolveS3Config(cp *rkev1.RKEControlPlane, snapshot *rkev1.ETCDSnapshot) (*s3Config, error) {
+	snapshotS3 := snapshot.SnapshotFile.S3
+	cpS3 := &rkev1.ETCDSnapshotS3{}
+	if cp.Spec.ETCD != nil && cp.Spec.ETCD.S3 != nil {
+		cpS3 = cp.Spec.ETCD.S3
+	}
+
+	cred, err := planner.GetS3Credential(h.secretCache, cp.Namespace, first(snapshotS3.CloudCredentialName, cpS3.CloudCredentialName))
+	if err != nil {
+		return nil, err
+	}
+
+	config := &s3Config{
+		endpoint:      first(snapshotS3.Endpoint, cred.Endpoint, cpS3.Endpoint, defaultS3Endpoint),
+		skipSSLVerify: snapshotS3.SkipSSLVerify || cred.SkipSSLVerify || cpS3.SkipSSLVerify,
+		region:        first(snapshotS3.Region, cred.Region, cpS3.Region),
+		bucket:        first(snapshotS3.Bucket, cred.Bucket, cpS3.Bucket),
+		accessKey:     cred.AccessKey,
+		secretKey:     cred.SecretKey,
+	}
+
+	// the snapshot may record the path of the endpoint CA file on the node that took the snapshot rather than its
+	// contents, in which case the CA is retrieved from the credential or control plane instead.
+	for _, ca := range []string{snapshotS3.EndpointCA, cred.EndpointCA, cpS3.EndpointCA} {
+		if data := endpointCAData(ca); data != nil {
+			config.endpointCA = data
+			break
+		}
+	}
+
+	config.key = snapshotKey(snapshot, first(snapshotS3.Folder, cred.Folder, cpS3.Folder))
+	if config.bucket == "" {
+		return nil, fmt.Errorf("unable to determine S3 bucket for etcd snapshot %s", snapshot.Name)
+	}
+	if config.accessKey == "" || config.secretKey == "" {
+		return nil, errNoS3Credentials
+	}
+	return config, nil
+}
+
+// endpointCAData returns the PEM encoded certificates of the given endpoint CA, which may be base64 encoded, or nil if
+// it doesn't contain any certificates.
+func endpointCAData(ca string) []byte {
+	data := []byte(ca)
+	if decoded, err := base64.StdEncoding.DecodeString(ca); err == nil {
+		data = decoded
+	}
+	if block, _ := pem.Decode(data); block == nil {
+		return nil
+	}
+	return data
+}
+
+// snapshotKey returns the object key of the snapshot, preferring the s3:// location recorded by the distribution.
+func snapshotKey(snapshot *rkev1.ETCDSnapshot, folder string) string {
+	if u, err := url.Parse(snapshot.SnapshotFile.Location); err == nil && u.Scheme == "s3" {
+		if key := strings.TrimPrefix(u.Path, "/"); key != "" {
+			return key
+		}
+	}
+	return path.Join(folder, snapshot.SnapshotFile.Name)
+}
+
+// getObject opens the snapshot object for reading.
+func (c *s3Config) getObject(ctx context.Context) (io.ReadCloser, error) {
+	client, err := c.client()
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.GetObject(ctx, c.bucket, c.key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving etcd snapshot %s from bucket %s: %w", c.key, c.bucket, err)
+	}
+	// GetObject is lazy, so the object is checked to surface errors such as a missing key before the download begins.
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("error retrieving etcd snapshot %s from bucket %s: %w", c.key, c.bucket, err)
+	}
+	return object, nil
+}
+
+func (c *s3Config) client() (*minio.Client, error) {
+	tr := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: c.skipSSLVerify,
+		},
+	}
+
+	if c.endpointCA != nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(c.endpointCA) {
+			return nil, fmt.Errorf("failed to parse S3 endpoint CA")
+		}
+		tr.TLSClientConfig.RootCAs = pool
+	}
+
+	// the IAM identity of rancher itself is never used, as clusters choose the bucket and endpoint
+	creds := credentials.NewStatic(c.accessKey, c.secretKey, "", credentials.SignatureDefault)
+
+	endpoint, secure := c.endpoint, true
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		endpoint, secure = u.Host, u.Scheme != "http"
+	}
+
+	return minio.New(endpoint, &minio.Options{
+		Creds:        creds,
+		Region:       c.region,
+		Secure:       secure,
+		BucketLookup: minio.BucketLookupAuto,
+		Transport:    tr,
+	})
+}
@@ -40,6 +40,7 @@
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
 	capi "sigs.k8s.io/cluster-api/api/v1beta1"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 	capiannotations "sigs.k8s.io/cluster-api/util/annotations"
@@ -85,21 +86,24 @@ func newInfraObject(obj runtime.Object) (*infraObject, error) {
 }
 
 type handler struct {
-	ctx                 context.Context
-	apply               apply.Apply
-	jobController       batchcontrollers.JobController
-	jobs                batchcontrollers.JobCache
-	pods                corecontrollers.PodCache
-	secrets             corecontrollers.SecretCache
-	capiClusterCache    capicontrollers.ClusterCache
-	machineCache        capicontrollers.MachineCache
-	machineClient       capicontrollers.MachineClient
-	machineSetCache     capicontrollers.MachineSetCache
-	namespaces          corecontrollers.NamespaceCache
-	nodeDriverCache     mgmtcontrollers.NodeDriverCache
-	dynamic             *dynamic.Controller
-	rancherClusterCache ranchercontrollers.ClusterCache
-	kubeconfigManager   *kubeconfig.Manager
+	ctx                  context.Context
+	apply                apply.Apply
+	jobController        batchcontrollers.JobController
+	jobs                 batchcontrollers.JobCache
+	pods                 corecontrollers.PodCache
+	secrets              corecontrollers.SecretCache
+	secretClient         corecontrollers.SecretClient
+	k8s                  kubernetes.Interface
+	capiClusterCache     capicontrollers.ClusterCache
+	machineCache         capicontrollers.MachineCache
+	machineClient        capicontrollers.MachineClient
+	machineSetCache      capicontrollers.MachineSetCache
+	namespaces           corecontrollers.NamespaceCache
+	nodeDriverCache      mgmtcontrollers.NodeDriverCache
+	dynamic              *dynamic.Controller
+	rancherClusterCache  ranchercontrollers.ClusterCache
+	rancherClusterClient ranchercontrollers.ClusterClient
+	kubeconfigManager    *kubeconfig.Manager
 }
 
 func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager *kubeconfig.Manager) {
@@ -110,19 +114,22 @@ func Register(ctx context.Context, clients *wrangler.Context, kubeconfigManager
 			clients.RBAC.RoleBinding(),
 			clients.RBAC.Role(),
 			clients.Batch.Job()),
-		pods:                clients.Core.Pod().Cache(),
-		jobController:       clients.Batch.Job(),
-		jobs:                clients.Batch.Job().Cache(),
-		secrets:             clients.Core.Secret().Cache(),
-		machineCache:        clients.CAPI.Machine().Cache(),
-		machineClient:       clients.CAPI.Machine(),
-		machineSetCache:     clients.CAPI.MachineSet().Cache(),
-		capiClusterCache:    clients.CAPI.Cluster().Cache(),
-		nodeDriverCache:     clients.Mgmt.NodeDriver().Cache(),
-		namespaces:          clients.Core.Namespace().Cache(),
-		dynamic:             clients.Dynamic,
-		rancherClusterCache: clients.Provisioning.Cluster().Cache(),
-		kubeconfigManager:   kubeconfigManager,
+		pods:                 clients.Core.Pod().Cache(),
+		jobController:        clients.Batch.Job(),
+		jobs:                 clients.Batch.Job().Cache(),
+		secrets:              clients.Core.Secret().Cache(),
+		secretClient:         clients.Core.Secret(),
+		k8s:                  clients.K8s,
+		machineCache:         clients.CAPI.Machine().Cache(),
+		machineClient:        clients.CAPI.Machine(),
+		machineSetCache:      clients.CAPI.MachineSet().Cache(),
+		capiClusterCache:     clients.CAPI.Cluster().Cache(),
+		nodeDriverCache:      clients.Mgmt.NodeDriver().Cache(),
+		namespaces:           clients.Core.Namespace().Cache(),
+		dynamic:              clients.Dynamic,
+		rancherClusterCache:  clients.Provisioning.Cluster().Cache(),
+		rancherClusterClient: clients.Provisioning.Cluster(),
+		kubeconfigManager:    kubeconfigManager,
 	}
 
 	removeHandler := generic.NewRemoveHandler("machine-provision-remove", clients.Dynamic.Update, h.OnRemove)
@@ -170,6 +177,10 @@ func (h *handler) OnJobChange(_ string, job *batchv1.Job) (*batchv1.Job, error)
 		return job, err
 	}
 
+	if err := h.captureJobLogs(infra, job); err != nil {
+		return job, err
+	}
+
 	if infra.data.String("status", "jobName") == "" {
 		infra.data.SetNested(job.Name, "status", "jobName")
 		_, err = h.dynamic.UpdateStatus(&unstructured.Unstructured{
@@ -377,6 +388,10 @@ func (h *handler) OnRemove(key string, obj runtime.Object) (runtime.Object, erro
 		}
 
 		if shouldCleanupObjects(job, infra.data) {
+			// The job and its pods are removed below, capture their logs first.
+			if err := h.captureJobLogs(infra, job); err != nil {
+				return obj, err
+			}
 			// Calling WithOwner(obj).ApplyObjects with no objects here will look for all objects with types passed to
 			// WithCacheTypes above that have an owner label (not owner reference) to the given obj. It will compare the existing
 			// objects it finds to the ones that are passed to ApplyObjects (which there are none in this case). The apply
@@ -471,6 +486,7 @@ func (h *handler) doRemove(infra *infraObject) (runtime.Object, error) {
 	}
 	newStatus.JobName = job.Name
 
+	previous := jobConditionStatus(infra, deleteJobConditionType)
 	err = reconcileStatus(infra.data, newStatus)
 	if err != nil {
 		return infra.obj, err
@@ -487,6 +503,7 @@ func (h *handler) doRemove(infra *infraObject) (runtime.Object, error) {
 	}); err != nil {
 		return infra.obj, err
 	}
+	h.observeJob(infra, job, deleteJobConditionType, previous)
 
 	return infra.obj, generic.ErrSkip
 }
@@ -561,6 +578,17 @@ func (h *handler) OnChange(obj runtime.Object) (runtime.Object, error) {
 		return obj, generic.ErrSkip
 	}
 
+	if handled, obj, err := h.assignVariant(infra, machine); handled || err != nil {
+		return obj, err
+	}
+
+	if infra.data.String("status", "failureReason") == string(capierrors.CreateMachineError) ||
+		infra.data.String("status", "createFailureMessage") != "" {
+		if handled, obj, err := h.retryCreate(infra, machine); handled || err != nil {
+			return obj, err
+		}
+	}
+
 	state, failure, err := h.run(infra, true)
 	if err != nil {
 		return obj, err
@@ -599,7 +627,8 @@ func (h *handler) OnChange(obj runtime.Object) (runtime.Object, error) {
 	}
 
 	job, err := h.jobs.Get(infra.meta.GetNamespace(), jobName)
-	if apierrors.IsNotFound(err) {
+	if apierrors.IsNotFound(err) || (err == nil && job.Spec.Template.Labels[InfraJobRemove] == "true") {
+		// The remove job that cleaned up after a failed attempt has not been replaced by the create job yet.
 		return h.dynamic.UpdateStatus(&unstructured.Unstructured{
 			Object: infra.data,
 		})
@@ -607,20 +636,42 @@ func (h *handler) OnChange(obj runtime.Object) (runtime.Object, error) {
 		return obj, err
 	}
 
+	if err := h.captureJobLogs(infra, job); err != nil {
+		return obj, err
+	}
+
 	newStatus, err := h.getMachineStatus(job)
 	if err != nil {
 		return obj, err
 	}
 	newStatus.JobName = job.Name
+	if newStatus.FailureReason == string(capierrors.CreateMachineError) {
+		_, pool, err := h.getMachinePool(infra, machine)
+		if err != nil {
+			return obj, err
+		}
+		withholdCreateFailure(pool, &newStatus)
+	}
 
+	previous := jobConditionStatus(infra, createJobConditionType)
 	err = reconcileStatus(infra.data, newStatus)
 	if err != nil {
 		return obj, err
 	}
 
-	return h.dynamic.UpdateStatus(&unstructured.Unstructured{
+	if cond := getCondition(infra.data, createJobConditionType); cond != nil && cond.Status() == "True" {
+		if err := h.resetPoolFailures(infra, machine); err != nil {
+			return obj, err
+		}
+	}
+
+	if obj, err = h.dynamic.UpdateStatus(&unstructured.Unstructured{
 		Object: infra.data,
-	})
+	}); err != nil {
+		return obj, err
+	}
+	h.observeJob(infra, job, createJobConditionType, previous)
+	return obj, nil
 }
 
 func (h *handler) run(infra *infraObject, create bool) (rkev1.RKEMachineStatus, bool, error) {
@@ -629,6 +680,13 @@ func (h *handler) run(infra *infraObject, create bool) (rkev1.RKEMachineStatus,
 	args := infra.data.Map("spec")
 	driver := getNodeDriverName(infra.typeMeta)
 
+	if create {
+		var err error
+		if args, err = h.applyVariant(infra, args); err != nil {
+			return rkev1.RKEMachineStatus{}, false, err
+		}
+	}
+
 	dArgs, err := h.getArgsEnvAndStatus(infra, args, driver, create)
 	if err != nil {
 		return rkev1.RKEMachineStatus{}, false, err
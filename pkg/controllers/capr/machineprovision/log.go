@@ -0,0 +1,150 @@
+package machineprovision
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/rancher/rancher/pkg/provisioningv2/machinelog"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// maxMachineLogsPerCluster is the number of provisioning log secrets kept per cluster, so that clusters with a lot of
+// machine churn do not accumulate them indefinitely.
+const maxMachineLogsPerCluster = 50
+
+// logTailLines bounds the number of lines read from a finished job pod, the result is further truncated to
+// machinelog.MaxLogBytes.
+var logTailLines int64 = 10000
+
+// captureJobLogs stores the logs of the finished pods of the given job in the provisioning log secret of the
+// infrastructure machine. The secret is owned by the CAPI cluster rather than the machine, so that the logs of a
+// machine that failed to provision are still available after the machine has been replaced.
+func (h *handler) captureJobLogs(infra *infraObject, job *batchv1.Job) error {
+	if !condition.Cond("Complete").IsTrue(job) && !condition.Cond("Failed").IsTrue(job) {
+		return nil
+	}
+
+	clusterName := infra.meta.GetLabels()[capi.ClusterNameLabel]
+	if clusterName == "" {
+		return nil
+	}
+
+	capiCluster, err := h.capiClusterCache.Get(infra.meta.GetNamespace(), clusterName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return err
+	}
+
+	pods, err := h.pods.List(job.Namespace, sel)
+	if err != nil {
+		return err
+	}
+
+	secretName := machinelog.SecretName(infra.meta.GetName())
+	secret, err := h.secrets.Get(infra.meta.GetNamespace(), secretName)
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName,
+				Namespace: infra.meta.GetNamespace(),
+				Labels: map[string]string{
+					machinelog.Label:      infra.meta.GetName(),
+					capi.ClusterNameLabel: clusterName,
+				},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: capi.GroupVersion.String(),
+					Kind:       "Cluster",
+					Name:       capiCluster.Name,
+					UID:        capiCluster.UID,
+				}},
+			},
+			Type: "rke.cattle.io/machine-provision-log",
+		}
+	} else if err != nil {
+		return err
+	} else {
+		secret = secret.DeepCopy()
+	}
+
+	attempts, err := machinelog.Decode(secret)
+	if err != nil {
+		return fmt.Errorf("decoding provisioning logs of machine %s/%s: %w", infra.meta.GetNamespace(), infra.meta.GetName(), err)
+	}
+
+	operation := "create"
+	if job.Spec.Template.Labels[InfraJobRemove] == "true" {
+		operation = "delete"
+	}
+
+	changed := false
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+			continue
+		}
+		if machinelog.Has(attempts, string(pod.UID)) {
+			continue
+		}
+		log, err := h.k8s.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+			Container: "machine",
+			TailLines: &logTailLines,
+		}).DoRaw(h.ctx)
+		if err != nil {
+			return fmt.Errorf("reading logs of pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		attempts = machinelog.Add(attempts, machinelog.NewAttempt(job.Name, operation, pod, log))
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	if err := machinelog.Encode(secret, attempts); err != nil {
+		return err
+	}
+
+	if secret.ResourceVersion != "" {
+		_, err = h.secretClient.Update(secret)
+		return err
+	}
+
+	if _, err := h.secretClient.Create(secret); err != nil {
+		return err
+	}
+	return h.pruneMachineLogs(infra.meta.GetNamespace(), clusterName)
+}
+
+// pruneMachineLogs removes the oldest provisioning log secrets of the cluster beyond maxMachineLogsPerCluster.
+func (h *handler) pruneMachineLogs(namespace, clusterName string) error {
+	sel, err := labels.Parse(fmt.Sprintf("%s,%s=%s", machinelog.Label, capi.ClusterNameLabel, clusterName))
+	if err != nil {
+		return err
+	}
+
+	secrets, err := h.secrets.List(namespace, sel)
+	if err != nil || len(secrets) <= maxMachineLogsPerCluster {
+		return err
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].CreationTimestamp.Before(&secrets[j].CreationTimestamp)
+	})
+	for _, secret := range secrets[:len(secrets)-maxMachineLogsPerCluster] {
+		if err := h.secretClient.Delete(secret.Namespace, secret.Name, nil); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
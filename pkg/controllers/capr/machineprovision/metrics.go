@@ -0,0 +1,57 @@
+package machineprovision
+
+import (
+	"time"
+
+	"github.com/rancher/rancher/pkg/metrics"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// jobConditionStatus returns the status of the given job condition of the infra machine, or an empty string if it is not
+// set.
+func jobConditionStatus(infra *infraObject, conditionType string) string {
+	if cond := getCondition(infra.data, conditionType); cond != nil {
+		return cond.Status()
+	}
+	return ""
+}
+
+// observeJob records the result of the provisioning job of the infra machine once its job condition has left the
+// previous status to become true or false, i.e. only the first time the finished job is reconciled into the status.
+func (h *handler) observeJob(infra *infraObject, job *batchv1.Job, conditionType, previous string) {
+	current := jobConditionStatus(infra, conditionType)
+	if current == previous || (current != string(corev1.ConditionTrue) && current != string(corev1.ConditionFalse)) {
+		return
+	}
+
+	cluster, err := h.rancherClusterCache.Get(infra.meta.GetNamespace(), infra.meta.GetLabels()[capi.ClusterNameLabel])
+	if err != nil || cluster.Status.ClusterName == "" {
+		return
+	}
+
+	action := "create"
+	if conditionType == deleteJobConditionType {
+		action = "delete"
+	}
+
+	var duration time.Duration
+	if end := jobEndTime(job); job.Status.StartTime != nil && end != nil {
+		duration = end.Sub(job.Status.StartTime.Time)
+	}
+	metrics.ObserveMachineProvisionJob(cluster.Status.ClusterName, getNodeDriverName(infra.typeMeta), action, current == string(corev1.ConditionTrue), duration)
+}
+
+func jobEndTime(job *batchv1.Job) *metav1.Time {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return &cond.LastTransitionTime
+		}
+	}
+	return nil
+}
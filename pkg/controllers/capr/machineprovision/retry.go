@@ -0,0 +1,442 @@
+package machineprovision
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"github.com/rancher/wrangler/v3/pkg/generic"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+const (
+	failureBudgetExhaustedConditionType = "FailureBudgetExhausted"
+
+	defaultRetryMaxAttempts = 3
+	defaultRetryBackoff     = 30 * time.Second
+	defaultRetryMaxBackoff  = 10 * time.Minute
+	maxLastFailures         = 5
+)
+
+// failureExpressions caches the compiled failure expressions of machine pools by expression, as they are matched against
+// the failure of every failed machine of the pool.
+var failureExpressions sync.Map
+
+// retryCreate handles an infrastructure machine whose create job failed, for machines of a pool that has a retry
+// policy or machine config variants. The failure of such machines is withheld from failureReason and failureMessage,
+// which CAPI copies to the machine and never clears, marking it as failed for good. The failed attempt is recorded
+// against the failure budget of the pool. If the failure is a capacity error and the machine has a machine config
+// variant left to fall back to, or if the failure is retryable and the machine has attempts left, the machine
+// infrastructure is cleaned up with a remove job, after the backoff unless falling back, and the create job is run
+// again. Otherwise the failure is reported. It returns false if the machine should be replaced instead, which is the
+// behavior for machines of pools without a retry policy and machines whose failure is reported.
+func (h *handler) retryCreate(infra *infraObject, machine *capi.Machine) (bool, runtime.Object, error) {
+	cluster, pool, err := h.getMachinePool(infra, machine)
+	if err != nil || pool == nil || !retriesCreate(pool) {
+		return false, infra.obj, err
+	}
+
+	attempt := 1
+	if n, _ := convert.ToNumber(data.GetValueN(infra.data, "status", "createAttempts")); n > 0 {
+		attempt = int(n)
+	}
+	failureMessage := infra.data.String("status", "createFailureMessage")
+	if failureMessage == "" {
+		failureMessage = infra.data.String("status", "failureMessage")
+	}
+
+	if counted, _ := convert.ToNumber(data.GetValueN(infra.data, "status", "failureCountedAttempt")); int(counted) < attempt {
+		// the attempt is marked as counted on the machine, so the failure is counted once no matter how often the
+		// machine is handled, or how many failures of the pool are recorded since.
+		if err := h.recordPoolFailure(cluster, pool, rancherv1.MachineProvisioningFailure{
+			Time:    metav1.Now(),
+			Machine: machine.Name,
+			Attempt: attempt,
+			Variant: infra.data.String("status", "machineConfigVariant"),
+			Message: failureMessage,
+		}); err != nil {
+			return true, infra.obj, err
+		}
+		infra.data.SetNested(int64(attempt), "status", "failureCountedAttempt")
+		obj, err := h.dynamic.UpdateStatus(&unstructured.Unstructured{
+			Object: infra.data,
+		})
+		return true, obj, err
+	}
+
+	exhausted := poolBudgetExhausted(cluster, pool)
+	if infra.data.String("status", "failureReason") == string(capierrors.CreateMachineError) {
+		if exhausted {
+			logrus.Infof("[machineprovision] %s/%s: failure budget of machine pool %s is used up, keeping failed machine %s", infra.meta.GetNamespace(), infra.meta.GetName(), pool.Name, machine.Name)
+			return true, infra.obj, generic.ErrSkip
+		}
+		return false, infra.obj, nil
+	}
+	if exhausted {
+		logrus.Infof("[machineprovision] %s/%s: failure budget of machine pool %s is used up, reporting the failure of machine %s", infra.meta.GetNamespace(), infra.meta.GetName(), pool.Name, machine.Name)
+		reportCreateFailure(infra.data)
+		obj, err := h.dynamic.UpdateStatus(&unstructured.Unstructured{
+			Object: infra.data,
+		})
+		return true, obj, err
+	}
+
+	now := time.Now()
+	nextRetry, err := time.Parse(time.RFC3339, infra.data.String("status", "nextRetryTime"))
+	if err != nil {
+		message, err := h.planRetry(infra, pool, attempt, now)
+		if err != nil {
+			return true, infra.obj, err
+		}
+		if message == "" {
+			logrus.Infof("[machineprovision] %s/%s: reporting the failure of machine %s, it is not retried anymore", infra.meta.GetNamespace(), infra.meta.GetName(), machine.Name)
+		} else if err := reconcileStatus(infra.data, readyCondition(message)); err != nil {
+			return true, infra.obj, err
+		}
+		obj, err := h.dynamic.UpdateStatus(&unstructured.Unstructured{
+			Object: infra.data,
+		})
+		return true, obj, err
+	}
+	if delay := nextRetry.Sub(now); delay > 0 {
+		h.EnqueueAfter(infra, delay)
+		return true, infra.obj, generic.ErrSkip
+	}
+
+	// The failed create job may have left infrastructure behind, remove it before running the create job again.
+	if _, _, err := h.run(infra, false); err != nil {
+		return true, infra.obj, err
+	}
+	job, err := h.jobs.Get(infra.meta.GetNamespace(), GetJobName(infra.meta.GetName()))
+	if apierrors.IsNotFound(err) {
+		return true, infra.obj, generic.ErrSkip
+	} else if err != nil {
+		return true, infra.obj, err
+	}
+	if job.Spec.Template.Labels[InfraJobRemove] != "true" ||
+		(!condition.Cond("Complete").IsTrue(job) && !condition.Cond("Failed").IsTrue(job)) {
+		// OnJobChange re-enqueues the machine once the remove job finishes.
+		return true, infra.obj, generic.ErrSkip
+	}
+
+	message := retryMessage(pool.RetryPolicy, attempt+1, infra.data.String("status", "machineConfigVariant"))
+	logrus.Infof("[machineprovision] %s/%s: %s of machine %s", infra.meta.GetNamespace(), infra.meta.GetName(), message, machine.Name)
+	infra.data.SetNested(int64(attempt+1), "status", "createAttempts")
+	data.RemoveValue(infra.data, "status", "createFailureMessage")
+	data.RemoveValue(infra.data, "status", "nextRetryTime")
+	state := readyCondition(message)
+	state.Conditions = append(state.Conditions, genericcondition.GenericCondition{
+		Type:    createJobConditionType,
+		Status:  corev1.ConditionUnknown,
+		Message: "creating machine provision job",
+	})
+	if err := reconcileStatus(infra.data, state); err != nil {
+		return true, infra.obj, err
+	}
+	obj, err := h.dynamic.UpdateStatus(&unstructured.Unstructured{
+		Object: infra.data,
+	})
+	return true, obj, err
+}
+
+// planRetry schedules the next create attempt of a machine whose failure is withheld, falling back to the next machine
+// config variant on a capacity error, and returns the message of the Ready condition of the machine. If the machine is
+// not retried anymore, its failure is reported instead and an empty message is returned.
+func (h *handler) planRetry(infra *infraObject, pool *rancherv1.RKEMachinePool, attempt int, now time.Time) (string, error) {
+	failureMessage := infra.data.String("status", "createFailureMessage")
+	variant, err := h.fallbackVariant(infra, pool, failureMessage)
+	if err != nil {
+		return "", err
+	}
+	if variant != "" {
+		infra.data.SetNested(now.UTC().Format(time.RFC3339), "status", "nextRetryTime")
+		return fmt.Sprintf("falling back to machine config variant %s after failure: %s", variant, failureMessage), nil
+	}
+
+	if policy := pool.RetryPolicy; policy != nil && attempt < maxAttempts(policy) {
+		retryable, err := isRetryable(policy, failureMessage)
+		if err != nil {
+			return "", err
+		}
+		if retryable {
+			infra.data.SetNested(now.Add(retryBackoff(policy, attempt)).UTC().Format(time.RFC3339), "status", "nextRetryTime")
+			return fmt.Sprintf("retrying machine provisioning (attempt %d of %d) after failure: %s", attempt+1, maxAttempts(policy), failureMessage), nil
+		}
+	}
+
+	reportCreateFailure(infra.data)
+	return "", nil
+}
+
+// retriesCreate returns whether failed machines of the machine pool may be created again in place.
+func retriesCreate(pool *rancherv1.RKEMachinePool) bool {
+	return pool != nil && (pool.RetryPolicy != nil || len(pool.MachineConfigVariants) > 0)
+}
+
+// withholdCreateFailure moves the failure of the create job of a machine that may be created again in place to
+// createFailureMessage, so that the CAPI machine is not marked as failed while the machine is retried.
+func withholdCreateFailure(pool *rancherv1.RKEMachinePool, status *rkev1.RKEMachineStatus) {
+	if !retriesCreate(pool) || status.FailureReason != string(capierrors.CreateMachineError) {
+		return
+	}
+	status.CreateFailureMessage = status.FailureMessage
+	status.FailureReason = ""
+	status.FailureMessage = ""
+}
+
+// reportCreateFailure reports the withheld failure of the create job of a machine through failureReason and
+// failureMessage, once the machine is not retried anymore.
+func reportCreateFailure(d data.Object) {
+	d.SetNested(string(capierrors.CreateMachineError), "status", "failureReason")
+	d.SetNested(d.String("status", "createFailureMessage"), "status", "failureMessage")
+	data.RemoveValue(d, "status", "createFailureMessage")
+	data.RemoveValue(d, "status", "nextRetryTime")
+}
+
+// getMachinePool returns the provisioning cluster and the machine pool of the given machine, or nil if the machine
+// does not belong to a machine pool.
+func (h *handler) getMachinePool(infra *infraObject, machine *capi.Machine) (*rancherv1.Cluster, *rancherv1.RKEMachinePool, error) {
+	poolName := machine.Labels[capr.RKEMachinePoolNameLabel]
+	if poolName == "" {
+		return nil, nil, nil
+	}
+
+	cluster, err := h.rancherClusterCache.Get(infra.meta.GetNamespace(), infra.meta.GetLabels()[capi.ClusterNameLabel])
+	if apierrors.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil || cluster.Spec.RKEConfig == nil {
+		return nil, nil, err
+	}
+
+	for i := range cluster.Spec.RKEConfig.MachinePools {
+		if cluster.Spec.RKEConfig.MachinePools[i].Name == poolName {
+			return cluster, &cluster.Spec.RKEConfig.MachinePools[i], nil
+		}
+	}
+	return nil, nil, nil
+}
+
+// recordPoolFailure counts the failure against the failure budget of the machine pool and adds it to the last failures
+// of the pool.
+func (h *handler) recordPoolFailure(cluster *rancherv1.Cluster, pool *rancherv1.RKEMachinePool, failure rancherv1.MachineProvisioningFailure) error {
+	return h.updatePoolStatus(cluster, pool.Name, func(status *rancherv1.MachinePoolStatus) {
+		status.ProvisioningFailures++
+		status.LastFailures = append(status.LastFailures, failure)
+		if len(status.LastFailures) > maxLastFailures {
+			status.LastFailures = status.LastFailures[len(status.LastFailures)-maxLastFailures:]
+		}
+		setBudgetCondition(status, pool.RetryPolicy, budgetExhausted(pool.RetryPolicy, status.ProvisioningFailures))
+	})
+}
+
+// poolBudgetExhausted returns whether the failure budget of the machine pool is used up.
+func poolBudgetExhausted(cluster *rancherv1.Cluster, pool *rancherv1.RKEMachinePool) bool {
+	for _, status := range cluster.Status.MachinePools {
+		if status.Name == pool.Name {
+			return budgetExhausted(pool.RetryPolicy, status.ProvisioningFailures)
+		}
+	}
+	return false
+}
+
+// resetPoolFailures clears the provisioning failures of the machine pool of the given machine after one of its
+// machines has been provisioned successfully.
+func (h *handler) resetPoolFailures(infra *infraObject, machine *capi.Machine) error {
+	cluster, pool, err := h.getMachinePool(infra, machine)
+	if err != nil || pool == nil {
+		return err
+	}
+
+	found := false
+	for _, status := range cluster.Status.MachinePools {
+		if status.Name == pool.Name && status.ProvisioningFailures > 0 {
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	return h.updatePoolStatus(cluster, pool.Name, func(status *rancherv1.MachinePoolStatus) {
+		status.ProvisioningFailures = 0
+		status.LastFailures = nil
+		setBudgetCondition(status, pool.RetryPolicy, false)
+	})
+}
+
+func (h *handler) updatePoolStatus(cluster *rancherv1.Cluster, poolName string, update func(status *rancherv1.MachinePoolStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cluster, err := h.rancherClusterClient.Get(cluster.Namespace, cluster.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		cluster = cluster.DeepCopy()
+		index := -1
+		for i, status := range cluster.Status.MachinePools {
+			if status.Name == poolName {
+				index = i
+			}
+		}
+		if index == -1 {
+			cluster.Status.MachinePools = append(cluster.Status.MachinePools, rancherv1.MachinePoolStatus{Name: poolName})
+			index = len(cluster.Status.MachinePools) - 1
+		}
+
+		before := cluster.Status.MachinePools[index].DeepCopy()
+		update(&cluster.Status.MachinePools[index])
+		if equalPoolStatus(before, &cluster.Status.MachinePools[index]) {
+			return nil
+		}
+
+		_, err = h.rancherClusterClient.UpdateStatus(cluster)
+		return err
+	})
+}
+
+func equalPoolStatus(a, b *rancherv1.MachinePoolStatus) bool {
+	if a.ProvisioningFailures != b.ProvisioningFailures || len(a.LastFailures) != len(b.LastFailures) ||
+		len(a.Conditions) != len(b.Conditions) {
+		return false
+	}
+	for i := range a.Conditions {
+		if a.Conditions[i].Type != b.Conditions[i].Type || a.Conditions[i].Status != b.Conditions[i].Status ||
+			a.Conditions[i].Message != b.Conditions[i].Message {
+			return false
+		}
+	}
+	return true
+}
+
+func setBudgetCondition(status *rancherv1.MachinePoolStatus, policy *rancherv1.RKEMachinePoolRetryPolicy, exhausted bool) {
+	cond := genericcondition.GenericCondition{
+		Type:   failureBudgetExhaustedConditionType,
+		Status: corev1.ConditionFalse,
+	}
+	if exhausted {
+		cond.Status = corev1.ConditionTrue
+		cond.Reason = "FailureBudgetExhausted"
+		cond.Message = fmt.Sprintf("%d machine provisioning attempts failed, exceeding the failure budget of %d: failed machines are no longer retried or replaced",
+			status.ProvisioningFailures, *policy.FailureBudget)
+		if n := len(status.LastFailures); n > 0 {
+			cond.Message += ", last failure: " + status.LastFailures[n-1].Message
+		}
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i := range status.Conditions {
+		if status.Conditions[i].Type != cond.Type {
+			continue
+		}
+		if status.Conditions[i].Status != cond.Status {
+			status.Conditions[i].LastTransitionTime = now
+		}
+		status.Conditions[i].Status = cond.Status
+		status.Conditions[i].Reason = cond.Reason
+		status.Conditions[i].Message = cond.Message
+		status.Conditions[i].LastUpdateTime = now
+		return
+	}
+	cond.LastTransitionTime = now
+	cond.LastUpdateTime = now
+	status.Conditions = append(status.Conditions, cond)
+}
+
+func budgetExhausted(policy *rancherv1.RKEMachinePoolRetryPolicy, failures int) bool {
+	return policy != nil && policy.FailureBudget != nil && failures > *policy.FailureBudget
+}
+
+func isRetryable(policy *rancherv1.RKEMachinePoolRetryPolicy, failureMessage string) (bool, error) {
+	if len(policy.RetryableFailures) == 0 {
+		return true, nil
+	}
+	for _, expr := range policy.RetryableFailures {
+		re, err := compileFailureExpression(expr)
+		if err != nil {
+			return false, fmt.Errorf("invalid retryable failure expression %q: %w", expr, err)
+		}
+		if re.MatchString(failureMessage) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// compileFailureExpression returns the compiled failure expression, compiling it only the first time it is used.
+func compileFailureExpression(expr string) (*regexp.Regexp, error) {
+	if re, ok := failureExpressions.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	failureExpressions.Store(expr, re)
+	return re, nil
+}
+
+func maxAttempts(policy *rancherv1.RKEMachinePoolRetryPolicy) int {
+	if policy.MaxAttempts > 0 {
+		return policy.MaxAttempts
+	}
+	return defaultRetryMaxAttempts
+}
+
+func retryMessage(policy *rancherv1.RKEMachinePoolRetryPolicy, attempt int, variant string) string {
+	message := fmt.Sprintf("retrying machine provisioning (attempt %d", attempt)
+	if policy != nil {
+		message += fmt.Sprintf(" of %d", maxAttempts(policy))
+	}
+	message += ")"
+	if variant != "" {
+		message += " with machine config variant " + variant
+	}
+	return message
+}
+
+// retryBackoff returns the delay before the next attempt after the given failed attempt, doubling the initial backoff
+// for every attempt up to the maximum backoff.
+func retryBackoff(policy *rancherv1.RKEMachinePoolRetryPolicy, attempt int) time.Duration {
+	backoff, maxBackoff := defaultRetryBackoff, defaultRetryMaxBackoff
+	if policy.Backoff != nil && policy.Backoff.Duration > 0 {
+		backoff = policy.Backoff.Duration
+	}
+	if policy.MaxBackoff != nil && policy.MaxBackoff.Duration > 0 {
+		maxBackoff = policy.MaxBackoff.Duration
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
+
+func readyCondition(message string) rkev1.RKEMachineStatus {
+	return rkev1.RKEMachineStatus{
+		Conditions: []genericcondition.GenericCondition{
+			{
+				Type:    "Ready",
+				Status:  corev1.ConditionFalse,
+				Message: message,
+			},
+		},
+	}
+}
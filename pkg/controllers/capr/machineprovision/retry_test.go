@@ -0,0 +1,179 @@
+package machineprovision
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   rancherv1.RKEMachinePoolRetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		{
+			name:     "default first retry",
+			attempt:  1,
+			expected: 30 * time.Second,
+		},
+		{
+			name:     "default doubles",
+			attempt:  3,
+			expected: 2 * time.Minute,
+		},
+		{
+			name:     "default capped",
+			attempt:  10,
+			expected: 10 * time.Minute,
+		},
+		{
+			name: "configured",
+			policy: rancherv1.RKEMachinePoolRetryPolicy{
+				Backoff:    &metav1.Duration{Duration: time.Minute},
+				MaxBackoff: &metav1.Duration{Duration: 3 * time.Minute},
+			},
+			attempt:  3,
+			expected: 3 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, retryBackoff(&tt.policy, tt.attempt))
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	policy := &rancherv1.RKEMachinePoolRetryPolicy{}
+	retryable, err := isRetryable(policy, "anything")
+	assert.NoError(t, err)
+	assert.True(t, retryable)
+
+	policy.RetryableFailures = []string{"(?i)quota", "timeout"}
+	retryable, err = isRetryable(policy, "QuotaExceeded: instance limit reached")
+	assert.NoError(t, err)
+	assert.True(t, retryable)
+
+	retryable, err = isRetryable(policy, "invalid ami")
+	assert.NoError(t, err)
+	assert.False(t, retryable)
+
+	policy.RetryableFailures = []string{"("}
+	_, err = isRetryable(policy, "invalid ami")
+	assert.Error(t, err)
+}
+
+func TestBudgetCondition(t *testing.T) {
+	budget := 2
+	policy := &rancherv1.RKEMachinePoolRetryPolicy{FailureBudget: &budget}
+	status := &rancherv1.MachinePoolStatus{
+		Name:                 "pool",
+		ProvisioningFailures: 3,
+		LastFailures:         []rancherv1.MachineProvisioningFailure{{Machine: "m", Attempt: 1, Message: "quota"}},
+	}
+
+	assert.False(t, budgetExhausted(nil, 10))
+	assert.False(t, budgetExhausted(&rancherv1.RKEMachinePoolRetryPolicy{}, 10))
+	assert.False(t, budgetExhausted(policy, 2))
+	assert.True(t, budgetExhausted(policy, 3))
+
+	setBudgetCondition(status, policy, true)
+	assert.Len(t, status.Conditions, 1)
+	assert.Equal(t, corev1.ConditionTrue, status.Conditions[0].Status)
+	assert.Contains(t, status.Conditions[0].Message, "last failure: quota")
+
+	setBudgetCondition(status, policy, false)
+	assert.Len(t, status.Conditions, 1)
+	assert.Equal(t, corev1.ConditionFalse, status.Conditions[0].Status)
+	assert.Empty(t, status.Conditions[0].Message)
+
+	pool := &rancherv1.RKEMachinePool{Name: "pool", RetryPolicy: policy}
+	cluster := &rancherv1.Cluster{Status: rancherv1.ClusterStatus{MachinePools: []rancherv1.MachinePoolStatus{*status}}}
+	assert.True(t, poolBudgetExhausted(cluster, pool))
+	assert.False(t, poolBudgetExhausted(&rancherv1.Cluster{}, pool))
+}
+
+func TestCompileFailureExpression(t *testing.T) {
+	re, err := compileFailureExpression("(?i)quota")
+	assert.NoError(t, err)
+	again, err := compileFailureExpression("(?i)quota")
+	assert.NoError(t, err)
+	assert.Same(t, re, again)
+
+	_, err = compileFailureExpression("(")
+	assert.Error(t, err)
+}
+
+func TestCreateFailureWithheldWhileRetried(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	pool := &rancherv1.RKEMachinePool{
+		Name:        "workers",
+		RetryPolicy: &rancherv1.RKEMachinePoolRetryPolicy{MaxAttempts: 2},
+	}
+	failedPod := &corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					ExitCode: 1,
+					Message:  "RequestLimitExceeded",
+				}},
+			}},
+		},
+	}
+	h := &handler{}
+	infra := &infraObject{data: data.Object{}}
+
+	// CAPI copies failureReason and failureMessage of the infrastructure machine to the machine, marking it as failed
+	for attempt := 1; attempt <= 2; attempt++ {
+		status := getMachineStatusFromPod(failedPod, createJobConditionType)
+		withholdCreateFailure(pool, &status)
+		assert.NoError(t, reconcileStatus(infra.data, status))
+		assert.Empty(t, infra.data.String("status", "failureReason"))
+		assert.Equal(t, "RequestLimitExceeded", infra.data.String("status", "createFailureMessage"))
+
+		message, err := h.planRetry(infra, pool, attempt, now)
+		assert.NoError(t, err)
+		if attempt < 2 {
+			assert.Equal(t, "retrying machine provisioning (attempt 2 of 2) after failure: RequestLimitExceeded", message)
+			assert.Empty(t, infra.data.String("status", "failureReason"))
+			assert.Empty(t, infra.data.String("status", "failureMessage"))
+			assert.Equal(t, now.Add(30*time.Second).Format(time.RFC3339), infra.data.String("status", "nextRetryTime"))
+
+			// the retry starts
+			data.RemoveValue(infra.data, "status", "createFailureMessage")
+			data.RemoveValue(infra.data, "status", "nextRetryTime")
+			continue
+		}
+
+		assert.Empty(t, message)
+		assert.Equal(t, string(capierrors.CreateMachineError), infra.data.String("status", "failureReason"))
+		assert.Equal(t, "RequestLimitExceeded", infra.data.String("status", "failureMessage"))
+		assert.Empty(t, infra.data.String("status", "createFailureMessage"))
+	}
+}
+
+func TestWithholdCreateFailure(t *testing.T) {
+	failed := rkev1.RKEMachineStatus{
+		FailureReason:  string(capierrors.CreateMachineError),
+		FailureMessage: "failed",
+	}
+
+	status := failed
+	withholdCreateFailure(&rancherv1.RKEMachinePool{}, &status)
+	assert.Equal(t, failed, status, "machines of pools without a retry policy or variants are replaced")
+
+	status = failed
+	withholdCreateFailure(&rancherv1.RKEMachinePool{RetryPolicy: &rancherv1.RKEMachinePoolRetryPolicy{}}, &status)
+	assert.Equal(t, rkev1.RKEMachineStatus{CreateFailureMessage: "failed"}, status)
+}
@@ -0,0 +1,270 @@
+package machineprovision
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/rancher/wrangler/v3/pkg/data/convert"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+// defaultFallbackFailures match the capacity and quota errors of the common cloud providers, which are worth trying
+// with another instance type or zone.
+var defaultFallbackFailures = []string{
+	`(?i)insufficient\w*capacity`,
+	`(?i)capacity.*(not available|unavailable|exceeded)`,
+	`(?i)out of (host )?capacity`,
+	`(?i)unsupported.*(instance type|availability zone)`,
+	`ZONE_RESOURCE_POOL_EXHAUSTED`,
+	`(?i)SkuNotAvailable|ZonalAllocationFailed|AllocationFailed|OverconstrainedAllocationRequest`,
+	`(?i)quota\w*exceeded|exceeded.*quota`,
+}
+
+// assignVariant assigns a machine config variant to a new machine of a pool with variants, before its create job is
+// run. It returns false if the machine does not need a variant or already has one.
+func (h *handler) assignVariant(infra *infraObject, machine *capi.Machine) (bool, runtime.Object, error) {
+	if infra.data.String("status", "machineConfigVariant") != "" || infra.data.String("status", "jobName") != "" {
+		return false, infra.obj, nil
+	}
+
+	cluster, pool, err := h.getMachinePool(infra, machine)
+	if err != nil || pool == nil || len(pool.MachineConfigVariants) == 0 {
+		return false, infra.obj, err
+	}
+
+	counts, err := h.variantCounts(cluster, pool, machine)
+	if err != nil {
+		return true, infra.obj, err
+	}
+
+	variant := selectVariant(pool.MachineConfigVariants, counts)
+	if variant == "" {
+		return false, infra.obj, nil
+	}
+
+	logrus.Infof("[machineprovision] %s/%s: creating machine %s with machine config variant %s of machine pool %s", infra.meta.GetNamespace(), infra.meta.GetName(), machine.Name, variant, pool.Name)
+	infra.data.SetNested(variant, "status", "machineConfigVariant")
+	obj, err := h.dynamic.UpdateStatus(&unstructured.Unstructured{
+		Object: infra.data,
+	})
+	return true, obj, err
+}
+
+// variantCounts returns the number of machines of the pool, other than the given machine, per machine config variant.
+func (h *handler) variantCounts(cluster *rancherv1.Cluster, pool *rancherv1.RKEMachinePool, machine *capi.Machine) (map[string]int, error) {
+	machines, err := h.machineCache.List(cluster.Namespace, labels.SelectorFromSet(labels.Set{
+		capi.ClusterNameLabel:        machine.Labels[capi.ClusterNameLabel],
+		capr.RKEMachinePoolNameLabel: pool.Name,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for _, m := range machines {
+		if m.Name == machine.Name || !m.DeletionTimestamp.IsZero() {
+			continue
+		}
+		ref := m.Spec.InfrastructureRef
+		infraMachine, err := h.dynamic.Get(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind), m.Namespace, ref.Name)
+		if apierrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		d, err := data.Convert(infraMachine)
+		if err != nil {
+			return nil, err
+		}
+		if variant := d.String("status", "machineConfigVariant"); variant != "" {
+			counts[variant]++
+		}
+	}
+	return counts, nil
+}
+
+// applyVariant returns the driver arguments of the machine with the fields of the machine config of its variant
+// overriding the machine config of the pool.
+func (h *handler) applyVariant(infra *infraObject, args data.Object) (data.Object, error) {
+	variantName := infra.data.String("status", "machineConfigVariant")
+	if variantName == "" {
+		return args, nil
+	}
+
+	machine, err := h.machineCache.Get(infra.meta.GetNamespace(), infra.meta.GetLabels()[CapiMachineName])
+	if err != nil {
+		return nil, err
+	}
+	_, pool, err := h.getMachinePool(infra, machine)
+	if err != nil {
+		return nil, err
+	}
+	if pool == nil || pool.NodeConfig == nil {
+		return args, nil
+	}
+
+	var variant *rancherv1.RKEMachineConfigVariant
+	for i := range pool.MachineConfigVariants {
+		if pool.MachineConfigVariants[i].Name == variantName {
+			variant = &pool.MachineConfigVariants[i]
+		}
+	}
+	if variant == nil || variant.NodeConfig == nil {
+		return nil, fmt.Errorf("machine config variant %s of machine pool %s does not exist", variantName, pool.Name)
+	}
+
+	apiVersion, kind := variant.NodeConfig.APIVersion, variant.NodeConfig.Kind
+	if apiVersion == "" {
+		apiVersion = capr.DefaultMachineConfigAPIVersion
+	}
+	if kind == "" {
+		kind = pool.NodeConfig.Kind
+	}
+	if kind != pool.NodeConfig.Kind {
+		return nil, fmt.Errorf("machine config variant %s of machine pool %s is a %s, expected a %s", variantName, pool.Name, kind, pool.NodeConfig.Kind)
+	}
+
+	nodeConfig, err := h.dynamic.Get(schema.FromAPIVersionAndKind(apiVersion, kind), infra.meta.GetNamespace(), variant.NodeConfig.Name)
+	if err != nil {
+		return nil, err
+	}
+	variantData, err := data.Convert(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec v3.DynamicSchemaSpec
+	if err := json.Unmarshal([]byte(pool.DynamicSchemaSpec), &spec); err != nil {
+		return nil, err
+	}
+
+	result := data.Object{}
+	for k, v := range args {
+		result[k] = v
+	}
+	for k, v := range variantData {
+		if _, ok := spec.ResourceFields[k]; ok {
+			result[k] = v
+		}
+	}
+	return result, nil
+}
+
+// fallbackVariant switches a machine whose create job failed with a capacity error to the next machine config variant
+// it has not failed with, recording the failed variant. It is called while the failure of the machine is withheld, so
+// the CAPI machine is only marked as failed once no variant is left. It returns the new variant, or an empty string if
+// the machine has no variant to fall back to.
+func (h *handler) fallbackVariant(infra *infraObject, pool *rancherv1.RKEMachinePool, failureMessage string) (string, error) {
+	current := infra.data.String("status", "machineConfigVariant")
+	if current == "" {
+		return "", nil
+	}
+
+	capacity, err := isCapacityFailure(pool, failureMessage)
+	if err != nil || !capacity {
+		return "", err
+	}
+
+	failed := convert.ToStringSlice(data.GetValueN(infra.data, "status", "failedMachineConfigVariants"))
+	next := nextVariant(pool.MachineConfigVariants, current, failed)
+	if next == "" {
+		return "", nil
+	}
+
+	var failedVariants []interface{}
+	for _, name := range append(failed, current) {
+		failedVariants = append(failedVariants, name)
+	}
+	infra.data.SetNested(failedVariants, "status", "failedMachineConfigVariants")
+	infra.data.SetNested(next, "status", "machineConfigVariant")
+	return next, nil
+}
+
+// selectVariant returns the variant of a new machine, given the number of machines per variant: the variant that is
+// furthest below its share of the machines by weight, preferring the variant with the lowest priority on a tie.
+func selectVariant(variants []rancherv1.RKEMachineConfigVariant, counts map[string]int) string {
+	ordered := byPriority(variants)
+	selected := -1
+	for i, variant := range ordered {
+		weight := variantWeight(variant)
+		if weight == 0 {
+			continue
+		}
+		// compare (count+1)/weight without dividing
+		if selected == -1 ||
+			(counts[variant.Name]+1)*variantWeight(ordered[selected]) < (counts[ordered[selected].Name]+1)*weight {
+			selected = i
+		}
+	}
+
+	if selected == -1 {
+		// every variant is fallback only, start with the first one
+		if len(ordered) > 0 {
+			return ordered[0].Name
+		}
+		return ""
+	}
+	return ordered[selected].Name
+}
+
+// nextVariant returns the variant with the lowest priority that is neither the current variant nor one of the failed
+// variants.
+func nextVariant(variants []rancherv1.RKEMachineConfigVariant, current string, failed []string) string {
+	tried := map[string]bool{current: true}
+	for _, name := range failed {
+		tried[name] = true
+	}
+	for _, variant := range byPriority(variants) {
+		if !tried[variant.Name] {
+			return variant.Name
+		}
+	}
+	return ""
+}
+
+func byPriority(variants []rancherv1.RKEMachineConfigVariant) []rancherv1.RKEMachineConfigVariant {
+	result := append([]rancherv1.RKEMachineConfigVariant{}, variants...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Priority < result[j].Priority
+	})
+	return result
+}
+
+func variantWeight(variant rancherv1.RKEMachineConfigVariant) int {
+	if variant.Weight == nil {
+		return 1
+	}
+	if *variant.Weight < 0 {
+		return 0
+	}
+	return *variant.Weight
+}
+
+func isCapacityFailure(pool *rancherv1.RKEMachinePool, failureMessage string) (bool, error) {
+	exprs := pool.MachineConfigFallbackFailures
+	if len(exprs) == 0 {
+		exprs = defaultFallbackFailures
+	}
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return false, fmt.Errorf("invalid machine config fallback failure expression %q: %w", expr, err)
+		}
+		if re.MatchString(failureMessage) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,120 @@
+package machineprovision
+
+import (
+	"testing"
+	"time"
+
+	rancherv1 "github.com/rancher/rancher/pkg/apis/provisioning.cattle.io/v1"
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/data"
+	"github.com/stretchr/testify/assert"
+	capierrors "sigs.k8s.io/cluster-api/errors"
+)
+
+func TestSelectVariant(t *testing.T) {
+	weight := func(w int) *int { return &w }
+	variants := []rancherv1.RKEMachineConfigVariant{
+		{Name: "zone-b", Weight: weight(1), Priority: 1},
+		{Name: "zone-a", Weight: weight(2)},
+		{Name: "spot", Weight: weight(0)},
+	}
+
+	counts := map[string]int{}
+	var selected []string
+	for i := 0; i < 6; i++ {
+		variant := selectVariant(variants, counts)
+		counts[variant]++
+		selected = append(selected, variant)
+	}
+	assert.Equal(t, []string{"zone-a", "zone-a", "zone-b", "zone-a", "zone-a", "zone-b"}, selected)
+	assert.Equal(t, map[string]int{"zone-a": 4, "zone-b": 2}, counts)
+
+	// scaling up after zone-a lost machines catches up on zone-a
+	assert.Equal(t, "zone-a", selectVariant(variants, map[string]int{"zone-a": 1, "zone-b": 3}))
+
+	assert.Equal(t, "spot", selectVariant([]rancherv1.RKEMachineConfigVariant{{Name: "spot", Weight: weight(0)}}, nil))
+	assert.Equal(t, "", selectVariant(nil, nil))
+}
+
+func TestNextVariant(t *testing.T) {
+	variants := []rancherv1.RKEMachineConfigVariant{
+		{Name: "c", Priority: 2},
+		{Name: "a"},
+		{Name: "b"},
+	}
+
+	assert.Equal(t, "b", nextVariant(variants, "a", nil))
+	assert.Equal(t, "c", nextVariant(variants, "b", []string{"a"}))
+	assert.Equal(t, "a", nextVariant(variants, "c", nil))
+	assert.Equal(t, "", nextVariant(variants, "c", []string{"a", "b"}))
+}
+
+func TestIsCapacityFailure(t *testing.T) {
+	pool := &rancherv1.RKEMachinePool{}
+	for _, message := range []string{
+		"Error creating machine: Error in driver during machine creation: InsufficientInstanceCapacity: We currently do not have sufficient m5.large capacity in the Availability Zone you requested (us-east-1a)",
+		"googleapi: Error 503: The zone 'projects/p/zones/us-central1-a' does not have enough resources available to fulfill the request. ZONE_RESOURCE_POOL_EXHAUSTED",
+		"compute.VirtualMachinesClient#CreateOrUpdate: Failure sending request: Code=\"SkuNotAvailable\"",
+		"Error launching source instance: Unsupported: Your requested instance type (m7g.large) is not supported in your requested Availability Zone",
+	} {
+		capacity, err := isCapacityFailure(pool, message)
+		assert.NoError(t, err)
+		assert.True(t, capacity, message)
+	}
+
+	capacity, err := isCapacityFailure(pool, "InvalidAMIID.NotFound: The image id '[ami-123]' does not exist")
+	assert.NoError(t, err)
+	assert.False(t, capacity)
+
+	pool.MachineConfigFallbackFailures = []string{"(?i)no hosts available"}
+	capacity, err = isCapacityFailure(pool, "No hosts available in zone")
+	assert.NoError(t, err)
+	assert.True(t, capacity)
+	capacity, err = isCapacityFailure(pool, "InsufficientInstanceCapacity")
+	assert.NoError(t, err)
+	assert.False(t, capacity)
+
+	pool.MachineConfigFallbackFailures = []string{"("}
+	_, err = isCapacityFailure(pool, "anything")
+	assert.Error(t, err)
+}
+
+func TestFallbackVariantWhileFailureWithheld(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	pool := &rancherv1.RKEMachinePool{
+		Name: "workers",
+		MachineConfigVariants: []rancherv1.RKEMachineConfigVariant{
+			{Name: "a", Priority: 0},
+			{Name: "b", Priority: 1},
+		},
+	}
+	h := &handler{}
+	infra := &infraObject{data: data.Object{}}
+	infra.data.SetNested("a", "status", "machineConfigVariant")
+	capacityFailure := rkev1.RKEMachineStatus{
+		FailureReason:  string(capierrors.CreateMachineError),
+		FailureMessage: "InsufficientInstanceCapacity",
+	}
+
+	status := capacityFailure
+	withholdCreateFailure(pool, &status)
+	assert.NoError(t, reconcileStatus(infra.data, status))
+	message, err := h.planRetry(infra, pool, 1, now)
+	assert.NoError(t, err)
+	assert.Equal(t, "falling back to machine config variant b after failure: InsufficientInstanceCapacity", message)
+	assert.Equal(t, "b", infra.data.String("status", "machineConfigVariant"))
+	assert.Empty(t, infra.data.String("status", "failureReason"))
+
+	// the variant fallback starts, and fails with the last variant
+	data.RemoveValue(infra.data, "status", "createFailureMessage")
+	data.RemoveValue(infra.data, "status", "nextRetryTime")
+	status = capacityFailure
+	withholdCreateFailure(pool, &status)
+	assert.NoError(t, reconcileStatus(infra.data, status))
+	assert.Empty(t, infra.data.String("status", "failureReason"))
+	message, err = h.planRetry(infra, pool, 2, now)
+	assert.NoError(t, err)
+	assert.Empty(t, message)
+	assert.Equal(t, string(capierrors.CreateMachineError), infra.data.String("status", "failureReason"))
+	assert.Equal(t, "InsufficientInstanceCapacity", infra.data.String("status", "failureMessage"))
+}
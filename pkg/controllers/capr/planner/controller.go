@@ -10,6 +10,7 @@
 	"github.com/rancher/rancher/pkg/capr"
 	caprplanner "github.com/rancher/rancher/pkg/capr/planner"
 	v1 "github.com/rancher/rancher/pkg/generated/controllers/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/metrics"
 	"github.com/rancher/rancher/pkg/wrangler"
 	"github.com/rancher/wrangler/v3/pkg/generic"
 	"github.com/rancher/wrangler/v3/pkg/relatedresource"
@@ -89,7 +90,9 @@ func (h *handler) OnChange(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPla
 	status.ObservedGeneration = cp.Generation
 
 	logrus.Debugf("[planner] rkecluster %s/%s: calling planner process", cp.Namespace, cp.Name)
+	start := time.Now()
 	status, err := h.planner.Process(cp, status)
+	observeMetrics(cp, status, err, time.Since(start))
 	if err != nil {
 		// planner.Process can encounter 3 types of errors:
 		// * planner.errWaiting - This is an error that indicates we are waiting for something, and will not re-enqueue the object
@@ -134,3 +137,46 @@ func (h *handler) OnChange(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPla
 	capr.Reconciled.Reason(&status, "")
 	return status, nil
 }
+
+// observeMetrics records the result of a planner reconcile and the phases of the etcd snapshot and encryption key
+// rotation operations of the control plane.
+func observeMetrics(cp *rkev1.RKEControlPlane, status rkev1.RKEControlPlaneStatus, err error, duration time.Duration) {
+	clusterID := cp.Spec.ManagementClusterName
+	if clusterID == "" {
+		return
+	}
+
+	result := metrics.PlannerResultSuccess
+	switch {
+	case err == nil:
+	case caprplanner.IsErrWaiting(err):
+		result = metrics.PlannerResultWaiting
+	case errors.Is(err, generic.ErrSkip):
+		result = metrics.PlannerResultSkipped
+	default:
+		result = metrics.PlannerResultFailure
+	}
+	metrics.ObservePlannerReconcile(clusterID, result, duration)
+
+	metrics.ObservePlannerOperationPhase(clusterID, "etcd-snapshot-create", string(status.ETCDSnapshotCreatePhase), etcdSnapshotResult(status.ETCDSnapshotCreatePhase))
+	metrics.ObservePlannerOperationPhase(clusterID, "etcd-snapshot-restore", string(status.ETCDSnapshotRestorePhase), etcdSnapshotResult(status.ETCDSnapshotRestorePhase))
+
+	var encryptionKeysResult string
+	switch status.RotateEncryptionKeysPhase {
+	case rkev1.RotateEncryptionKeysPhaseDone:
+		encryptionKeysResult = metrics.PlannerResultSuccess
+	case rkev1.RotateEncryptionKeysPhaseFailed:
+		encryptionKeysResult = metrics.PlannerResultFailure
+	}
+	metrics.ObservePlannerOperationPhase(clusterID, "rotate-encryption-keys", string(status.RotateEncryptionKeysPhase), encryptionKeysResult)
+}
+
+func etcdSnapshotResult(phase rkev1.ETCDSnapshotPhase) string {
+	switch phase {
+	case rkev1.ETCDSnapshotPhaseFinished:
+		return metrics.PlannerResultSuccess
+	case rkev1.ETCDSnapshotPhaseFailed:
+		return metrics.PlannerResultFailure
+	}
+	return ""
+}
@@ -0,0 +1,601 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/rancher/rancher/pkg/api/steve/catalog/types"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/kstatus"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/repo"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	appInstallByRelease      = "appInstallByRelease"
+	appInstallByOperation    = "appInstallByOperation"
+	appInstallByRepo         = "appInstallByRepo"
+	appInstallByValuesSource = "appInstallByValuesSource"
+
+	// appInstallReapplyInterval is the minimum time between two helm operations applying the same desired state, so
+	// that failing operations are not retried in a loop and the App of a successful operation has time to be updated.
+	appInstallReapplyInterval = 5 * time.Minute
+	appInstallDefaultTimeout  = 5 * time.Minute
+
+	// appInstallPolicyName is the name of the ValidatingAdmissionPolicy allowing only the users that can impersonate
+	// the service account of an AppInstall to create, change or uninstall it.
+	appInstallPolicyName = "appinstalls.catalog.cattle.io"
+)
+
+// AppInstallOperations starts the helm operations converging the releases of AppInstalls.
+type AppInstallOperations interface {
+	Upgrade(ctx context.Context, user user.Info, namespace, name string, options io.Reader, imageOverride string) (*catalog.Operation, error)
+	Uninstall(ctx context.Context, user user.Info, namespace, name string, options io.Reader, imageOverride string) (*catalog.Operation, error)
+}
+
+// AppInstallContent provides the index of the ClusterRepos the charts of AppInstalls are installed from.
+type AppInstallContent interface {
+	Index(namespace, name, targetK8sVersion string, skipFilter bool) (*repo.IndexFile, error)
+}
+
+type appInstallHandler struct {
+	ctx         context.Context
+	operations  AppInstallOperations
+	content     AppInstallContent
+	appInstalls catalogcontrollers.AppInstallController
+	apps        catalogcontrollers.AppCache
+	ops         catalogcontrollers.OperationCache
+	secrets     corecontrollers.SecretCache
+	configMaps  corecontrollers.ConfigMapCache
+
+	// policyErr is the reason the admission policy of AppInstalls could not be installed, in which case no helm
+	// operation is started for AppInstalls.
+	policyErr error
+}
+
+func RegisterAppInstalls(ctx context.Context,
+	apply apply.Apply,
+	operations AppInstallOperations,
+	content AppInstallContent,
+	appInstalls catalogcontrollers.AppInstallController,
+	apps catalogcontrollers.AppController,
+	ops catalogcontrollers.OperationController,
+	clusterRepos catalogcontrollers.ClusterRepoController,
+	secrets corecontrollers.SecretController,
+	configMaps corecontrollers.ConfigMapController,
+) {
+	h := appInstallHandler{
+		ctx:         ctx,
+		operations:  operations,
+		content:     content,
+		appInstalls: appInstalls,
+		apps:        apps.Cache(),
+		ops:         ops.Cache(),
+		secrets:     secrets.Cache(),
+		configMaps:  configMaps.Cache(),
+	}
+
+	// the helm operations of an AppInstall run with the permissions of its service account, which must not be usable
+	// by the users that cannot impersonate it
+	if err := apply.WithSetID("helm-app-install").ApplyObjects(appInstallPolicy()...); err != nil {
+		h.policyErr = fmt.Errorf("failed to install the admission policy %s: %w", appInstallPolicyName, err)
+		logrus.Errorf("[helm] %v", h.policyErr)
+	}
+
+	appInstalls.Cache().AddIndexer(appInstallByRelease, func(obj *catalog.AppInstall) ([]string, error) {
+		return []string{obj.Namespace + "/" + releaseName(obj)}, nil
+	})
+	appInstalls.Cache().AddIndexer(appInstallByOperation, func(obj *catalog.AppInstall) ([]string, error) {
+		if obj.Status.OperationName == "" {
+			return nil, nil
+		}
+		return []string{obj.Namespace + "/" + obj.Status.OperationName}, nil
+	})
+	appInstalls.Cache().AddIndexer(appInstallByRepo, func(obj *catalog.AppInstall) ([]string, error) {
+		return []string{obj.Spec.RepoName}, nil
+	})
+	appInstalls.Cache().AddIndexer(appInstallByValuesSource, func(obj *catalog.AppInstall) ([]string, error) {
+		var result []string
+		for _, source := range obj.Spec.ValuesFrom {
+			if source.SecretKeyRef != nil {
+				result = append(result, "Secret/"+obj.Namespace+"/"+source.SecretKeyRef.Name)
+			}
+			if source.ConfigMapKeyRef != nil {
+				result = append(result, "ConfigMap/"+obj.Namespace+"/"+source.ConfigMapKeyRef.Name)
+			}
+		}
+		return result, nil
+	})
+
+	catalogcontrollers.RegisterAppInstallStatusHandler(ctx, appInstalls, "", "helm-app-install", h.onChange)
+	appInstalls.OnRemove(ctx, "helm-app-install-remove", h.onRemove)
+
+	relatedresource.Watch(ctx, "helm-app-install-app", h.resolver(appInstallByRelease, ""), appInstalls, apps)
+	relatedresource.Watch(ctx, "helm-app-install-operation", h.resolver(appInstallByOperation, ""), appInstalls, ops)
+	relatedresource.Watch(ctx, "helm-app-install-repo", h.resolver(appInstallByRepo, ""), appInstalls, clusterRepos)
+	relatedresource.Watch(ctx, "helm-app-install-secret", h.resolver(appInstallByValuesSource, "Secret/"), appInstalls, secrets)
+	relatedresource.Watch(ctx, "helm-app-install-configmap", h.resolver(appInstallByValuesSource, "ConfigMap/"), appInstalls, configMaps)
+}
+
+// resolver returns a relatedresource.Resolver enqueuing the AppInstalls indexed with the key of the changed object.
+func (h *appInstallHandler) resolver(indexName, prefix string) relatedresource.Resolver {
+	return func(namespace, name string, _ runtime.Object) ([]relatedresource.Key, error) {
+		key := name
+		if namespace != "" {
+			key = namespace + "/" + name
+		}
+		appInstalls, err := h.appInstalls.Cache().GetByIndex(indexName, prefix+key)
+		if err != nil {
+			return nil, err
+		}
+		var result []relatedresource.Key
+		for _, appInstall := range appInstalls {
+			result = append(result, relatedresource.NewKey(appInstall.Namespace, appInstall.Name))
+		}
+		return result, nil
+	}
+}
+
+// onChange converges the release of the AppInstall to its desired state by starting a helm upgrade operation whenever
+// the release, as reported by its App, drifts from it.
+func (h *appInstallHandler) onChange(obj *catalog.AppInstall, status catalog.AppInstallStatus) (catalog.AppInstallStatus, error) {
+	if obj.DeletionTimestamp != nil {
+		return status, nil
+	}
+	status.ObservedGeneration = obj.Generation
+	if h.policyErr != nil {
+		return setNotInstalled(status, h.policyErr.Error()), nil
+	}
+
+	var op *catalog.Operation
+	if status.OperationName != "" {
+		var err error
+		op, err = h.ops.Get(obj.Namespace, status.OperationName)
+		if apierrors.IsNotFound(err) {
+			op = nil
+		} else if err != nil {
+			return status, err
+		}
+	}
+	if op != nil && !kstatus.Reconciling.IsFalse(op) {
+		catalog.AppInstallInstalled.Unknown(&status)
+		catalog.AppInstallInstalled.Message(&status, fmt.Sprintf("waiting for operation %s", op.Name))
+		return status, nil
+	}
+
+	index, err := h.content.Index("", obj.Spec.RepoName, "", false)
+	if err != nil {
+		return status, err
+	}
+	version, err := resolveVersion(index.Entries[obj.Spec.Chart], obj.Spec.Version, status.Version, obj.Spec.AutoUpgrade)
+	if err != nil {
+		return setNotInstalled(status, err.Error()), nil
+	}
+	values, err := h.desiredValues(obj)
+	if err != nil {
+		return setNotInstalled(status, err.Error()), err
+	}
+	hash, err := desiredHash(obj.Spec.Chart, version, values)
+	if err != nil {
+		return status, err
+	}
+	status.Version = version
+
+	app, err := h.apps.Get(obj.Namespace, releaseName(obj))
+	if apierrors.IsNotFound(err) {
+		app = nil
+	} else if err != nil {
+		return status, err
+	}
+	if app != nil && app.Spec.Info != nil && isPending(app.Spec.Info.Status) {
+		catalog.AppInstallInstalled.Unknown(&status)
+		catalog.AppInstallInstalled.Message(&status, fmt.Sprintf("release is %s", app.Spec.Info.Status))
+		return status, nil
+	}
+
+	drift, err := h.driftReason(obj, app, version, values)
+	if err != nil {
+		return status, err
+	}
+	if drift == "" {
+		catalog.AppInstallDrifted.False(&status)
+		catalog.AppInstallDrifted.Message(&status, "")
+		catalog.AppInstallInstalled.True(&status)
+		catalog.AppInstallInstalled.Message(&status, "")
+		return status, nil
+	}
+	catalog.AppInstallDrifted.True(&status)
+	catalog.AppInstallDrifted.Message(&status, drift)
+
+	// the same desired state is not applied again right away, whether the last operation failed or its App is not
+	// updated yet
+	if op != nil && status.OperationHash == hash {
+		if wait := time.Until(op.CreationTimestamp.Add(appInstallReapplyInterval)); wait > 0 {
+			message := fmt.Sprintf("operation %s did not converge the release", op.Name)
+			if kstatus.Stalled.IsTrue(op) {
+				message = fmt.Sprintf("operation %s failed: %s", op.Name, kstatus.Stalled.GetMessage(op))
+			}
+			h.appInstalls.EnqueueAfter(obj.Namespace, obj.Name, wait)
+			return setNotInstalled(status, message), nil
+		}
+	}
+
+	op, err = h.upgrade(obj, version, values)
+	if err != nil {
+		return setNotInstalled(status, err.Error()), err
+	}
+	status.OperationName = op.Name
+	status.OperationHash = hash
+	catalog.AppInstallInstalled.Unknown(&status)
+	catalog.AppInstallInstalled.Message(&status, fmt.Sprintf("waiting for operation %s", op.Name))
+	return status, nil
+}
+
+// onRemove uninstalls the release of the AppInstall if requested.
+func (h *appInstallHandler) onRemove(_ string, obj *catalog.AppInstall) (*catalog.AppInstall, error) {
+	if !obj.Spec.UninstallOnDelete || h.policyErr != nil {
+		return obj, nil
+	}
+	app, err := h.apps.Get(obj.Namespace, releaseName(obj))
+	if apierrors.IsNotFound(err) {
+		return obj, nil
+	} else if err != nil {
+		return obj, err
+	}
+	if app.Spec.Info != nil && app.Spec.Info.Status == catalog.StatusUninstalling {
+		return obj, nil
+	}
+
+	uninstall, err := json.Marshal(types.ChartUninstallAction{
+		Timeout: &metav1.Duration{Duration: operationTimeout(obj)},
+	})
+	if err != nil {
+		return obj, err
+	}
+	_, err = h.operations.Uninstall(h.ctx, operationUser(obj), app.Namespace, app.Name, bytes.NewReader(uninstall), "")
+	return obj, err
+}
+
+// upgrade starts a helm upgrade operation installing or upgrading the release to the given version and values.
+func (h *appInstallHandler) upgrade(obj *catalog.AppInstall, version string, values map[string]interface{}) (*catalog.Operation, error) {
+	upgrade, err := json.Marshal(types.ChartUpgradeAction{
+		Timeout:    &metav1.Duration{Duration: operationTimeout(obj)},
+		Wait:       true,
+		Install:    true,
+		MaxHistory: 5,
+		Namespace:  obj.Namespace,
+		Charts: []types.ChartUpgrade{
+			{
+				ChartName:   obj.Spec.Chart,
+				Version:     version,
+				ReleaseName: releaseName(obj),
+				Values:      values,
+				ResetValues: true,
+				Annotations: map[string]string{
+					"catalog.cattle.io/ui-source-repo-type": "cluster",
+					"catalog.cattle.io/ui-source-repo":      obj.Spec.RepoName,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.operations.Upgrade(h.ctx, operationUser(obj), "", obj.Spec.RepoName, bytes.NewReader(upgrade), "")
+}
+
+// driftReason returns why the release reported by the App differs from the desired chart, version and values, or an
+// empty string if it does not.
+func (h *appInstallHandler) driftReason(obj *catalog.AppInstall, app *catalog.App, version string, values map[string]interface{}) (string, error) {
+	if app == nil || app.Spec.Info == nil || app.Spec.Info.Status == catalog.StatusUninstalled {
+		return fmt.Sprintf("release %s is not installed", releaseName(obj)), nil
+	}
+	if app.Spec.Info.Status != catalog.StatusDeployed {
+		return fmt.Sprintf("release %s is %s", releaseName(obj), app.Spec.Info.Status), nil
+	}
+	if app.Spec.Chart == nil || app.Spec.Chart.Metadata == nil || app.Spec.Chart.Metadata.Name != obj.Spec.Chart {
+		return fmt.Sprintf("release %s is not installed from chart %s", releaseName(obj), obj.Spec.Chart), nil
+	}
+	if app.Spec.Chart.Metadata.Version != version {
+		return fmt.Sprintf("release %s has chart version %s instead of %s", releaseName(obj), app.Spec.Chart.Metadata.Version, version), nil
+	}
+
+	config, err := h.releaseConfig(app)
+	if err != nil {
+		return "", err
+	}
+	if equal, err := valuesEqual(config, values); err != nil {
+		return "", err
+	} else if !equal {
+		return fmt.Sprintf("values of release %s differ from the desired values", releaseName(obj)), nil
+	}
+	return "", nil
+}
+
+// releaseConfig returns the values of the release from the helm release Secret or ConfigMap owning the App.
+func (h *appInstallHandler) releaseConfig(app *catalog.App) (map[string]interface{}, error) {
+	obj, err := releaseStorage(h.secrets, h.configMaps, app)
+	if err != nil {
+		return nil, err
+	}
+	return helm.ReleaseConfig(obj)
+}
+
+// desiredValues merges the values documents taken from ValuesFrom in order, then the values of the AppInstall.
+func (h *appInstallHandler) desiredValues(obj *catalog.AppInstall) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, source := range obj.Spec.ValuesFrom {
+		data, err := h.valuesSource(obj.Namespace, source)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			continue
+		}
+		document := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return nil, fmt.Errorf("failed to parse values: %w", err)
+		}
+		mergeValues(values, document)
+	}
+	mergeValues(values, obj.Spec.Values)
+	return normalizeValues(values)
+}
+
+// valuesSource returns the values document referenced by the source, or nil if it is optional and missing.
+func (h *appInstallHandler) valuesSource(namespace string, source catalog.AppInstallValuesSource) ([]byte, error) {
+	switch {
+	case source.SecretKeyRef != nil:
+		ref := source.SecretKeyRef
+		secret, err := h.secrets.Get(namespace, ref.Name)
+		if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		data, ok := secret.Data[ref.Key]
+		if !ok && !isOptional(ref.Optional) {
+			return nil, fmt.Errorf("secret %s/%s has no key %s", namespace, ref.Name, ref.Key)
+		}
+		return data, nil
+	case source.ConfigMapKeyRef != nil:
+		ref := source.ConfigMapKeyRef
+		configMap, err := h.configMaps.Get(namespace, ref.Name)
+		if apierrors.IsNotFound(err) && isOptional(ref.Optional) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if data, ok := configMap.Data[ref.Key]; ok {
+			return []byte(data), nil
+		}
+		data, ok := configMap.BinaryData[ref.Key]
+		if !ok && !isOptional(ref.Optional) {
+			return nil, fmt.Errorf("configmap %s/%s has no key %s", namespace, ref.Name, ref.Key)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("values source must reference a secret or a configmap key")
+}
+
+// resolveVersion returns the version of the chart to install: the newest version matching the constraint, or the
+// current version if it still matches and auto upgrades are disabled.
+func resolveVersion(versions repo.ChartVersions, constraint, current string, autoUpgrade bool) (string, error) {
+	if len(versions) == 0 {
+		return "", fmt.Errorf("chart not found")
+	}
+	if constraint == "" {
+		constraint = "*"
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %s: %w", constraint, err)
+	}
+
+	var newest *semver.Version
+	for _, version := range versions {
+		v, err := semver.NewVersion(version.Version)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if !autoUpgrade && version.Version == current {
+			return current, nil
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	if newest == nil {
+		return "", fmt.Errorf("no version of the chart matches %s", constraint)
+	}
+	return newest.Original(), nil
+}
+
+// mergeValues merges src into dst recursively, src taking precedence.
+func mergeValues(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeValues(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// normalizeValues returns the values as decoded from JSON, so that values decoded from YAML, JSON or built in Go compare
+// equal.
+func normalizeValues(values map[string]interface{}) (map[string]interface{}, error) {
+	if values == nil {
+		return map[string]interface{}{}, nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func valuesEqual(a, b map[string]interface{}) (bool, error) {
+	a, err := normalizeValues(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = normalizeValues(b)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(a, b), nil
+}
+
+// desiredHash identifies the chart, version and values the release is converging to.
+func desiredHash(chart, version string, values map[string]interface{}) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s", chart, version, data)))
+	return hex.EncodeToString(digest[:]), nil
+}
+
+func setNotInstalled(status catalog.AppInstallStatus, message string) catalog.AppInstallStatus {
+	catalog.AppInstallInstalled.False(&status)
+	catalog.AppInstallInstalled.Message(&status, message)
+	return status
+}
+
+func isPending(status catalog.Status) bool {
+	return status == catalog.StatusPendingInstall || status == catalog.StatusPendingUpgrade ||
+		status == catalog.StatusPendingRollback || status == catalog.StatusUninstalling
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
+
+func releaseName(obj *catalog.AppInstall) string {
+	if obj.Spec.ReleaseName != "" {
+		return obj.Spec.ReleaseName
+	}
+	return obj.Name
+}
+
+func operationTimeout(obj *catalog.AppInstall) time.Duration {
+	if obj.Spec.Timeout != nil && obj.Spec.Timeout.Duration > 0 {
+		return obj.Spec.Timeout.Duration
+	}
+	return appInstallDefaultTimeout
+}
+
+// appInstallPolicy returns the ValidatingAdmissionPolicy, and its binding, refusing AppInstalls created, changed or
+// deleted with UninstallOnDelete by users that cannot impersonate their service account.
+func appInstallPolicy() []runtime.Object {
+	failurePolicy := admissionregistrationv1.Fail
+	reason := metav1.StatusReasonForbidden
+	return []runtime.Object{
+		&admissionregistrationv1.ValidatingAdmissionPolicy{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+				Kind:       "ValidatingAdmissionPolicy",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: appInstallPolicyName,
+			},
+			Spec: admissionregistrationv1.ValidatingAdmissionPolicySpec{
+				FailurePolicy: &failurePolicy,
+				MatchConstraints: &admissionregistrationv1.MatchResources{
+					ResourceRules: []admissionregistrationv1.NamedRuleWithOperations{{
+						RuleWithOperations: admissionregistrationv1.RuleWithOperations{
+							Operations: []admissionregistrationv1.OperationType{
+								admissionregistrationv1.Create,
+								admissionregistrationv1.Update,
+								admissionregistrationv1.Delete,
+							},
+							Rule: admissionregistrationv1.Rule{
+								APIGroups:   []string{catalog.SchemeGroupVersion.Group},
+								APIVersions: []string{catalog.SchemeGroupVersion.Version},
+								Resources:   []string{"appinstalls"},
+							},
+						},
+					}},
+				},
+				MatchConditions: []admissionregistrationv1.MatchCondition{{
+					// updates of the status or the metadata only, and deletions keeping the release, start no operation
+					Name: "starts-operations",
+					Expression: "request.operation == 'CREATE' || " +
+						"(request.operation == 'UPDATE' && object.spec != oldObject.spec) || " +
+						"(request.operation == 'DELETE' && has(oldObject.spec.uninstallOnDelete) && oldObject.spec.uninstallOnDelete)",
+				}},
+				Variables: []admissionregistrationv1.Variable{
+					{
+						Name:       "appInstall",
+						Expression: "request.operation == 'DELETE' ? oldObject : object",
+					},
+					{
+						Name: "serviceAccount",
+						Expression: "has(variables.appInstall.spec.serviceAccountName) && variables.appInstall.spec.serviceAccountName != '' ? " +
+							"variables.appInstall.spec.serviceAccountName : 'default'",
+					},
+				},
+				Validations: []admissionregistrationv1.Validation{{
+					Expression: "authorizer.group('').resource('serviceaccounts').namespace(variables.appInstall.metadata.namespace)" +
+						".name(variables.serviceAccount).check('impersonate').allowed()",
+					MessageExpression: "'user ' + request.userInfo.username + ' cannot impersonate service account ' + " +
+						"variables.serviceAccount + ' of the AppInstall'",
+					Reason: &reason,
+				}},
+			},
+		},
+		&admissionregistrationv1.ValidatingAdmissionPolicyBinding{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: admissionregistrationv1.SchemeGroupVersion.String(),
+				Kind:       "ValidatingAdmissionPolicyBinding",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: appInstallPolicyName,
+			},
+			Spec: admissionregistrationv1.ValidatingAdmissionPolicyBindingSpec{
+				PolicyName:        appInstallPolicyName,
+				ValidationActions: []admissionregistrationv1.ValidationAction{admissionregistrationv1.Deny},
+			},
+		},
+	}
+}
+
+// operationUser returns the service account of the AppInstall, whose permissions the helm operations run with. Only
+// users that can impersonate the service account can create or change the AppInstall, see appInstallPolicy.
+func operationUser(obj *catalog.AppInstall) user.Info {
+	serviceAccount := obj.Spec.ServiceAccountName
+	if serviceAccount == "" {
+		serviceAccount = "default"
+	}
+	return &user.DefaultInfo{
+		Name:   serviceaccount.MakeUsername(obj.Namespace, serviceAccount),
+		Groups: serviceaccount.MakeGroupNames(obj.Namespace),
+	}
+}
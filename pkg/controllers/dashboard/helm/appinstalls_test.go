@@ -0,0 +1,81 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestResolveVersion(t *testing.T) {
+	versions := repo.ChartVersions{}
+	for _, v := range []string{"2.1.0-rc1", "2.0.0", "1.3.0", "1.2.1", "1.2.0", "invalid"} {
+		versions = append(versions, &repo.ChartVersion{Metadata: &chart.Metadata{Name: "test", Version: v}})
+	}
+
+	tests := []struct {
+		name        string
+		constraint  string
+		current     string
+		autoUpgrade bool
+		expected    string
+		expectErr   bool
+	}{
+		{name: "newest stable version by default", expected: "2.0.0"},
+		{name: "exact version", constraint: "1.2.0", expected: "1.2.0"},
+		{name: "exact pre-release version", constraint: "2.1.0-rc1", expected: "2.1.0-rc1"},
+		{name: "newest version in range", constraint: "~1.2", expected: "1.2.1"},
+		{name: "current version is kept", constraint: "^1.0.0", current: "1.2.0", expected: "1.2.0"},
+		{name: "current version is upgraded", constraint: "^1.0.0", current: "1.2.0", autoUpgrade: true, expected: "1.3.0"},
+		{name: "current version out of range", constraint: "~1.3", current: "1.2.0", expected: "1.3.0"},
+		{name: "no version in range", constraint: ">=3.0.0", expectErr: true},
+		{name: "invalid constraint", constraint: "not a constraint", expectErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := resolveVersion(versions, tt.constraint, tt.current, tt.autoUpgrade)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, version)
+		})
+	}
+
+	_, err := resolveVersion(nil, "", "", false)
+	assert.Error(t, err, "chart not in the index")
+}
+
+func TestMergeValues(t *testing.T) {
+	values := map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "1.0"},
+		"replicas": 1,
+	}
+	mergeValues(values, map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "2.0"},
+		"service":  map[string]interface{}{"type": "ClusterIP"},
+		"replicas": nil,
+	})
+	assert.Equal(t, map[string]interface{}{
+		"image":    map[string]interface{}{"repository": "nginx", "tag": "2.0"},
+		"service":  map[string]interface{}{"type": "ClusterIP"},
+		"replicas": nil,
+	}, values)
+}
+
+func TestValuesEqual(t *testing.T) {
+	equal, err := valuesEqual(map[string]interface{}{"replicas": 3, "list": []string{"a"}}, map[string]interface{}{"replicas": float64(3), "list": []interface{}{"a"}})
+	require.NoError(t, err)
+	assert.True(t, equal)
+
+	equal, err = valuesEqual(nil, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.True(t, equal)
+
+	equal, err = valuesEqual(map[string]interface{}{"replicas": 3}, map[string]interface{}{"replicas": 2})
+	require.NoError(t, err)
+	assert.False(t, equal)
+}
@@ -3,6 +3,7 @@
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/moby/locker"
 	"github.com/rancher/lasso/pkg/client"
 	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
@@ -224,3 +225,16 @@ func (a *appHandler) isNamespaced(gvk schema.GroupVersionKind) bool {
 	}
 	return nsed
 }
+
+// releaseStorage returns the helm release Secret or ConfigMap owning the App.
+func releaseStorage(secrets corecontrollers.SecretCache, configMaps corecontrollers.ConfigMapCache, app *v1.App) (runtime.Object, error) {
+	for _, owner := range app.OwnerReferences {
+		switch owner.Kind {
+		case "Secret":
+			return secrets.Get(app.Namespace, owner.Name)
+		case "ConfigMap":
+			return configMaps.Get(app.Namespace, owner.Name)
+		}
+	}
+	return nil, fmt.Errorf("failed to find the helm release storing app %s/%s", app.Namespace, app.Name)
+}
@@ -0,0 +1,204 @@
+package helm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/relatedresource"
+	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	clusterReleaseByChart = "clusterReleaseByChart"
+	clusterReleaseByRepo  = "clusterReleaseByRepo"
+)
+
+// ClusterReleaseContent provides the index of the ClusterRepos the charts of ClusterReleases are compared with.
+type ClusterReleaseContent interface {
+	Index(namespace, name, targetK8sVersion string, skipFilter bool) (*repo.IndexFile, error)
+}
+
+type clusterReleaseHandler struct {
+	content         ClusterReleaseContent
+	clusterReleases catalogcontrollers.ClusterReleaseCache
+	clusterRepos    catalogcontrollers.ClusterRepoCache
+
+	// indexes caches the index of each ClusterRepo by the resource version of its index ConfigMap, so that the indexes
+	// are not decoded again for each ClusterRelease.
+	indexesLock sync.Mutex
+	indexes     map[string]cachedIndex
+}
+
+type cachedIndex struct {
+	resourceVersion string
+	index           *repo.IndexFile
+}
+
+// RegisterClusterReleases flags the ClusterReleases whose chart is deprecated or older than the newest version of the
+// chart available in the ClusterRepos.
+func RegisterClusterReleases(ctx context.Context,
+	content ClusterReleaseContent,
+	clusterReleases catalogcontrollers.ClusterReleaseController,
+	clusterRepos catalogcontrollers.ClusterRepoController,
+) {
+	h := &clusterReleaseHandler{
+		content:         content,
+		clusterReleases: clusterReleases.Cache(),
+		clusterRepos:    clusterRepos.Cache(),
+		indexes:         map[string]cachedIndex{},
+	}
+
+	clusterReleases.Cache().AddIndexer(clusterReleaseByChart, func(obj *catalog.ClusterRelease) ([]string, error) {
+		return []string{obj.Spec.Chart}, nil
+	})
+	clusterReleases.Cache().AddIndexer(clusterReleaseByRepo, func(obj *catalog.ClusterRelease) ([]string, error) {
+		var result []string
+		if obj.Spec.RepoName != "" {
+			result = append(result, obj.Spec.RepoName)
+		}
+		if obj.Status.LatestVersionRepoName != "" && obj.Status.LatestVersionRepoName != obj.Spec.RepoName {
+			result = append(result, obj.Status.LatestVersionRepoName)
+		}
+		return result, nil
+	})
+
+	catalogcontrollers.RegisterClusterReleaseStatusHandler(ctx, clusterReleases, "", "helm-cluster-release", h.onChange)
+	relatedresource.Watch(ctx, "helm-cluster-release-repo", h.resolveRepo, clusterReleases, clusterRepos)
+}
+
+func (h *clusterReleaseHandler) onChange(obj *catalog.ClusterRelease, status catalog.ClusterReleaseStatus) (catalog.ClusterReleaseStatus, error) {
+	repos, err := h.clusterRepos.List(labels.Everything())
+	if err != nil {
+		return status, err
+	}
+
+	var indexes []repoIndex
+	for _, clusterRepo := range repos {
+		index, err := h.index(clusterRepo)
+		if err != nil {
+			return status, err
+		}
+		if index != nil {
+			indexes = append(indexes, repoIndex{name: clusterRepo.Name, index: index})
+		}
+	}
+	return releaseStatus(obj, indexes), nil
+}
+
+// resolveRepo enqueues the ClusterReleases of the charts of a ClusterRepo whose index changed, and the ClusterReleases
+// installed from the ClusterRepo or whose newest version was found in it.
+func (h *clusterReleaseHandler) resolveRepo(_, name string, obj runtime.Object) ([]relatedresource.Key, error) {
+	releases, err := h.clusterReleases.GetByIndex(clusterReleaseByRepo, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if clusterRepo, ok := obj.(*catalog.ClusterRepo); ok {
+		index, err := h.index(clusterRepo)
+		if err != nil {
+			return nil, err
+		}
+		if index != nil {
+			for chartName := range index.Entries {
+				chartReleases, err := h.clusterReleases.GetByIndex(clusterReleaseByChart, chartName)
+				if err != nil {
+					return nil, err
+				}
+				releases = append(releases, chartReleases...)
+			}
+		}
+	} else {
+		h.indexesLock.Lock()
+		delete(h.indexes, name)
+		h.indexesLock.Unlock()
+	}
+
+	keys := make([]relatedresource.Key, 0, len(releases))
+	for _, release := range releases {
+		keys = append(keys, relatedresource.NewKey(release.Namespace, release.Name))
+	}
+	return keys, nil
+}
+
+// index returns the index of the ClusterRepo, or nil if it hasn't been downloaded yet.
+func (h *clusterReleaseHandler) index(clusterRepo *catalog.ClusterRepo) (*repo.IndexFile, error) {
+	if clusterRepo.Status.IndexConfigMapName == "" {
+		return nil, nil
+	}
+
+	h.indexesLock.Lock()
+	defer h.indexesLock.Unlock()
+
+	if cached, ok := h.indexes[clusterRepo.Name]; ok && cached.resourceVersion == clusterRepo.Status.IndexConfigMapResourceVersion {
+		return cached.index, nil
+	}
+	index, err := h.content.Index("", clusterRepo.Name, "", true)
+	if err != nil {
+		return nil, err
+	}
+	h.indexes[clusterRepo.Name] = cachedIndex{
+		resourceVersion: clusterRepo.Status.IndexConfigMapResourceVersion,
+		index:           index,
+	}
+	return index, nil
+}
+
+type repoIndex struct {
+	name  string
+	index *repo.IndexFile
+}
+
+// releaseStatus compares the chart of the release with the versions of the chart in the indexes of the ClusterRepos.
+// Pre-release versions are only considered newer if the release itself runs a pre-release.
+func releaseStatus(release *catalog.ClusterRelease, indexes []repoIndex) catalog.ClusterReleaseStatus {
+	status := catalog.ClusterReleaseStatus{
+		Deprecated: release.Spec.Deprecated,
+	}
+	installed, _ := semver.NewVersion(release.Spec.Version)
+
+	var latest *semver.Version
+	for _, ri := range indexes {
+		var (
+			newest        *repo.ChartVersion
+			newestVersion *semver.Version
+		)
+		for _, chartVersion := range ri.index.Entries[release.Spec.Chart] {
+			version, err := semver.NewVersion(chartVersion.Version)
+			if err != nil {
+				continue
+			}
+			if chartVersion.Version == release.Spec.Version && chartVersion.Deprecated && fromRepo(release, ri.name) {
+				status.Deprecated = true
+			}
+			if newestVersion == nil || version.GreaterThan(newestVersion) {
+				newest, newestVersion = chartVersion, version
+			}
+			if version.Prerelease() != "" && (installed == nil || installed.Prerelease() == "") {
+				continue
+			}
+			if latest == nil || version.GreaterThan(latest) {
+				latest = version
+				status.LatestVersion = chartVersion.Version
+				status.LatestVersionRepoName = ri.name
+			}
+		}
+		// helm deprecates a chart by deprecating its newest version
+		if newest != nil && newest.Deprecated && fromRepo(release, ri.name) {
+			status.Deprecated = true
+		}
+	}
+
+	status.Outdated = installed != nil && latest != nil && installed.LessThan(latest)
+	return status
+}
+
+// fromRepo returns whether the release may have been installed from the ClusterRepo. Releases whose ClusterRepo is
+// unknown are compared with every ClusterRepo.
+func fromRepo(release *catalog.ClusterRelease, repoName string) bool {
+	return release.Spec.RepoName == "" || release.Spec.RepoName == repoName
+}
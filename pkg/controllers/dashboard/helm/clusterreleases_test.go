@@ -0,0 +1,81 @@
+package helm
+
+import (
+	"testing"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+func TestReleaseStatus(t *testing.T) {
+	newIndex := func(versions map[string]bool) *repo.IndexFile {
+		index := repo.NewIndexFile()
+		for version, deprecated := range versions {
+			index.Entries["app"] = append(index.Entries["app"], &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: version, Deprecated: deprecated}})
+		}
+		return index
+	}
+	indexes := []repoIndex{
+		{name: "stable", index: newIndex(map[string]bool{"1.0.0": true, "1.1.0": false, "1.2.0": false, "2.0.0-rc1": false})},
+		{name: "partner", index: newIndex(map[string]bool{"1.3.0": false, "invalid": false})},
+		{name: "archived", index: newIndex(map[string]bool{"0.9.0": false, "1.0.1": true})},
+	}
+
+	tests := []struct {
+		name     string
+		spec     catalog.ClusterReleaseSpec
+		expected catalog.ClusterReleaseStatus
+	}{
+		{
+			name:     "newest version in any repository",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.2.0", RepoName: "stable"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Outdated: true},
+		},
+		{
+			name:     "up to date release",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.3.0", RepoName: "partner"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner"},
+		},
+		{
+			name:     "deprecated version of the repository of the release",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.0.0", RepoName: "stable"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Outdated: true, Deprecated: true},
+		},
+		{
+			name:     "version deprecated in another repository",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.0.0", RepoName: "partner"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Outdated: true},
+		},
+		{
+			name:     "chart deprecated in the repository of the release",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "0.9.0", RepoName: "archived"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Outdated: true, Deprecated: true},
+		},
+		{
+			name:     "unknown repository",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.0.0"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Outdated: true, Deprecated: true},
+		},
+		{
+			name:     "pre-release",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "2.0.0-beta1", RepoName: "stable"},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "2.0.0-rc1", LatestVersionRepoName: "stable", Outdated: true},
+		},
+		{
+			name:     "chart deprecated when installed",
+			spec:     catalog.ClusterReleaseSpec{Chart: "app", Version: "1.3.0", Deprecated: true},
+			expected: catalog.ClusterReleaseStatus{LatestVersion: "1.3.0", LatestVersionRepoName: "partner", Deprecated: true},
+		},
+		{
+			name: "unknown chart",
+			spec: catalog.ClusterReleaseSpec{Chart: "other", Version: "1.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, releaseStatus(&catalog.ClusterRelease{Spec: tt.spec}, indexes))
+		})
+	}
+}
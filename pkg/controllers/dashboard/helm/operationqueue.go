@@ -0,0 +1,302 @@
+package helm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	namespaces "github.com/rancher/rancher/pkg/namespace"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/rancher/wrangler/v3/pkg/kstatus"
+	"github.com/rancher/wrangler/v3/pkg/kv"
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// operationQueueKey is the key the queue of the operations waiting to run is dispatched with.
+	operationQueueKey = "_helm-operation-queue_"
+
+	// operationStartTimeout is how long a started operation can be without a pod before it is marked as failed.
+	operationStartTimeout = 5 * time.Minute
+
+	// leftOperationTTL is how long cancelled operations and operations which failed to start are kept. They have no
+	// pod to be removed with.
+	leftOperationTTL = time.Hour
+)
+
+// OperationStarter creates the pods of the operations leaving the queue.
+type OperationStarter interface {
+	StartQueued(ctx context.Context, namespace, name string) error
+}
+
+type operationQueueHandler struct {
+	ctx        context.Context
+	k8s        kubernetes.Interface
+	starter    OperationStarter
+	operations catalogcontrollers.OperationController
+	// maxConcurrent returns the maximum number of operations running at once, no limit if not positive.
+	maxConcurrent func() int
+}
+
+// RegisterOperationQueue dispatches the queue of the helm operations waiting to run. The queue is kept in the status
+// of the operations, so that it is rebuilt from them when the controller starts on the leader.
+func RegisterOperationQueue(ctx context.Context,
+	k8s kubernetes.Interface,
+	starter OperationStarter,
+	operations catalogcontrollers.OperationController) {
+
+	h := operationQueueHandler{
+		ctx:           ctx,
+		k8s:           k8s,
+		starter:       starter,
+		operations:    operations,
+		maxConcurrent: maxConcurrentOperations,
+	}
+
+	operations.OnChange(ctx, "helm-operation-queue", h.onChange)
+}
+
+// maxConcurrentOperations returns the value of the helm-operation-max-concurrency setting.
+func maxConcurrentOperations() int {
+	limit, err := strconv.Atoi(settings.HelmOperationMaxConcurrency.Get())
+	if err != nil {
+		logrus.Errorf("failed to parse setting %s, not limiting the number of helm operations: %v", settings.HelmOperationMaxConcurrency.Name, err)
+		return 0
+	}
+	return limit
+}
+
+// onChange dispatches the queue whenever an operation changes, as operations leave the queue when others complete.
+func (h *operationQueueHandler) onChange(key string, operation *catalog.Operation) (*catalog.Operation, error) {
+	if key == operationQueueKey {
+		return nil, h.dispatch()
+	}
+	if operation == nil {
+		// the Secrets of the operation are not owned by it
+		namespace, name := kv.RSplit(key, "/")
+		for _, secretName := range []string{helmop.QueueSecretName(namespace, name), helmop.RollbackSecretName(namespace, name)} {
+			err := h.k8s.CoreV1().Secrets(namespaces.System).Delete(h.ctx, secretName, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+	h.operations.Enqueue("", operationQueueKey)
+	return operation, nil
+}
+
+// dispatch starts the queued operations which can run, records the position of the others in their status, and
+// removes the operations which left the queue without a pod once they expire.
+func (h *operationQueueHandler) dispatch() error {
+	operations, err := h.operations.Cache().List("", labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	var (
+		now     = time.Now()
+		running []*catalog.Operation
+		queued  []*catalog.Operation
+		// next is when the queue is dispatched again for an operation to expire, if any
+		next time.Duration
+	)
+	requeueIn := func(wait time.Duration) {
+		if next == 0 || wait < next {
+			next = wait
+		}
+	}
+
+	for _, operation := range operations {
+		queue := operation.Status.Queue
+		switch {
+		case queue != nil && queue.State == catalog.OperationQueued:
+			queued = append(queued, operation)
+		case queue != nil && (queue.State == catalog.OperationCancelled || queue.State == catalog.OperationFailed):
+			if wait := leftQueueAt(operation).Add(leftOperationTTL).Sub(now); wait > 0 {
+				requeueIn(wait)
+				continue
+			}
+			err := h.operations.Delete(operation.Namespace, operation.Name, &metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		case queue != nil && queue.State == catalog.OperationStarted && operation.Status.PodName == "":
+			// the pod of the operation is being created
+			if wait := leftQueueAt(operation).Add(operationStartTimeout).Sub(now); wait > 0 {
+				running = append(running, operation)
+				requeueIn(wait)
+				continue
+			}
+			if err := h.failStart(operation); err != nil {
+				return err
+			}
+		case operationRunning(operation):
+			running = append(running, operation)
+		}
+	}
+
+	start, waiting := planQueue(running, queued, h.maxConcurrent())
+	for _, operation := range start {
+		if err := h.starter.StartQueued(h.ctx, operation.Namespace, operation.Name); err != nil {
+			return err
+		}
+	}
+	for _, position := range waiting {
+		if err := h.updatePosition(position); err != nil {
+			return err
+		}
+	}
+
+	if next > 0 {
+		h.operations.EnqueueAfter("", operationQueueKey, next)
+	}
+	return nil
+}
+
+// failStart marks a started operation whose pod was never recorded as failed, so that it stops holding its releases.
+func (h *operationQueueHandler) failStart(operation *catalog.Operation) error {
+	operation = operation.DeepCopy()
+	operation.Status.Queue.State = catalog.OperationFailed
+	kstatus.SetError(&operation.Status, fmt.Sprintf("the pod of the operation was not created within %s", operationStartTimeout))
+	_, err := h.operations.UpdateStatus(operation)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// updatePosition records the position of a queued operation in its status when it changed.
+func (h *operationQueueHandler) updatePosition(position queuePosition) error {
+	queue := position.operation.Status.Queue
+	if queue.Position == position.position && queue.Reason == position.reason {
+		return nil
+	}
+
+	operation := position.operation.DeepCopy()
+	operation.Status.Queue.Position = position.position
+	operation.Status.Queue.Reason = position.reason
+	kstatus.SetTransitioning(&operation.Status, fmt.Sprintf("queued at position %d: %s", position.position, position.reason))
+	_, err := h.operations.UpdateStatus(operation)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// leftQueueAt returns when the operation left the queue.
+func leftQueueAt(operation *catalog.Operation) time.Time {
+	if operation.Status.Queue.StartTime != nil {
+		return operation.Status.Queue.StartTime.Time
+	}
+	return operation.CreationTimestamp.Time
+}
+
+// operationRunning returns true if the pod of the operation is running its commands. Operations are done once the
+// operation controller observed that their pod terminated or is gone, and the health check of their release starts
+// then.
+func operationRunning(operation *catalog.Operation) bool {
+	if operation.Status.PodName == "" {
+		return false
+	}
+	if operation.Status.HealthCheck != nil && operation.Status.HealthCheck.StartTime != nil {
+		return false
+	}
+	return !kstatus.Reconciling.IsFalse(operation)
+}
+
+// operationReleases returns the releases the operation acts on, as <namespace>/<name>. Operations created before the
+// queue only record the release of their status.
+func operationReleases(operation *catalog.Operation) []string {
+	if operation.Status.Queue != nil {
+		return operation.Status.Queue.Releases
+	}
+	if operation.Status.Release == "" {
+		return nil
+	}
+	return []string{operation.Status.Namespace + "/" + operation.Status.Release}
+}
+
+// queuePosition is the position and reason to record in the status of a queued operation.
+type queuePosition struct {
+	operation *catalog.Operation
+	position  int
+	reason    string
+}
+
+// planQueue returns the queued operations which can start given the running ones, and the position of those which
+// keep waiting. Operations run in the order they were queued, an operation only passing the operations queued before
+// it if they wait for other releases.
+func planQueue(running, queued []*catalog.Operation, limit int) ([]*catalog.Operation, []queuePosition) {
+	queued = append([]*catalog.Operation(nil), queued...)
+	sort.SliceStable(queued, func(i, j int) bool {
+		a, b := queued[i], queued[j]
+		if !a.Status.Queue.QueueTime.Equal(&b.Status.Queue.QueueTime) {
+			return a.Status.Queue.QueueTime.Before(&b.Status.Queue.QueueTime)
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	count := len(running)
+	releases := map[string]bool{}
+	for _, operation := range running {
+		for _, release := range operationReleases(operation) {
+			releases[release] = true
+		}
+	}
+
+	var (
+		start   []*catalog.Operation
+		waiting []queuePosition
+		// claimed are the releases of the operations which keep waiting
+		claimed = map[string]bool{}
+	)
+	for _, operation := range queued {
+		reason := waitReason(operation.Status.Queue.Releases, releases, claimed, count, limit)
+		if reason == "" {
+			start = append(start, operation)
+			count++
+			for _, release := range operation.Status.Queue.Releases {
+				releases[release] = true
+			}
+			continue
+		}
+		for _, release := range operation.Status.Queue.Releases {
+			claimed[release] = true
+		}
+		waiting = append(waiting, queuePosition{
+			operation: operation,
+			position:  len(waiting) + 1,
+			reason:    reason,
+		})
+	}
+	return start, waiting
+}
+
+// waitReason returns why an operation on the given releases can't run yet, or an empty string if it can. The releases
+// claimed by the operations queued before it are passed so that operations on the same release keep their order.
+func waitReason(releases []string, running, claimed map[string]bool, count, limit int) string {
+	for _, release := range releases {
+		if running[release] || claimed[release] {
+			return fmt.Sprintf("waiting for another operation on release %s", release)
+		}
+	}
+	if limit > 0 && count >= limit {
+		return fmt.Sprintf("waiting for one of the %d running operations to complete", count)
+	}
+	return ""
+}
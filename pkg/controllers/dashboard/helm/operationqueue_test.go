@@ -0,0 +1,115 @@
+package helm
+
+import (
+	"testing"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/kstatus"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var queueStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newQueuedOperation returns an operation queued in the given order on the given releases.
+func newQueuedOperation(name string, order int, releases ...string) *catalog.Operation {
+	return &catalog.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Status: catalog.OperationStatus{
+			Queue: &catalog.OperationQueueStatus{
+				State:     catalog.OperationQueued,
+				QueueTime: metav1.NewTime(queueStart.Add(time.Duration(order) * time.Second)),
+				Releases:  releases,
+			},
+		},
+	}
+}
+
+func operationNames(operations []*catalog.Operation) []string {
+	var result []string
+	for _, operation := range operations {
+		result = append(result, operation.Name)
+	}
+	return result
+}
+
+func TestPlanQueueReleases(t *testing.T) {
+	a := newQueuedOperation("a", 0, "default/app")
+	b := newQueuedOperation("b", 1, "default/app")
+	c := newQueuedOperation("c", 2, "default/other", "default/app")
+	d := newQueuedOperation("d", 3, "default/other")
+	e := newQueuedOperation("e", 4, "default/last")
+
+	start, waiting := planQueue([]*catalog.Operation{a}, []*catalog.Operation{e, d, c, b}, 0)
+	assert.Equal(t, []string{"e"}, operationNames(start))
+	// d doesn't run before c, which was queued before it on the same release
+	assert.Equal(t, []queuePosition{
+		{operation: b, position: 1, reason: "waiting for another operation on release default/app"},
+		{operation: c, position: 2, reason: "waiting for another operation on release default/app"},
+		{operation: d, position: 3, reason: "waiting for another operation on release default/other"},
+	}, waiting)
+
+	start, waiting = planQueue(nil, []*catalog.Operation{b, c, d}, 0)
+	assert.Equal(t, []string{"b"}, operationNames(start))
+	assert.Len(t, waiting, 2)
+}
+
+func TestPlanQueueMaxConcurrent(t *testing.T) {
+	a := newQueuedOperation("a", 0, "default/a")
+	b := newQueuedOperation("b", 1, "default/b")
+	c := newQueuedOperation("c", 2, "default/c")
+	d := newQueuedOperation("d", 3)
+
+	start, waiting := planQueue(nil, []*catalog.Operation{a, b, c, d}, 2)
+	assert.Equal(t, []string{"a", "b"}, operationNames(start))
+	assert.Equal(t, []queuePosition{
+		{operation: c, position: 1, reason: "waiting for one of the 2 running operations to complete"},
+		{operation: d, position: 2, reason: "waiting for one of the 2 running operations to complete"},
+	}, waiting)
+
+	start, waiting = planQueue([]*catalog.Operation{a}, []*catalog.Operation{c, d}, 2)
+	assert.Equal(t, []string{"c"}, operationNames(start))
+	assert.Equal(t, []queuePosition{{operation: d, position: 1, reason: "waiting for one of the 2 running operations to complete"}}, waiting)
+
+	start, waiting = planQueue([]*catalog.Operation{a, b}, []*catalog.Operation{c, d}, 0)
+	assert.Equal(t, []string{"c", "d"}, operationNames(start), "no limit if not positive")
+	assert.Empty(t, waiting)
+}
+
+func TestPlanQueueLegacyOperations(t *testing.T) {
+	// operations created before the queue hold the release of their status
+	legacy := &catalog.Operation{
+		Status: catalog.OperationStatus{
+			Namespace: "default",
+			Release:   "app",
+			PodName:   "helm-operation-legacy",
+		},
+	}
+	start, waiting := planQueue([]*catalog.Operation{legacy}, []*catalog.Operation{newQueuedOperation("a", 0, "default/app")}, 0)
+	assert.Empty(t, start)
+	assert.Len(t, waiting, 1)
+}
+
+func TestOperationRunning(t *testing.T) {
+	running := &catalog.Operation{Status: catalog.OperationStatus{PodName: "helm-operation-abcde"}}
+	kstatus.SetTransitioning(&running.Status, "running operation")
+	assert.True(t, operationRunning(running))
+
+	done := running.DeepCopy()
+	kstatus.SetActive(&done.Status)
+	assert.False(t, operationRunning(done))
+
+	failed := running.DeepCopy()
+	kstatus.SetError(&failed.Status, "failed")
+	assert.False(t, operationRunning(failed))
+
+	verifying := running.DeepCopy()
+	verifying.Status.HealthCheck = &catalog.OperationHealthCheck{StartTime: &metav1.Time{Time: queueStart}}
+	assert.False(t, operationRunning(verifying), "the releases are rolled back by another operation once the pod completed")
+
+	assert.False(t, operationRunning(&catalog.Operation{}), "operations without a pod are not running")
+}
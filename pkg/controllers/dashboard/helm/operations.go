@@ -1,42 +1,82 @@
 package helm
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/rancher/lasso/pkg/client"
 	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2/helm"
+	"github.com/rancher/rancher/pkg/catalogv2/helmop"
 	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	namespaces "github.com/rancher/rancher/pkg/namespace"
 	corecontrollers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	"github.com/rancher/wrangler/v3/pkg/kstatus"
 	"github.com/rancher/wrangler/v3/pkg/relatedresource"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
 const (
 	podIndex = "byPod"
+
+	// healthCheckInterval is the interval the health of an upgraded release is verified at.
+	healthCheckInterval = 10 * time.Second
 )
 
+// errRolloutFailed is returned for workloads of a release which can't be rolled out.
+var errRolloutFailed = errors.New("rollout failed")
+
+// OperationRollbacks rolls back releases which failed the health check of their upgrade.
+type OperationRollbacks interface {
+	Rollback(ctx context.Context, user user.Info, namespace, releaseName string, revision int, tolerations []corev1.Toleration) (*catalog.Operation, error)
+}
+
 type operationHandler struct {
-	ctx             context.Context
-	pods            corecontrollers.PodCache
-	k8s             kubernetes.Interface
-	operationsCache catalogcontrollers.OperationCache
+	ctx                 context.Context
+	pods                corecontrollers.PodCache
+	k8s                 kubernetes.Interface
+	rollbacks           OperationRollbacks
+	operations          catalogcontrollers.OperationController
+	operationsCache     catalogcontrollers.OperationCache
+	apps                catalogcontrollers.AppCache
+	secrets             corecontrollers.SecretCache
+	configMaps          corecontrollers.ConfigMapCache
+	sharedClientFactory client.SharedClientFactory
 }
 
 func RegisterOperations(ctx context.Context,
 	k8s kubernetes.Interface,
+	rollbacks OperationRollbacks,
+	sharedClientFactory client.SharedClientFactory,
 	pods corecontrollers.PodController,
-	operations catalogcontrollers.OperationController) {
+	operations catalogcontrollers.OperationController,
+	apps catalogcontrollers.AppCache,
+	secrets corecontrollers.SecretCache,
+	configMaps corecontrollers.ConfigMapCache) {
 
 	o := operationHandler{
-		ctx:             ctx,
-		k8s:             k8s,
-		pods:            pods.Cache(),
-		operationsCache: operations.Cache(),
+		ctx:                 ctx,
+		k8s:                 k8s,
+		rollbacks:           rollbacks,
+		pods:                pods.Cache(),
+		operations:          operations,
+		operationsCache:     operations.Cache(),
+		apps:                apps,
+		secrets:             secrets,
+		configMaps:          configMaps,
+		sharedClientFactory: sharedClientFactory,
 	}
 
 	operations.Cache().AddIndexer(podIndex, indexOperationsByPod)
@@ -66,6 +106,9 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 	if status.PodName == "" || status.PodNamespace == "" {
 		return status, nil
 	}
+	if status.HealthCheck != nil && status.HealthCheck.StartTime != nil {
+		return o.verifyHealth(operation, status)
+	}
 
 	pod, err := o.pods.Get(status.PodNamespace, status.PodName)
 	if apierrors.IsNotFound(err) {
@@ -85,6 +128,20 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 			status.PodCreated = true
 			if container.State.Terminated.ExitCode == 0 {
 				kstatus.SetActive(&status)
+				if status.DryRun && status.Diff == nil {
+					// the pod is still cleaned up so that it stops, the diff is computed again on the next change
+					diff, err := o.diff(status.Namespace, pod)
+					if err != nil {
+						kstatus.SetError(&status, err.Error())
+					}
+					status.Diff = diff
+				}
+				if status.HealthCheck != nil {
+					status.HealthCheck.StartTime = &container.State.Terminated.FinishedAt
+					catalog.OperationHealthy.Unknown(&status)
+					catalog.OperationHealthy.Message(&status, "waiting for the release to be recorded")
+					kstatus.SetTransitioning(&status, "verifying the health of the release")
+				}
 			} else {
 				kstatus.SetError(&status,
 					fmt.Sprintf("%s exit code: %d",
@@ -104,6 +161,206 @@ func (o *operationHandler) onOperationChange(operation *catalog.Operation, statu
 	return status, nil
 }
 
+// verifyHealth verifies that the workloads of the release upgraded by the operation are rolled out within the timeout
+// of the health check, and rolls the release back to its previous revision otherwise.
+func (o *operationHandler) verifyHealth(operation *catalog.Operation, status catalog.OperationStatus) (catalog.OperationStatus, error) {
+	healthCheck := status.HealthCheck
+	if catalog.OperationHealthy.IsTrue(&status) {
+		return status, nil
+	}
+	if catalog.OperationHealthy.IsFalse(&status) {
+		return o.rollback(operation, status)
+	}
+
+	ready, message, err := o.releaseReady(status.Namespace, status.Release, healthCheck.PreviousRevision)
+	failed := errors.Is(err, errRolloutFailed)
+	if failed {
+		message = err.Error()
+	} else if err != nil {
+		return status, err
+	}
+	if ready {
+		catalog.OperationHealthy.True(&status)
+		catalog.OperationHealthy.Message(&status, "")
+		kstatus.SetActive(&status)
+		return status, o.deleteRollback(operation)
+	}
+
+	if failed || time.Since(healthCheck.StartTime.Time) > healthCheck.Timeout.Duration {
+		catalog.OperationHealthy.False(&status)
+		catalog.OperationHealthy.Message(&status, fmt.Sprintf("release was not healthy within %s: %s", healthCheck.Timeout.Duration, message))
+		return o.rollback(operation, status)
+	}
+
+	catalog.OperationHealthy.Message(&status, message)
+	kstatus.SetTransitioning(&status, "verifying the health of the release")
+	o.operations.EnqueueAfter(operation.Namespace, operation.Name, healthCheckInterval)
+	return status, nil
+}
+
+// releaseReady returns whether the workloads of the upgraded release are rolled out, and which one is not otherwise.
+// An errRolloutFailed error is returned if a workload can't be rolled out.
+func (o *operationHandler) releaseReady(namespace, name string, previousRevision int) (bool, string, error) {
+	app, err := o.apps.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return false, "waiting for the release to be recorded", nil
+	} else if err != nil {
+		return false, "", err
+	}
+	if app.Spec.Version <= previousRevision {
+		return false, "waiting for the release to be recorded", nil
+	}
+
+	for _, resource := range app.Spec.Resources {
+		gvk := schema.FromAPIVersionAndKind(resource.APIVersion, resource.Kind)
+		if !helm.HasRolloutStatus(gvk) {
+			continue
+		}
+		client, err := o.sharedClientFactory.ForKind(gvk)
+		if err != nil {
+			return false, "", err
+		}
+		obj := &unstructured.Unstructured{}
+		if err := client.Get(o.ctx, resource.Namespace, resource.Name, obj, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+			return false, fmt.Sprintf("%s %s/%s: not found", resource.Kind, resource.Namespace, resource.Name), nil
+		} else if err != nil {
+			return false, "", err
+		}
+		ready, message, err := helm.RolloutStatus(obj)
+		if err != nil {
+			return false, "", fmt.Errorf("%w: %s %s/%s: %v", errRolloutFailed, resource.Kind, resource.Namespace, resource.Name, err)
+		}
+		if !ready {
+			return false, fmt.Sprintf("%s %s/%s: %s", resource.Kind, resource.Namespace, resource.Name, message), nil
+		}
+	}
+	return true, "", nil
+}
+
+// rollback rolls back the release which failed the health check to its previous revision through a rollback operation,
+// then waits for it to complete. The operation fails once the release is rolled back.
+func (o *operationHandler) rollback(operation *catalog.Operation, status catalog.OperationStatus) (catalog.OperationStatus, error) {
+	healthCheck := status.HealthCheck
+	unhealthy := catalog.OperationHealthy.GetMessage(&status)
+	if healthCheck.RollbackOperationName == "" {
+		// the release, revision and identity of the rollback are taken from the rollback Secret of the operation, as its
+		// status is writable by the users allowed to update operations
+		rollback, err := helmop.GetHealthCheckRollback(o.secrets, operation)
+		if err != nil {
+			kstatus.SetError(&status, fmt.Sprintf("%s, not rolled back: %v", unhealthy, err))
+			return status, nil
+		}
+		if rollback.PreviousRevision == 0 {
+			// the release was installed by the operation, there is no revision to roll back to
+			kstatus.SetError(&status, unhealthy)
+			return status, o.deleteRollback(operation)
+		}
+
+		rollbackOp, err := o.rollbacks.Rollback(o.ctx, rollback.UserInfo(), rollback.Namespace, rollback.Release, rollback.PreviousRevision, status.Tolerations)
+		if err != nil {
+			return status, err
+		}
+		healthCheck.RollbackOperationName = rollbackOp.Name
+		catalog.OperationRolledBack.Unknown(&status)
+		catalog.OperationRolledBack.Message(&status, fmt.Sprintf("rolling back to revision %d by operation %s", rollback.PreviousRevision, rollbackOp.Name))
+	}
+
+	rollbackOp, err := o.operationsCache.Get(status.Namespace, healthCheck.RollbackOperationName)
+	switch {
+	case apierrors.IsNotFound(err):
+		// the rollback operation was just created or was deleted
+		if !catalog.OperationRolledBack.IsUnknown(&status) {
+			return status, nil
+		}
+	case err != nil:
+		return status, err
+	case kstatus.Stalled.IsTrue(rollbackOp):
+		catalog.OperationRolledBack.False(&status)
+		catalog.OperationRolledBack.Message(&status, fmt.Sprintf("rollback operation %s failed: %s", rollbackOp.Name, kstatus.Stalled.GetMessage(rollbackOp)))
+		kstatus.SetError(&status, unhealthy)
+		return status, o.deleteRollback(operation)
+	case kstatus.Reconciling.IsFalse(rollbackOp):
+		catalog.OperationRolledBack.True(&status)
+		catalog.OperationRolledBack.Message(&status, fmt.Sprintf("rolled back by operation %s", rollbackOp.Name))
+		kstatus.SetError(&status, unhealthy)
+		return status, o.deleteRollback(operation)
+	}
+
+	kstatus.SetTransitioning(&status, "rolling back the release")
+	o.operations.EnqueueAfter(operation.Namespace, operation.Name, healthCheckInterval)
+	return status, nil
+}
+
+// deleteRollback deletes the rollback Secret of the operation once its health check completed.
+func (o *operationHandler) deleteRollback(operation *catalog.Operation) error {
+	err := o.k8s.CoreV1().Secrets(namespaces.System).Delete(o.ctx, helmop.RollbackSecretName(operation.Namespace, operation.Name), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// diff compares the releases rendered by the dry-run operation run in the pod, which it wrote to a Secret in the
+// namespace of the releases, with the current releases. The Secret is deleted once the releases are compared.
+func (o *operationHandler) diff(namespace string, pod *corev1.Pod) ([]catalog.ResourceDiff, error) {
+	secretName := helm.DryRunSecretPrefix + pod.Name
+	secret, err := o.k8s.CoreV1().Secrets(namespace).Get(o.ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the releases rendered by operation pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	output, err := gzip.NewReader(bytes.NewReader(secret.Data[helm.DryRunSecretKey]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the releases rendered by operation pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+	releases, err := helm.RenderedReleases(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the releases rendered by operation pod %s/%s: %w", pod.Namespace, pod.Name, err)
+	}
+
+	result := []catalog.ResourceDiff{}
+	for _, release := range releases {
+		current, err := o.currentManifest(release.Namespace, release.Name)
+		if err != nil {
+			return nil, err
+		}
+		diff, err := helm.ManifestDiff(release.Name, release.Namespace, current, release.Manifest, o.isNamespaced)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare the manifest of release %s/%s: %w", release.Namespace, release.Name, err)
+		}
+		result = append(result, diff...)
+	}
+
+	err = o.k8s.CoreV1().Secrets(namespace).Delete(o.ctx, secretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	return helm.TruncateDiffs(result), nil
+}
+
+// currentManifest returns the manifest of the installed release, or an empty manifest if it isn't installed.
+func (o *operationHandler) currentManifest(namespace, name string) (string, error) {
+	app, err := o.apps.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	obj, err := releaseStorage(o.secrets, o.configMaps, app)
+	if err != nil {
+		return "", err
+	}
+	return helm.ReleaseManifest(obj)
+}
+
+func (o *operationHandler) isNamespaced(gvk schema.GroupVersionKind) bool {
+	_, nsed, err := o.sharedClientFactory.ResourceForGVK(gvk)
+	if err != nil {
+		return false
+	}
+	return nsed
+}
+
 func (o *operationHandler) cleanup(pod *corev1.Pod) error {
 	running := false
 	success := false
@@ -18,6 +18,12 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		wrangler.Catalog.ClusterRepo(),
 		wrangler.Core.ConfigMap(),
 		wrangler.Core.Secret().Cache())
+	RegisterRepoMirrors(ctx,
+		wrangler.Apply,
+		wrangler.CatalogContentManager,
+		wrangler.Catalog.ClusterRepo(),
+		wrangler.Core.ConfigMap(),
+		wrangler.Core.Secret().Cache())
 	RegisterApps(ctx,
 		wrangler.Apply,
 		wrangler.ControllerFactory.SharedCacheFactory().SharedClientFactory(),
@@ -26,6 +32,29 @@ func Register(ctx context.Context, wrangler *wrangler.Context) {
 		wrangler.Catalog.App())
 	RegisterOperations(ctx,
 		wrangler.K8s,
+		wrangler.HelmOperations,
+		wrangler.ControllerFactory.SharedCacheFactory().SharedClientFactory(),
 		wrangler.Core.Pod(),
+		wrangler.Catalog.Operation(),
+		wrangler.Catalog.App().Cache(),
+		wrangler.Core.Secret().Cache(),
+		wrangler.Core.ConfigMap().Cache())
+	RegisterOperationQueue(ctx,
+		wrangler.K8s,
+		wrangler.HelmOperations,
 		wrangler.Catalog.Operation())
+	RegisterAppInstalls(ctx,
+		wrangler.Apply,
+		wrangler.HelmOperations,
+		wrangler.CatalogContentManager,
+		wrangler.Catalog.AppInstall(),
+		wrangler.Catalog.App(),
+		wrangler.Catalog.Operation(),
+		wrangler.Catalog.ClusterRepo(),
+		wrangler.Core.Secret(),
+		wrangler.Core.ConfigMap())
+	RegisterClusterReleases(ctx,
+		wrangler.CatalogContentManager,
+		wrangler.Catalog.ClusterRelease(),
+		wrangler.Catalog.ClusterRepo())
 }
@@ -0,0 +1,450 @@
+package helm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/catalogv2"
+	"github.com/rancher/rancher/pkg/catalogv2/oci"
+	catalogcontrollers "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/image"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/rancher/wrangler/v3/pkg/genericcondition"
+	name2 "github.com/rancher/wrangler/v3/pkg/name"
+	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	mirrorCondition = catalog.RepoMirrored
+	// mirrorImageListKey is the key of the push list in the image list ConfigMap of a mirror.
+	mirrorImageListKey = "images.txt"
+	// maxMirrorErrors is the number of mirroring errors reported in the Mirrored condition of a ClusterRepo.
+	maxMirrorErrors = 5
+)
+
+// MirrorContent provides the index and the chart tarballs of the ClusterRepos mirrored to OCI registries.
+type MirrorContent interface {
+	Index(namespace, name, targetK8sVersion string, skipFilter bool) (*repo.IndexFile, error)
+	VerifiedChart(namespace, name, chartName, version string, skipFilter bool) (io.ReadCloser, error)
+}
+
+type repoMirrorHandler struct {
+	ctx          context.Context
+	content      MirrorContent
+	clusterRepos catalogcontrollers.ClusterRepoController
+	secrets      corev1controllers.SecretCache
+	apply        apply.Apply
+	// queue holds the names of the ClusterRepos whose mirror is synchronized by the mirror worker, so that pushing
+	// charts and copying images doesn't hold the workers of the ClusterRepo controller.
+	queue workqueue.TypedRateLimitingInterface[string]
+}
+
+func RegisterRepoMirrors(ctx context.Context,
+	apply apply.Apply,
+	content MirrorContent,
+	clusterRepos catalogcontrollers.ClusterRepoController,
+	configMaps corev1controllers.ConfigMapController,
+	secrets corev1controllers.SecretCache) {
+	h := &repoMirrorHandler{
+		ctx:          ctx,
+		content:      content,
+		clusterRepos: clusterRepos,
+		secrets:      secrets,
+		// The index ConfigMaps of the ClusterRepo are applied with the same owner, so the image list needs its own set.
+		apply: apply.WithSetID("helm-clusterrepo-mirror").WithCacheTypes(configMaps).WithStrictCaching().WithSetOwnerReference(false, false),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "helm-clusterrepo-mirror"}),
+	}
+
+	clusterRepos.OnChange(ctx, "helm-clusterrepo-mirror", h.onChange)
+	go h.run()
+}
+
+// onChange queues the synchronization of the mirror of a ClusterRepo once its index is downloaded, whenever the index
+// or the spec of the ClusterRepo change, and at every refresh interval of the ClusterRepo. Failed synchronizations are
+// retried by the mirror worker with a backoff.
+func (h *repoMirrorHandler) onChange(key string, clusterRepo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+	if clusterRepo == nil || clusterRepo.DeletionTimestamp != nil {
+		return clusterRepo, nil
+	}
+	if clusterRepo.Spec.Mirror == nil {
+		if clusterRepo.Status.Mirror == nil {
+			return clusterRepo, nil
+		}
+		return h.removeMirror(clusterRepo)
+	}
+
+	due, next := mirrorDue(clusterRepo)
+	switch {
+	case !due:
+		if next > 0 {
+			h.clusterRepos.EnqueueAfter(clusterRepo.Name, next)
+		}
+	case mirrorFailed(clusterRepo):
+		// the worker retries failed synchronizations with a backoff, unless it didn't get to, like when rancher restarted
+		if h.queue.NumRequeues(clusterRepo.Name) == 0 {
+			h.queue.AddRateLimited(clusterRepo.Name)
+		}
+	default:
+		h.queue.Add(clusterRepo.Name)
+	}
+	return clusterRepo, nil
+}
+
+// mirrorDue returns whether the mirror of the ClusterRepo must be synchronized, or else how long until it must be.
+// Mirrors whose last synchronization failed are due until they are synchronized.
+func mirrorDue(clusterRepo *catalog.ClusterRepo) (bool, time.Duration) {
+	if clusterRepo.Spec.Mirror == nil || clusterRepo.Spec.Enabled != nil && !*clusterRepo.Spec.Enabled || clusterRepo.Status.IndexConfigMapName == "" {
+		return false, 0
+	}
+
+	status := clusterRepo.Status.Mirror
+	if status == nil ||
+		status.ObservedGeneration != clusterRepo.Generation ||
+		status.IndexConfigMapResourceVersion != clusterRepo.Status.IndexConfigMapResourceVersion ||
+		!condition.Cond(mirrorCondition).IsTrue(clusterRepo) {
+		return true, 0
+	}
+
+	interval := defaultInterval
+	if registry.IsOCI(clusterRepo.Spec.URL) {
+		interval = defaultOCIInterval
+	}
+	if clusterRepo.Spec.RefreshInterval > 0 {
+		interval = time.Duration(clusterRepo.Spec.RefreshInterval) * time.Second
+	}
+	if next := status.SyncTime.Add(interval); timeNow().Before(next) {
+		return false, next.Sub(timeNow())
+	}
+	return true, 0
+}
+
+// mirrorFailed returns whether the last synchronization of the mirror of the ClusterRepo failed, for its current spec
+// and index.
+func mirrorFailed(clusterRepo *catalog.ClusterRepo) bool {
+	status := clusterRepo.Status.Mirror
+	return status != nil &&
+		status.ObservedGeneration == clusterRepo.Generation &&
+		status.IndexConfigMapResourceVersion == clusterRepo.Status.IndexConfigMapResourceVersion &&
+		condition.Cond(mirrorCondition).IsFalse(clusterRepo)
+}
+
+// run synchronizes the mirrors queued by onChange until the context is done.
+func (h *repoMirrorHandler) run() {
+	go func() {
+		<-h.ctx.Done()
+		h.queue.ShutDown()
+	}()
+
+	for {
+		name, shutdown := h.queue.Get()
+		if shutdown {
+			return
+		}
+		if err := h.syncMirror(name); err != nil {
+			logrus.Errorf("Failed to mirror clusterrepo %s: %v", name, err)
+			h.queue.AddRateLimited(name)
+		} else {
+			h.queue.Forget(name)
+		}
+		h.queue.Done(name)
+	}
+}
+
+// syncMirror synchronizes the mirror of the ClusterRepo, if it is still due, and records the result in its status.
+func (h *repoMirrorHandler) syncMirror(name string) error {
+	clusterRepo, err := h.clusterRepos.Cache().Get(name)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if due, _ := mirrorDue(clusterRepo); !due || clusterRepo.DeletionTimestamp != nil {
+		return nil
+	}
+
+	logrus.Debugf("Synchronizing the mirror of clusterrepo %s to %s", clusterRepo.Name, clusterRepo.Spec.Mirror.URL)
+	mirrorStatus, syncErr := h.sync(clusterRepo)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		clusterRepo, err := h.clusterRepos.Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if clusterRepo.Spec.Mirror == nil {
+			return nil
+		}
+		newStatus := clusterRepo.Status.DeepCopy()
+		newStatus.Mirror = mirrorStatus
+		condition.Cond(mirrorCondition).SetError(newStatus, "", syncErr)
+		if equality.Semantic.DeepEqual(newStatus, &clusterRepo.Status) {
+			return nil
+		}
+		clusterRepo.Status = *newStatus
+		_, err = h.clusterRepos.UpdateStatus(clusterRepo)
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// sync pushes the selected chart versions of the ClusterRepo to the OCI registry of its mirror, and copies the images
+// they reference to the image registry of the mirror, if any. The chart versions which were already mirrored to the
+// same registries with the same digest are skipped. The charts which failed to be mirrored are retried at the next
+// synchronization.
+func (h *repoMirrorHandler) sync(clusterRepo *catalog.ClusterRepo) (*catalog.RepoMirrorStatus, error) {
+	mirror := clusterRepo.Spec.Mirror
+	status := &catalog.RepoMirrorStatus{
+		ObservedGeneration:            clusterRepo.Generation,
+		IndexConfigMapResourceVersion: clusterRepo.Status.IndexConfigMapResourceVersion,
+		URL:                           mirror.URL,
+		ImageRegistry:                 mirror.ImageRegistry,
+	}
+	mirrored := map[string]*catalog.MirroredChartStatus{}
+	if previous := clusterRepo.Status.Mirror; previous != nil {
+		status.SyncTime = previous.SyncTime
+		if previous.URL == mirror.URL && previous.ImageRegistry == mirror.ImageRegistry {
+			for i := range previous.Charts {
+				mirrored[previous.Charts[i].Name+":"+previous.Charts[i].Version] = &previous.Charts[i]
+			}
+		}
+	}
+
+	filters, err := mirrorFilters(mirror.Charts)
+	if err != nil {
+		return status, err
+	}
+	index, err := h.content.Index("", clusterRepo.Name, "", true)
+	if err != nil {
+		return status, fmt.Errorf("failed to get the index: %w", err)
+	}
+	secret, err := catalogv2.GetSecret(h.secrets, &catalog.RepoSpec{ClientSecret: mirror.ClientSecret}, "")
+	if err != nil {
+		return status, fmt.Errorf("failed to get the client secret of the mirror: %w", err)
+	}
+	registrySpec := catalog.RepoSpec{
+		InsecurePlainHTTP:     mirror.InsecurePlainHTTP,
+		CABundle:              mirror.CABundle,
+		InsecureSkipTLSverify: mirror.InsecureSkipTLSverify,
+	}
+
+	var (
+		errs      []string
+		imagesSet = map[string]struct{}{}
+		copied    = map[string]bool{}
+	)
+	for _, chart := range mirroredCharts(index, filters) {
+		chartStatus, err := h.mirrorChart(clusterRepo.Name, mirror, registrySpec, secret, chart, mirrored[chart.Name+":"+chart.Version], copied)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("chart %s:%s: %v", chart.Name, chart.Version, err))
+			continue
+		}
+		status.Charts = append(status.Charts, chartStatus)
+		for _, img := range chartStatus.Images {
+			imagesSet[img] = struct{}{}
+		}
+	}
+
+	images := make([]string, 0, len(imagesSet))
+	for img := range imagesSet {
+		images = append(images, img)
+	}
+	sort.Strings(images)
+	status.Images = len(images)
+
+	cm, err := h.applyImageList(clusterRepo, images)
+	if err != nil {
+		return status, fmt.Errorf("failed to apply the image list: %w", err)
+	}
+	status.ImageListConfigMapName = cm.Name
+	status.ImageListConfigMapNamespace = cm.Namespace
+
+	if failed := len(errs); failed > 0 {
+		if failed > maxMirrorErrors {
+			errs = append(errs[:maxMirrorErrors], "...")
+		}
+		return status, fmt.Errorf("failed to mirror %d charts: %s", failed, strings.Join(errs, "; "))
+	}
+	status.SyncTime = metav1.NewTime(timeNow().UTC())
+	return status, nil
+}
+
+// mirrorChart pushes the chart version to <url>/<chart name>:<version> and copies the images referenced by its values
+// to the image registry of the mirror, if any, skipping the images already copied by this synchronization. The chart
+// version is not pushed again if it was previously mirrored with the same digest, which is taken from the index when
+// it has one.
+func (h *repoMirrorHandler) mirrorChart(repoName string, mirror *catalog.RepoMirror, registrySpec catalog.RepoSpec, secret *corev1.Secret, chart *repo.ChartVersion, previous *catalog.MirroredChartStatus, copied map[string]bool) (catalog.MirroredChartStatus, error) {
+	if previous != nil && chart.Digest != "" && previous.Digest == chart.Digest {
+		return *previous, nil
+	}
+
+	reader, err := h.content.VerifiedChart("", repoName, chart.Name, chart.Version, true)
+	if err != nil {
+		return catalog.MirroredChartStatus{}, err
+	}
+	defer reader.Close()
+	chartTar, err := io.ReadAll(reader)
+	if err != nil {
+		return catalog.MirroredChartStatus{}, err
+	}
+	digest := sha256.Sum256(chartTar)
+	chartStatus := catalog.MirroredChartStatus{
+		Name:    chart.Name,
+		Version: chart.Version,
+		Digest:  hex.EncodeToString(digest[:]),
+	}
+	if previous != nil && previous.Digest == chartStatus.Digest {
+		return *previous, nil
+	}
+
+	client, err := oci.NewClient(fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(mirror.URL, "/"), chart.Name, oci.ChartTag(chart.Version)), registrySpec, secret)
+	if err != nil {
+		return chartStatus, err
+	}
+	if err := client.PushChart(h.ctx, chartTar); err != nil {
+		return chartStatus, err
+	}
+	chartStatus.Images, err = image.ChartImages(bytes.NewReader(chartTar), chart.Name+":"+chart.Version)
+	if err != nil {
+		return chartStatus, err
+	}
+
+	if mirror.ImageRegistry != "" {
+		for _, img := range chartStatus.Images {
+			if copied[img] {
+				continue
+			}
+			if err := h.mirrorImage(mirror.ImageRegistry, registrySpec, secret, img); err != nil {
+				return chartStatus, fmt.Errorf("image %s: %w", img, err)
+			}
+			copied[img] = true
+		}
+	}
+	return chartStatus, nil
+}
+
+// mirrorImage copies the image to the image registry of the mirror.
+func (h *repoMirrorHandler) mirrorImage(imageRegistry string, registrySpec catalog.RepoSpec, secret *corev1.Secret, img string) error {
+	mirrored, err := oci.MirroredImage(imageRegistry, img)
+	if err != nil {
+		return err
+	}
+	client, err := oci.NewClient(mirrored, registrySpec, secret)
+	if err != nil {
+		return err
+	}
+	return client.CopyImage(h.ctx, img)
+}
+
+// applyImageList writes the push list of the images referenced by the mirrored charts to a ConfigMap owned by the
+// ClusterRepo.
+func (h *repoMirrorHandler) applyImageList(clusterRepo *catalog.ClusterRepo, images []string) (*corev1.ConfigMap, error) {
+	owner := mirrorOwner(clusterRepo)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name2.SafeConcatName(clusterRepo.Name, "mirror", "images"),
+			Namespace:       GetConfigMapNamespace(""),
+			OwnerReferences: []metav1.OwnerReference{owner},
+		},
+		Data: map[string]string{
+			mirrorImageListKey: strings.Join(images, "\n"),
+		},
+	}
+	return cm, h.apply.WithOwner(toOwnerObject("", owner)).ApplyObjects(cm)
+}
+
+// removeMirror deletes the image list of a ClusterRepo which is no longer mirrored and clears its mirror status. The
+// charts and images already pushed to the registries are left in place.
+func (h *repoMirrorHandler) removeMirror(clusterRepo *catalog.ClusterRepo) (*catalog.ClusterRepo, error) {
+	if err := h.apply.WithOwner(toOwnerObject("", mirrorOwner(clusterRepo))).ApplyObjects(); err != nil {
+		return clusterRepo, err
+	}
+
+	clusterRepo = clusterRepo.DeepCopy()
+	clusterRepo.Status.Mirror = nil
+	var conditions []genericcondition.GenericCondition
+	for _, c := range clusterRepo.Status.Conditions {
+		if c.Type != string(mirrorCondition) {
+			conditions = append(conditions, c)
+		}
+	}
+	clusterRepo.Status.Conditions = conditions
+	return h.clusterRepos.UpdateStatus(clusterRepo)
+}
+
+func mirrorOwner(clusterRepo *catalog.ClusterRepo) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: catalog.SchemeGroupVersion.Group + "/" + catalog.SchemeGroupVersion.Version,
+		Kind:       "ClusterRepo",
+		Name:       clusterRepo.Name,
+		UID:        clusterRepo.UID,
+	}
+}
+
+// mirrorFilters parses the version constraints of the mirrored charts, indexed by chart name. A nil constraint selects
+// all the versions of the chart.
+func mirrorFilters(charts []catalog.MirroredChart) (map[string]*semver.Constraints, error) {
+	filters := map[string]*semver.Constraints{}
+	for _, chart := range charts {
+		if chart.Versions == "" {
+			filters[chart.Name] = nil
+			continue
+		}
+		constraint, err := semver.NewConstraint(chart.Versions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid versions %q of chart %s: %w", chart.Versions, chart.Name, err)
+		}
+		filters[chart.Name] = constraint
+	}
+	return filters, nil
+}
+
+// mirroredCharts returns the chart versions of the index selected by the filters, sorted by chart name. All the chart
+// versions are selected if there are no filters.
+func mirroredCharts(index *repo.IndexFile, filters map[string]*semver.Constraints) []*repo.ChartVersion {
+	names := make([]string, 0, len(index.Entries))
+	for name := range index.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []*repo.ChartVersion
+	for _, name := range names {
+		constraint, ok := filters[name]
+		if len(filters) > 0 && !ok {
+			continue
+		}
+		for _, chart := range index.Entries[name] {
+			if constraint != nil {
+				version, err := semver.NewVersion(chart.Version)
+				if err != nil || !constraint.Check(version) {
+					continue
+				}
+			}
+			result = append(result, chart)
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"testing"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/wrangler/v3/pkg/condition"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/repo"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMirroredCharts(t *testing.T) {
+	index := repo.NewIndexFile()
+	for name, versions := range map[string][]string{
+		"app":     {"2.0.0", "1.2.0", "1.1.0", "invalid"},
+		"agent":   {"0.2.0", "0.1.0"},
+		"monitor": {"3.0.0-rc1", "2.0.0"},
+	} {
+		for _, version := range versions {
+			index.Entries[name] = append(index.Entries[name], &repo.ChartVersion{Metadata: &chart.Metadata{Name: name, Version: version}})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		charts    []catalog.MirroredChart
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "all charts without filters",
+			expected: []string{"agent:0.2.0", "agent:0.1.0", "app:2.0.0", "app:1.2.0", "app:1.1.0", "app:invalid", "monitor:3.0.0-rc1", "monitor:2.0.0"},
+		},
+		{
+			name:     "all versions of a chart",
+			charts:   []catalog.MirroredChart{{Name: "agent"}},
+			expected: []string{"agent:0.2.0", "agent:0.1.0"},
+		},
+		{
+			name:     "version range",
+			charts:   []catalog.MirroredChart{{Name: "app", Versions: ">= 1.2.0"}, {Name: "monitor", Versions: "^2"}},
+			expected: []string{"app:2.0.0", "app:1.2.0", "monitor:2.0.0"},
+		},
+		{
+			name:     "unknown chart",
+			charts:   []catalog.MirroredChart{{Name: "unknown"}},
+			expected: nil,
+		},
+		{
+			name:      "invalid version range",
+			charts:    []catalog.MirroredChart{{Name: "app", Versions: "not a range"}},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filters, err := mirrorFilters(tt.charts)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var actual []string
+			for _, chart := range mirroredCharts(index, filters) {
+				actual = append(actual, chart.Name+":"+chart.Version)
+			}
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+type fakeMirrorContent struct {
+	chart     []byte
+	downloads int
+}
+
+func (f *fakeMirrorContent) Index(string, string, string, bool) (*repo.IndexFile, error) {
+	return repo.NewIndexFile(), nil
+}
+
+func (f *fakeMirrorContent) VerifiedChart(string, string, string, string, bool) (io.ReadCloser, error) {
+	f.downloads++
+	return io.NopCloser(bytes.NewReader(f.chart)), nil
+}
+
+func TestMirrorChartSkipsMirroredDigest(t *testing.T) {
+	chartTar := []byte("chart")
+	sum := sha256.Sum256(chartTar)
+	digest := hex.EncodeToString(sum[:])
+	previous := &catalog.MirroredChartStatus{Name: "app", Version: "1.0.0", Digest: digest, Images: []string{"rancher/app:v1.0.0"}}
+	mirror := &catalog.RepoMirror{URL: "oci://registry.example.com/charts", ImageRegistry: "registry.example.com/mirror"}
+
+	tests := []struct {
+		name          string
+		indexDigest   string
+		wantDownloads int
+	}{
+		{
+			name:          "digest of the index",
+			indexDigest:   digest,
+			wantDownloads: 0,
+		},
+		{
+			name:          "digest of the downloaded chart",
+			wantDownloads: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := &fakeMirrorContent{chart: chartTar}
+			h := &repoMirrorHandler{content: content}
+			chartVersion := &repo.ChartVersion{Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"}, Digest: tt.indexDigest}
+
+			// the registries are not reachable, so pushing the chart or copying its images would fail
+			status, err := h.mirrorChart("repo", mirror, catalog.RepoSpec{}, &corev1.Secret{}, chartVersion, previous, map[string]bool{})
+			require.NoError(t, err)
+			assert.Equal(t, *previous, status)
+			assert.Equal(t, tt.wantDownloads, content.downloads)
+		})
+	}
+}
+
+func TestMirrorDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	newRepo := func(status *catalog.RepoMirrorStatus, mirrored bool) *catalog.ClusterRepo {
+		clusterRepo := &catalog.ClusterRepo{
+			ObjectMeta: metav1.ObjectMeta{Name: "repo", Generation: 2},
+			Spec:       catalog.RepoSpec{URL: "https://charts.example.com", Mirror: &catalog.RepoMirror{URL: "oci://registry.example.com/charts"}},
+			Status:     catalog.RepoStatus{IndexConfigMapName: "repo-index", IndexConfigMapResourceVersion: "10", Mirror: status},
+		}
+		if status != nil {
+			condition.Cond(mirrorCondition).SetError(clusterRepo, "", nil)
+			if !mirrored {
+				condition.Cond(mirrorCondition).SetError(clusterRepo, "", assert.AnError)
+			}
+		}
+		return clusterRepo
+	}
+	synced := func(generation int64, resourceVersion string, syncTime time.Time) *catalog.RepoMirrorStatus {
+		return &catalog.RepoMirrorStatus{ObservedGeneration: generation, IndexConfigMapResourceVersion: resourceVersion, SyncTime: metav1.NewTime(syncTime)}
+	}
+
+	tests := []struct {
+		name       string
+		repo       *catalog.ClusterRepo
+		wantDue    bool
+		wantNext   time.Duration
+		wantFailed bool
+	}{
+		{
+			name:    "never synchronized",
+			repo:    newRepo(nil, false),
+			wantDue: true,
+		},
+		{
+			name:     "synchronized within the interval",
+			repo:     newRepo(synced(2, "10", now.Add(-time.Minute)), true),
+			wantNext: time.Hour - time.Minute,
+		},
+		{
+			name:    "synchronized before the interval",
+			repo:    newRepo(synced(2, "10", now.Add(-2*time.Hour)), true),
+			wantDue: true,
+		},
+		{
+			name:    "index changed",
+			repo:    newRepo(synced(2, "9", now.Add(-time.Minute)), true),
+			wantDue: true,
+		},
+		{
+			name:    "spec changed after a failure",
+			repo:    newRepo(synced(1, "10", now.Add(-time.Minute)), false),
+			wantDue: true,
+		},
+		{
+			name:       "failed",
+			repo:       newRepo(synced(2, "10", now.Add(-time.Minute)), false),
+			wantDue:    true,
+			wantFailed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due, next := mirrorDue(tt.repo)
+			assert.Equal(t, tt.wantDue, due)
+			assert.Equal(t, tt.wantNext, next)
+			assert.Equal(t, tt.wantFailed, mirrorFailed(tt.repo))
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+)
+
+// allowlistSettings are the settings restricting the plugins that can be loaded.
+var allowlistSettings = map[string]struct{}{
+	settings.UIPluginAllowedNames.Name:     {},
+	settings.UIPluginAllowedEndpoints.Name: {},
+}
+
+// checkAllowed returns an error if the name or the endpoint of the plugin isn't allowed by the ui-plugin-allowed-names
+// and ui-plugin-allowed-endpoints settings.
+func checkAllowed(plugin *v1.UIPluginEntry) error {
+	if names := splitSetting(settings.UIPluginAllowedNames.Get()); len(names) > 0 && !nameAllowed(plugin.Name, names) {
+		return fmt.Errorf("plugin name [%s] is not allowed by setting %s", plugin.Name, settings.UIPluginAllowedNames.Name)
+	}
+	if endpoints := splitSetting(settings.UIPluginAllowedEndpoints.Get()); len(endpoints) > 0 && !endpointAllowed(plugin.Endpoint, endpoints) {
+		return fmt.Errorf("plugin endpoint [%s] is not allowed by setting %s", plugin.Endpoint, settings.UIPluginAllowedEndpoints.Name)
+	}
+	return nil
+}
+
+func nameAllowed(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointAllowed returns whether the endpoint has the scheme and host of one of the allowed URLs, and a path equal to
+// or below its path once cleaned, so that neither another host nor ".." elements can escape the allowed prefix.
+func endpointAllowed(endpoint string, allowed []string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	endpointPath := path.Clean("/" + u.Path)
+	for _, a := range allowed {
+		allowedURL, err := url.Parse(a)
+		if err != nil || allowedURL.Host == "" {
+			continue
+		}
+		if !strings.EqualFold(u.Scheme, allowedURL.Scheme) || !strings.EqualFold(u.Host, allowedURL.Host) {
+			continue
+		}
+		allowedPath := path.Clean("/" + allowedURL.Path)
+		if allowedPath == "/" || endpointPath == allowedPath || strings.HasPrefix(endpointPath, allowedPath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func splitSetting(value string) []string {
+	var result []string
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"testing"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/settings"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_checkAllowed(t *testing.T) {
+	testCases := []struct {
+		name             string
+		allowedNames     string
+		allowedEndpoints string
+		plugin           v1.UIPluginEntry
+		expectedErr      string
+	}{
+		{
+			name:   "everything allowed when the settings are empty",
+			plugin: v1.UIPluginEntry{Name: "test-plugin", Endpoint: "https://plugins.example.com/test-plugin"},
+		},
+		{
+			name:             "allowed name and endpoint",
+			allowedNames:     "elemental, rancher-*",
+			allowedEndpoints: "http://ui-plugin-operator-svc.cattle-ui-plugin-system:8080/plugin,https://plugins.example.com/",
+			plugin:           v1.UIPluginEntry{Name: "rancher-test", Endpoint: "https://PLUGINS.example.com/rancher-test/1.0.0"},
+		},
+		{
+			name:         "name not allowed",
+			allowedNames: "elemental,rancher-*",
+			plugin:       v1.UIPluginEntry{Name: "test-plugin", Endpoint: "https://plugins.example.com/test-plugin"},
+			expectedErr:  "plugin name [test-plugin] is not allowed by setting ui-plugin-allowed-names",
+		},
+		{
+			name:             "endpoint on another host",
+			allowedEndpoints: "https://plugins.example.com",
+			plugin:           v1.UIPluginEntry{Name: "test-plugin", Endpoint: "https://plugins.example.com.evil.io/test-plugin"},
+			expectedErr:      "plugin endpoint [https://plugins.example.com.evil.io/test-plugin] is not allowed by setting ui-plugin-allowed-endpoints",
+		},
+		{
+			name:             "endpoint with another scheme",
+			allowedEndpoints: "https://plugins.example.com",
+			plugin:           v1.UIPluginEntry{Name: "test-plugin", Endpoint: "http://plugins.example.com/test-plugin"},
+			expectedErr:      "plugin endpoint [http://plugins.example.com/test-plugin] is not allowed by setting ui-plugin-allowed-endpoints",
+		},
+		{
+			name:             "endpoint escaping the allowed path",
+			allowedEndpoints: "https://plugins.example.com/allowed",
+			plugin:           v1.UIPluginEntry{Name: "test-plugin", Endpoint: "https://plugins.example.com/allowed/../other"},
+			expectedErr:      "plugin endpoint [https://plugins.example.com/allowed/../other] is not allowed by setting ui-plugin-allowed-endpoints",
+		},
+		{
+			name:             "endpoint sharing a prefix with the allowed path",
+			allowedEndpoints: "https://plugins.example.com/allowed",
+			plugin:           v1.UIPluginEntry{Name: "test-plugin", Endpoint: "https://plugins.example.com/allowed-not"},
+			expectedErr:      "plugin endpoint [https://plugins.example.com/allowed-not] is not allowed by setting ui-plugin-allowed-endpoints",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, settings.UIPluginAllowedNames.Set(tc.allowedNames))
+			assert.NoError(t, settings.UIPluginAllowedEndpoints.Set(tc.allowedEndpoints))
+			t.Cleanup(func() {
+				settings.UIPluginAllowedNames.Set("")
+				settings.UIPluginAllowedEndpoints.Set("")
+			})
+
+			err := checkAllowed(&tc.plugin)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
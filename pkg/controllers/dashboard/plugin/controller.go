@@ -18,6 +18,7 @@
 	"github.com/sirupsen/logrus"
 
 	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	v3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	plugincontroller "github.com/rancher/rancher/pkg/generated/controllers/catalog.cattle.io/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
@@ -39,6 +40,7 @@ func Register(
 		pluginCache:     wContext.Catalog.UIPlugin().Cache(),
 	}
 	wContext.Catalog.UIPlugin().OnChange(ctx, "on-ui-plugin-change", h.OnPluginChange)
+	wContext.Mgmt.Setting().OnChange(ctx, "ui-plugin-allowlist", h.OnSettingChange)
 }
 
 type handler struct {
@@ -51,10 +53,21 @@ func (h *handler) OnPluginChange(key string, plugin *v1.UIPlugin) (*v1.UIPlugin,
 	triggered := timeNow().UTC()
 	forceUpdate := false
 	//indexing all plugins
-	cachedPlugins, err := h.pluginCache.List(h.systemNamespace, labels.Everything())
+	listedPlugins, err := h.pluginCache.List(h.systemNamespace, labels.Everything())
 	if err != nil {
 		return plugin, fmt.Errorf("failed to list plugins from cache: %w", err)
 	}
+	// plugins that aren't allowed, or whose pinned integrity wasn't verified, are left out of the indexes, so that they
+	// are neither served nor kept in the filesystem cache
+	var cachedPlugins []*v1.UIPlugin
+	for _, cachedPlugin := range listedPlugins {
+		if checkAllowed(&cachedPlugin.Spec.Plugin) == nil && Verified(&UIPlugin{
+			UIPluginEntry: &cachedPlugin.Spec.Plugin,
+			CacheState:    cachedPlugin.Status.CacheState,
+		}) {
+			cachedPlugins = append(cachedPlugins, cachedPlugin)
+		}
+	}
 	err = Index.Generate(cachedPlugins)
 	if err != nil {
 		return plugin, fmt.Errorf("failed to generate index with cached plugins: %w", err)
@@ -89,6 +102,26 @@ func (h *handler) OnPluginChange(key string, plugin *v1.UIPlugin) (*v1.UIPlugin,
 		return plugin, nil
 	}
 	defer h.plugin.UpdateStatus(plugin)
+	if err := checkAllowed(&plugin.Spec.Plugin); err != nil {
+		logrus.Warnf("refusing to load plugin [%s]: %s", plugin.Spec.Plugin.Name, err.Error())
+		plugin.Status.ObservedGeneration = plugin.Generation
+		plugin.Status.CacheState = Disabled
+		plugin.Status.Ready = false
+		plugin.Status.Error = err.Error()
+		plugin.Status.RetryNumber = 0
+		plugin.Status.RetryAt = metav1.Time{}
+		return plugin, nil
+	}
+	if plugin.Spec.Plugin.NoCache && plugin.Spec.Plugin.Integrity != nil {
+		logrus.Warnf("refusing to load plugin [%s]: %s", plugin.Spec.Plugin.Name, errIntegrityNoCache.Error())
+		plugin.Status.ObservedGeneration = plugin.Generation
+		plugin.Status.CacheState = Disabled
+		plugin.Status.Ready = false
+		plugin.Status.Error = errIntegrityNoCache.Error()
+		plugin.Status.RetryNumber = 0
+		plugin.Status.RetryAt = metav1.Time{}
+		return plugin, nil
+	}
 	if plugin.Spec.Plugin.NoCache {
 		plugin.Status.CacheState = Disabled
 	} else {
@@ -111,7 +144,12 @@ func (h *handler) OnPluginChange(key string, plugin *v1.UIPlugin) (*v1.UIPlugin,
 	defer AnonymousIndex.CacheState(plugin)
 
 	err = FsCache.SyncWithControllersCache(plugin, forceUpdate)
-	if errors.Is(err, errMaxFileSizeError) {
+	if errors.Is(err, errMaxFileSizeError) && plugin.Spec.Plugin.Integrity != nil {
+		// falling back to proxying the plugin would bypass the verification of its files
+		plugin.Status.Ready = false
+		plugin.Status.Error = "Failed to cache plugin with pinned integrity due to max file size limit"
+		return h.retry(plugin, err)
+	} else if errors.Is(err, errMaxFileSizeError) {
 		logrus.Errorf("one of the files is more than the defaultUIPluginFileByteSize limit %s", strconv.FormatInt(maxFileSize, 10))
 		// update CRD to remove cache
 		plugin.Spec.Plugin.NoCache = true
@@ -133,6 +171,10 @@ func (h *handler) OnPluginChange(key string, plugin *v1.UIPlugin) (*v1.UIPlugin,
 		plugin.Status.CacheState = Disabled
 		plugin.Status.Ready = true
 		return plugin, nil
+	} else if errors.Is(err, errIntegrityMismatch) {
+		plugin.Status.Ready = false
+		plugin.Status.Error = "Failed to verify the integrity of the plugin files: " + err.Error()
+		return h.retry(plugin, err)
 	} else if err != nil {
 		plugin.Status.Ready = false
 		plugin.Status.Error = "Failed to cache plugin"
@@ -152,6 +194,24 @@ func (h *handler) OnPluginChange(key string, plugin *v1.UIPlugin) (*v1.UIPlugin,
 	return plugin, nil
 }
 
+// OnSettingChange enqueues all the plugins when the settings restricting the plugins that can be loaded change.
+func (h *handler) OnSettingChange(_ string, setting *v3.Setting) (*v3.Setting, error) {
+	if setting == nil {
+		return nil, nil
+	}
+	if _, ok := allowlistSettings[setting.Name]; !ok {
+		return setting, nil
+	}
+	plugins, err := h.pluginCache.List(h.systemNamespace, labels.Everything())
+	if err != nil {
+		return setting, fmt.Errorf("failed to list plugins from cache: %w", err)
+	}
+	for _, plugin := range plugins {
+		h.plugin.Enqueue(plugin.Namespace, plugin.Name)
+	}
+	return setting, nil
+}
+
 func (h *handler) retry(plugin *v1.UIPlugin, err error) (*v1.UIPlugin, error) {
 	logrus.WithError(err).Error("failed to sync filesystem cache with controller cache")
 	backoff := calculateBackoff(plugin.Status.RetryNumber).Round(time.Second)
@@ -44,6 +44,11 @@
 type FSCache struct {
 }
 
+type cachedFile struct {
+	path string
+	data []byte
+}
+
 type PackageJSON struct {
 	Version string `json:"version,omitempty"`
 }
@@ -83,6 +88,10 @@ func (c FSCache) SyncWithControllersCache(p *v1.UIPlugin, forceUpdate bool) erro
 	if err != nil {
 		return fmt.Errorf("failed to get files.txt file. Error: %w", err)
 	}
+	// all the files are fetched and verified before any is cached, so that a plugin failing verification is never
+	// partially cached and then considered as cached
+	verifier := newIntegrityVerifier(plugin.Integrity)
+	var fetched []cachedFile
 	for _, file := range files {
 		if file == "" {
 			continue
@@ -91,12 +100,21 @@ func (c FSCache) SyncWithControllersCache(p *v1.UIPlugin, forceUpdate bool) erro
 		if err != nil {
 			return fmt.Errorf("failed to fetch file [%s] .Error: %w", file, err)
 		}
+		if err := verifier.verifyFile(file, data); err != nil {
+			return fmt.Errorf("failed to verify plugin [Name: %s Version: %s]. Error: %w", plugin.Name, plugin.Version, err)
+		}
 		path, err := filepathsecure.SecureJoin(FSCacheRootDir, filepath.Join(plugin.Name, plugin.Version, file))
 		if err != nil {
 			return fmt.Errorf("failed to build file [%s] path for caching. Error: %w", file, err)
 		}
-		if err := c.Save(data, path); err != nil {
-			logrus.Debugf("failed to cache plugin [Name: %s Version: %s] in filesystem [path: %s]", plugin.Name, plugin.Version, path)
+		fetched = append(fetched, cachedFile{path: path, data: data})
+	}
+	if err := verifier.verifyManifest(); err != nil {
+		return fmt.Errorf("failed to verify plugin [Name: %s Version: %s]. Error: %w", plugin.Name, plugin.Version, err)
+	}
+	for _, f := range fetched {
+		if err := c.Save(f.data, f.path); err != nil {
+			logrus.Debugf("failed to cache plugin [Name: %s Version: %s] in filesystem [path: %s]", plugin.Name, plugin.Version, f.path)
 		}
 	}
 
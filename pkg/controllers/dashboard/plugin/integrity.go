@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+)
+
+var errIntegrityMismatch = errors.New("integrity verification failed")
+
+// errIntegrityNoCache is the error of the plugins with pinned integrity which aren't cached, as their files would be
+// proxied from their endpoint without being verified.
+var errIntegrityNoCache = errors.New("plugins with pinned integrity can't set noCache, their files must be verified and cached")
+
+var sriAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// Verified returns whether the files of the plugin can be served: the files of plugins with pinned integrity are only
+// served from the filesystem cache once they were verified, never proxied from the endpoint of the plugin.
+func Verified(plugin *UIPlugin) bool {
+	return plugin.Integrity == nil || !plugin.NoCache && plugin.CacheState == Cached
+}
+
+// integrityVerifier verifies the files of a plugin against the digests pinned in its spec.
+type integrityVerifier struct {
+	integrity *v1.UIPluginIntegrity
+	manifest  strings.Builder
+}
+
+func newIntegrityVerifier(integrity *v1.UIPluginIntegrity) *integrityVerifier {
+	return &integrityVerifier{integrity: integrity}
+}
+
+// verifyFile checks the file against its pinned digest and adds it to the manifest of the plugin.
+func (v *integrityVerifier) verifyFile(file string, data []byte) error {
+	if v.integrity == nil {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	fmt.Fprintf(&v.manifest, "%s  %s\n", hex.EncodeToString(sum[:]), file)
+
+	if v.integrity.Files == nil {
+		return nil
+	}
+	digest, ok := v.integrity.Files[file]
+	if !ok {
+		return fmt.Errorf("%w: file [%s] has no digest", errIntegrityMismatch, file)
+	}
+	if err := verifySRI(data, digest); err != nil {
+		return fmt.Errorf("file [%s]: %w", file, err)
+	}
+	return nil
+}
+
+// verifyManifest checks the manifest of the files verified so far against the pinned manifest digest.
+func (v *integrityVerifier) verifyManifest() error {
+	if v.integrity == nil || v.integrity.ManifestDigest == "" {
+		return nil
+	}
+	if err := verifySRI([]byte(v.manifest.String()), v.integrity.ManifestDigest); err != nil {
+		return fmt.Errorf("manifest: %w", err)
+	}
+	return nil
+}
+
+// verifySRI checks the data against a Subresource Integrity value. As in browsers, the value may hold several
+// whitespace separated digests, and the data must match one of the digests using the strongest algorithm.
+func verifySRI(data []byte, sri string) error {
+	type digest struct {
+		algorithm string
+		sum       []byte
+	}
+	var digests []digest
+	for _, token := range strings.Fields(sri) {
+		algorithm, encoded, ok := strings.Cut(token, "-")
+		if !ok {
+			continue
+		}
+		if _, ok := sriAlgorithms[algorithm]; !ok {
+			continue
+		}
+		encoded, _, _ = strings.Cut(encoded, "?")
+		sum, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("%w: invalid digest [%s]: %v", errIntegrityMismatch, token, err)
+		}
+		digests = append(digests, digest{algorithm: algorithm, sum: sum})
+	}
+	if len(digests) == 0 {
+		return fmt.Errorf("%w: no sha256, sha384 or sha512 digest in [%s]", errIntegrityMismatch, sri)
+	}
+
+	// the names of the supported algorithms sort by strength
+	strongest := ""
+	for _, d := range digests {
+		if d.algorithm > strongest {
+			strongest = d.algorithm
+		}
+	}
+	h := sriAlgorithms[strongest]()
+	h.Write(data)
+	sum := h.Sum(nil)
+	for _, d := range digests {
+		if d.algorithm == strongest && subtle.ConstantTimeCompare(d.sum, sum) == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s digest does not match", errIntegrityMismatch, strongest)
+}
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha384SRI(data string) string {
+	sum := sha512.Sum384([]byte(data))
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func Test_verifySRI(t *testing.T) {
+	data := "console.log('plugin')"
+	sum256 := sha256.Sum256([]byte(data))
+	sha256SRI := "sha256-" + base64.StdEncoding.EncodeToString(sum256[:])
+
+	testCases := []struct {
+		name  string
+		sri   string
+		valid bool
+	}{
+		{name: "matching digest", sri: sha384SRI(data), valid: true},
+		{name: "matching digest with options", sri: sha256SRI + "?foo", valid: true},
+		{name: "other data", sri: sha384SRI("alert('pwned')")},
+		{name: "strongest digest is used", sri: sha256SRI + " " + sha384SRI("alert('pwned')")},
+		{name: "one of the strongest digests matches", sri: sha384SRI("alert('pwned')") + " " + sha384SRI(data), valid: true},
+		{name: "unsupported algorithm", sri: "md5-" + base64.StdEncoding.EncodeToString([]byte("digest"))},
+		{name: "invalid digest", sri: "sha384-not base64!"},
+		{name: "empty", sri: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := verifySRI([]byte(data), tc.sri)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, errIntegrityMismatch)
+			}
+		})
+	}
+}
+
+func TestSyncWithControllersCacheIntegrity(t *testing.T) {
+	files := map[string]string{
+		"plugin/package.json": `{"version": "0.1.0"}`,
+		"plugin/index.js":     "console.log('plugin')",
+		"plugin/index.css":    "body {}",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/files.txt" {
+			w.Write([]byte("plugin/index.js\nplugin/index.css\n"))
+			return
+		}
+		content, ok := files[r.URL.Path[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var manifest string
+	for _, file := range []string{"plugin/index.js", "plugin/index.css"} {
+		sum := sha256.Sum256([]byte(files[file]))
+		manifest += hex.EncodeToString(sum[:]) + "  " + file + "\n"
+	}
+
+	testCases := []struct {
+		name      string
+		integrity *v1.UIPluginIntegrity
+		valid     bool
+	}{
+		{
+			name:  "no integrity",
+			valid: true,
+		},
+		{
+			name: "matching file digests",
+			integrity: &v1.UIPluginIntegrity{Files: map[string]string{
+				"plugin/index.js":  sha384SRI(files["plugin/index.js"]),
+				"plugin/index.css": sha384SRI(files["plugin/index.css"]),
+			}},
+			valid: true,
+		},
+		{
+			name: "mismatching file digest",
+			integrity: &v1.UIPluginIntegrity{Files: map[string]string{
+				"plugin/index.js":  sha384SRI("alert('pwned')"),
+				"plugin/index.css": sha384SRI(files["plugin/index.css"]),
+			}},
+		},
+		{
+			name: "file without digest",
+			integrity: &v1.UIPluginIntegrity{Files: map[string]string{
+				"plugin/index.js": sha384SRI(files["plugin/index.js"]),
+			}},
+		},
+		{
+			name:      "matching manifest digest",
+			integrity: &v1.UIPluginIntegrity{ManifestDigest: sha384SRI(manifest)},
+			valid:     true,
+		},
+		{
+			name:      "mismatching manifest digest",
+			integrity: &v1.UIPluginIntegrity{ManifestDigest: sha384SRI("other manifest")},
+		},
+	}
+
+	osStat = os.Stat
+	isDirEmpty = isDirectoryEmpty
+	rootDir := FSCacheRootDir
+	defer func() { FSCacheRootDir = rootDir }()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			FSCacheRootDir = t.TempDir()
+			plugin := &v1.UIPlugin{Spec: v1.UIPluginSpec{Plugin: v1.UIPluginEntry{
+				Name:      "test-plugin",
+				Version:   "0.1.0",
+				Endpoint:  server.URL,
+				Integrity: tc.integrity,
+			}}}
+
+			err := FSCache{}.SyncWithControllersCache(plugin, false)
+			_, statErr := os.Stat(filepath.Join(FSCacheRootDir, "test-plugin", "0.1.0", "plugin", "index.js"))
+			if tc.valid {
+				require.NoError(t, err)
+				assert.NoError(t, statErr)
+			} else {
+				assert.ErrorIs(t, err, errIntegrityMismatch)
+				// nothing is cached when any file fails verification
+				_, statErr = os.Stat(filepath.Join(FSCacheRootDir, "test-plugin"))
+				assert.ErrorIs(t, statErr, os.ErrNotExist)
+			}
+		})
+	}
+}
+
+func TestVerified(t *testing.T) {
+	integrity := &v1.UIPluginIntegrity{ManifestDigest: sha384SRI("manifest")}
+
+	testCases := []struct {
+		name     string
+		entry    v1.UIPluginEntry
+		state    string
+		verified bool
+	}{
+		{name: "no pinned integrity", entry: v1.UIPluginEntry{NoCache: true}, state: Disabled, verified: true},
+		{name: "verified and cached", entry: v1.UIPluginEntry{Integrity: integrity}, state: Cached, verified: true},
+		{name: "pending verification", entry: v1.UIPluginEntry{Integrity: integrity}, state: Pending},
+		{name: "not cached", entry: v1.UIPluginEntry{Integrity: integrity, NoCache: true}, state: Cached},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.verified, Verified(&UIPlugin{UIPluginEntry: &tc.entry, CacheState: tc.state}))
+		})
+	}
+}
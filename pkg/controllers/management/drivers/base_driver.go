@@ -19,6 +19,7 @@
 	"strings"
 
 	"github.com/pkg/errors"
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/sirupsen/logrus"
 )
 
@@ -28,6 +29,10 @@ type BaseDriver struct {
 	DriverHash   string
 	DriverName   string
 	BinaryPrefix string
+	// Signature is verified before the downloaded driver is staged, if set.
+	Signature *Signature
+	// SignatureVerification is the result of the signature verification of the last download.
+	SignatureVerification *v32.DriverSignatureVerification
 }
 
 func (d *BaseDriver) Name() string {
@@ -142,6 +147,17 @@ func (d *BaseDriver) stage(forceUpdate bool) error {
 		return err
 	}
 
+	if d.Signature != nil {
+		content, err := os.ReadFile(tempFile.Name())
+		if err != nil {
+			return err
+		}
+		d.SignatureVerification = d.Signature.verify(content, d.URL)
+		if !d.SignatureVerification.Verified {
+			return fmt.Errorf("signature verification of driver %s failed: %s", d.URL, d.SignatureVerification.Message)
+		}
+	}
+
 	driverName, err = d.copyBinary(cacheFilePrefix, tempFile.Name())
 	if err != nil {
 		return err
@@ -337,7 +353,7 @@ func (d *BaseDriver) download(dest io.Writer) error {
 }
 
 func (d *BaseDriver) cacheFile() string {
-	key := sha256Bytes([]byte(d.URL + d.DriverHash))
+	key := sha256Bytes([]byte(d.URL + d.DriverHash + d.Signature.cacheKey()))
 
 	base := os.Getenv("CATTLE_HOME")
 	if base == "" {
@@ -80,13 +80,23 @@ func (l *Lifecycle) Create(obj *v3.KontainerDriver) (runtime.Object, error) {
 }
 
 func (l *Lifecycle) driverExists(obj *v3.KontainerDriver) bool {
-	return drivers.NewKontainerDriver(obj.Spec.BuiltIn, obj.Status.DisplayName, obj.Spec.URL, obj.Spec.Checksum).Exists()
+	driver := drivers.NewKontainerDriver(obj.Spec.BuiltIn, obj.Status.DisplayName, obj.Spec.URL, obj.Spec.Checksum)
+	driver.Signature = drivers.NewSignature(obj.Spec.Signature, obj.Spec.URL)
+	return driver.Exists()
 }
 
 func (l *Lifecycle) download(obj *v3.KontainerDriver) (*v3.KontainerDriver, error) {
 	driver := drivers.NewKontainerDriver(obj.Spec.BuiltIn, obj.Status.DisplayName, obj.Spec.URL, obj.Spec.Checksum)
+	driver.Signature = drivers.NewSignature(obj.Spec.Signature, obj.Spec.URL)
 	err := driver.Stage(false)
+	if driver.SignatureVerification != nil {
+		obj.Status.SignatureVerification = driver.SignatureVerification
+	}
 	if err != nil {
+		if driver.SignatureVerification != nil {
+			// record the failed verification, the driver stays in the downloading state and is never activated
+			return obj, err
+		}
 		return nil, err
 	}
 
@@ -439,6 +449,7 @@ func (l *Lifecycle) Remove(obj *v3.KontainerDriver) (runtime.Object, error) {
 	logrus.Infof("remove kontainerdriver %v", obj.Name)
 
 	driver := drivers.NewKontainerDriver(obj.Spec.BuiltIn, obj.Name, obj.Spec.URL, obj.Spec.Checksum)
+	driver.Signature = drivers.NewSignature(obj.Spec.Signature, obj.Spec.URL)
 	err := driver.Remove()
 	if err != nil {
 		return nil, err
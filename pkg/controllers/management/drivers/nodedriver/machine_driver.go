@@ -103,6 +103,7 @@ func (m *Lifecycle) download(obj *v32.NodeDriver) (*v32.NodeDriver, error) {
 	err := errs.New("not found")
 	// if node driver was created, we also activate the driver by default
 	driver := drivers.NewDynamicDriver(obj.Spec.Builtin, obj.Spec.DisplayName, obj.Spec.URL, obj.Spec.Checksum)
+	driver.Signature = drivers.NewSignature(obj.Spec.Signature, obj.Spec.URL)
 	schemaName := obj.Spec.DisplayName + "config"
 	var existingSchema *v32.DynamicSchema
 	if obj.Spec.DisplayName != "" {
@@ -147,8 +148,13 @@ func (m *Lifecycle) download(obj *v32.NodeDriver) (*v32.NodeDriver, error) {
 			return nil, err
 		}
 
-		if err := driver.Stage(forceUpdate); err != nil {
-			return nil, err
+		err := driver.Stage(forceUpdate)
+		if driver.SignatureVerification != nil {
+			obj.Status.SignatureVerification = driver.SignatureVerification
+		}
+		if err != nil {
+			// the object is returned so the failed signature verification is recorded on the status
+			return obj, err
 		}
 		return obj, nil
 	})
@@ -0,0 +1,230 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	v32 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
+	"github.com/rancher/rancher/pkg/settings"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	signatureFormatCosign   = "cosign"
+	signatureFormatMinisign = "minisign"
+
+	// maxSignatureSize bounds the size of a downloaded signature file.
+	maxSignatureSize = 64 * 1024
+)
+
+// Signature is the resolved signature configuration of a driver.
+type Signature struct {
+	URL        string
+	PublicKeys []string
+}
+
+// NewSignature returns the signature configuration of a driver with the given URL. The keys of the
+// driver-signature-public-keys setting are used if the driver does not set its own key. It returns nil if the driver
+// does not need to be verified, which is the case if it does not configure a signature and the
+// driver-signature-required setting is not enabled.
+func NewSignature(signature *v32.DriverSignature, driverURL string) *Signature {
+	if signature == nil && !strings.EqualFold(settings.DriverSignatureRequired.Get(), "true") {
+		return nil
+	}
+
+	result := &Signature{
+		URL: driverURL + ".sig",
+	}
+	publicKeys := settings.DriverSignaturePublicKeys.Get()
+	if signature != nil {
+		if signature.URL != "" {
+			result.URL = signature.URL
+		}
+		if signature.PublicKey != "" {
+			publicKeys = signature.PublicKey
+		}
+	}
+	result.PublicKeys = splitPublicKeys(publicKeys)
+	return result
+}
+
+// cacheKey identifies the signature configuration, so that a driver binary cached with a different configuration,
+// or without verification, is not used.
+func (s *Signature) cacheKey() string {
+	if s == nil {
+		return ""
+	}
+	return s.URL + strings.Join(s.PublicKeys, "")
+}
+
+// splitPublicKeys returns the PEM blocks and the minisign public keys found in the given keys.
+func splitPublicKeys(keys string) []string {
+	var result []string
+	rest := []byte(keys)
+	for {
+		block, remaining := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		result = append(result, string(pem.EncodeToMemory(block)))
+		rest = remaining
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(rest))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// minisign public key files start with an untrusted comment
+		if line == "" || strings.HasPrefix(line, "untrusted comment:") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+// verify verifies the detached signature of the driver binary, returning the verification result that is recorded on
+// the status of the driver.
+func (s *Signature) verify(content []byte, driverURL string) *v32.DriverSignatureVerification {
+	result := &v32.DriverSignatureVerification{
+		URL:  driverURL,
+		Time: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if len(s.PublicKeys) == 0 {
+		result.Message = "no public key is configured to verify the driver signature"
+		return result
+	}
+
+	signature, err := downloadSignature(s.URL)
+	if err != nil {
+		result.Message = fmt.Sprintf("failed to download signature %s: %v", s.URL, err)
+		return result
+	}
+
+	if bytes.HasPrefix(signature, []byte("untrusted comment:")) {
+		result.Format = signatureFormatMinisign
+		result.KeyID, err = verifyMinisign(content, signature, s.PublicKeys)
+	} else {
+		result.Format = signatureFormatCosign
+		result.KeyID, err = verifyCosign(content, signature, s.PublicKeys)
+	}
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Verified = true
+	return result
+}
+
+func downloadSignature(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+}
+
+// verifyCosign verifies a signature created with "cosign sign-blob --key", which is the base64 encoded signature of
+// the SHA256 digest of the binary. It returns the ID of the key that verified the signature.
+func verifyCosign(content, signature []byte, publicKeys []string) (string, error) {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return "", fmt.Errorf("invalid cosign signature: %w", err)
+	}
+	digest := sha256.Sum256(content)
+
+	for _, key := range publicKeys {
+		block, _ := pem.Decode([]byte(key))
+		if block == nil {
+			continue
+		}
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		verified := false
+		switch pub := pub.(type) {
+		case *ecdsa.PublicKey:
+			verified = ecdsa.VerifyASN1(pub, digest[:], sig)
+		case *rsa.PublicKey:
+			verified = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+		case ed25519.PublicKey:
+			verified = ed25519.Verify(pub, content, sig)
+		}
+		if verified {
+			keyDigest := sha256.Sum256(block.Bytes)
+			return hex.EncodeToString(keyDigest[:8]), nil
+		}
+	}
+
+	return "", fmt.Errorf("cosign signature does not match any of the configured public keys")
+}
+
+// verifyMinisign verifies a minisign signature, including its trusted comment. It returns the ID of the key that
+// verified the signature.
+func verifyMinisign(content, signature []byte, publicKeys []string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(string(signature)), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return "", fmt.Errorf("invalid minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 74 {
+		return "", fmt.Errorf("invalid minisign signature")
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return "", fmt.Errorf("invalid minisign global signature")
+	}
+
+	algorithm, keyID, sigBytes := string(sig[:2]), sig[2:10], sig[10:]
+	message := content
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		digest := blake2b.Sum512(content)
+		message = digest[:]
+	default:
+		return "", fmt.Errorf("unsupported minisign signature algorithm %q", algorithm)
+	}
+
+	for _, key := range publicKeys {
+		pub, err := base64.StdEncoding.DecodeString(key)
+		if err != nil || len(pub) != 42 || string(pub[:2]) != "Ed" || !bytes.Equal(pub[2:10], keyID) {
+			continue
+		}
+		publicKey := ed25519.PublicKey(pub[10:])
+		if !ed25519.Verify(publicKey, message, sigBytes) {
+			return "", fmt.Errorf("minisign signature verification failed")
+		}
+		trustedComment := strings.TrimPrefix(strings.TrimRight(lines[2], "\r"), "trusted comment: ")
+		if !ed25519.Verify(publicKey, append(append([]byte{}, sigBytes...), trustedComment...), globalSig) {
+			return "", fmt.Errorf("minisign trusted comment verification failed")
+		}
+		return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(keyID)), nil
+	}
+
+	return "", fmt.Errorf("minisign signature key %016X does not match any of the configured public keys", binary.LittleEndian.Uint64(keyID))
+}
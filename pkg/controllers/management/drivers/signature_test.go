@@ -0,0 +1,94 @@
+package drivers
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestVerifyCosign(t *testing.T) {
+	content := []byte("docker-machine-driver-test")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherDER, err := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	require.NoError(t, err)
+	otherPublicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherDER}))
+
+	digest := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.NoError(t, err)
+	signature := []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+
+	keys := splitPublicKeys(otherPublicKey + publicKey)
+	require.Len(t, keys, 2)
+
+	keyID, err := verifyCosign(content, signature, keys)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, keyID)
+
+	_, err = verifyCosign([]byte("tampered"), signature, keys)
+	assert.Error(t, err)
+
+	_, err = verifyCosign(content, signature, []string{otherPublicKey})
+	assert.Error(t, err)
+}
+
+func TestVerifyMinisign(t *testing.T) {
+	content := []byte("kontainer-engine-driver-test")
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	publicKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...))
+
+	minisign := func(content []byte, trustedComment string) []byte {
+		digest := blake2b.Sum512(content)
+		sig := ed25519.Sign(priv, digest[:])
+		globalSig := ed25519.Sign(priv, append(append([]byte{}, sig...), trustedComment...))
+		return []byte(fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+			base64.StdEncoding.EncodeToString(append(append([]byte("ED"), keyID...), sig...)),
+			trustedComment,
+			base64.StdEncoding.EncodeToString(globalSig)))
+	}
+
+	keys := splitPublicKeys("untrusted comment: minisign public key 0807060504030201\n" + publicKey + "\n")
+	require.Equal(t, []string{publicKey}, keys)
+
+	signature := minisign(content, "timestamp:1700000000\tfile:driver")
+	id, err := verifyMinisign(content, signature, keys)
+	assert.NoError(t, err)
+	assert.Equal(t, "0807060504030201", id)
+
+	_, err = verifyMinisign([]byte("tampered"), signature, keys)
+	assert.Error(t, err)
+
+	lines := strings.Split(string(signature), "\n")
+	lines[2] = "trusted comment: forged"
+	_, err = verifyMinisign(content, []byte(strings.Join(lines, "\n")), keys)
+	assert.Error(t, err)
+
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), 9, 9, 9, 9, 9, 9, 9, 9), otherPub...))
+	_, err = verifyMinisign(content, signature, []string{otherKey})
+	assert.Error(t, err)
+}
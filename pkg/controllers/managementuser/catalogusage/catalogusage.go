@@ -0,0 +1,210 @@
+// Package catalogusage collects the Helm releases of downstream clusters as ClusterReleases in the management cluster.
+package catalogusage
+
+import (
+	"context"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/generated/clientset/versioned"
+	"github.com/rancher/rancher/pkg/types/config"
+	"github.com/rancher/wrangler/v3/pkg/apply"
+	"github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// allReleasesKey is queued whenever an App changes, so that the ClusterReleases of all the Apps of the cluster are
+	// applied at once and the ClusterReleases of deleted Apps are pruned.
+	allReleasesKey = "_catalog_usage_all_"
+	// syncDelay batches the changes of the Apps of a cluster, like when its agent reconnects.
+	syncDelay = 5 * time.Second
+
+	sourceRepoTypeAnnotation = "catalog.cattle.io/ui-source-repo-type"
+	sourceRepoAnnotation     = "catalog.cattle.io/ui-source-repo"
+)
+
+type handler struct {
+	clusterName string
+	// apps is an informer of the Apps of the cluster trimmed by trimApp, as the values, the manifest and the resources
+	// of the releases of a cluster can be large and are not needed.
+	apps  cache.SharedIndexInformer
+	apply apply.Apply
+	queue workqueue.TypedRateLimitingInterface[string]
+}
+
+// Register starts collecting the Apps of the downstream cluster as ClusterReleases in the namespace of the cluster in
+// the management cluster.
+func Register(ctx context.Context, cluster *config.UserContext) error {
+	client, err := versioned.NewForConfig(&cluster.RESTConfig)
+	if err != nil {
+		return err
+	}
+	apps := cache.NewSharedIndexInformer(&cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return client.CatalogV1().Apps("").List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return client.CatalogV1().Apps("").Watch(ctx, options)
+		},
+	}, &catalog.App{}, 0, cache.Indexers{})
+	if err := apps.SetTransform(trimApp); err != nil {
+		return err
+	}
+
+	h := &handler{
+		clusterName: cluster.ClusterName,
+		apps:        apps,
+		apply: cluster.Management.Wrangler.Apply.
+			WithSetID("catalog-usage-" + cluster.ClusterName).
+			WithGVK(catalog.SchemeGroupVersion.WithKind("ClusterRelease")).
+			WithDefaultNamespace(cluster.ClusterName).
+			WithListerNamespace(cluster.ClusterName),
+		queue: workqueue.NewTypedRateLimitingQueueWithConfig(workqueue.DefaultTypedControllerRateLimiter[string](),
+			workqueue.TypedRateLimitingQueueConfig[string]{Name: "catalog-usage-" + cluster.ClusterName}),
+	}
+	enqueue := func(interface{}) {
+		h.queue.AddAfter(allReleasesKey, syncDelay)
+	}
+	if _, err := apps.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+		DeleteFunc: enqueue,
+	}); err != nil {
+		return err
+	}
+
+	go apps.Run(ctx.Done())
+	go h.run(ctx)
+	return nil
+}
+
+// run applies the ClusterReleases of the Apps of the cluster whenever they change, once the Apps are listed, until the
+// context is done.
+func (h *handler) run(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		h.queue.ShutDown()
+	}()
+	if !cache.WaitForCacheSync(ctx.Done(), h.apps.HasSynced) {
+		return
+	}
+
+	for {
+		key, shutdown := h.queue.Get()
+		if shutdown {
+			return
+		}
+		if err := h.sync(); err != nil {
+			logrus.Errorf("Failed to apply the cluster releases of cluster %s: %v", h.clusterName, err)
+			h.queue.AddRateLimited(key)
+		} else {
+			h.queue.Forget(key)
+		}
+		h.queue.Done(key)
+	}
+}
+
+// sync applies the ClusterReleases of the Apps of the cluster and prunes the ClusterReleases of deleted Apps.
+func (h *handler) sync() error {
+	apps := h.apps.GetStore().List()
+	objs := make([]runtime.Object, 0, len(apps))
+	for _, obj := range apps {
+		app, ok := obj.(*catalog.App)
+		if !ok {
+			continue
+		}
+		if release := clusterRelease(h.clusterName, app); release != nil {
+			objs = append(objs, release)
+		}
+	}
+	return h.apply.ApplyObjects(objs...)
+}
+
+// trimApp keeps the fields of an App summarized by its ClusterRelease, so that the values, the manifest and the
+// resources of the release aren't cached.
+func trimApp(obj interface{}) (interface{}, error) {
+	app, ok := obj.(*catalog.App)
+	if !ok {
+		return obj, nil
+	}
+
+	trimmed := &catalog.App{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            app.Name,
+			Namespace:       app.Namespace,
+			UID:             app.UID,
+			ResourceVersion: app.ResourceVersion,
+		},
+		Spec: catalog.ReleaseSpec{
+			Name:    app.Spec.Name,
+			Version: app.Spec.Version,
+		},
+	}
+	if info := app.Spec.Info; info != nil {
+		trimmed.Spec.Info = &catalog.Info{
+			Status:       info.Status,
+			LastDeployed: info.LastDeployed,
+		}
+	}
+	if app.Spec.Chart != nil && app.Spec.Chart.Metadata != nil {
+		metadata := app.Spec.Chart.Metadata
+		trimmed.Spec.Chart = &catalog.Chart{Metadata: &catalog.Metadata{
+			Name:       metadata.Name,
+			Version:    metadata.Version,
+			AppVersion: metadata.AppVersion,
+			Deprecated: metadata.Deprecated,
+		}}
+		for _, key := range []string{sourceRepoTypeAnnotation, sourceRepoAnnotation} {
+			if value, ok := metadata.Annotations[key]; ok {
+				if trimmed.Spec.Chart.Metadata.Annotations == nil {
+					trimmed.Spec.Chart.Metadata.Annotations = map[string]string{}
+				}
+				trimmed.Spec.Chart.Metadata.Annotations[key] = value
+			}
+		}
+	}
+	return trimmed, nil
+}
+
+// clusterRelease returns the ClusterRelease summarizing the App of a release of the cluster, or nil if the App has no
+// chart.
+func clusterRelease(clusterName string, app *catalog.App) *catalog.ClusterRelease {
+	if app.Spec.Chart == nil || app.Spec.Chart.Metadata == nil {
+		return nil
+	}
+	metadata := app.Spec.Chart.Metadata
+
+	release := catalog.NewClusterRelease(clusterName, app.Namespace+"."+app.Name, catalog.ClusterRelease{
+		Spec: catalog.ClusterReleaseSpec{
+			ClusterName:      clusterName,
+			ReleaseName:      app.Spec.Name,
+			ReleaseNamespace: app.Namespace,
+			Revision:         app.Spec.Version,
+			Chart:            metadata.Name,
+			Version:          metadata.Version,
+			AppVersion:       metadata.AppVersion,
+			Deprecated:       metadata.Deprecated,
+		},
+	})
+	if app.Spec.Info != nil {
+		release.Spec.Status = app.Spec.Info.Status
+		release.Spec.LastDeployed = app.Spec.Info.LastDeployed
+	}
+	if metadata.Annotations[sourceRepoTypeAnnotation] == "cluster" {
+		release.Spec.RepoName = metadata.Annotations[sourceRepoAnnotation]
+	}
+
+	release.Labels = map[string]string{
+		catalog.ClusterReleaseClusterLabel: clusterName,
+	}
+	if len(validation.IsValidLabelValue(metadata.Name)) == 0 {
+		release.Labels[catalog.ClusterReleaseChartLabel] = metadata.Name
+	}
+	return release
+}
@@ -0,0 +1,119 @@
+package catalogusage
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	catalog "github.com/rancher/rancher/pkg/apis/catalog.cattle.io/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestClusterRelease(t *testing.T) {
+	lastDeployed := metav1.NewTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	app := &catalog.App{
+		ObjectMeta: metav1.ObjectMeta{Name: "rancher-monitoring", Namespace: "cattle-monitoring-system"},
+		Spec: catalog.ReleaseSpec{
+			Name:    "rancher-monitoring",
+			Version: 3,
+			Info:    &catalog.Info{Status: catalog.StatusDeployed, LastDeployed: &lastDeployed},
+			Chart: &catalog.Chart{Metadata: &catalog.Metadata{
+				Name:       "rancher-monitoring",
+				Version:    "103.1.0+up45.31.1",
+				AppVersion: "0.65.1",
+				Annotations: map[string]string{
+					sourceRepoTypeAnnotation: "cluster",
+					sourceRepoAnnotation:     "rancher-charts",
+				},
+			}},
+		},
+	}
+
+	expected := &catalog.ClusterRelease{
+		TypeMeta: metav1.TypeMeta{APIVersion: "catalog.cattle.io/v1", Kind: "ClusterRelease"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cattle-monitoring-system.rancher-monitoring",
+			Namespace: "c-m-abcdef",
+			Labels: map[string]string{
+				catalog.ClusterReleaseClusterLabel: "c-m-abcdef",
+				catalog.ClusterReleaseChartLabel:   "rancher-monitoring",
+			},
+		},
+		Spec: catalog.ClusterReleaseSpec{
+			ClusterName:      "c-m-abcdef",
+			ReleaseName:      "rancher-monitoring",
+			ReleaseNamespace: "cattle-monitoring-system",
+			Revision:         3,
+			Status:           catalog.StatusDeployed,
+			LastDeployed:     &lastDeployed,
+			Chart:            "rancher-monitoring",
+			Version:          "103.1.0+up45.31.1",
+			AppVersion:       "0.65.1",
+			RepoName:         "rancher-charts",
+		},
+	}
+	assert.Equal(t, expected, clusterRelease("c-m-abcdef", app))
+
+	// charts installed from namespaced repositories, or whose name isn't a valid label value
+	app.Spec.Chart.Metadata.Annotations[sourceRepoTypeAnnotation] = "namespace"
+	app.Spec.Chart.Metadata.Name = strings.Repeat("a", 64)
+	actual := clusterRelease("c-m-abcdef", app)
+	assert.Empty(t, actual.Spec.RepoName)
+	assert.NotContains(t, actual.Labels, catalog.ClusterReleaseChartLabel)
+
+	app.Spec.Chart = nil
+	assert.Nil(t, clusterRelease("c-m-abcdef", app))
+}
+
+func TestTrimApp(t *testing.T) {
+	lastDeployed := metav1.NewTime(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	app := &catalog.App{
+		ObjectMeta: metav1.ObjectMeta{Name: "rancher-monitoring", Namespace: "cattle-monitoring-system", ResourceVersion: "42"},
+		Spec: catalog.ReleaseSpec{
+			Name:      "rancher-monitoring",
+			Version:   3,
+			Info:      &catalog.Info{Status: catalog.StatusDeployed, LastDeployed: &lastDeployed, Notes: "notes", Readme: "readme"},
+			Values:    map[string]interface{}{"password": "s3cr3t"},
+			Resources: []catalog.ReleaseResource{{Kind: "Secret", Name: "monitoring"}},
+			Chart: &catalog.Chart{
+				Metadata: &catalog.Metadata{
+					Name:        "rancher-monitoring",
+					Version:     "103.1.0+up45.31.1",
+					AppVersion:  "0.65.1",
+					Description: "monitoring",
+					Annotations: map[string]string{
+						sourceRepoTypeAnnotation: "cluster",
+						sourceRepoAnnotation:     "rancher-charts",
+						"catalog.cattle.io/os":   "linux",
+					},
+				},
+				Values: map[string]interface{}{"image": "rancher/monitoring"},
+			},
+		},
+	}
+
+	obj, err := trimApp(app)
+	require.NoError(t, err)
+	trimmed := obj.(*catalog.App)
+	assert.Equal(t, clusterRelease("c-m-abcdef", app), clusterRelease("c-m-abcdef", trimmed))
+	assert.Equal(t, "42", trimmed.ResourceVersion)
+	assert.Nil(t, trimmed.Spec.Values)
+	assert.Nil(t, trimmed.Spec.Resources)
+	assert.Nil(t, trimmed.Spec.Chart.Values)
+	assert.Empty(t, trimmed.Spec.Info.Notes)
+	assert.Empty(t, trimmed.Spec.Chart.Metadata.Description)
+	assert.NotContains(t, trimmed.Spec.Chart.Metadata.Annotations, "catalog.cattle.io/os")
+
+	app.Spec.Chart = nil
+	obj, err = trimApp(app)
+	require.NoError(t, err)
+	assert.Nil(t, clusterRelease("c-m-abcdef", obj.(*catalog.App)))
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "cattle-monitoring-system/rancher-monitoring"}
+	obj, err = trimApp(tombstone)
+	require.NoError(t, err)
+	assert.Equal(t, tombstone, obj)
+}
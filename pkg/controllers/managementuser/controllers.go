@@ -6,6 +6,7 @@
 
 	apimgmtv3 "github.com/rancher/rancher/pkg/apis/management.cattle.io/v3"
 	"github.com/rancher/rancher/pkg/controllers/managementlegacy/compose/common"
+	"github.com/rancher/rancher/pkg/controllers/managementuser/catalogusage"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/cavalidator"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/certsexpiration"
 	"github.com/rancher/rancher/pkg/controllers/managementuser/clusterauthtoken"
@@ -45,6 +46,9 @@ func Register(ctx context.Context, mgmt *config.ScaledContext, cluster *config.U
 		machinerole.Register(ctx, cluster)
 	}
 	cavalidator.Register(ctx, cluster)
+	if err := catalogusage.Register(ctx, cluster); err != nil {
+		return err
+	}
 
 	registerImpersonationCaches(cluster)
 
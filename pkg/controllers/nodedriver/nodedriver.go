@@ -41,6 +41,7 @@ func onChange(_ string, obj *v3.NodeDriver) (*v3.NodeDriver, error) {
 	}
 
 	driver := drivers.NewDynamicDriver(obj.Spec.Builtin, obj.Spec.DisplayName, obj.Spec.URL, obj.Spec.Checksum)
+	driver.Signature = drivers.NewSignature(obj.Spec.Signature, obj.Spec.URL)
 	if driver.Exists() {
 		return obj, nil
 	}
@@ -344,6 +344,11 @@ func (h *handler) OnRancherClusterChange(obj *rancherv1.Cluster, status rancherv
 
 		reconcileCondition(&status, capr.Updated, rkeCP, capr.Ready)
 		reconcileCondition(&status, capr.Provisioned, rkeCP, capr.Ready)
+		for _, cond := range []condition.Cond{capr.ETCDSnapshotVerified, capr.ETCDQuorumAvailable, capr.ETCDFaultTolerant} {
+			if cond.GetStatus(rkeCP) != "" {
+				reconcileCondition(&status, cond, rkeCP, cond)
+			}
+		}
 
 		// If the Stable condition is not true, then copy the Ready condition from the rkeControlPlane to the v1.Clusters object
 		// Otherwise, use the v3 clusters Ready condition. Note that we use `IsTrue` here because `IsFalse` specifically looks
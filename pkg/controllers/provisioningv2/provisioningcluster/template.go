@@ -141,6 +141,29 @@ func toMachineTemplate(machinePoolName string, cluster *rancherv1.Cluster, machi
 		return nil, err
 	}
 
+	// The machine configs of the variants are applied to the machines by the machineprovision controller, they are only
+	// owned by the cluster here so that they are removed with it.
+	for _, variant := range machinePool.MachineConfigVariants {
+		if variant.NodeConfig == nil {
+			continue
+		}
+		variantAPIVersion, variantKind := variant.NodeConfig.APIVersion, variant.NodeConfig.Kind
+		if variantAPIVersion == "" {
+			variantAPIVersion = capr.DefaultMachineConfigAPIVersion
+		}
+		if variantKind == "" {
+			variantKind = kind
+		}
+		variantGVK := schema.FromAPIVersionAndKind(variantAPIVersion, variantKind)
+		variantConfig, err := dynamic.Get(variantGVK, cluster.Namespace, variant.NodeConfig.Name)
+		if err != nil {
+			return nil, err
+		}
+		if err := takeOwnership(dynamic, cluster, variantConfig); err != nil {
+			return nil, err
+		}
+	}
+
 	machinePoolData, err := data.Convert(nodeConfig.DeepCopyObject())
 	if err != nil {
 		return nil, err
@@ -297,7 +320,8 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 
 	machinePoolNames := map[string]bool{}
 	for _, machinePool := range cluster.Spec.RKEConfig.MachinePools {
-		if machinePool.Quantity != nil && *machinePool.Quantity == 0 {
+		minSize, maxSize, autoscaled := capr.GetMachinePoolAutoscalingBounds(cluster.Spec.RKEConfig, machinePool)
+		if !autoscaled && machinePool.Quantity != nil && *machinePool.Quantity == 0 {
 			continue
 		}
 		if machinePool.Name == "" || machinePool.NodeConfig == nil || machinePool.NodeConfig.Name == "" || machinePool.NodeConfig.Kind == "" {
@@ -309,6 +333,15 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 			return nil, fmt.Errorf("at least one role of etcd, control-plane or worker must be assigned to machinePool [%s]", machinePool.Name)
 		}
 
+		if autoscaled {
+			if machinePool.EtcdRole || machinePool.ControlPlaneRole {
+				return nil, fmt.Errorf("machinePool [%s] with etcd or control-plane role cannot be autoscaled", machinePool.Name)
+			}
+			if minSize < 1 || maxSize < minSize {
+				return nil, fmt.Errorf("invalid autoscaling bounds for machinePool [%s]: minSize must be at least 1 and no greater than maxSize", machinePool.Name)
+			}
+		}
+
 		if machinePoolNames[machinePool.Name] {
 			return nil, fmt.Errorf("duplicate machinePool name [%s] used", machinePool.Name)
 		}
@@ -363,16 +396,29 @@ func machineDeployments(cluster *rancherv1.Cluster, capiCluster *capi.Cluster, d
 			return nil, err
 		}
 
+		machineDeploymentAnnotations := machinePool.MachineDeploymentAnnotations
+		replicas := machinePool.Quantity
+		if autoscaled {
+			// The replicas are owned by the cluster autoscaler, and are defaulted by CAPI to be within the bounds.
+			machineDeploymentAnnotations = map[string]string{}
+			for k, v := range machinePool.MachineDeploymentAnnotations {
+				machineDeploymentAnnotations[k] = v
+			}
+			machineDeploymentAnnotations[capi.AutoscalerMinSizeAnnotation] = strconv.Itoa(int(minSize))
+			machineDeploymentAnnotations[capi.AutoscalerMaxSizeAnnotation] = strconv.Itoa(int(maxSize))
+			replicas = nil
+		}
+
 		machineDeployment := &capi.MachineDeployment{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace:   cluster.Namespace,
 				Name:        machineDeploymentName,
 				Labels:      machineDeploymentLabels,
-				Annotations: machinePool.MachineDeploymentAnnotations,
+				Annotations: machineDeploymentAnnotations,
 			},
 			Spec: capi.MachineDeploymentSpec{
 				ClusterName: capiCluster.Name,
-				Replicas:    machinePool.Quantity,
+				Replicas:    replicas,
 				Strategy: &capi.MachineDeploymentStrategy{
 					// RollingUpdate is the default, so no harm in setting it here.
 					Type: capi.RollingUpdateMachineDeploymentStrategyType,
@@ -527,6 +573,8 @@ func rkeControlPlane(cluster *rancherv1.Cluster) (*rkev1.RKEControlPlane, error)
 	filteredClusterSpec.RKEConfig.ETCDSnapshotCreate = nil
 	filteredClusterSpec.RKEConfig.RotateEncryptionKeys = nil
 	filteredClusterSpec.RKEConfig.RotateCertificates = nil
+	filteredClusterSpec.RKEConfig.ETCDMaintenance = nil
+	filteredClusterSpec.RKEConfig.NodeMaintenance = nil
 	b64GZCluster, err := capr.CompressInterface(filteredClusterSpec)
 	if err != nil {
 		logrus.Errorf("cluster: %s/%s : error while gz/b64 encoding cluster specification: %v", cluster.Namespace, cluster.Name, err)
@@ -551,6 +599,8 @@ func rkeControlPlane(cluster *rancherv1.Cluster) (*rkev1.RKEControlPlane, error)
 			ETCDSnapshotCreate:       rkeConfig.ETCDSnapshotCreate,
 			RotateCertificates:       rkeConfig.RotateCertificates,
 			RotateEncryptionKeys:     rkeConfig.RotateEncryptionKeys,
+			ETCDMaintenance:          rkeConfig.ETCDMaintenance,
+			NodeMaintenance:          rkeConfig.NodeMaintenance,
 			KubernetesVersion:        cluster.Spec.KubernetesVersion,
 			ManagementClusterName:    cluster.Status.ClusterName, // management cluster
 			AgentEnvVars:             cluster.Spec.AgentEnvVars,
@@ -0,0 +1,269 @@
+package provisioninglog
+
+import (
+	"fmt"
+	"strings"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	capicontrollers "github.com/rancher/rancher/pkg/generated/controllers/cluster.x-k8s.io/v1beta1"
+	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningevents"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
+)
+
+const (
+	machineKeyPrefix         = "machine/"
+	drainKeyPrefix           = "drain/"
+	nodeMaintenanceKeyPrefix = "node-maintenance/"
+)
+
+// eventHandler records the structured provisioning events of the planner, machines and drains of a cluster. Events are
+// derived from the state of the objects, so an event is only recorded once a change has been observed.
+type eventHandler struct {
+	recorder      *provisioningevents.Recorder
+	clusterCache  provisioningcontrollers.ClusterCache
+	machinesCache capicontrollers.MachineCache
+}
+
+func (h *eventHandler) OnControlPlane(_ string, cp *rkev1.RKEControlPlane) (*rkev1.RKEControlPlane, error) {
+	if cp == nil || !cp.DeletionTimestamp.IsZero() || cp.Spec.ManagementClusterName == "" {
+		return cp, nil
+	}
+	if err := h.recorder.Record(cp.Spec.ManagementClusterName, controlPlaneObservations(cp)...); err != nil {
+		return cp, err
+	}
+
+	machines, err := h.machinesCache.List(cp.Namespace, labels.SelectorFromSet(labels.Set{capi.ClusterNameLabel: cp.Spec.ClusterName}))
+	if err != nil {
+		return cp, err
+	}
+	existing := map[string]bool{}
+	for _, machine := range machines {
+		existing[machine.Name] = true
+	}
+	return cp, h.recorder.Forget(cp.Spec.ManagementClusterName, func(key string) bool {
+		for _, prefix := range []string{machineKeyPrefix, drainKeyPrefix, nodeMaintenanceKeyPrefix} {
+			if machineName, ok := strings.CutPrefix(key, prefix); ok {
+				return existing[machineName]
+			}
+		}
+		return true
+	})
+}
+
+func (h *eventHandler) OnMachine(_ string, machine *capi.Machine) (*capi.Machine, error) {
+	if machine == nil {
+		return nil, nil
+	}
+	namespace, err := h.managementClusterName(machine.Namespace, machine.Labels[capi.ClusterNameLabel])
+	if err != nil || namespace == "" {
+		return machine, err
+	}
+	return machine, h.recorder.Record(namespace, machineObservation(machine))
+}
+
+func (h *eventHandler) OnSecret(_ string, secret *corev1.Secret) (*corev1.Secret, error) {
+	if secret == nil || secret.Type != capr.SecretTypeMachinePlan || secret.Labels[capr.MachineNameLabel] == "" {
+		return secret, nil
+	}
+	namespace, err := h.managementClusterName(secret.Namespace, secret.Labels[capr.ClusterNameLabel])
+	if err != nil || namespace == "" {
+		return secret, err
+	}
+	return secret, h.recorder.Record(namespace, drainObservation(secret.Labels[capr.MachineNameLabel], secret.Annotations))
+}
+
+// managementClusterName returns the name of the management cluster of the provisioning cluster, which is the namespace
+// the events are stored in.
+func (h *eventHandler) managementClusterName(namespace, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	cluster, err := h.clusterCache.Get(namespace, name)
+	if apierrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return cluster.Status.ClusterName, nil
+}
+
+// controlPlaneObservations returns the state of the planner and of the operations it runs.
+func controlPlaneObservations(cp *rkev1.RKEControlPlane) []provisioningevents.Observation {
+	phase := "Updating"
+	if !cp.Status.Initialized {
+		phase = "Provisioning"
+	} else if capr.Ready.IsTrue(cp) {
+		phase = "Ready"
+	}
+	message := capr.Ready.GetMessage(cp)
+	if message == "" {
+		message = "cluster is " + strings.ToLower(phase)
+	}
+	severity := provisioningevents.SeverityInfo
+	if capr.Ready.GetReason(cp) == "Error" {
+		severity = provisioningevents.SeverityError
+	}
+
+	observations := []provisioningevents.Observation{{
+		Key:   string(provisioningevents.SourcePlanner),
+		Value: phase + "/" + message,
+		Event: provisioningevents.Event{
+			Source:   provisioningevents.SourcePlanner,
+			Phase:    phase,
+			Severity: severity,
+			Message:  message,
+		},
+	}}
+
+	var etcdMaintenanceMessage, nodeMaintenanceMessage string
+	if cp.Status.ETCDMaintenanceStatus != nil {
+		etcdMaintenanceMessage = cp.Status.ETCDMaintenanceStatus.Message
+	}
+	if cp.Status.NodeMaintenanceStatus != nil {
+		nodeMaintenanceMessage = cp.Status.NodeMaintenanceStatus.Message
+	}
+	for _, operation := range []struct {
+		source         provisioningevents.Source
+		phase, message string
+		failed         bool
+		name           string
+	}{
+		{provisioningevents.SourceETCDSnapshotCreate, string(cp.Status.ETCDSnapshotCreatePhase), "", cp.Status.ETCDSnapshotCreatePhase == rkev1.ETCDSnapshotPhaseFailed, "etcd snapshot creation"},
+		{provisioningevents.SourceETCDSnapshotRestore, string(cp.Status.ETCDSnapshotRestorePhase), "", cp.Status.ETCDSnapshotRestorePhase == rkev1.ETCDSnapshotPhaseFailed, "etcd snapshot restore"},
+		{provisioningevents.SourceEncryptionKeys, string(cp.Status.RotateEncryptionKeysPhase), "", cp.Status.RotateEncryptionKeysPhase == rkev1.RotateEncryptionKeysPhaseFailed, "encryption key rotation"},
+		{provisioningevents.SourceETCDMaintenance, string(cp.Status.ETCDMaintenancePhase), etcdMaintenanceMessage, cp.Status.ETCDMaintenancePhase == rkev1.ETCDMaintenancePhaseFailed, "etcd maintenance"},
+		{provisioningevents.SourceNodeMaintenance, string(cp.Status.NodeMaintenancePhase), nodeMaintenanceMessage, cp.Status.NodeMaintenancePhase == rkev1.NodeMaintenancePhaseFailed, "node maintenance"},
+	} {
+		if operation.phase == "" {
+			continue
+		}
+		message := operation.message
+		if message == "" {
+			message = fmt.Sprintf("%s entered phase %s", operation.name, operation.phase)
+		}
+		severity := provisioningevents.SeverityInfo
+		if operation.failed {
+			severity = provisioningevents.SeverityError
+		}
+		observations = append(observations, provisioningevents.Observation{
+			Key:   string(operation.source),
+			Value: operation.phase,
+			Event: provisioningevents.Event{
+				Source:   operation.source,
+				Phase:    operation.phase,
+				Severity: severity,
+				Message:  message,
+			},
+		})
+	}
+
+	if cp.Status.NodeMaintenanceStatus != nil {
+		for _, node := range cp.Status.NodeMaintenanceStatus.Nodes {
+			severity := provisioningevents.SeverityInfo
+			if node.Phase == rkev1.NodeMaintenanceNodePhaseFailed {
+				severity = provisioningevents.SeverityError
+			}
+			message := node.Message
+			if message == "" {
+				message = fmt.Sprintf("node maintenance of machine %s entered phase %s", node.MachineName, node.Phase)
+			}
+			observations = append(observations, provisioningevents.Observation{
+				Key:   nodeMaintenanceKeyPrefix + node.MachineName,
+				Value: string(node.Phase),
+				Event: provisioningevents.Event{
+					Source:   provisioningevents.SourceNodeMaintenance,
+					Phase:    string(node.Phase),
+					Machine:  node.MachineName,
+					Severity: severity,
+					Message:  message,
+				},
+			})
+		}
+	}
+
+	return observations
+}
+
+// machineObservation returns the provisioning phase of the machine, along with any provisioning failure.
+func machineObservation(machine *capi.Machine) provisioningevents.Observation {
+	var failure string
+	if machine.Status.FailureMessage != nil {
+		failure = *machine.Status.FailureMessage
+	} else if cond := conditions.Get(machine, capi.InfrastructureReadyCondition); cond != nil && cond.Severity == capi.ConditionSeverityError {
+		failure = cond.Message
+	}
+
+	observation := provisioningevents.Observation{
+		Key:   machineKeyPrefix + machine.Name,
+		Value: machine.Status.Phase,
+		Event: provisioningevents.Event{
+			Source:   provisioningevents.SourceMachine,
+			Phase:    machine.Status.Phase,
+			Machine:  machine.Name,
+			Severity: provisioningevents.SeverityInfo,
+			Message:  fmt.Sprintf("machine %s is %s", machine.Name, strings.ToLower(machine.Status.Phase)),
+		},
+	}
+	if machine.Status.Phase == "" {
+		return observation
+	}
+	if failure != "" {
+		observation.Value += "/" + failure
+		observation.Event.Severity = provisioningevents.SeverityError
+		observation.Event.Message += ": " + failure
+	}
+	return observation
+}
+
+// drainObservation returns the drain step of the machine from the annotations of its plan secret.
+func drainObservation(machineName string, annotations map[string]string) provisioningevents.Observation {
+	var (
+		step     string
+		message  string
+		severity = provisioningevents.SeverityInfo
+	)
+	switch {
+	case annotations[capr.DrainErrorAnnotation] != "":
+		step = "Error"
+		message = fmt.Sprintf("error draining machine %s: %s", machineName, annotations[capr.DrainErrorAnnotation])
+		severity = provisioningevents.SeverityWarning
+	case annotations[capr.UnCordonAnnotation] != "":
+		step = "Uncordoning"
+		message = fmt.Sprintf("uncordoning machine %s", machineName)
+	case annotations[capr.DrainAnnotation] != "" && annotations[capr.DrainDoneAnnotation] == annotations[capr.DrainAnnotation]:
+		step = "Drained"
+		message = fmt.Sprintf("drained machine %s", machineName)
+	case annotations[capr.DrainAnnotation] != "":
+		step = "Draining"
+		message = fmt.Sprintf("draining machine %s", machineName)
+	default:
+		// the annotations are removed once the node has been uncordoned
+		message = fmt.Sprintf("uncordoned machine %s", machineName)
+	}
+
+	phase := step
+	if phase == "" {
+		phase = "Uncordoned"
+	}
+	value := step
+	if step == "Error" {
+		value += "/" + annotations[capr.DrainErrorAnnotation]
+	}
+	return provisioningevents.Observation{
+		Key:   drainKeyPrefix + machineName,
+		Value: value,
+		Event: provisioningevents.Event{
+			Source:   provisioningevents.SourceDrain,
+			Phase:    phase,
+			Machine:  machineName,
+			Severity: severity,
+			Message:  message,
+		},
+	}
+}
@@ -0,0 +1,121 @@
+package provisioninglog
+
+import (
+	"testing"
+
+	rkev1 "github.com/rancher/rancher/pkg/apis/rke.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/capr"
+	"github.com/rancher/rancher/pkg/provisioningevents"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	capi "sigs.k8s.io/cluster-api/api/v1beta1"
+)
+
+func TestDrainObservation(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		value       string
+		phase       string
+		severity    provisioningevents.Severity
+	}{
+		{
+			name:        "draining",
+			annotations: map[string]string{capr.DrainAnnotation: "{}"},
+			value:       "Draining",
+			phase:       "Draining",
+			severity:    provisioningevents.SeverityInfo,
+		},
+		{
+			name:        "drained",
+			annotations: map[string]string{capr.DrainAnnotation: "{}", capr.DrainDoneAnnotation: "{}"},
+			value:       "Drained",
+			phase:       "Drained",
+			severity:    provisioningevents.SeverityInfo,
+		},
+		{
+			name:        "uncordoning",
+			annotations: map[string]string{capr.DrainAnnotation: "{}", capr.DrainDoneAnnotation: "{}", capr.UnCordonAnnotation: "{}"},
+			value:       "Uncordoning",
+			phase:       "Uncordoning",
+			severity:    provisioningevents.SeverityInfo,
+		},
+		{
+			name:        "error",
+			annotations: map[string]string{capr.DrainAnnotation: "{}", capr.DrainErrorAnnotation: "pdb violated"},
+			value:       "Error/pdb violated",
+			phase:       "Error",
+			severity:    provisioningevents.SeverityWarning,
+		},
+		{
+			name:        "uncordoned",
+			annotations: nil,
+			value:       "",
+			phase:       "Uncordoned",
+			severity:    provisioningevents.SeverityInfo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observation := drainObservation("m1", tt.annotations)
+			assert.Equal(t, "drain/m1", observation.Key)
+			assert.Equal(t, tt.value, observation.Value)
+			assert.Equal(t, tt.phase, observation.Event.Phase)
+			assert.Equal(t, tt.severity, observation.Event.Severity)
+			assert.Equal(t, "m1", observation.Event.Machine)
+		})
+	}
+}
+
+func TestMachineObservation(t *testing.T) {
+	machine := &capi.Machine{
+		ObjectMeta: metav1.ObjectMeta{Name: "m1"},
+		Status:     capi.MachineStatus{Phase: string(capi.MachinePhaseProvisioning)},
+	}
+	observation := machineObservation(machine)
+	assert.Equal(t, "machine/m1", observation.Key)
+	assert.Equal(t, "Provisioning", observation.Value)
+	assert.Equal(t, provisioningevents.SeverityInfo, observation.Event.Severity)
+	assert.Equal(t, "machine m1 is provisioning", observation.Event.Message)
+
+	machine.Status.Phase = string(capi.MachinePhaseFailed)
+	failure := "quota exceeded"
+	machine.Status.FailureMessage = &failure
+	observation = machineObservation(machine)
+	assert.Equal(t, "Failed/quota exceeded", observation.Value)
+	assert.Equal(t, provisioningevents.SeverityError, observation.Event.Severity)
+	assert.Equal(t, "machine m1 is failed: quota exceeded", observation.Event.Message)
+}
+
+func TestControlPlaneObservations(t *testing.T) {
+	cp := &rkev1.RKEControlPlane{}
+	capr.Ready.SetError(cp, "", assert.AnError)
+	cp.Status.ETCDSnapshotRestorePhase = rkev1.ETCDSnapshotPhaseRestore
+	cp.Status.NodeMaintenancePhase = rkev1.NodeMaintenancePhaseFailed
+	cp.Status.NodeMaintenanceStatus = &rkev1.NodeMaintenanceStatus{
+		Message: "machine m1: operation failed",
+		Nodes: []rkev1.NodeMaintenanceNodeStatus{
+			{MachineName: "m1", Phase: rkev1.NodeMaintenanceNodePhaseFailed, Message: "operation failed"},
+		},
+	}
+
+	observations := map[string]provisioningevents.Observation{}
+	for _, observation := range controlPlaneObservations(cp) {
+		observations[observation.Key] = observation
+	}
+	assert.Len(t, observations, 4)
+
+	planner := observations["planner"]
+	assert.Equal(t, "Provisioning", planner.Event.Phase)
+	assert.Equal(t, provisioningevents.SeverityError, planner.Event.Severity)
+	assert.Equal(t, assert.AnError.Error(), planner.Event.Message)
+
+	restore := observations["etcd-snapshot-restore"]
+	assert.Equal(t, "Restore", restore.Value)
+	assert.Equal(t, "etcd snapshot restore entered phase Restore", restore.Event.Message)
+
+	assert.Equal(t, provisioningevents.SeverityError, observations["node-maintenance"].Event.Severity)
+	assert.Equal(t, "machine m1: operation failed", observations["node-maintenance"].Event.Message)
+	assert.Equal(t, "m1", observations["node-maintenance/m1"].Event.Machine)
+	assert.Equal(t, provisioningevents.SeverityError, observations["node-maintenance/m1"].Event.Severity)
+}
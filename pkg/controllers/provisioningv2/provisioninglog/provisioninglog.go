@@ -11,6 +11,7 @@
 	"github.com/rancher/rancher/pkg/capr"
 	"github.com/rancher/rancher/pkg/controllers/dashboard/clusterindex"
 	provisioningcontrollers "github.com/rancher/rancher/pkg/generated/controllers/provisioning.cattle.io/v1"
+	"github.com/rancher/rancher/pkg/provisioningevents"
 	"github.com/rancher/rancher/pkg/wrangler"
 	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -36,6 +37,15 @@ func Register(ctx context.Context, clients *wrangler.Context) {
 
 	clients.Core.Namespace().OnChange(ctx, "prov-log-namespace", h.OnNamespace)
 	clients.Core.ConfigMap().OnChange(ctx, "prov-log-configmap", h.OnConfigMap)
+
+	e := &eventHandler{
+		recorder:      provisioningevents.NewRecorder(clients.Core.ConfigMap()),
+		clusterCache:  clients.Provisioning.Cluster().Cache(),
+		machinesCache: clients.CAPI.Machine().Cache(),
+	}
+	clients.RKE.RKEControlPlane().OnChange(ctx, "prov-events-controlplane", e.OnControlPlane)
+	clients.CAPI.Machine().OnChange(ctx, "prov-events-machine", e.OnMachine)
+	clients.Core.Secret().OnChange(ctx, "prov-events-plan-secret", e.OnSecret)
 }
 
 type handler struct {
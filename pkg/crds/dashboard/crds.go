@@ -93,7 +93,8 @@ func List(cfg *rest.Config) (_ []crd.CRD, err error) {
 				WithStatus().
 				WithCategories("catalog").
 				WithColumn("Target Namespace", ".status.podNamespace").
-				WithColumn("Command", ".status.command")
+				WithColumn("Command", ".status.command").
+				WithColumn("Queue", ".status.queue.state")
 		}),
 		newCRD(&catalogv1.App{}, func(c crd.CRD) crd.CRD {
 			return c.
@@ -105,6 +106,26 @@ func List(cfg *rest.Config) (_ []crd.CRD, err error) {
 				WithColumn("Release Version", ".spec.version").
 				WithColumn("Status", ".spec.info.status")
 		}),
+		newCRD(&catalogv1.AppInstall{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithStatus().
+				WithCategories("catalog").
+				WithColumn("Repo", ".spec.repoName").
+				WithColumn("Chart", ".spec.chart").
+				WithColumn("Version", ".status.version").
+				WithColumn("Operation", ".status.operationName")
+		}),
+		newCRD(&catalogv1.ClusterRelease{}, func(c crd.CRD) crd.CRD {
+			return c.
+				WithStatus().
+				WithCategories("catalog").
+				WithColumn("Cluster", ".spec.clusterName").
+				WithColumn("Release", ".spec.releaseName").
+				WithColumn("Release Namespace", ".spec.releaseNamespace").
+				WithColumn("Chart", ".spec.chart").
+				WithColumn("Version", ".spec.version").
+				WithColumn("Latest Version", ".status.latestVersion")
+		}),
 	}
 
 	if features.Fleet.Enabled() {
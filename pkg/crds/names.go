@@ -36,6 +36,8 @@ func RequiredCRDs() []string {
 func BasicCRDs() []string {
 	return []string{
 		"apps.catalog.cattle.io",
+		"appinstalls.catalog.cattle.io",
+		"clusterreleases.catalog.cattle.io",
 		"clusterrepos.catalog.cattle.io",
 		"operations.catalog.cattle.io",
 		"apiservices.management.cattle.io",
@@ -173,6 +175,7 @@ func UIPluginsCRD() []string {
 var MigratedResources = map[string]bool{
 	"activedirectoryproviders.management.cattle.io":                   false,
 	"apiservices.management.cattle.io":                                false,
+	"appinstalls.catalog.cattle.io":                                   false,
 	"apps.catalog.cattle.io":                                          false,
 	"authconfigs.management.cattle.io":                                false,
 	"authproviders.management.cattle.io":                              false,
@@ -185,6 +188,7 @@ func UIPluginsCRD() []string {
 	"clusterclasses.cluster.x-k8s.io":                                 false,
 	"clusterproxyconfigs.management.cattle.io":                        true,
 	"clusterregistrationtokens.management.cattle.io":                  false,
+	"clusterreleases.catalog.cattle.io":                               false,
 	"clusterrepos.catalog.cattle.io":                                  true,
 	"clusterresourcesetbindings.addons.cluster.x-k8s.io":              false,
 	"clusterroletemplatebindings.management.cattle.io":                true,
@@ -29,6 +29,8 @@
 type CatalogV1Interface interface {
 	RESTClient() rest.Interface
 	AppsGetter
+	AppInstallsGetter
+	ClusterReleasesGetter
 	ClusterReposGetter
 	OperationsGetter
 	UIPluginsGetter
@@ -43,6 +45,14 @@ func (c *CatalogV1Client) Apps(namespace string) AppInterface {
 	return newApps(c, namespace)
 }
 
+func (c *CatalogV1Client) AppInstalls(namespace string) AppInstallInterface {
+	return newAppInstalls(c, namespace)
+}
+
+func (c *CatalogV1Client) ClusterReleases(namespace string) ClusterReleaseInterface {
+	return newClusterReleases(c, namespace)
+}
+
 func (c *CatalogV1Client) ClusterRepos() ClusterRepoInterface {
 	return newClusterRepos(c)
 }
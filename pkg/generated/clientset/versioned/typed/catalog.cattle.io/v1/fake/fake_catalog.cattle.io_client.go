@@ -32,6 +32,14 @@ func (c *FakeCatalogV1) Apps(namespace string) v1.AppInterface {
 	return &FakeApps{c, namespace}
 }
 
+func (c *FakeCatalogV1) AppInstalls(namespace string) v1.AppInstallInterface {
+	return &FakeAppInstalls{c, namespace}
+}
+
+func (c *FakeCatalogV1) ClusterReleases(namespace string) v1.ClusterReleaseInterface {
+	return &FakeClusterReleases{c, namespace}
+}
+
 func (c *FakeCatalogV1) ClusterRepos() v1.ClusterRepoInterface {
 	return &FakeClusterRepos{c}
 }
@@ -20,6 +20,10 @@
 
 type AppExpansion interface{}
 
+type AppInstallExpansion interface{}
+
+type ClusterReleaseExpansion interface{}
+
 type ClusterRepoExpansion interface{}
 
 type OperationExpansion interface{}
@@ -32,6 +32,8 @@ func init() {
 
 type Interface interface {
 	App() AppController
+	AppInstall() AppInstallController
+	ClusterRelease() ClusterReleaseController
 	ClusterRepo() ClusterRepoController
 	Operation() OperationController
 	UIPlugin() UIPluginController
@@ -51,6 +53,14 @@ func (v *version) App() AppController {
 	return generic.NewController[*v1.App, *v1.AppList](schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "App"}, "apps", true, v.controllerFactory)
 }
 
+func (v *version) AppInstall() AppInstallController {
+	return generic.NewController[*v1.AppInstall, *v1.AppInstallList](schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "AppInstall"}, "appinstalls", true, v.controllerFactory)
+}
+
+func (v *version) ClusterRelease() ClusterReleaseController {
+	return generic.NewController[*v1.ClusterRelease, *v1.ClusterReleaseList](schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "ClusterRelease"}, "clusterreleases", true, v.controllerFactory)
+}
+
 func (v *version) ClusterRepo() ClusterRepoController {
 	return generic.NewNonNamespacedController[*v1.ClusterRepo, *v1.ClusterRepoList](schema.GroupVersionKind{Group: "catalog.cattle.io", Version: "v1", Kind: "ClusterRepo"}, "clusterrepos", v.controllerFactory)
 }
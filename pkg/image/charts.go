@@ -7,6 +7,7 @@
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
@@ -213,6 +214,11 @@ func decodeValuesFilesInTgz(tgzPath string) ([]map[interface{}]interface{}, erro
 		return nil, err
 	}
 	defer tgz.Close()
+	return decodeValuesFilesInTgzReader(tgz)
+}
+
+// decodeValuesFilesInTgzReader reads the tarball from tgz and returns a slice of values corresponding to values.yaml files found inside of it.
+func decodeValuesFilesInTgzReader(tgz io.Reader) ([]map[interface{}]interface{}, error) {
 	gzr, err := gzip.NewReader(tgz)
 	if err != nil {
 		return nil, err
@@ -239,6 +245,29 @@ func decodeValuesFilesInTgz(tgzPath string) ([]map[interface{}]interface{}, erro
 	}
 }
 
+// ChartImages returns the sorted list of the Linux and Windows images referenced by the values files of the chart
+// tarball read from tgz.
+func ChartImages(tgz io.Reader, chartNameAndVersion string) ([]string, error) {
+	versionValues, err := decodeValuesFilesInTgzReader(tgz)
+	if err != nil {
+		return nil, err
+	}
+	imagesSet := map[string]map[string]struct{}{}
+	for _, values := range versionValues {
+		for _, osType := range []OSType{Linux, Windows} {
+			if err := pickImagesFromValuesMap(imagesSet, values, chartNameAndVersion, osType, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	images := make([]string, 0, len(imagesSet))
+	for image := range imagesSet {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
 // walkMap walks inputMap and calls the callback function on all map type nodes including the root node.
 func walkMap(inputMap interface{}, callback func(map[interface{}]interface{})) {
 	switch data := inputMap.(type) {
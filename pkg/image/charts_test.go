@@ -1,6 +1,9 @@
 package image
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"testing"
 
 	assertlib "github.com/stretchr/testify/assert"
@@ -193,3 +196,38 @@ func TestCompareRancherVersionToConstraint(t *testing.T) {
 		assert.Equalf(tc.expected, actual, "testcase: %v", tc)
 	}
 }
+
+func TestChartImages(t *testing.T) {
+	assert := assertlib.New(t)
+
+	files := map[string]string{
+		"chart/Chart.yaml": "name: chart\nversion: 0.1.2\n",
+		"chart/values.yaml": `image:
+  repository: rancher/app
+  tag: v1.0.0
+agent:
+  image:
+    repository: rancher/agent-windows
+    tag: 1.2
+    os: windows
+`,
+		"chart/charts/sub/values.yaml": "image:\n  repository: rancher/sub\n  tag: v2.0.0\n",
+	}
+	buf := &bytes.Buffer{}
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for name, content := range files {
+		assert.NoError(tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		assert.NoError(err)
+	}
+	assert.NoError(tw.Close())
+	assert.NoError(gzw.Close())
+
+	images, err := ChartImages(buf, "chart:0.1.2")
+	assert.NoError(err)
+	assert.Equal([]string{"rancher/agent-windows:1.2", "rancher/app:v1.0.0", "rancher/sub:v2.0.0"}, images)
+
+	_, err = ChartImages(bytes.NewBufferString("not a tarball"), "chart:0.1.2")
+	assert.Error(err)
+}
@@ -49,6 +49,12 @@ func Register(ctx context.Context, scaledContext *config.ScaledContext) {
 	prometheus.MustRegister(numNodes)
 	prometheus.MustRegister(numCores)
 
+	// etcd snapshot verification metrics
+	registerETCDSnapshotVerificationMetrics()
+
+	// planner and machine provisioning metrics
+	registerProvisioningMetrics()
+
 	gc := metricGarbageCollector{
 		clusterLister:  scaledContext.Management.Clusters("").Controller().Lister(),
 		nodeLister:     scaledContext.Management.Nodes("").Controller().Lister(),
@@ -0,0 +1,224 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// PlannerResultSuccess is the result of a planner reconcile that fully reconciled the cluster, or of an operation
+	// that finished.
+	PlannerResultSuccess = "success"
+	// PlannerResultWaiting is the result of a planner reconcile that is waiting on machines or other controllers.
+	PlannerResultWaiting = "waiting"
+	// PlannerResultSkipped is the result of a planner reconcile that was skipped and will be retried.
+	PlannerResultSkipped = "skipped"
+	// PlannerResultFailure is the result of a planner reconcile that returned an error, or of an operation that failed.
+	PlannerResultFailure = "failure"
+
+	// MachinesWaitingOnDrain is the reason of machines whose nodes are being drained.
+	MachinesWaitingOnDrain = "drain"
+	// MachinesWaitingOnProbes is the reason of machines whose plan was applied but whose probes are not healthy yet.
+	MachinesWaitingOnProbes = "probes"
+	// MachinesWaitingOnPlan is the reason of machines whose plan has not been applied yet.
+	MachinesWaitingOnPlan = "plan"
+)
+
+var (
+	plannerReconcileDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr_planner",
+			Name:      "reconcile_duration_seconds",
+			Help:      "Duration of the plan reconciles of a cluster by result",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"cluster", "result"},
+	)
+	plannerReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr_planner",
+			Name:      "reconcile_errors_total",
+			Help:      "Number of plan reconciles of a cluster that returned an error",
+		}, []string{"cluster"},
+	)
+	plannerMachinesWaiting = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: "capr_planner",
+			Name:      "machines_waiting",
+			Help:      "Number of machines of a cluster that are waiting on a drain, their probes or the application of their plan",
+		}, []string{"cluster", "reason"},
+	)
+	plannerOperationPhaseDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr_planner",
+			Name:      "operation_phase_duration_seconds",
+			Help:      "Duration of the phases of the etcd snapshot, restore and rotation operations of a cluster",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+		}, []string{"cluster", "operation", "phase"},
+	)
+	plannerOperationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr_planner",
+			Name:      "operation_duration_seconds",
+			Help:      "Duration of the etcd snapshot, restore and rotation operations of a cluster by result",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 14),
+		}, []string{"cluster", "operation", "result"},
+	)
+	plannerOperations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr_planner",
+			Name:      "operations_total",
+			Help:      "Number of finished etcd snapshot, restore and rotation operations of a cluster by result",
+		}, []string{"cluster", "operation", "result"},
+	)
+	machineProvisionJobDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: "capr_machineprovision",
+			Name:      "job_duration_seconds",
+			Help:      "Duration of the machine provisioning jobs of a cluster by driver, action and result",
+			Buckets:   prometheus.ExponentialBuckets(15, 2, 9),
+		}, []string{"cluster", "driver", "action", "result"},
+	)
+	machineProvisionJobFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: "capr_machineprovision",
+			Name:      "job_failures_total",
+			Help:      "Number of failed machine provisioning jobs of a cluster by driver and action",
+		}, []string{"cluster", "driver", "action"},
+	)
+
+	provisioningCollectors = []interface{}{
+		plannerReconcileDuration,
+		plannerReconcileErrors,
+		plannerMachinesWaiting,
+		plannerOperationPhaseDuration,
+		plannerOperationDuration,
+		plannerOperations,
+		machineProvisionJobDuration,
+		machineProvisionJobFailures,
+	}
+
+	plannerOperationTracker = operationTracker{}
+)
+
+func registerProvisioningMetrics() {
+	for _, collector := range provisioningCollectors {
+		prometheus.MustRegister(collector.(prometheus.Collector))
+	}
+}
+
+// ObservePlannerReconcile records the duration and result of a plan reconcile of the given management cluster.
+func ObservePlannerReconcile(clusterID, result string, duration time.Duration) {
+	if !prometheusMetrics {
+		return
+	}
+	plannerReconcileDuration.With(prometheus.Labels{"cluster": clusterID, "result": result}).Observe(duration.Seconds())
+	if result == PlannerResultFailure {
+		plannerReconcileErrors.With(prometheus.Labels{"cluster": clusterID}).Inc()
+	}
+}
+
+// SetPlannerMachinesWaiting records the number of machines of the given management cluster that are waiting, by reason.
+func SetPlannerMachinesWaiting(clusterID string, drain, probes, plan int) {
+	if !prometheusMetrics {
+		return
+	}
+	plannerMachinesWaiting.With(prometheus.Labels{"cluster": clusterID, "reason": MachinesWaitingOnDrain}).Set(float64(drain))
+	plannerMachinesWaiting.With(prometheus.Labels{"cluster": clusterID, "reason": MachinesWaitingOnProbes}).Set(float64(probes))
+	plannerMachinesWaiting.With(prometheus.Labels{"cluster": clusterID, "reason": MachinesWaitingOnPlan}).Set(float64(plan))
+}
+
+// ObservePlannerOperationPhase records the current phase of an operation of the given management cluster. The result is
+// empty while the operation is running, and PlannerResultSuccess or PlannerResultFailure once it has finished. The phase
+// is expected to be observed on every reconcile: durations are measured between phase changes, and an operation is only
+// counted once per finished run.
+func ObservePlannerOperationPhase(clusterID, operation, phase, result string) {
+	if !prometheusMetrics {
+		return
+	}
+	plannerOperationTracker.observe(clusterID, operation, phase, result, time.Now())
+}
+
+// ObserveMachineProvisionJob records the duration and result of a finished machine provisioning job of the given
+// management cluster. The action is either create or delete.
+func ObserveMachineProvisionJob(clusterID, driver, action string, success bool, duration time.Duration) {
+	if !prometheusMetrics {
+		return
+	}
+	result := PlannerResultSuccess
+	if !success {
+		result = PlannerResultFailure
+		machineProvisionJobFailures.With(prometheus.Labels{"cluster": clusterID, "driver": driver, "action": action}).Inc()
+	}
+	if duration > 0 {
+		machineProvisionJobDuration.With(prometheus.Labels{"cluster": clusterID, "driver": driver, "action": action, "result": result}).Observe(duration.Seconds())
+	}
+}
+
+type trackedOperation struct {
+	phase      string
+	finished   bool
+	start      time.Time
+	phaseStart time.Time
+}
+
+// operationTracker keeps the last observed phase of the operations of each cluster in memory. Operations that were
+// already running when they were first observed, e.g. after a restart, are counted once they finish but their durations
+// are not recorded.
+type operationTracker struct {
+	lock       sync.Mutex
+	operations map[string]*trackedOperation
+}
+
+func (t *operationTracker) observe(clusterID, operation, phase, result string, now time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.operations == nil {
+		t.operations = map[string]*trackedOperation{}
+	}
+	key := clusterID + "/" + operation
+	tracked, ok := t.operations[key]
+	if !ok {
+		// a finished operation was either already recorded before a restart or never observed running
+		t.operations[key] = &trackedOperation{phase: phase, finished: phase == "" || result != ""}
+		return
+	}
+	if tracked.phase == phase {
+		return
+	}
+
+	if !tracked.finished && !tracked.phaseStart.IsZero() {
+		plannerOperationPhaseDuration.With(prometheus.Labels{"cluster": clusterID, "operation": operation, "phase": tracked.phase}).Observe(now.Sub(tracked.phaseStart).Seconds())
+	}
+	if tracked.finished {
+		// a new run of the operation has started
+		tracked.start = now
+	}
+	tracked.phase = phase
+	tracked.phaseStart = now
+	tracked.finished = phase == "" || result != ""
+
+	if result == "" {
+		return
+	}
+	if !tracked.start.IsZero() {
+		plannerOperationDuration.With(prometheus.Labels{"cluster": clusterID, "operation": operation, "result": result}).Observe(now.Sub(tracked.start).Seconds())
+	}
+	plannerOperations.With(prometheus.Labels{"cluster": clusterID, "operation": operation, "result": result}).Inc()
+}
+
+// forget drops the operations of clusters that no longer exist.
+func (t *operationTracker) forget(exists func(clusterID string) bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for key := range t.operations {
+		clusterID, _, _ := strings.Cut(key, "/")
+		if !exists(clusterID) {
+			delete(t.operations, key)
+		}
+	}
+}
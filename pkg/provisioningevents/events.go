@@ -0,0 +1,174 @@
+// Package provisioningevents keeps a bounded, structured timeline of provisioning events per cluster. The events are
+// stored in a ConfigMap in the namespace of the management cluster, next to the free-text provisioning log.
+package provisioningevents
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	ConfigMapName = "provisioning-events"
+	// MaxEvents is the number of events kept per cluster. The oldest events are dropped once it is exceeded.
+	MaxEvents = 500
+
+	eventsKey = "events"
+	stateKey  = "state"
+)
+
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+var severityLevels = map[Severity]int{
+	SeverityInfo:    0,
+	SeverityWarning: 1,
+	SeverityError:   2,
+}
+
+// Source identifies the component an event was observed on.
+type Source string
+
+const (
+	SourcePlanner             Source = "planner"
+	SourceMachine             Source = "machine"
+	SourceDrain               Source = "drain"
+	SourceETCDSnapshotCreate  Source = "etcd-snapshot-create"
+	SourceETCDSnapshotRestore Source = "etcd-snapshot-restore"
+	SourceETCDMaintenance     Source = "etcd-maintenance"
+	SourceEncryptionKeys      Source = "rotate-encryption-keys"
+	SourceNodeMaintenance     Source = "node-maintenance"
+)
+
+type Event struct {
+	Time     metav1.Time `json:"time"`
+	Source   Source      `json:"source"`
+	Phase    string      `json:"phase,omitempty"`
+	Machine  string      `json:"machine,omitempty"`
+	Severity Severity    `json:"severity"`
+	Message  string      `json:"message,omitempty"`
+}
+
+// EventList is the response of the events link of a cluster.
+type EventList struct {
+	Data []Event `json:"data"`
+}
+
+// Append appends the events to the ring, dropping the oldest events if it exceeds MaxEvents.
+func Append(events []Event, newEvents ...Event) []Event {
+	events = append(events, newEvents...)
+	if len(events) > MaxEvents {
+		events = append([]Event(nil), events[len(events)-MaxEvents:]...)
+	}
+	return events
+}
+
+// Decode returns the events and the last observed state of every source stored in the ConfigMap.
+func Decode(cm *corev1.ConfigMap) ([]Event, map[string]string, error) {
+	var (
+		events []Event
+		state  = map[string]string{}
+	)
+	if cm == nil {
+		return events, state, nil
+	}
+	if data := cm.Data[eventsKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &events); err != nil {
+			return nil, nil, fmt.Errorf("decoding %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+	}
+	if data := cm.Data[stateKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return nil, nil, fmt.Errorf("decoding %s/%s: %w", cm.Namespace, cm.Name, err)
+		}
+	}
+	return events, state, nil
+}
+
+// Encode stores the events and state in the data of the ConfigMap.
+func Encode(cm *corev1.ConfigMap, events []Event, state map[string]string) error {
+	eventData, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	stateData, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[eventsKey] = string(eventData)
+	cm.Data[stateKey] = string(stateData)
+	return nil
+}
+
+// Filter selects events. Empty fields match every event.
+type Filter struct {
+	Source  Source
+	Phase   string
+	Machine string
+	// Severity is the minimum severity of the events.
+	Severity Severity
+	Since    time.Time
+	// Limit returns only the most recent events.
+	Limit int
+}
+
+// FilterFromQuery parses a filter from the source, phase, machine, severity, since (RFC3339) and limit query
+// parameters.
+func FilterFromQuery(query url.Values) (Filter, error) {
+	f := Filter{
+		Source:   Source(query.Get("source")),
+		Phase:    query.Get("phase"),
+		Machine:  query.Get("machine"),
+		Severity: Severity(query.Get("severity")),
+	}
+	if _, ok := severityLevels[f.Severity]; f.Severity != "" && !ok {
+		return f, fmt.Errorf("invalid severity %q", f.Severity)
+	}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return f, fmt.Errorf("invalid since %q: %w", since, err)
+		}
+		f.Since = t
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 0 {
+			return f, fmt.Errorf("invalid limit %q", limit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// Apply returns the events matching the filter, oldest first.
+func (f Filter) Apply(events []Event) []Event {
+	result := []Event{}
+	for _, event := range events {
+		if f.Source != "" && event.Source != f.Source ||
+			f.Phase != "" && event.Phase != f.Phase ||
+			f.Machine != "" && event.Machine != f.Machine ||
+			f.Severity != "" && severityLevels[event.Severity] < severityLevels[f.Severity] ||
+			!f.Since.IsZero() && event.Time.Time.Before(f.Since) {
+			continue
+		}
+		result = append(result, event)
+	}
+	if f.Limit > 0 && len(result) > f.Limit {
+		result = result[len(result)-f.Limit:]
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package provisioningevents
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAppend(t *testing.T) {
+	var events []Event
+	for i := 0; i < MaxEvents+10; i++ {
+		events = Append(events, Event{Message: fmt.Sprint(i)})
+	}
+	assert.Len(t, events, MaxEvents)
+	assert.Equal(t, "10", events[0].Message)
+	assert.Equal(t, fmt.Sprint(MaxEvents+9), events[MaxEvents-1].Message)
+}
+
+func TestEncodeDecode(t *testing.T) {
+	cm := &corev1.ConfigMap{}
+	events := []Event{{Time: metav1.NewTime(time.Unix(100, 0)), Source: SourceMachine, Phase: "Running", Machine: "m1", Severity: SeverityInfo}}
+	state := map[string]string{"machine/m1": "Running"}
+	assert.NoError(t, Encode(cm, events, state))
+
+	decodedEvents, decodedState, err := Decode(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, state, decodedState)
+	if assert.Len(t, decodedEvents, 1) {
+		assert.True(t, events[0].Time.Equal(&decodedEvents[0].Time))
+		assert.Equal(t, "m1", decodedEvents[0].Machine)
+	}
+
+	cm.Data[eventsKey] = "{"
+	_, _, err = Decode(cm)
+	assert.Error(t, err)
+}
+
+func TestObserve(t *testing.T) {
+	now := metav1.NewTime(time.Unix(100, 0))
+	state := map[string]string{}
+	var events []Event
+
+	running := Observation{Key: "machine/m1", Value: "Running", Event: Event{Source: SourceMachine, Phase: "Running"}}
+	assert.True(t, observe(&events, state, now, running))
+	assert.False(t, observe(&events, state, now, running))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, now, events[0].Time)
+		assert.Equal(t, SeverityInfo, events[0].Severity)
+	}
+
+	// observations without a source only update the state
+	assert.True(t, observe(&events, state, now, Observation{Key: "machine/m1"}))
+	assert.Empty(t, state)
+	assert.Len(t, events, 1)
+}
+
+func TestFilter(t *testing.T) {
+	base := time.Unix(1000, 0)
+	events := []Event{
+		{Time: metav1.NewTime(base), Source: SourcePlanner, Phase: "Provisioning", Severity: SeverityInfo},
+		{Time: metav1.NewTime(base.Add(time.Minute)), Source: SourceMachine, Phase: "Failed", Machine: "m1", Severity: SeverityError},
+		{Time: metav1.NewTime(base.Add(2 * time.Minute)), Source: SourceDrain, Phase: "Error", Machine: "m1", Severity: SeverityWarning},
+		{Time: metav1.NewTime(base.Add(3 * time.Minute)), Source: SourceMachine, Phase: "Running", Machine: "m2", Severity: SeverityInfo},
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []Event
+		err      bool
+	}{
+		{name: "no filter", query: "", expected: events},
+		{name: "source", query: "source=machine", expected: []Event{events[1], events[3]}},
+		{name: "machine and phase", query: "machine=m1&phase=Error", expected: []Event{events[2]}},
+		{name: "minimum severity", query: "severity=warning", expected: []Event{events[1], events[2]}},
+		{name: "since", query: "since=" + url.QueryEscape(base.Add(2*time.Minute).Format(time.RFC3339)), expected: []Event{events[2], events[3]}},
+		{name: "limit keeps the most recent events", query: "limit=1", expected: []Event{events[3]}},
+		{name: "no match", query: "machine=m3", expected: []Event{}},
+		{name: "invalid severity", query: "severity=fatal", err: true},
+		{name: "invalid since", query: "since=yesterday", err: true},
+		{name: "invalid limit", query: "limit=-1", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.query)
+			assert.NoError(t, err)
+			filter, err := FilterFromQuery(query)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, filter.Apply(events))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package provisioningevents
+
+import (
+	corev1controllers "github.com/rancher/wrangler/v3/pkg/generated/controllers/core/v1"
+	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Observation is the current state of something that is tracked for a cluster, such as the phase of a machine. Its
+// event is recorded whenever the value of its key changes. Observations without an event source only update the state,
+// and an empty value removes the key from the state.
+type Observation struct {
+	Key   string
+	Value string
+	Event Event
+}
+
+// Recorder records the events of observations in the provisioning events ConfigMap of the cluster.
+type Recorder struct {
+	configMapsCache corev1controllers.ConfigMapCache
+	configMaps      corev1controllers.ConfigMapClient
+}
+
+func NewRecorder(configMaps corev1controllers.ConfigMapController) *Recorder {
+	return &Recorder{
+		configMapsCache: configMaps.Cache(),
+		configMaps:      configMaps,
+	}
+}
+
+// Record records the events of the observations that changed since they were last recorded in the given namespace.
+// Conflicting updates are returned as errors, so the calling handler is retried with the latest state.
+func (r *Recorder) Record(namespace string, observations ...Observation) error {
+	cm, err := r.configMapsCache.Get(namespace, ConfigMapName)
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      ConfigMapName,
+				Namespace: namespace,
+			},
+		}
+	} else if err != nil {
+		return err
+	}
+
+	events, state, err := Decode(cm)
+	if err != nil {
+		logrus.Warnf("[provisioningevents] discarding provisioning events: %v", err)
+		events, state = nil, map[string]string{}
+	}
+
+	if !observe(&events, state, metav1.Now(), observations...) {
+		return nil
+	}
+
+	cm = cm.DeepCopy()
+	if err := Encode(cm, events, state); err != nil {
+		return err
+	}
+	if cm.ResourceVersion == "" {
+		_, err = r.configMaps.Create(cm)
+	} else {
+		_, err = r.configMaps.Update(cm)
+	}
+	return err
+}
+
+// observe applies the observations to the state and appends the events of the observations that changed it. It returns
+// true if the state changed.
+func observe(events *[]Event, state map[string]string, now metav1.Time, observations ...Observation) bool {
+	changed := false
+	for _, o := range observations {
+		if state[o.Key] == o.Value {
+			continue
+		}
+		if o.Value == "" {
+			delete(state, o.Key)
+		} else {
+			state[o.Key] = o.Value
+		}
+		if o.Event.Source != "" {
+			if o.Event.Time.IsZero() {
+				o.Event.Time = now
+			}
+			if o.Event.Severity == "" {
+				o.Event.Severity = SeverityInfo
+			}
+			*events = Append(*events, o.Event)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// Forget removes the keys that should not be kept from the state stored in the given namespace, e.g. the keys of
+// machines that no longer exist. Their events are kept until they drop out of the ring.
+func (r *Recorder) Forget(namespace string, keep func(key string) bool) error {
+	cm, err := r.configMapsCache.Get(namespace, ConfigMapName)
+	if apierrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	events, state, err := Decode(cm)
+	if err != nil {
+		// the next recorded observation resets the ConfigMap
+		return nil
+	}
+
+	var observations []Observation
+	for key := range state {
+		if !keep(key) {
+			observations = append(observations, Observation{Key: key})
+		}
+	}
+	if !observe(&events, state, metav1.Now(), observations...) {
+		return nil
+	}
+
+	cm = cm.DeepCopy()
+	if err := Encode(cm, events, state); err != nil {
+		return err
+	}
+	_, err = r.configMaps.Update(cm)
+	return err
+}
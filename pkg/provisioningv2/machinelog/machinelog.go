@@ -0,0 +1,146 @@
+// Package machinelog stores the output of the jobs that create and delete the infrastructure of node driver machines,
+// so that it remains available after the jobs have been cleaned up.
+package machinelog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/rancher/wrangler/v3/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Label is set on the secrets holding provisioning logs, with the name of the infrastructure machine as value.
+	Label = "rke.cattle.io/machine-provision-log"
+	// MaxAttempts is the number of job attempts for which logs are kept per machine.
+	MaxAttempts = 5
+	// MaxLogBytes is the maximum size of the log kept for a single attempt. Longer logs are truncated from the start,
+	// as the end of the log is where failures are reported.
+	MaxLogBytes = 256 * 1024
+
+	dataKey = "attempts.json.gz"
+)
+
+// Attempt is the log of a single pod run by a machine provisioning job.
+type Attempt struct {
+	JobName string `json:"jobName,omitempty"`
+	PodName string `json:"podName,omitempty"`
+	PodUID  string `json:"podUID,omitempty"`
+	// Operation is either "create" or "delete".
+	Operation  string       `json:"operation,omitempty"`
+	StartTime  *metav1.Time `json:"startTime,omitempty"`
+	FinishTime *metav1.Time `json:"finishTime,omitempty"`
+	Succeeded  bool         `json:"succeeded"`
+	Truncated  bool         `json:"truncated,omitempty"`
+	Log        string       `json:"log,omitempty"`
+}
+
+// SecretName returns the name of the secret holding the provisioning logs of the given infrastructure machine.
+func SecretName(infraMachineName string) string {
+	return name.SafeConcatName(infraMachineName, "provision", "log")
+}
+
+// NewAttempt returns an attempt with the given log, truncated to MaxLogBytes.
+func NewAttempt(jobName, operation string, pod *corev1.Pod, log []byte) Attempt {
+	attempt := Attempt{
+		JobName:   jobName,
+		PodName:   pod.Name,
+		PodUID:    string(pod.UID),
+		Operation: operation,
+		StartTime: pod.Status.StartTime,
+		Succeeded: pod.Status.Phase == corev1.PodSucceeded,
+	}
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			finishTime := terminated.FinishedAt
+			attempt.FinishTime = &finishTime
+		}
+	}
+	if len(log) > MaxLogBytes {
+		log = log[len(log)-MaxLogBytes:]
+		attempt.Truncated = true
+	}
+	attempt.Log = string(log)
+	return attempt
+}
+
+// Add adds the attempt to the attempts, replacing an existing attempt of the same pod, and drops the oldest attempts
+// beyond MaxAttempts. Attempts are ordered by start time.
+func Add(attempts []Attempt, attempt Attempt) []Attempt {
+	result := make([]Attempt, 0, len(attempts)+1)
+	for _, existing := range attempts {
+		if existing.PodUID != attempt.PodUID {
+			result = append(result, existing)
+		}
+	}
+	result = append(result, attempt)
+	sort.SliceStable(result, func(i, j int) bool {
+		return startTime(result[i]).Before(startTime(result[j]))
+	})
+	if len(result) > MaxAttempts {
+		result = result[len(result)-MaxAttempts:]
+	}
+	return result
+}
+
+// Has returns true if the attempts contain an attempt for the given pod.
+func Has(attempts []Attempt, podUID string) bool {
+	for _, attempt := range attempts {
+		if attempt.PodUID == podUID {
+			return true
+		}
+	}
+	return false
+}
+
+func startTime(attempt Attempt) time.Time {
+	if attempt.StartTime == nil {
+		return time.Time{}
+	}
+	return attempt.StartTime.Time
+}
+
+// Encode sets the attempts as the compressed data of the secret.
+func Encode(secret *corev1.Secret, attempts []Attempt) error {
+	b, err := json.Marshal(attempts)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	secret.Data = map[string][]byte{
+		dataKey: buf.Bytes(),
+	}
+	return nil
+}
+
+// Decode returns the attempts stored in the secret.
+func Decode(secret *corev1.Secret) ([]Attempt, error) {
+	data := secret.Data[dataKey]
+	if len(data) == 0 {
+		return nil, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	b, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+	var attempts []Attempt
+	return attempts, json.Unmarshal(b, &attempts)
+}
@@ -0,0 +1,76 @@
+package machinelog
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func attempt(uid string, minute int) Attempt {
+	start := metav1.NewTime(time.Date(2024, 1, 1, 0, minute, 0, 0, time.UTC).Local())
+	return Attempt{PodUID: uid, StartTime: &start}
+}
+
+func TestAdd(t *testing.T) {
+	var attempts []Attempt
+	for i := 0; i < MaxAttempts+2; i++ {
+		attempts = Add(attempts, attempt(string(rune('a'+i)), i))
+	}
+	require.Len(t, attempts, MaxAttempts)
+	assert.Equal(t, "c", attempts[0].PodUID)
+	assert.Equal(t, "g", attempts[MaxAttempts-1].PodUID)
+
+	replaced := attempt("e", 4)
+	replaced.Log = "replaced"
+	attempts = Add(attempts, replaced)
+	require.Len(t, attempts, MaxAttempts)
+	assert.Equal(t, "replaced", attempts[2].Log)
+	assert.True(t, Has(attempts, "e"))
+	assert.False(t, Has(attempts, "a"))
+}
+
+func TestNewAttempt(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", UID: types.UID("uid")},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+			}},
+		},
+	}
+
+	a := NewAttempt("job", "create", pod, []byte("short"))
+	assert.False(t, a.Succeeded)
+	assert.False(t, a.Truncated)
+	assert.NotNil(t, a.FinishTime)
+	assert.Equal(t, "short", a.Log)
+
+	log := strings.Repeat("x", MaxLogBytes) + "end"
+	a = NewAttempt("job", "create", pod, []byte(log))
+	assert.True(t, a.Truncated)
+	assert.Len(t, a.Log, MaxLogBytes)
+	assert.True(t, strings.HasSuffix(a.Log, "end"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	secret := &corev1.Secret{}
+	attempts, err := Decode(secret)
+	require.NoError(t, err)
+	assert.Empty(t, attempts)
+
+	expected := []Attempt{attempt("a", 1), attempt("b", 2)}
+	expected[1].Log = strings.Repeat("line\n", 1000)
+	require.NoError(t, Encode(secret, expected))
+	assert.Less(t, len(secret.Data[dataKey]), len(expected[1].Log))
+
+	attempts, err = Decode(secret)
+	require.NoError(t, err)
+	assert.Equal(t, expected, attempts)
+}
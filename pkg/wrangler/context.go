@@ -371,14 +371,15 @@ func NewContext(ctx context.Context, clientConfig clientcmd.ClientConfig, restCo
 		k8s.Discovery(),
 		core.Core().V1().ConfigMap().Cache(),
 		core.Core().V1().Secret().Cache(),
-		helm.Catalog().V1().ClusterRepo().Cache())
+		helm.Catalog().V1().ClusterRepo())
 
 	helmop := helmop.NewOperations(cg,
 		helm.Catalog().V1(),
 		rbac.Rbac().V1(),
 		content,
 		core.Core().V1().Pod(),
-		core.Core().V1().Node())
+		core.Core().V1().Node(),
+		core.Core().V1().Secret())
 
 	cache := memory.NewMemCacheClient(k8s.Discovery())
 	restMapper := restmapper.NewDeferredDiscoveryRESTMapper(cache)